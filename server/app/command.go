@@ -0,0 +1,110 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/backup"
+	"bbs-go/config"
+)
+
+// RunCommand 执行子命令，全局参数需要写在子命令之前，例如：
+//
+//	./bbs-go -config ./bbs-go.yaml backup -o ./backup.zip
+//	./bbs-go -config ./bbs-go.yaml restore -i ./backup.zip -dialect postgres -url "host=127.0.0.1 user=bbsgo dbname=bbsgo sslmode=disable"
+func RunCommand(args []string) {
+	var err error
+	switch args[0] {
+	case "backup":
+		err = runBackup(args[1:])
+	case "restore":
+		err = runRestore(args[1:])
+	default:
+		err = fmt.Errorf("未知命令：%s，支持的命令：backup、restore", args[0])
+	}
+	if err != nil {
+		logrus.Error(err)
+		os.Exit(1)
+	}
+}
+
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	var (
+		output      = fs.String("o", "", "备份文件路径，默认为 bbs-go-backup-{时间}.zip")
+		dialect     = fs.String("dialect", "", "数据库类型：mysql、postgres、sqlite3，默认使用配置文件中的MySQL")
+		url         = fs.String("url", "", "数据库连接地址，与 -dialect 一起使用")
+		skipUploads = fs.Bool("skip-uploads", false, "不备份本地上传文件")
+	)
+	_ = fs.Parse(args)
+
+	db, err := openCommandDB(*dialect, *url)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if len(*output) == 0 {
+		*output = "bbs-go-backup-" + time.Now().Format("20060102150405") + ".zip"
+	}
+	if err := backup.Backup(db, *output, commandUploadsPath(*skipUploads)); err != nil {
+		return err
+	}
+	logrus.Info("备份完成：", *output)
+	return nil
+}
+
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	var (
+		input       = fs.String("i", "", "备份文件路径")
+		dialect     = fs.String("dialect", "", "数据库类型：mysql、postgres、sqlite3，默认使用配置文件中的MySQL")
+		url         = fs.String("url", "", "数据库连接地址，与 -dialect 一起使用")
+		skipUploads = fs.Bool("skip-uploads", false, "不恢复本地上传文件")
+	)
+	_ = fs.Parse(args)
+	if len(*input) == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db, err := openCommandDB(*dialect, *url)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := backup.Restore(db, *input, commandUploadsPath(*skipUploads)); err != nil {
+		return err
+	}
+	logrus.Info("恢复完成：", *input)
+	return nil
+}
+
+// openCommandDB 未指定数据库时使用配置文件中的MySQL
+func openCommandDB(dialect, url string) (*gorm.DB, error) {
+	if len(dialect) == 0 && len(url) == 0 {
+		dialect, url = "mysql", config.Instance.MySqlUrl
+	} else if len(dialect) == 0 || len(url) == 0 {
+		return nil, fmt.Errorf("-dialect 和 -url 需要同时指定")
+	}
+	db, err := gorm.Open(dialect, url)
+	if err != nil {
+		return nil, err
+	}
+	db.LogMode(config.Instance.ShowSql)
+	return db, nil
+}
+
+// commandUploadsPath 仅本地上传方式需要备份上传文件，阿里云OSS中的文件请使用OSS自身的备份功能
+func commandUploadsPath(skip bool) string {
+	if skip || config.Instance.Uploader.Enable != "local" {
+		return ""
+	}
+	return config.Instance.Uploader.Local.Path
+}
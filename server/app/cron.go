@@ -1,13 +1,24 @@
 package app
 
 import (
+	"sync/atomic"
+	"time"
+
 	"github.com/robfig/cron"
 	"github.com/sirupsen/logrus"
 
+	"bbs-go/common/email"
+	"bbs-go/config"
+	"bbs-go/model/constants"
 	"bbs-go/services"
 	"bbs-go/sitemap"
 )
 
+var (
+	scheduler   *cron.Cron
+	cronRunning int64 // 执行中的定时任务数
+)
+
 func startSchedule() {
 	c := cron.New()
 
@@ -18,16 +29,71 @@ func startSchedule() {
 		services.ProjectService.GenerateRss()
 	})
 
-	// Generate sitemap
-	addCronFunc(c, "@every 2h", func() {
-		sitemap.Generate()
+	// Webhook重试
+	addCronFunc(c, "@every 1m", func() {
+		services.WebhookDeliveryService.RetryPending()
+	})
+
+	// 摘要邮件：每天8点发送每日摘要，每周一8点发送每周摘要
+	addCronFunc(c, "0 0 8 * * *", func() {
+		enqueueDigest(constants.NotifyModeDaily)
 	})
+	addCronFunc(c, "0 0 8 * * 1", func() {
+		enqueueDigest(constants.NotifyModeWeekly)
+	})
+
+	// 清理过期的用户数据导出
+	addCronFunc(c, "@every 1h", func() {
+		services.UserExportService.CleanExpired()
+	})
+
+	// 执行冷静期结束的注销账号申请
+	addCronFunc(c, "@every 1h", func() {
+		services.UserDeletionService.EnqueueDue()
+	})
+
+	// Generate sitemap
+	if config.Instance.Sitemap.Enabled {
+		addCronFunc(c, "@every 2h", func() {
+			sitemap.Generate()
+		})
+	}
 
 	c.Start()
+	scheduler = c
+}
+
+// stopSchedule 停止定时任务，并等待执行中的定时任务完成，超时后返回false
+func stopSchedule(timeout time.Duration) bool {
+	if scheduler == nil {
+		return true
+	}
+	scheduler.Stop()
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&cronRunning) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+func enqueueDigest(period string) {
+	if !email.Enabled() {
+		return
+	}
+	if _, err := services.JobService.Enqueue(constants.JobTypeDigest, period); err != nil {
+		logrus.Error(err)
+	}
 }
 
 func addCronFunc(c *cron.Cron, sepc string, cmd func()) {
-	err := c.AddFunc(sepc, cmd)
+	err := c.AddFunc(sepc, func() {
+		atomic.AddInt64(&cronRunning, 1)
+		defer atomic.AddInt64(&cronRunning, -1)
+		cmd()
+	})
 	if err != nil {
 		logrus.Error(err)
 	}
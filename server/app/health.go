@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/common/email"
+	"bbs-go/common/uploader"
+	"bbs-go/config"
+)
+
+// 单项检查的超时时间
+const healthCheckTimeout = 3 * time.Second
+
+// 收到停止信号后置为1，/readyz 返回503
+var shuttingDown int32
+
+// healthz 存活检查，进程能够处理请求即返回成功
+func healthz(ctx iris.Context) {
+	_, _ = ctx.JSON(iris.Map{"status": "ok"})
+}
+
+// readyz 就绪检查，检查数据库、上传存储，开启 Health.CheckSmtp 时检查SMTP服务器；任意一项失败或正在停机时返回503
+func readyz(ctx iris.Context) {
+	if atomic.LoadInt32(&shuttingDown) == 1 {
+		ctx.StatusCode(iris.StatusServiceUnavailable)
+		_, _ = ctx.JSON(iris.Map{"status": "shutting down"})
+		return
+	}
+
+	checks := map[string]func() error{
+		"db":       checkDB,
+		"uploader": uploader.Ping,
+	}
+	if config.Instance.Health.CheckSmtp && email.IsSmtp() {
+		checks["smtp"] = checkSmtp
+	}
+	var (
+		results = make(map[string]string, len(checks))
+		ok      = true
+	)
+	for name, check := range checks {
+		if err := runHealthCheck(check); err != nil {
+			results[name] = err.Error()
+			ok = false
+		} else {
+			results[name] = "ok"
+		}
+	}
+	status := "ok"
+	if !ok {
+		status = "fail"
+		ctx.StatusCode(iris.StatusServiceUnavailable)
+	}
+	_, _ = ctx.JSON(iris.Map{"status": status, "checks": results})
+}
+
+// runHealthCheck 执行检查，超时后不再等待
+func runHealthCheck(check func() error) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- check()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(healthCheckTimeout):
+		return errors.New("timeout")
+	}
+}
+
+func checkDB() error {
+	c, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	return simple.DB().DB().PingContext(c)
+}
+
+func checkSmtp() error {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(config.Instance.Smtp.Host, config.Instance.Smtp.Port), healthCheckTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -4,16 +4,13 @@ import (
 	"context"
 	"net/http"
 	"os"
-	"os/signal"
 	"strings"
-	"syscall"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/graphql-go/graphql"
 
 	"github.com/iris-contrib/middleware/cors"
 	"github.com/kataras/iris/v12"
-	"github.com/kataras/iris/v12/middleware/logger"
 	"github.com/kataras/iris/v12/middleware/recover"
 	"github.com/kataras/iris/v12/mvc"
 	"github.com/mlogclub/simple"
@@ -32,7 +29,8 @@ func InitIris() {
 	app := iris.New()
 	app.Logger().SetLevel("warn")
 	app.Use(recover.New())
-	app.Use(logger.New())
+	app.Use(middleware.RequestContext)
+	app.Use(middleware.Metrics)
 	app.Use(cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"}, // allows everything, use that to change the hosts.
 		AllowCredentials: true,
@@ -41,6 +39,8 @@ func InitIris() {
 		AllowedHeaders:   []string{"*"},
 	}))
 	app.AllowMethods(iris.MethodOptions)
+	app.Use(middleware.ApiTokenAuth)
+	app.Use(middleware.RateLimit)
 
 	app.OnAnyErrorCode(func(ctx iris.Context) {
 		path := ctx.Path()
@@ -56,6 +56,10 @@ func InitIris() {
 	app.Any("/", func(i iris.Context) {
 		_, _ = i.HTML("<h1>Powered by bbs-go</h1>")
 	})
+	app.Get("/healthz", healthz)
+	app.Get("/readyz", readyz)
+
+	initMetrics(app)
 
 	graph.InitTopicType()
 
@@ -67,9 +71,13 @@ func InitIris() {
 		m.Party("/project").Handle(new(api.ProjectController))
 		m.Party("/login").Handle(new(api.LoginController))
 		m.Party("/user").Handle(new(api.UserController))
+		m.Party("/user/api-tokens").Handle(new(api.ApiTokenController))
+		m.Party("/user/third-accounts").Handle(new(api.ThirdAccountController))
+		m.Party("/notification").Handle(new(api.NotificationController))
 		m.Party("/tag").Handle(new(api.TagController))
 		m.Party("/comment").Handle(new(api.CommentController))
 		m.Party("/favorite").Handle(new(api.FavoriteController))
+		m.Party("/reaction").Handle(new(api.ReactionController))
 		m.Party("/config").Handle(new(api.ConfigController))
 		m.Party("/upload").Handle(new(api.UploadController))
 		m.Party("/link").Handle(new(api.LinkController))
@@ -96,6 +104,23 @@ func InitIris() {
 		m.Party("/user-score").Handle(new(admin.UserScoreController))
 		m.Party("/user-score-log").Handle(new(admin.UserScoreLogController))
 		m.Party("/operate-log").Handle(new(admin.OperateLogController))
+		m.Party("/webhook").Handle(new(admin.WebhookController))
+		m.Party("/job").Handle(new(admin.JobController))
+		m.Party("/email-template").Handle(new(admin.EmailTemplateController))
+		m.Party("/email-outbox").Handle(new(admin.EmailOutboxController))
+	})
+
+	// feed
+	mvc.Configure(app.Party("/feed"), func(m *mvc.Application) {
+		m.Handle(new(api.FeedController))
+	})
+
+	// activitypub
+	mvc.Configure(app.Party("/ap"), func(m *mvc.Application) {
+		m.Handle(new(api.ActivityPubController))
+	})
+	mvc.Configure(app.Party("/.well-known"), func(m *mvc.Application) {
+		m.Handle(new(api.WellKnownController))
 	})
 
 	app.Get("/api/img/proxy", func(i iris.Context) {
@@ -138,10 +163,10 @@ func InitIris() {
 	}
 
 	server := &http.Server{Addr: ":" + config.Instance.Port}
-	handleSignal(server)
-	err := app.Run(iris.Server(server), iris.WithConfiguration(iris.Configuration{
+	done := handleSignal(server)
+	err := app.Run(iris.Server(server), iris.WithoutServerError(iris.ErrServerClosed), iris.WithConfiguration(iris.Configuration{
 		DisableStartupLog:                 false,
-		DisableInterruptHandler:           false,
+		DisableInterruptHandler:           true, // 由 handleSignal 处理停止信号
 		DisablePathCorrection:             false,
 		EnablePathEscape:                  false,
 		FireMethodNotAllowed:              false,
@@ -155,22 +180,5 @@ func InitIris() {
 		logrus.Error(err)
 		os.Exit(-1)
 	}
-}
-
-func handleSignal(server *http.Server) {
-	c := make(chan os.Signal)
-	signal.Notify(c, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
-
-	go func() {
-		s := <-c
-		logrus.Infof("got signal [%s], exiting now", s)
-		if err := server.Close(); nil != err {
-			logrus.Errorf("server close failed: " + err.Error())
-		}
-
-		simple.CloseDB()
-
-		logrus.Infof("Exited")
-		os.Exit(0)
-	}()
+	<-done
 }
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/cache"
+	"bbs-go/common/event"
+	"bbs-go/common/metrics"
+	"bbs-go/config"
+	"bbs-go/middleware"
+	"bbs-go/model/constants"
+	"bbs-go/services"
+)
+
+// 单独监听的监控指标服务，未配置 Metrics.Addr 时为空
+var metricsServer *http.Server
+
+// initMetrics 注册状态类指标；配置了 Metrics.Addr 时在该地址单独提供 /metrics，否则在站点端口提供并要求令牌
+func initMetrics(app *iris.Application) {
+	conf := config.Instance.Metrics
+	if !conf.Enabled {
+		return
+	}
+	registerMetricsCollectors()
+
+	if len(conf.Addr) == 0 {
+		if len(conf.Token) == 0 {
+			logrus.Warn("Metrics.Token 和 Metrics.Addr 均未配置，不提供 /metrics")
+			return
+		}
+		app.Get(middleware.MetricsPath, middleware.MetricsHandler)
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(middleware.MetricsPath, func(w http.ResponseWriter, r *http.Request) {
+		if !middleware.MetricsTokenValid(r.Header.Get("Authorization")) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = metrics.WriteTo(w)
+	})
+	metricsServer = &http.Server{Addr: conf.Addr, Handler: mux}
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logrus.Error("metrics server error: ", err)
+		}
+	}()
+}
+
+func registerMetricsCollectors() {
+	metrics.NewGaugeFunc("bbs_db_connections", "数据库连接数，state：open、in_use、idle",
+		[]string{"state"}, func() []metrics.Sample {
+			stats := simple.DB().DB().Stats()
+			return []metrics.Sample{
+				{Labels: []string{"open"}, Value: float64(stats.OpenConnections)},
+				{Labels: []string{"in_use"}, Value: float64(stats.InUse)},
+				{Labels: []string{"idle"}, Value: float64(stats.Idle)},
+			}
+		})
+	metrics.NewCounterFunc("bbs_db_wait_total", "等待数据库连接的次数", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(simple.DB().DB().Stats().WaitCount)}}
+	})
+	metrics.NewCounterFunc("bbs_db_wait_seconds_total", "等待数据库连接的总耗时", nil, func() []metrics.Sample {
+		return []metrics.Sample{{Value: simple.DB().DB().Stats().WaitDuration.Seconds()}}
+	})
+
+	// 站内消息、邮件等都通过后台任务队列处理
+	metrics.NewGaugeFunc("bbs_job_queue_depth", "后台任务队列中的任务数，status：pending、running",
+		[]string{"type", "status"}, func() []metrics.Sample {
+			stats, err := services.JobService.QueueStats()
+			if err != nil {
+				logrus.Error(err)
+				return nil
+			}
+			var samples []metrics.Sample
+			for _, stat := range stats {
+				status := "pending"
+				if stat.Status == constants.JobStatusRunning {
+					status = "running"
+				}
+				samples = append(samples, metrics.Sample{Labels: []string{stat.Type, status}, Value: float64(stat.Count)})
+			}
+			return samples
+		})
+
+	metrics.NewCounterFunc("bbs_event_queue_overflow_total", "异步事件队列已满时在新协程中处理的事件数", nil,
+		func() []metrics.Sample {
+			return []metrics.Sample{{Value: float64(event.Overflow())}}
+		})
+
+	metrics.NewCounterFunc("bbs_cache_requests_total", "缓存访问次数，result：hit、miss",
+		[]string{"cache", "result"}, func() []metrics.Sample {
+			var samples []metrics.Sample
+			for name, stats := range cache.Stats() {
+				samples = append(samples,
+					metrics.Sample{Labels: []string{name, "hit"}, Value: float64(stats.HitCount)},
+					metrics.Sample{Labels: []string{name, "miss"}, Value: float64(stats.MissCount)})
+			}
+			return samples
+		})
+	metrics.NewGaugeFunc("bbs_cache_hit_ratio", "缓存命中率", []string{"cache"}, func() []metrics.Sample {
+		var samples []metrics.Sample
+		for name, stats := range cache.Stats() {
+			samples = append(samples, metrics.Sample{Labels: []string{name}, Value: stats.HitRate()})
+		}
+		return samples
+	})
+}
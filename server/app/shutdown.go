@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/event"
+	"bbs-go/common/tracing"
+	"bbs-go/config"
+	"bbs-go/services"
+)
+
+// handleSignal 收到停止信号后优雅停机，返回的 channel 在停机完成后关闭
+func handleSignal(server *http.Server) <-chan struct{} {
+	done := make(chan struct{})
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+
+	go func() {
+		s := <-c
+		logrus.Infof("got signal [%s], shutting down", s)
+		shutdown(server)
+		logrus.Infof("Exited")
+		close(done)
+	}()
+	return done
+}
+
+// shutdown 依次执行：/readyz 返回503并等待 Shutdown.Delay、停止接收请求并等待执行中的请求、停止定时任务、
+// 处理完内存中的异步事件、等待执行中的后台任务、导出剩余的 span，最后关闭数据库；除 Delay 外各步骤共用 Shutdown.Timeout。
+// 站内消息、邮件等都保存在后台任务表中，未执行的任务在重新启动后继续执行
+func shutdown(server *http.Server) {
+	atomic.StoreInt32(&shuttingDown, 1)
+	if delay := config.Instance.Shutdown.Delay; delay > 0 {
+		time.Sleep(time.Duration(delay) * time.Second)
+	}
+
+	timeout := config.Instance.Shutdown.Timeout
+	if timeout <= 0 {
+		timeout = 30
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Second)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logrus.Warn("http server did not finish in time: ", err)
+		_ = server.Close()
+	}
+	if metricsServer != nil {
+		_ = metricsServer.Shutdown(ctx)
+	}
+	if !stopSchedule(time.Until(deadline)) {
+		logrus.Warn("cron jobs did not finish in time")
+	}
+	if !event.Wait(time.Until(deadline)) {
+		logrus.Warn("async events did not finish in time")
+	}
+	if !services.JobService.Shutdown(time.Until(deadline)) {
+		logrus.Warn("job workers did not finish in time")
+	}
+	if !tracing.Shutdown(time.Until(deadline)) {
+		logrus.Warn("spans were not exported in time")
+	}
+
+	simple.CloseDB()
+}
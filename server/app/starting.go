@@ -2,9 +2,16 @@ package app
 
 import (
 	"bbs-go/common"
+	"bbs-go/services"
 )
 
 func StartOn() {
+	// 开启链路追踪
+	initTracing()
+
+	// 开启后台任务
+	services.JobService.Start()
+
 	if !common.IsProd() {
 		return
 	}
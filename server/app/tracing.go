@@ -0,0 +1,30 @@
+package app
+
+import (
+	"github.com/mlogclub/simple"
+
+	"bbs-go/common/tracing"
+	"bbs-go/config"
+)
+
+// initTracing 开启链路追踪，并为数据库操作注册回调
+func initTracing() {
+	conf := config.Instance.Tracing
+	if !conf.Enabled {
+		return
+	}
+	endpoint := conf.Endpoint
+	if len(endpoint) == 0 {
+		endpoint = "http://127.0.0.1:4318/v1/traces"
+	}
+	serviceName := conf.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = "bbs-go"
+	}
+	tracing.Init(tracing.Options{
+		ServiceName: serviceName,
+		SampleRatio: conf.SampleRatio,
+		Exporter:    tracing.NewOtlpExporter(endpoint, conf.Headers, serviceName),
+	})
+	tracing.RegisterGormCallbacks(simple.DB())
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/model"
+)
+
+// 备份文件为zip压缩包，结构如下：
+//
+//	manifest.json：格式、版本、数据表及行数
+//	tables/{表名}.jsonl：每行一条记录，键为数据库列名
+//	uploads/：本地上传目录下的文件
+const (
+	Format    = "bbs-go-backup"
+	Version   = 1 // 备份格式版本，格式不兼容时递增
+	batchSize = 1000
+
+	manifestName = "manifest.json"
+	tablesDir    = "tables/"
+	uploadsDir   = "uploads/"
+)
+
+type Manifest struct {
+	Format     string      `json:"format"`
+	Version    int         `json:"version"`
+	Dialect    string      `json:"dialect"`    // 备份时的数据库类型
+	CreateTime int64       `json:"createTime"` // 备份时间
+	Tables     []TableInfo `json:"tables"`
+	Uploads    int         `json:"uploads"` // 上传文件数量
+}
+
+type TableInfo struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+// Backup 备份 model.Models 中的所有数据表，uploadsPath 不为空时同时备份该目录下的上传文件；
+// 先写入临时文件，完成后再重命名，避免留下不完整的备份。
+// 所有数据表在同一个可重复读的只读事务中读取，备份期间站点可以正常写入，备份的各表数据是同一时刻的快照
+func Backup(db *gorm.DB, filename, uploadsPath string) (err error) {
+	tmp := filename + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	zw := zip.NewWriter(file)
+	manifest := &Manifest{
+		Format:     Format,
+		Version:    Version,
+		Dialect:    db.Dialect().GetName(),
+		CreateTime: simple.NowTimestamp(),
+	}
+	tx := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err = tx.Error; err != nil {
+		_ = file.Close()
+		return err
+	}
+	for _, m := range model.Models {
+		info, err := dumpTable(tx, zw, m)
+		if err != nil {
+			tx.Rollback()
+			_ = file.Close()
+			return errors.New("备份数据表 " + info.Name + " 失败：" + err.Error())
+		}
+		manifest.Tables = append(manifest.Tables, info)
+		logrus.Info("已备份数据表：", info.Name, "，", info.Rows, "行")
+	}
+	tx.Rollback()
+	if len(uploadsPath) > 0 {
+		if manifest.Uploads, err = dumpUploads(zw, uploadsPath, tmp); err != nil {
+			_ = file.Close()
+			return errors.New("备份上传文件失败：" + err.Error())
+		}
+		logrus.Info("已备份上传文件：", manifest.Uploads, "个")
+	}
+	if err = writeJson(zw, manifestName, manifest); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// dumpTable 按主键顺序分批读取数据表
+func dumpTable(db *gorm.DB, zw *zip.Writer, m interface{}) (TableInfo, error) {
+	info := TableInfo{Name: db.NewScope(m).TableName()}
+	w, err := zw.Create(tablesDir + info.Name + ".jsonl")
+	if err != nil {
+		return info, err
+	}
+	sliceType := reflect.SliceOf(reflect.TypeOf(m).Elem())
+	var cursor int64
+	for {
+		list := reflect.New(sliceType)
+		if err := db.Where("id > ?", cursor).Order("id asc").Limit(batchSize).Find(list.Interface()).Error; err != nil {
+			return info, err
+		}
+		if list.Elem().Len() == 0 {
+			return info, nil
+		}
+		for i := 0; i < list.Elem().Len(); i++ {
+			scope := db.NewScope(list.Elem().Index(i).Addr().Interface())
+			row, err := rowValues(scope)
+			if err != nil {
+				return info, err
+			}
+			line, err := json.Marshal(row)
+			if err != nil {
+				return info, err
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return info, err
+			}
+			cursor = scope.PrimaryKeyValue().(int64)
+			info.Rows++
+		}
+	}
+}
+
+// rowValues 记录各列的值，sql.NullString 等类型使用写入数据库时的值，与数据库类型无关
+func rowValues(scope *gorm.Scope) (map[string]interface{}, error) {
+	row := make(map[string]interface{})
+	for _, field := range scope.Fields() {
+		if field.IsIgnored || !field.IsNormal {
+			continue
+		}
+		value := field.Field.Interface()
+		if valuer, ok := value.(driver.Valuer); ok {
+			v, err := valuer.Value()
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		}
+		row[field.DBName] = value
+	}
+	return row, nil
+}
+
+// dumpUploads 备份上传文件，备份文件位于上传目录下时跳过正在写入的临时文件
+func dumpUploads(zw *zip.Writer, uploadsPath, tmp string) (count int, err error) {
+	if _, err := os.Stat(uploadsPath); os.IsNotExist(err) {
+		return 0, nil
+	}
+	tmp, _ = filepath.Abs(tmp)
+	err = filepath.Walk(uploadsPath, func(path string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+		if abs, _ := filepath.Abs(path); abs == tmp {
+			return nil
+		}
+		rel, err := filepath.Rel(uploadsPath, path)
+		if err != nil {
+			return err
+		}
+		w, err := zw.Create(uploadsDir + filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(w, f); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	return
+}
+
+func writeJson(zw *zip.Writer, name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
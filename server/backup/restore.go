@@ -0,0 +1,244 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/model"
+)
+
+// Restore 将备份恢复到空数据库，目标数据库可以与备份时的数据库类型不同；
+// uploadsPath 不为空时将上传文件恢复到该目录
+func Restore(db *gorm.DB, filename, uploadsPath string) error {
+	zr, err := zip.OpenReader(filename)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	manifest, err := readManifest(files[manifestName])
+	if err != nil {
+		return err
+	}
+
+	if err := migrate(db); err != nil {
+		return err
+	}
+	models := make(map[string]interface{})
+	for _, m := range model.Models {
+		table := db.NewScope(m).TableName()
+		var count int64
+		if err := db.Model(m).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("目标数据库不是空数据库，数据表 " + table + " 中已有数据")
+		}
+		models[table] = m
+	}
+
+	for _, table := range manifest.Tables {
+		m, ok := models[table.Name]
+		if !ok {
+			logrus.Warn("数据表 ", table.Name, " 已不存在，跳过")
+			continue
+		}
+		f, ok := files[tablesDir+table.Name+".jsonl"]
+		if !ok {
+			return errors.New("备份文件中缺少数据表 " + table.Name)
+		}
+		rows, err := loadTable(db, f, m)
+		if err != nil {
+			return errors.New("恢复数据表 " + table.Name + " 失败：" + err.Error())
+		}
+		if err := resetSequence(db, table.Name); err != nil {
+			return err
+		}
+		logrus.Info("已恢复数据表：", table.Name, "，", rows, "行")
+	}
+
+	if len(uploadsPath) > 0 {
+		count, err := restoreUploads(zr.File, uploadsPath)
+		if err != nil {
+			return errors.New("恢复上传文件失败：" + err.Error())
+		}
+		logrus.Info("已恢复上传文件：", count, "个")
+	}
+	return nil
+}
+
+func readManifest(f *zip.File) (*Manifest, error) {
+	if f == nil {
+		return nil, errors.New("不是有效的备份文件")
+	}
+	r, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	manifest := &Manifest{}
+	if err := json.NewDecoder(r).Decode(manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Format != Format {
+		return nil, errors.New("不是有效的备份文件")
+	}
+	if manifest.Version > Version {
+		return nil, errors.New("备份文件版本 " + strconv.Itoa(manifest.Version) + " 高于当前支持的版本，请升级后再恢复")
+	}
+	return manifest, nil
+}
+
+// migrate 创建数据表，PostgreSQL 没有 longtext 类型，创建同名的 domain
+func migrate(db *gorm.DB) error {
+	if db.Dialect().GetName() == "postgres" {
+		var count int64
+		if err := db.Raw("select count(*) from pg_type where typname = 'longtext'").Row().Scan(&count); err != nil {
+			return err
+		}
+		if count == 0 {
+			if err := db.Exec("create domain longtext as text").Error; err != nil {
+				return err
+			}
+		}
+	}
+	return db.AutoMigrate(model.Models...).Error
+}
+
+// loadTable 按备份中的主键写入，每批数据在一个事务中写入
+func loadTable(db *gorm.DB, f *zip.File, m interface{}) (rows int64, err error) {
+	r, err := f.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	var (
+		reader = bufio.NewReader(r)
+		typ    = reflect.TypeOf(m).Elem()
+		tx     = db.Begin()
+	)
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			row := make(map[string]json.RawMessage)
+			if err := json.Unmarshal(line, &row); err != nil {
+				return rows, err
+			}
+			value := reflect.New(typ).Interface()
+			for _, field := range tx.NewScope(value).Fields() {
+				if raw, ok := row[field.DBName]; ok && !field.IsIgnored && field.IsNormal {
+					if err := setField(field, raw); err != nil {
+						return rows, errors.New(field.DBName + "：" + err.Error())
+					}
+				}
+			}
+			if err := tx.Set("gorm:save_associations", false).Create(value).Error; err != nil {
+				return rows, err
+			}
+			rows++
+			if rows%batchSize == 0 {
+				if err := tx.Commit().Error; err != nil {
+					return rows, err
+				}
+				tx = db.Begin()
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return rows, readErr
+		}
+	}
+	return rows, tx.Commit().Error
+}
+
+// setField 设置列的值，sql.NullString 等类型通过 Scan 设置
+func setField(field *gorm.Field, raw json.RawMessage) error {
+	ptr := field.Field.Addr().Interface()
+	if scanner, ok := ptr.(sql.Scanner); ok {
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				value = i
+			} else if value, err = n.Float64(); err != nil {
+				return err
+			}
+		}
+		return scanner.Scan(value)
+	}
+	return json.Unmarshal(raw, ptr)
+}
+
+// resetSequence 写入指定主键后，PostgreSQL 需要更新自增序列
+func resetSequence(db *gorm.DB, table string) error {
+	if db.Dialect().GetName() != "postgres" {
+		return nil
+	}
+	return db.Exec("select setval(pg_get_serial_sequence(?, 'id'), coalesce((select max(id) from "+table+"), 0) + 1, false)", table).Error
+}
+
+func restoreUploads(files []*zip.File, uploadsPath string) (count int, err error) {
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, uploadsDir) || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		rel := strings.TrimPrefix(f.Name, uploadsDir)
+		if strings.Contains(rel, "..") {
+			return count, errors.New("无效的文件路径：" + f.Name)
+		}
+		if err := extractFile(f, filepath.Join(uploadsPath, filepath.FromSlash(rel))); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+func extractFile(f *zip.File, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, r); err != nil {
+		_ = w.Close()
+		return err
+	}
+	return w.Close()
+}
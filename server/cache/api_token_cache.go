@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/goburrow/cache"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/model"
+	"bbs-go/repositories"
+)
+
+var ApiTokenCache = newApiTokenCache()
+
+type apiTokenCache struct {
+	cache cache.LoadingCache
+}
+
+func newApiTokenCache() *apiTokenCache {
+	return &apiTokenCache{
+		cache: cache.NewLoadingCache(
+			func(key cache.Key) (value cache.Value, e error) {
+				value = repositories.ApiTokenRepository.GetByTokenHash(simple.DB(), key.(string))
+				return
+			},
+			cache.WithMaximumSize(1000),
+			cache.WithExpireAfterAccess(10*time.Minute),
+		),
+	}
+}
+
+func (c *apiTokenCache) Get(tokenHash string) *model.ApiToken {
+	if len(tokenHash) == 0 {
+		return nil
+	}
+	val, err := c.cache.Get(tokenHash)
+	if err != nil {
+		return nil
+	}
+	if val != nil {
+		return val.(*model.ApiToken)
+	}
+	return nil
+}
+
+func (c *apiTokenCache) Invalidate(tokenHash string) {
+	c.cache.Invalidate(tokenHash)
+}
@@ -0,0 +1,29 @@
+package cache
+
+import (
+	"github.com/goburrow/cache"
+)
+
+// Stats 各缓存的命中统计，key为缓存名称，用于监控
+func Stats() map[string]*cache.Stats {
+	caches := map[string]cache.Cache{
+		"user":              UserCache.cache,
+		"user_score":        UserCache.scoreCache,
+		"user_name":         UserCache.nameCache,
+		"user_token":        UserTokenCache.cache,
+		"api_token":         ApiTokenCache.cache,
+		"sys_config":        SysConfigCache.cache,
+		"tag":               TagCache.cache,
+		"article_tag":       ArticleTagCache.cache,
+		"article_recommend": ArticleCache.recommendCache,
+		"article_hot":       ArticleCache.hotCache,
+		"topic_recommend":   TopicCache.recommendCache,
+	}
+	stats := make(map[string]*cache.Stats, len(caches))
+	for name, c := range caches {
+		s := &cache.Stats{}
+		c.Stats(s)
+		stats[name] = s
+	}
+	return stats
+}
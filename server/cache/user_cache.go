@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"bbs-go/model"
+	"bbs-go/model/constants"
 	"bbs-go/repositories"
 
 	"github.com/goburrow/cache"
@@ -13,6 +14,7 @@ import (
 type userCache struct {
 	cache      cache.LoadingCache
 	scoreCache cache.LoadingCache
+	nameCache  cache.LoadingCache
 }
 
 var UserCache = newUserCache()
@@ -41,6 +43,15 @@ func newUserCache() *userCache {
 			cache.WithMaximumSize(1000),
 			cache.WithExpireAfterAccess(30*time.Minute),
 		),
+		nameCache: cache.NewLoadingCache(
+			func(key cache.Key) (value cache.Value, err error) {
+				value = findUserIdByName(key.(string))
+				return
+			},
+			cache.WithMaximumSize(1000),
+			cache.WithExpireAfterAccess(30*time.Minute),
+			cache.WithRefreshAfterWrite(5*time.Minute), // 用户名、昵称可能会修改，定期重新加载
+		),
 	}
 }
 
@@ -70,3 +81,40 @@ func (c *userCache) GetScore(userId int64) int {
 func (c *userCache) InvalidateScore(userId int64) {
 	c.scoreCache.Invalidate(userId)
 }
+
+// GetByName 根据用户名或昵称获取用户，先匹配用户名，昵称只在唯一时匹配，用于解析@提及；已注销的用户返回nil
+func (c *userCache) GetByName(name string) *model.User {
+	if len(name) == 0 {
+		return nil
+	}
+	val, err := c.nameCache.Get(name)
+	if err != nil {
+		return nil
+	}
+	user := c.Get(val.(int64))
+	if user == nil || user.Status == constants.UserStatusCanceled {
+		return nil
+	}
+	return user
+}
+
+// InvalidateName 用户名、昵称修改或用户注销后清除对应关系
+func (c *userCache) InvalidateName(names ...string) {
+	for _, name := range names {
+		if len(name) > 0 {
+			c.nameCache.Invalidate(name)
+		}
+	}
+}
+
+func findUserIdByName(name string) int64 {
+	if user := repositories.UserRepository.GetByUsername(simple.DB(), name); user != nil {
+		return user.Id
+	}
+	users := repositories.UserRepository.Find(simple.DB(), simple.NewSqlCnd().
+		Where("nickname = ? and status <> ?", name, constants.UserStatusCanceled).Limit(2))
+	if len(users) == 1 {
+		return users[0].Id
+	}
+	return 0
+}
@@ -2,6 +2,7 @@ package collect
 
 import (
 	"bbs-go/model/constants"
+	"context"
 	"errors"
 
 	"bbs-go/common"
@@ -17,16 +18,16 @@ func NewSpiderApi() *SpiderApi {
 	return &SpiderApi{}
 }
 
-func (api *SpiderApi) Publish(article *Article) (articleId int64, err error) {
+func (api *SpiderApi) Publish(ctx context.Context, article *Article) (articleId int64, err error) {
 	if article.Summary == "" {
 		article.Summary = common.GetSummary(article.ContentType, article.Content)
 	}
 
 	if len(article.Tags) == 0 {
-		article.Tags = api.AnalyzeTags(article)
+		article.Tags = api.AnalyzeTags(ctx, article)
 	}
 
-	t, err := services.ArticleService.Publish(article.UserId, article.Title, article.Summary, article.Content,
+	t, err := services.ArticleService.Publish(ctx, article.UserId, article.Title, article.Summary, article.Content,
 		article.ContentType, article.Tags, article.SourceUrl)
 	if err == nil {
 		articleId = t.Id
@@ -38,13 +39,13 @@ func (api *SpiderApi) Publish(article *Article) (articleId int64, err error) {
 	return
 }
 
-func (api *SpiderApi) PublishComment(comment *Comment) (commentId int64, err error) {
+func (api *SpiderApi) PublishComment(ctx context.Context, comment *Comment) (commentId int64, err error) {
 	if len(comment.Content) == 0 {
 		err = errors.New("评论内容不能为空")
 		return
 	}
 
-	c, err := services.CommentService.Publish(comment.UserId, &model.CreateCommentForm{
+	c, err := services.CommentService.Publish(ctx, comment.UserId, &model.CreateCommentForm{
 		EntityType:  comment.EntityType,
 		EntityId:    comment.EntityId,
 		Content:     comment.Content,
@@ -60,12 +61,12 @@ func (api *SpiderApi) PublishComment(comment *Comment) (commentId int64, err err
 	return
 }
 
-func (api *SpiderApi) AnalyzeTags(article *Article) []string {
+func (api *SpiderApi) AnalyzeTags(ctx context.Context, article *Article) []string {
 	var analyzeRet *baiduai.AiAnalyzeRet
 	if article.ContentType == constants.ContentTypeMarkdown {
-		analyzeRet, _ = baiduai.GetAi().AnalyzeMarkdown(article.Title, article.Content)
+		analyzeRet, _ = baiduai.GetAi().AnalyzeMarkdown(ctx, article.Title, article.Content)
 	} else if article.ContentType == constants.ContentTypeHtml {
-		analyzeRet, _ = baiduai.GetAi().AnalyzeHtml(article.Title, article.Content)
+		analyzeRet, _ = baiduai.GetAi().AnalyzeHtml(ctx, article.Title, article.Content)
 	}
 	var tags []string
 	if analyzeRet != nil {
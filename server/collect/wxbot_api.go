@@ -2,6 +2,7 @@ package collect
 
 import (
 	"bbs-go/model/constants"
+	"context"
 	"errors"
 	"strings"
 
@@ -20,7 +21,7 @@ func NewWxbotApi() *WxbotApi {
 	return &WxbotApi{}
 }
 
-func (api *WxbotApi) Publish(article *WxArticle) (*model.Article, error) {
+func (api *WxbotApi) Publish(ctx context.Context, article *WxArticle) (*model.Article, error) {
 	if len(article.Title) == 0 || len(article.HtmlContent) == 0 {
 		return nil, errors.New("内容为空")
 	}
@@ -32,7 +33,7 @@ func (api *WxbotApi) Publish(article *WxArticle) (*model.Article, error) {
 		summary = simple.GetSummary(article.TextContent, 256)
 	}
 
-	return services.ArticleService.Publish(userId, article.Title, summary, article.HtmlContent, constants.ContentTypeHtml,
+	return services.ArticleService.Publish(ctx, userId, article.Title, summary, article.HtmlContent, constants.ContentTypeHtml,
 		tags, article.Url)
 }
 
@@ -0,0 +1,242 @@
+package activitypub
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// ActivityPub，文档：https://www.w3.org/TR/activitypub/
+const (
+	ContentType   = "application/activity+json"
+	LdContentType = `application/ld+json; profile="https://www.w3.org/ns/activitystreams"`
+	JrdType       = "application/jrd+json" // WebFinger
+
+	ContextActivityStreams = "https://www.w3.org/ns/activitystreams"
+	ContextSecurity        = "https://w3id.org/security/v1"
+
+	Public = "https://www.w3.org/ns/activitystreams#Public" // 公开可见
+)
+
+// 对象类型
+const (
+	TypePerson                = "Person"
+	TypeNote                  = "Note"
+	TypeArticle               = "Article"
+	TypeTombstone             = "Tombstone"
+	TypeOrderedCollection     = "OrderedCollection"
+	TypeOrderedCollectionPage = "OrderedCollectionPage"
+)
+
+// 活动类型
+const (
+	TypeCreate = "Create"
+	TypeUpdate = "Update"
+	TypeDelete = "Delete"
+	TypeFollow = "Follow"
+	TypeAccept = "Accept"
+	TypeUndo   = "Undo"
+)
+
+// Actor 用户
+type Actor struct {
+	Context           interface{} `json:"@context,omitempty"`
+	Id                string      `json:"id"`
+	Type              string      `json:"type"`
+	PreferredUsername string      `json:"preferredUsername"`
+	Name              string      `json:"name,omitempty"`
+	Summary           string      `json:"summary,omitempty"`
+	Url               Iri         `json:"url,omitempty"`
+	Icon              *Image      `json:"icon,omitempty"`
+	Inbox             string      `json:"inbox"`
+	Outbox            string      `json:"outbox,omitempty"`
+	Followers         string      `json:"followers,omitempty"`
+	Endpoints         *Endpoints  `json:"endpoints,omitempty"`
+	PublicKey         *PublicKey  `json:"publicKey,omitempty"`
+}
+
+type Endpoints struct {
+	SharedInbox string `json:"sharedInbox,omitempty"`
+}
+
+type PublicKey struct {
+	Id           string `json:"id"`
+	Owner        string `json:"owner"`
+	PublicKeyPem string `json:"publicKeyPem"`
+}
+
+// Image 头像，部分实例返回数组，解析时取第一个
+type Image struct {
+	Type      string `json:"type"`
+	MediaType string `json:"mediaType,omitempty"`
+	Url       string `json:"url"`
+}
+
+func (i *Image) UnmarshalJSON(data []byte) error {
+	type image Image
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		var list []image
+		if err := json.Unmarshal(data, &list); err != nil || len(list) == 0 {
+			return err
+		}
+		*i = Image(list[0])
+		return nil
+	}
+	return json.Unmarshal(data, (*image)(i))
+}
+
+// Object 话题、文章、动态、评论等内容
+type Object struct {
+	Context      interface{} `json:"@context,omitempty"`
+	Id           string      `json:"id"`
+	Type         string      `json:"type"`
+	AttributedTo Iri         `json:"attributedTo,omitempty"`
+	Name         string      `json:"name,omitempty"`
+	Summary      string      `json:"summary,omitempty"`
+	Content      string      `json:"content,omitempty"`
+	MediaType    string      `json:"mediaType,omitempty"`
+	Url          Iri         `json:"url,omitempty"`
+	InReplyTo    Iri         `json:"inReplyTo,omitempty"`
+	Published    string      `json:"published,omitempty"`
+	Updated      string      `json:"updated,omitempty"`
+	To           IriList     `json:"to,omitempty"`
+	Cc           IriList     `json:"cc,omitempty"`
+}
+
+// Activity 活动，Object 可能是链接或者完整的对象
+type Activity struct {
+	Context   interface{}     `json:"@context,omitempty"`
+	Id        string          `json:"id"`
+	Type      string          `json:"type"`
+	Actor     Iri             `json:"actor"`
+	Object    json.RawMessage `json:"object"`
+	To        IriList         `json:"to,omitempty"`
+	Cc        IriList         `json:"cc,omitempty"`
+	Published string          `json:"published,omitempty"`
+}
+
+// NewActivity 创建活动，object 为链接（string）或者对象
+func NewActivity(id, activityType, actor string, object interface{}) (*Activity, error) {
+	data, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+	return &Activity{
+		Context: ContextActivityStreams,
+		Id:      id,
+		Type:    activityType,
+		Actor:   Iri(actor),
+		Object:  data,
+	}, nil
+}
+
+// ObjectId 活动对象的链接
+func (a *Activity) ObjectId() string {
+	var iri Iri
+	if err := json.Unmarshal(a.Object, &iri); err != nil {
+		return ""
+	}
+	return string(iri)
+}
+
+// ObjectType 活动对象的类型，对象为链接时返回空
+func (a *Activity) ObjectType() string {
+	var v struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(a.Object, &v); err != nil {
+		return ""
+	}
+	return v.Type
+}
+
+// DecodeObject 解析活动对象
+func (a *Activity) DecodeObject(v interface{}) error {
+	return json.Unmarshal(a.Object, v)
+}
+
+// OrderedCollection 有序集合，用于发件箱、关注者列表
+type OrderedCollection struct {
+	Context      interface{}   `json:"@context,omitempty"`
+	Id           string        `json:"id"`
+	Type         string        `json:"type"`
+	TotalItems   int           `json:"totalItems"`
+	OrderedItems []interface{} `json:"orderedItems,omitempty"`
+}
+
+// WebFinger 文档：https://tools.ietf.org/html/rfc7033
+type WebFinger struct {
+	Subject string          `json:"subject"`
+	Aliases []string        `json:"aliases,omitempty"`
+	Links   []WebFingerLink `json:"links"`
+}
+
+type WebFingerLink struct {
+	Rel  string `json:"rel"`
+	Type string `json:"type,omitempty"`
+	Href string `json:"href"`
+}
+
+// Iri 链接，兼容字符串、带id（或href）的对象以及数组（取第一个）
+type Iri string
+
+func (i *Iri) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+	switch data[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*i = Iri(s)
+	case '{':
+		var v struct {
+			Id   string `json:"id"`
+			Href string `json:"href"`
+		}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		if len(v.Id) > 0 {
+			*i = Iri(v.Id)
+		} else {
+			*i = Iri(v.Href)
+		}
+	case '[':
+		var list []Iri
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		if len(list) > 0 {
+			*i = list[0]
+		}
+	}
+	return nil
+}
+
+// IriList 链接列表，兼容单个链接
+type IriList []string
+
+func (l *IriList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		var list []Iri
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		for _, iri := range list {
+			*l = append(*l, string(iri))
+		}
+		return nil
+	}
+	var iri Iri
+	if err := json.Unmarshal(data, &iri); err != nil {
+		return err
+	}
+	if len(iri) > 0 {
+		*l = IriList{string(iri)}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package activitypub
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"bbs-go/config"
+)
+
+const (
+	timeout        = 10 * time.Second
+	userAgent      = "bbs-go"
+	maxBodyLen     = 1 << 20 // 读取的响应内容最大长度
+	maxErrorLength = 512     // 错误信息中保留的响应内容长度
+)
+
+// ErrUnsafeUrl 远程地址不是 https 或者解析到了内网地址
+var ErrUnsafeUrl = errors.New("unsafe remote url")
+
+// 远程地址由收件箱请求中的内容决定，只允许访问公网地址，在建立连接时（DNS解析之后）检查，避免 DNS rebinding；
+// 不使用环境变量中的代理，否则检查的是代理的地址
+var httpClient = &http.Client{
+	Timeout: timeout,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: timeout,
+			Control: denyPrivateAddress,
+		}).DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return errors.New("stopped after 5 redirects")
+		}
+		return CheckUrl(req.URL.String())
+	},
+}
+
+// 不允许访问的网段：本机、内网、链路本地、组播、保留地址
+var deniedNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// IsPublicIP 是否公网地址
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range deniedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// allowPrivateHosts 是否允许访问内网地址和使用 http，仅用于在本机启动两个实例测试联邦，正式环境不生效
+func allowPrivateHosts() bool {
+	return config.Instance != nil && config.Instance.Env != "prod" && config.Instance.ActivityPub.AllowPrivateHosts
+}
+
+func denyPrivateAddress(network, address string, _ syscall.RawConn) error {
+	if allowPrivateHosts() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicIP(net.ParseIP(host)) {
+		return fmt.Errorf("%w: %s", ErrUnsafeUrl, address)
+	}
+	return nil
+}
+
+// CheckUrl 远程地址必须使用 https
+func CheckUrl(rawUrl string) error {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "http" && allowPrivateHosts() {
+		u.Scheme = "https"
+	}
+	if u.Scheme != "https" || len(u.Host) == 0 {
+		return fmt.Errorf("%w: %s", ErrUnsafeUrl, rawUrl)
+	}
+	return nil
+}
+
+// Get 获取远程对象，keyId 不为空时对请求签名（部分实例开启了授权获取）
+func Get(url, keyId, privateKeyPem string, v interface{}) error {
+	if err := CheckUrl(url); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", ContentType+", "+LdContentType)
+	req.Header.Set("User-Agent", userAgent)
+	if len(keyId) > 0 {
+		if err := Sign(req, keyId, privateKeyPem, nil); err != nil {
+			return err
+		}
+	}
+	body, err := do(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+// Post 投递活动到收件箱，使用发送者的密钥签名
+func Post(inbox, keyId, privateKeyPem string, body []byte) error {
+	if err := CheckUrl(inbox); err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, inbox, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", ContentType)
+	req.Header.Set("User-Agent", userAgent)
+	if err := Sign(req, keyId, privateKeyPem, body); err != nil {
+		return err
+	}
+	_, err = do(req)
+	return err
+}
+
+func do(req *http.Request) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxBodyLen))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(body) > maxErrorLength {
+			body = body[:maxErrorLength]
+		}
+		return nil, errors.New(req.Method + " " + req.URL.String() + ": " + strconv.Itoa(resp.StatusCode) + " " + string(body))
+	}
+	return body, nil
+}
@@ -0,0 +1,149 @@
+package activitypub
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"bbs-go/config"
+)
+
+func TestPostToInbox(t *testing.T) {
+	privateKeyPem, publicKeyPem, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyId := "https://remote.example/ap/users/1#main-key"
+	body := []byte(`{"type":"Create"}`)
+
+	var verifyErr error
+	received := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+		data, _ := ioutil.ReadAll(r.Body)
+		signature, err := ParseSignature(r)
+		if err == nil && signature.KeyId != keyId {
+			err = errors.New("unexpected keyId: " + signature.KeyId)
+		}
+		if err == nil {
+			err = signature.Verify(r, r.Host, publicKeyPem, data)
+		}
+		if err != nil {
+			verifyErr = err
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	// 测试服务器监听在本机地址，使用测试服务器的客户端
+	defaultClient := httpClient
+	httpClient = server.Client()
+	defer func() { httpClient = defaultClient }()
+
+	if err := Post(server.URL+"/ap/inbox", keyId, privateKeyPem, body); err != nil {
+		t.Fatalf("post: %v, verify: %v", err, verifyErr)
+	}
+	if !received {
+		t.Fatal("inbox not called")
+	}
+
+	// 使用其他密钥签名，收件箱拒绝
+	otherPrivateKeyPem, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Post(server.URL+"/ap/inbox", keyId, otherPrivateKeyPem, body); err == nil {
+		t.Fatal("post signed with other key should be rejected")
+	}
+}
+
+func TestRejectUnsafeUrl(t *testing.T) {
+	for _, u := range []string{"http://remote.example/inbox", "ftp://remote.example/inbox", "https:///inbox", "/inbox"} {
+		if err := CheckUrl(u); !errors.Is(err, ErrUnsafeUrl) {
+			t.Errorf("%s: expected ErrUnsafeUrl, got %v", u, err)
+		}
+	}
+	if err := CheckUrl("https://remote.example/inbox"); err != nil {
+		t.Error(err)
+	}
+	if err := Post("http://remote.example/inbox", "", "", nil); !errors.Is(err, ErrUnsafeUrl) {
+		t.Errorf("expected ErrUnsafeUrl, got %v", err)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "0.0.0.0", "100.64.0.1", "::1", "::", "fe80::1", "fd00::1", "::ffff:127.0.0.1"} {
+		if IsPublicIP(net.ParseIP(ip)) {
+			t.Errorf("%s should not be public", ip)
+		}
+	}
+	for _, ip := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		if !IsPublicIP(net.ParseIP(ip)) {
+			t.Errorf("%s should be public", ip)
+		}
+	}
+}
+
+func TestDenyLoopbackDial(t *testing.T) {
+	privateKeyPem, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// 域名解析到本机地址时同样拒绝
+	u, _ := url.Parse(server.URL)
+	_, port, _ := net.SplitHostPort(u.Host)
+	for _, inbox := range []string{server.URL + "/inbox", "https://localhost:" + port + "/inbox"} {
+		if err := Post(inbox, "https://remote.example/key", privateKeyPem, nil); !errors.Is(err, ErrUnsafeUrl) {
+			t.Errorf("%s: expected ErrUnsafeUrl, got %v", inbox, err)
+		}
+	}
+	if called {
+		t.Fatal("loopback server should not be called")
+	}
+}
+
+func TestAllowPrivateHosts(t *testing.T) {
+	privateKeyPem, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+	defer func() { config.Instance = nil }()
+
+	// 本机联调时允许 http 和本机地址
+	config.Instance = &config.Config{Env: "dev"}
+	config.Instance.ActivityPub.AllowPrivateHosts = true
+	if err := Post(server.URL+"/inbox", "http://127.0.0.1/key", privateKeyPem, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !called {
+		t.Fatal("inbox not called")
+	}
+
+	// 正式环境中不生效
+	called = false
+	config.Instance.Env = "prod"
+	if err := Post(server.URL+"/inbox", "http://127.0.0.1/key", privateKeyPem, nil); !errors.Is(err, ErrUnsafeUrl) {
+		t.Errorf("expected ErrUnsafeUrl, got %v", err)
+	}
+	if called {
+		t.Fatal("loopback server should not be called in prod")
+	}
+}
@@ -0,0 +1,211 @@
+package activitypub
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTP Signatures，文档：https://tools.ietf.org/html/draft-cavage-http-signatures-12
+const (
+	signatureAlgorithm = "rsa-sha256"
+	requestTarget      = "(request-target)"
+	maxClockSkew       = time.Hour // 允许的请求时间误差
+	keyBits            = 2048
+)
+
+// 签名的请求头，POST 请求额外签名 digest
+var (
+	getSignedHeaders  = []string{requestTarget, "host", "date"}
+	postSignedHeaders = []string{requestTarget, "host", "date", "digest"}
+)
+
+// GenerateKey 生成RSA密钥对，返回PEM格式的私钥和公钥
+func GenerateKey() (privateKeyPem, publicKeyPem string, err error) {
+	key, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return "", "", err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privateKeyPem = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	publicKeyPem = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+	return
+}
+
+// Digest 请求内容摘要
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "SHA-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Sign 使用私钥对请求签名，有请求内容时同时设置 Digest
+func Sign(req *http.Request, keyId, privateKeyPem string, body []byte) error {
+	key, err := parsePrivateKey(privateKeyPem)
+	if err != nil {
+		return err
+	}
+	headers := getSignedHeaders
+	if body != nil {
+		headers = postSignedHeaders
+		req.Header.Set("Digest", Digest(body))
+	}
+	req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+
+	sum := sha256.Sum256([]byte(signingString(req.Method, req.URL.RequestURI(), req.URL.Host, req.Header, headers)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Signature", `keyId="`+keyId+`",algorithm="`+signatureAlgorithm+`",headers="`+
+		strings.Join(headers, " ")+`",signature="`+base64.StdEncoding.EncodeToString(signature)+`"`)
+	return nil
+}
+
+// Signature 请求中的签名
+type Signature struct {
+	KeyId     string
+	Algorithm string
+	Headers   []string
+	Signature []byte
+}
+
+// ParseSignature 解析 Signature 请求头，兼容 Authorization: Signature ...
+func ParseSignature(r *http.Request) (*Signature, error) {
+	value := r.Header.Get("Signature")
+	if len(value) == 0 {
+		value = strings.TrimPrefix(r.Header.Get("Authorization"), "Signature ")
+	}
+	if len(value) == 0 {
+		return nil, errors.New("missing signature")
+	}
+	ret := &Signature{Headers: []string{"date"}}
+	for _, part := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		v := strings.Trim(kv[1], `"`)
+		switch kv[0] {
+		case "keyId":
+			ret.KeyId = v
+		case "algorithm":
+			ret.Algorithm = v
+		case "headers":
+			ret.Headers = strings.Fields(strings.ToLower(v))
+		case "signature":
+			signature, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, errors.New("invalid signature")
+			}
+			ret.Signature = signature
+		}
+	}
+	if len(ret.KeyId) == 0 || len(ret.Signature) == 0 {
+		return nil, errors.New("invalid signature")
+	}
+	return ret, nil
+}
+
+// Verify 使用公钥校验签名；host 为本站域名，服务部署在反向代理之后时请求中的 Host 可能已被改写；
+// 有请求内容时必须签名 digest 并校验摘要
+func (s *Signature) Verify(r *http.Request, host, publicKeyPem string, body []byte) error {
+	if len(s.Algorithm) > 0 && s.Algorithm != signatureAlgorithm && s.Algorithm != "hs2019" {
+		return errors.New("unsupported signature algorithm: " + s.Algorithm)
+	}
+	if !contains(s.Headers, requestTarget) || !contains(s.Headers, "date") {
+		return errors.New("signature must cover (request-target) and date")
+	}
+	date, err := http.ParseTime(r.Header.Get("Date"))
+	if err != nil {
+		return errors.New("invalid date")
+	}
+	if skew := time.Since(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return errors.New("date out of range")
+	}
+	if body != nil {
+		if !contains(s.Headers, "digest") {
+			return errors.New("signature must cover digest")
+		}
+		if r.Header.Get("Digest") != Digest(body) {
+			return errors.New("digest mismatch")
+		}
+	}
+
+	key, err := parsePublicKey(publicKeyPem)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256([]byte(signingString(r.Method, r.URL.RequestURI(), host, r.Header, s.Headers)))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], s.Signature)
+}
+
+func signingString(method, uri, host string, header http.Header, headers []string) string {
+	var lines []string
+	for _, h := range headers {
+		switch h {
+		case requestTarget:
+			lines = append(lines, requestTarget+": "+strings.ToLower(method)+" "+uri)
+		case "host":
+			lines = append(lines, "host: "+host)
+		default:
+			lines = append(lines, h+": "+strings.Join(header.Values(h), ", "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func parsePrivateKey(privateKeyPem string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPem))
+	if block == nil {
+		return nil, errors.New("invalid private key")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, ok := key.(*rsa.PrivateKey); ok {
+		return rsaKey, nil
+	}
+	return nil, errors.New("unsupported private key")
+}
+
+func parsePublicKey(publicKeyPem string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPem))
+	if block == nil {
+		return nil, errors.New("invalid public key")
+	}
+	if block.Type == "RSA PUBLIC KEY" {
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if rsaKey, ok := key.(*rsa.PublicKey); ok {
+		return rsaKey, nil
+	}
+	return nil, errors.New("unsupported public key")
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package activitypub
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newSignedRequest(t *testing.T, privateKeyPem string, body []byte) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/ap/inbox", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Sign(req, "https://remote.example/ap/users/1#main-key", privateKeyPem, body); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestSignVerify(t *testing.T) {
+	privateKeyPem, publicKeyPem, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte(`{"type":"Follow"}`)
+	req := newSignedRequest(t, privateKeyPem, body)
+
+	signature, err := ParseSignature(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signature.KeyId != "https://remote.example/ap/users/1#main-key" {
+		t.Fatalf("unexpected keyId: %s", signature.KeyId)
+	}
+	if err := signature.Verify(req, "example.com", publicKeyPem, body); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// 内容被篡改
+	if err := signature.Verify(req, "example.com", publicKeyPem, []byte(`{"type":"Delete"}`)); err == nil {
+		t.Fatal("tampered body should fail")
+	}
+	// 摘要随内容一起被篡改
+	tampered := []byte(`{"type":"Delete"}`)
+	req.Header.Set("Digest", Digest(tampered))
+	if err := signature.Verify(req, "example.com", publicKeyPem, tampered); err == nil {
+		t.Fatal("tampered digest should fail")
+	}
+	req.Header.Set("Digest", Digest(body))
+
+	// 域名不一致
+	if err := signature.Verify(req, "other.example", publicKeyPem, body); err == nil {
+		t.Fatal("wrong host should fail")
+	}
+	// 其他密钥
+	_, otherPublicKeyPem, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signature.Verify(req, "example.com", otherPublicKeyPem, body); err == nil {
+		t.Fatal("wrong key should fail")
+	}
+	// 过期的请求
+	req.Header.Set("Date", time.Now().Add(-2*maxClockSkew).UTC().Format(http.TimeFormat))
+	if err := signature.Verify(req, "example.com", publicKeyPem, body); err == nil {
+		t.Fatal("expired date should fail")
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://example.com/ap/inbox", nil)
+	if _, err := ParseSignature(req); err == nil {
+		t.Fatal("missing signature should fail")
+	}
+	req.Header.Set("Authorization", `Signature keyId="https://remote.example/key",headers="(request-target) host date",signature="c2ln"`)
+	signature, err := ParseSignature(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(signature.Headers) != 3 || string(signature.Signature) != "sig" {
+		t.Fatalf("unexpected signature: %+v", signature)
+	}
+}
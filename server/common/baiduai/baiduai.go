@@ -1,6 +1,7 @@
 package baiduai
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/tidwall/gjson"
 
+	"bbs-go/common/tracing"
 	"bbs-go/config"
 )
 
@@ -53,7 +55,7 @@ func (a *ai) GetToken() string {
 	return a.accessToken
 }
 
-func (a *ai) GetTags(title, content string) *AiTags {
+func (a *ai) GetTags(ctx context.Context, title, content string) *AiTags {
 	if title == "" || content == "" {
 		return nil
 	}
@@ -67,7 +69,7 @@ func (a *ai) GetTags(title, content string) *AiTags {
 	}
 
 	url := "https://aip.baidubce.com/rpc/2.0/nlp/v1/keyword?charset=UTF-8&access_token=" + a.GetToken()
-	response, err := resty.New().R().SetBody(string(bytesData)).Post(url)
+	response, err := newRequest(ctx).SetBody(string(bytesData)).Post(url)
 	if err != nil {
 		return nil
 	}
@@ -80,7 +82,7 @@ func (a *ai) GetTags(title, content string) *AiTags {
 	return tags
 }
 
-func (a *ai) GetCategories(title, content string) *AiCategories {
+func (a *ai) GetCategories(ctx context.Context, title, content string) *AiCategories {
 	if title == "" || content == "" {
 		return nil
 	}
@@ -95,7 +97,7 @@ func (a *ai) GetCategories(title, content string) *AiCategories {
 	}
 
 	url := "https://aip.baidubce.com/rpc/2.0/nlp/v1/topic?charset=UTF-8&access_token=" + a.GetToken()
-	response, err := resty.New().R().SetBody(string(bytesData)).Post(url)
+	response, err := newRequest(ctx).SetBody(string(bytesData)).Post(url)
 	if err != nil {
 		return nil
 	}
@@ -108,7 +110,7 @@ func (a *ai) GetCategories(title, content string) *AiCategories {
 	return categories
 }
 
-func (a *ai) GetNewsSummary(title, content string, maxSummaryLen int) (string, error) {
+func (a *ai) GetNewsSummary(ctx context.Context, title, content string, maxSummaryLen int) (string, error) {
 	if title == "" || content == "" {
 		return "", errors.New("标题或内容为空")
 	}
@@ -127,7 +129,7 @@ func (a *ai) GetNewsSummary(title, content string, maxSummaryLen int) (string, e
 	}
 
 	url := "https://aip.baidubce.com/rpc/2.0/nlp/v1/news_summary?charset=UTF-8&access_token=" + a.GetToken()
-	response, err := resty.New().R().SetBody(string(bytesData)).Post(url)
+	response, err := newRequest(ctx).SetBody(string(bytesData)).Post(url)
 	if err != nil {
 		return "", err
 	}
@@ -135,12 +137,12 @@ func (a *ai) GetNewsSummary(title, content string, maxSummaryLen int) (string, e
 	return ret.String(), nil
 }
 
-func (a *ai) AnalyzeMarkdown(title, markdownStr string) (*AiAnalyzeRet, error) {
+func (a *ai) AnalyzeMarkdown(ctx context.Context, title, markdownStr string) (*AiAnalyzeRet, error) {
 	content, _ := markdown.New(markdown.SummaryLen(0)).Run(markdownStr)
-	return a.AnalyzeHtml(title, content)
+	return a.AnalyzeHtml(ctx, title, content)
 }
 
-func (a *ai) AnalyzeHtml(title, html string) (*AiAnalyzeRet, error) {
+func (a *ai) AnalyzeHtml(ctx context.Context, title, html string) (*AiAnalyzeRet, error) {
 	if title == "" || html == "" {
 		return nil, errors.New("内容为空")
 	}
@@ -149,16 +151,16 @@ func (a *ai) AnalyzeHtml(title, html string) (*AiAnalyzeRet, error) {
 		return nil, err
 	}
 	text := doc.Text()
-	return a.AnalyzeText(title, text)
+	return a.AnalyzeText(ctx, title, text)
 }
 
-func (a *ai) AnalyzeText(title, text string) (*AiAnalyzeRet, error) {
+func (a *ai) AnalyzeText(ctx context.Context, title, text string) (*AiAnalyzeRet, error) {
 	if title == "" || text == "" {
 		return nil, errors.New("内容为空")
 	}
-	aiCategories := a.GetCategories(title, text)
-	aiTags := a.GetTags(title, text)
-	summary, _ := a.GetNewsSummary(title, text, 256)
+	aiCategories := a.GetCategories(ctx, title, text)
+	aiTags := a.GetTags(ctx, title, text)
+	summary, _ := a.GetNewsSummary(ctx, title, text, 256)
 
 	set := hashset.New()
 	if aiCategories != nil {
@@ -188,3 +190,7 @@ func (a *ai) AnalyzeText(title, text string) (*AiAnalyzeRet, error) {
 		Summary: summary,
 	}, nil
 }
+
+func newRequest(ctx context.Context) *resty.Request {
+	return resty.New().SetTransport(tracing.Transport(nil)).R().SetContext(ctx)
+}
@@ -7,4 +7,5 @@ var (
 	ForbiddenError      = simple.NewError(1001, "已被禁言")
 	UserDisabled        = simple.NewError(1002, "账号已禁用")
 	InObservationPeriod = simple.NewError(1003, "账号尚在观察期")
+	TooManyRequests     = simple.NewError(1004, "操作过于频繁，请稍后再试")
 )
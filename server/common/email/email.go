@@ -1,99 +1,81 @@
 package email
 
 import (
-	"bbs-go/model"
-	"bytes"
-	"crypto/tls"
-	"html/template"
-	"net"
-	"net/smtp"
+	"context"
+	"errors"
+	"net/textproto"
 
 	"github.com/jordan-wright/email"
-	"github.com/sirupsen/logrus"
 
+	"bbs-go/common/tracing"
 	"bbs-go/config"
 )
 
-var emailTemplate = `
-<div style="background-color:white;border-top:2px solid #12ADDB;box-shadow:0 1px 3px #AAAAAA;line-height:180%;padding:0 15px 12px;width:500px;margin:50px auto;color:#555555;font-family:'Century Gothic','Trebuchet MS','Hiragino Sans GB',微软雅黑,'Microsoft Yahei',Tahoma,Helvetica,Arial,'SimSun',sans-serif;font-size:12px;">
-    <h2 style="border-bottom:1px solid #DDD;font-size:14px;font-weight:normal;padding:13px 0 10px 8px;">
-        <span style="color: #12ADDB;font-weight:bold;">
-            {{.Title}}
-        </span>
-    </h2>
-    <div style="padding:0 12px 0 12px; margin-top:18px;">
-        {{if .Content}}
-		<p>
-            {{.Content}}
-        </p>
-		{{end}}
-		{{if .QuoteContent}}
-		<div style="background-color: #f5f5f5;padding: 10px 15px;margin:18px 0;word-wrap:break-word;">
-            {{.QuoteContent}}
-        </div>
-		{{end}}
-       
-		{{if .link}}
-        <p>
-            <a style="text-decoration:none; color:#12addb" href="{{.link.Url}}" target="_blank" rel="noopener">{{.link.Title}}</a>
-        </p>
-		{{end}}
-    </div>
-</div>
-`
+// Message 待发送的邮件
+type Message struct {
+	To      string
+	Subject string
+	Html    string
+	Text    string            // 纯文本内容，不为空时与Html一起以 multipart/alternative 格式发送
+	Headers map[string]string // 额外的邮件头，例如 List-Unsubscribe
+}
+
+// Enabled 是否配置了邮件发送方式
+func Enabled() bool {
+	return GetTransport() != nil
+}
 
-// SendTemplateEmail 发送模版邮件
-func SendTemplateEmail(to, subject, title, content, quote string, link *model.ActionLink) error {
-	tpl, err := template.New("emailTemplate").Parse(emailTemplate)
-	if err != nil {
-		return err
+// IsSmtp 是否通过SMTP服务器发送
+func IsSmtp() bool {
+	_, ok := GetTransport().(*smtpTransport)
+	return ok
+}
+
+// Send 使用配置的发送方式发送邮件，ctx 用于链路追踪
+func Send(ctx context.Context, msg *Message) error {
+	transport := GetTransport()
+	if transport == nil {
+		return errors.New("未配置邮件发送方式")
 	}
-	var b bytes.Buffer
-	err = tpl.Execute(&b, map[string]interface{}{
-		"Title":        title,
-		"Content":      content,
-		"QuoteContent": quote,
-		"link":         link,
-	})
-	if err != nil {
-		return err
+	_, span := tracing.Start(ctx, "email.send", tracing.KindClient)
+	defer span.End()
+	switch transport.(type) {
+	case *smtpTransport:
+		span.SetAttribute("email.transport", TransportSmtp)
+		span.SetAttribute("net.peer.name", config.Instance.Smtp.Host)
+	case *fileTransport:
+		span.SetAttribute("email.transport", TransportFile)
+	case *logTransport:
+		span.SetAttribute("email.transport", TransportLog)
 	}
-	html := b.String()
-	return SendEmail(to, subject, html)
-}
 
-// SendEmail 发送邮件
-func SendEmail(to string, subject, html string) error {
-	var (
-		host      = config.Instance.Smtp.Host
-		port      = config.Instance.Smtp.Port
-		username  = config.Instance.Smtp.Username
-		password  = config.Instance.Smtp.Password
-		ssl       = config.Instance.Smtp.SSL
-		addr      = net.JoinHostPort(host, port)
-		auth      = smtp.PlainAuth("", username, password, host)
-		tlsConfig = &tls.Config{
-			InsecureSkipVerify: true,
-			ServerName:         host,
-		}
-	)
+	err := transport.Send(buildEmail(msg))
+	span.RecordError(err)
+	return err
+}
 
+func buildEmail(msg *Message) *email.Email {
 	e := email.NewEmail()
-	e.From = config.Instance.Smtp.Username
-	e.To = []string{to}
-	e.Subject = subject
-	e.HTML = []byte(html)
-
-	if ssl {
-		if err := e.SendWithTLS(addr, auth, tlsConfig); err != nil {
-			logrus.Error("发送邮件异常", err)
-			return err
-		}
-	} else {
-		if err := e.Send(addr, auth); err != nil {
-			logrus.Error("发送邮件异常", err)
-			return err
+	e.From = from()
+	e.To = []string{msg.To}
+	e.Subject = msg.Subject
+	e.HTML = []byte(msg.Html)
+	if len(msg.Text) > 0 {
+		e.Text = []byte(msg.Text)
+	}
+	if len(msg.Headers) > 0 {
+		e.Headers = textproto.MIMEHeader{}
+		for k, v := range msg.Headers {
+			e.Headers.Set(k, v)
 		}
 	}
-	return nil
+	return e
+}
+
+// from 发件人，未配置时使用SMTP账号
+func from() string {
+	if len(config.Instance.Mail.From) > 0 {
+		return config.Instance.Mail.From
+	}
+	return config.Instance.Smtp.Username
 }
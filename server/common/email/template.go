@@ -0,0 +1,243 @@
+package email
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"bbs-go/model"
+	"bbs-go/model/constants"
+)
+
+// Template 邮件模板，主题和纯文本内容使用 text/template，HTML内容使用 html/template
+type Template struct {
+	Name        string                 `json:"name"`        // 模板名称
+	Description string                 `json:"description"` // 说明
+	Variables   string                 `json:"variables"`   // 可用变量说明
+	Subject     string                 `json:"subject"`     // 主题
+	Html        string                 `json:"html"`        // HTML内容
+	Text        string                 `json:"text"`        // 纯文本内容，为空时只发送HTML
+	Sample      map[string]interface{} `json:"-"`           // 预览使用的示例数据
+}
+
+// Rendered 渲染后的邮件内容
+type Rendered struct {
+	Subject string `json:"subject"`
+	Html    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// Section 带标题的链接列表，用于摘要邮件
+type Section struct {
+	Title string
+	Items []*model.ActionLink
+}
+
+// Validate 校验模板语法
+func (t *Template) Validate() error {
+	if _, err := texttemplate.New("subject").Parse(t.Subject); err != nil {
+		return err
+	}
+	if _, err := htmltemplate.New("html").Parse(t.Html); err != nil {
+		return err
+	}
+	if _, err := texttemplate.New("text").Parse(t.Text); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Render 渲染模板
+func (t *Template) Render(data interface{}) (*Rendered, error) {
+	var (
+		ret = &Rendered{}
+		b   bytes.Buffer
+	)
+	subjectTpl, err := texttemplate.New("subject").Parse(t.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := subjectTpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	// 主题不能换行
+	ret.Subject = strings.Join(strings.Fields(b.String()), " ")
+
+	b.Reset()
+	htmlTpl, err := htmltemplate.New("html").Parse(t.Html)
+	if err != nil {
+		return nil, err
+	}
+	if err := htmlTpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	ret.Html = b.String()
+
+	if len(strings.TrimSpace(t.Text)) > 0 {
+		b.Reset()
+		textTpl, err := texttemplate.New("text").Parse(t.Text)
+		if err != nil {
+			return nil, err
+		}
+		if err := textTpl.Execute(&b, data); err != nil {
+			return nil, err
+		}
+		ret.Text = strings.TrimSpace(b.String())
+	}
+	return ret, nil
+}
+
+// GetDefaultTemplate 内置模板，不存在时返回nil
+func GetDefaultTemplate(name string) *Template {
+	for _, t := range DefaultTemplates {
+		if t.Name == name {
+			ret := *t
+			return &ret
+		}
+	}
+	return nil
+}
+
+// 公共变量，所有模板都可以使用
+const commonVariables = "{{.SiteTitle}} 网站名称；{{.BaseUrl}} 网站地址；"
+
+// DefaultTemplates 内置模板
+var DefaultTemplates = []*Template{
+	{
+		Name:        constants.EmailTemplateVerify,
+		Description: "邮箱验证",
+		Variables:   commonVariables + "{{.Nickname}} 用户昵称；{{.Url}} 验证链接；{{.ExpireHours}} 链接有效小时数",
+		Subject:     "邮箱验证 - {{.SiteTitle}}",
+		Html: layout("邮箱验证 - {{.SiteTitle}}", `
+        <p>{{.Nickname}}，你好：</p>
+        <p>该邮件用于验证你在 {{.SiteTitle}} 中设置邮箱的正确性，请在{{.ExpireHours}}小时内完成验证。</p>
+        <p>
+            <a style="text-decoration:none; color:#12addb" href="{{.Url}}" target="_blank" rel="noopener">点击这里验证邮箱&gt;&gt;</a>
+        </p>`),
+		Text: `{{.Nickname}}，你好：
+
+该邮件用于验证你在 {{.SiteTitle}} 中设置邮箱的正确性，请在{{.ExpireHours}}小时内完成验证。
+
+验证链接：{{.Url}}`,
+		Sample: map[string]interface{}{
+			"Nickname":    "张三",
+			"Url":         "https://example.com/user/email/verify?token=xxx",
+			"ExpireHours": 24,
+		},
+	},
+	{
+		Name:        constants.EmailTemplateResetPassword,
+		Description: "重置密码",
+		Variables:   commonVariables + "{{.Nickname}} 用户昵称；{{.Url}} 重置密码链接；{{.ExpireHours}} 链接有效小时数",
+		Subject:     "重置密码 - {{.SiteTitle}}",
+		Html: layout("重置密码 - {{.SiteTitle}}", `
+        <p>{{.Nickname}}，你好：</p>
+        <p>该邮件用于重置你在 {{.SiteTitle}} 中的密码，请在{{.ExpireHours}}小时内完成重置。如果不是你本人操作，请忽略该邮件。</p>
+        <p>
+            <a style="text-decoration:none; color:#12addb" href="{{.Url}}" target="_blank" rel="noopener">点击这里重置密码&gt;&gt;</a>
+        </p>`),
+		Text: `{{.Nickname}}，你好：
+
+该邮件用于重置你在 {{.SiteTitle}} 中的密码，请在{{.ExpireHours}}小时内完成重置。如果不是你本人操作，请忽略该邮件。
+
+重置密码链接：{{.Url}}`,
+		Sample: map[string]interface{}{
+			"Nickname":    "张三",
+			"Url":         "https://example.com/user/email/reset?token=xxx",
+			"ExpireHours": 24,
+		},
+	},
+	{
+		Name:        constants.EmailTemplateNotice,
+		Description: "消息提醒",
+		Variables:   commonVariables + "{{.Content}} 消息内容；{{.QuoteContent}} 引用内容；{{.Url}} 查看链接；{{.UnsubscribeUrl}} 退订链接",
+		Subject:     "新消息提醒 - {{.SiteTitle}}",
+		Html: layout("新消息提醒 - {{.SiteTitle}}", `
+        <p>{{.Content}}</p>
+		{{if .QuoteContent}}
+        <div style="background-color: #f5f5f5;padding: 10px 15px;margin:18px 0;word-wrap:break-word;">
+            {{.QuoteContent}}
+        </div>
+		{{end}}
+        <p>
+            <a style="text-decoration:none; color:#12addb" href="{{.Url}}" target="_blank" rel="noopener">点击查看详情</a>
+        </p>`),
+		Text: `{{.Content}}
+{{if .QuoteContent}}
+> {{.QuoteContent}}
+{{end}}
+查看详情：{{.Url}}
+{{if .UnsubscribeUrl}}
+退订邮件通知：{{.UnsubscribeUrl}}{{end}}`,
+		Sample: map[string]interface{}{
+			"Content":        "李四 回复了你的话题：写得很好",
+			"QuoteContent":   "《示例话题》",
+			"Url":            "https://example.com/user/messages",
+			"UnsubscribeUrl": "https://example.com/user/unsubscribe?token=xxx",
+		},
+	},
+	{
+		Name:        constants.EmailTemplateDigest,
+		Description: "摘要邮件",
+		Variables: commonVariables + "{{.PeriodName}} 摘要周期（每日、每周）；" +
+			"{{.Sections}} 内容列表，每项包含 Title 和 Items，Items 中每项包含 Title 和 Url；{{.UnsubscribeUrl}} 退订链接",
+		Subject: "{{.PeriodName}}摘要 - {{.SiteTitle}}",
+		Html: layout("{{.PeriodName}}摘要 - {{.SiteTitle}}", `
+        <p>以下是你在 {{.SiteTitle}} 错过的内容：</p>
+		{{range .Sections}}
+		<p style="margin:16px 0 4px 0;font-weight:bold;">{{.Title}}</p>
+		<ul style="margin:0;padding-left:18px;">
+			{{range .Items}}
+			<li style="margin:6px 0;">
+				<a style="text-decoration:none; color:#12addb" href="{{.Url}}" target="_blank" rel="noopener">{{.Title}}</a>
+			</li>
+			{{end}}
+		</ul>
+		{{end}}`),
+		Text: `以下是你在 {{.SiteTitle}} 错过的内容：
+{{range .Sections}}
+{{.Title}}
+{{range .Items}}- {{.Title}}
+  {{.Url}}
+{{end}}{{end}}
+{{if .UnsubscribeUrl}}退订邮件通知：{{.UnsubscribeUrl}}{{end}}`,
+		Sample: map[string]interface{}{
+			"PeriodName": "每日",
+			"Sections": []*Section{
+				{
+					Title: "你有 2 条未读消息",
+					Items: []*model.ActionLink{
+						{Title: "李四 回复了你的话题：写得很好", Url: "https://example.com/user/messages"},
+						{Title: "王五 回复了你的评论：同意", Url: "https://example.com/user/messages"},
+					},
+				},
+				{
+					Title: "热门话题",
+					Items: []*model.ActionLink{
+						{Title: "示例话题", Url: "https://example.com/topic/1"},
+					},
+				},
+			},
+			"UnsubscribeUrl": "https://example.com/user/unsubscribe?token=xxx",
+		},
+	},
+}
+
+// layout 内置模板的公共布局
+func layout(title, body string) string {
+	return `
+<div style="background-color:white;border-top:2px solid #12ADDB;box-shadow:0 1px 3px #AAAAAA;line-height:180%;padding:0 15px 12px;width:500px;margin:50px auto;color:#555555;font-family:'Century Gothic','Trebuchet MS','Hiragino Sans GB',微软雅黑,'Microsoft Yahei',Tahoma,Helvetica,Arial,'SimSun',sans-serif;font-size:12px;">
+    <h2 style="border-bottom:1px solid #DDD;font-size:14px;font-weight:normal;padding:13px 0 10px 8px;">
+        <span style="color: #12ADDB;font-weight:bold;">` + title + `</span>
+    </h2>
+    <div style="padding:0 12px 0 12px; margin-top:18px;">` + body + `
+    </div>
+	{{if .UnsubscribeUrl}}
+	<div style="padding:12px 12px 0 12px;border-top:1px solid #EEE;color:#999999;">
+		不想再收到此类邮件？<a style="text-decoration:none; color:#999999" href="{{.UnsubscribeUrl}}" target="_blank" rel="noopener">退订邮件通知</a>
+	</div>
+	{{end}}
+</div>
+`
+}
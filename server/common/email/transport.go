@@ -0,0 +1,128 @@
+package email
+
+import (
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jordan-wright/email"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/config"
+)
+
+// 发送方式
+const (
+	TransportSmtp = "smtp" // 通过SMTP服务器发送
+	TransportFile = "file" // 写入本地目录（Maildir格式），用于开发和测试
+	TransportLog  = "log"  // 仅打印日志
+)
+
+// Transport 邮件发送方式
+type Transport interface {
+	Send(e *email.Email) error
+}
+
+// GetTransport 根据配置获取发送方式，未配置Mail.Transport时，配置了SMTP服务器则使用smtp，否则返回nil
+func GetTransport() Transport {
+	switch config.Instance.Mail.Transport {
+	case TransportSmtp:
+		return &smtpTransport{}
+	case TransportFile:
+		return &fileTransport{dir: config.Instance.Mail.FileDir}
+	case TransportLog:
+		return &logTransport{}
+	case "":
+		if len(config.Instance.Smtp.Host) > 0 {
+			return &smtpTransport{}
+		}
+	default:
+		logrus.Error("不支持的邮件发送方式：", config.Instance.Mail.Transport)
+	}
+	return nil
+}
+
+type smtpTransport struct {
+}
+
+func (t *smtpTransport) Send(e *email.Email) error {
+	var (
+		host      = config.Instance.Smtp.Host
+		port      = config.Instance.Smtp.Port
+		username  = config.Instance.Smtp.Username
+		password  = config.Instance.Smtp.Password
+		ssl       = config.Instance.Smtp.SSL
+		addr      = net.JoinHostPort(host, port)
+		auth      = smtp.PlainAuth("", username, password, host)
+		tlsConfig = &tls.Config{
+			InsecureSkipVerify: true,
+			ServerName:         host,
+		}
+	)
+	if len(host) == 0 {
+		return errors.New("未配置SMTP服务器")
+	}
+
+	if ssl {
+		if err := e.SendWithTLS(addr, auth, tlsConfig); err != nil {
+			logrus.Error("发送邮件异常", err)
+			return err
+		}
+	} else {
+		if err := e.Send(addr, auth); err != nil {
+			logrus.Error("发送邮件异常", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// fileTransport 将邮件按Maildir格式写入目录：先写入tmp，完成后移动到new，可以直接用邮件客户端打开
+type fileTransport struct {
+	dir string
+}
+
+var fileSeq uint64
+
+func (t *fileTransport) Send(e *email.Email) error {
+	dir := t.dir
+	if len(dir) == 0 {
+		dir = "mails"
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), os.ModePerm); err != nil {
+			return err
+		}
+	}
+	data, err := e.Bytes()
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + "." + strconv.Itoa(os.Getpid()) + "_" +
+		strconv.FormatUint(atomic.AddUint64(&fileSeq, 1), 10) + "." + hostname + ".eml"
+	tmpFile := filepath.Join(dir, "tmp", name)
+	if err := ioutil.WriteFile(tmpFile, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpFile, filepath.Join(dir, "new", name))
+}
+
+type logTransport struct {
+}
+
+func (t *logTransport) Send(e *email.Email) error {
+	logrus.WithFields(logrus.Fields{
+		"type":    "email",
+		"to":      e.To,
+		"subject": e.Subject,
+	}).Info(string(e.Text))
+	return nil
+}
@@ -0,0 +1,193 @@
+package event
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Event 领域事件
+type Event interface {
+	// Key 事件所属的实体，同一实体的事件按发布顺序异步处理
+	Key() string
+}
+
+var eventType = reflect.TypeOf((*Event)(nil)).Elem()
+
+// 异步处理的队列数和每个队列的长度
+const (
+	asyncWorkers   = 8
+	asyncQueueSize = 1024
+)
+
+var defaultBus = NewBus(asyncWorkers, asyncQueueSize)
+
+// Subscribe 同步订阅，处理函数在发布事件的协程中按订阅顺序执行，fn 的格式为：func(*XxxEvent)
+func Subscribe(fn interface{}) {
+	defaultBus.Subscribe(fn)
+}
+
+// SubscribeAsync 异步订阅，fn 的格式为：func(*XxxEvent)
+func SubscribeAsync(fn interface{}) {
+	defaultBus.SubscribeAsync(fn)
+}
+
+// Publish 发布事件
+func Publish(e Event) {
+	defaultBus.Publish(e)
+}
+
+// Wait 等待异步队列中的事件处理完成，超时后返回false，用于停机前处理完内存中的事件
+func Wait(timeout time.Duration) bool {
+	return defaultBus.Wait(timeout)
+}
+
+// Overflow 异步队列已满时在新协程中处理的事件数
+func Overflow() int64 {
+	return defaultBus.Overflow()
+}
+
+type handler struct {
+	name string
+	fn   reflect.Value
+}
+
+type asyncTask struct {
+	event    Event
+	handlers []handler
+}
+
+type Bus struct {
+	pending       int64 // 已放入队列但未处理完成的事件数，放在第一个字段保证32位平台上原子操作的对齐
+	overflow      int64 // 队列已满时在新协程中处理的事件数
+	mutex         sync.RWMutex
+	syncHandlers  map[reflect.Type][]handler
+	asyncHandlers map[reflect.Type][]handler
+	queues        []chan *asyncTask
+	startOnce     sync.Once
+}
+
+// NewBus 创建事件总线，异步事件按Key分配到 workers 个队列中，每个队列由一个协程顺序处理
+func NewBus(workers, queueSize int) *Bus {
+	b := &Bus{
+		syncHandlers:  make(map[reflect.Type][]handler),
+		asyncHandlers: make(map[reflect.Type][]handler),
+		queues:        make([]chan *asyncTask, workers),
+	}
+	for i := range b.queues {
+		b.queues[i] = make(chan *asyncTask, queueSize)
+	}
+	return b
+}
+
+func (b *Bus) Subscribe(fn interface{}) {
+	t, h := newHandler(fn)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.syncHandlers[t] = append(b.syncHandlers[t], h)
+}
+
+func (b *Bus) SubscribeAsync(fn interface{}) {
+	t, h := newHandler(fn)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.asyncHandlers[t] = append(b.asyncHandlers[t], h)
+}
+
+// Publish 发布事件，先执行同步处理函数，然后将事件放入异步队列；
+// 队列已满时不阻塞发布方（异步处理函数中发布事件时可能就是在处理该队列），在新协程中处理，不再保证同一实体的顺序；
+// 处理函数的panic会被捕获并记录日志，不影响其他处理函数和发布方
+func (b *Bus) Publish(e Event) {
+	t := reflect.TypeOf(e)
+	b.mutex.RLock()
+	syncHandlers := b.syncHandlers[t]
+	asyncHandlers := b.asyncHandlers[t]
+	b.mutex.RUnlock()
+
+	for _, h := range syncHandlers {
+		h.call(e)
+	}
+	if len(asyncHandlers) > 0 {
+		b.startOnce.Do(b.start)
+		atomic.AddInt64(&b.pending, 1)
+		task := &asyncTask{event: e, handlers: asyncHandlers}
+		select {
+		case b.queues[b.queueIndex(e.Key())] <- task:
+		default:
+			if overflow := atomic.AddInt64(&b.overflow, 1); overflow%1000 == 1 {
+				logrus.Warn("event queue is full, handled in new goroutine: ", overflow)
+			}
+			go b.run(task)
+		}
+	}
+}
+
+// Overflow 队列已满时在新协程中处理的事件数
+func (b *Bus) Overflow() int64 {
+	return atomic.LoadInt64(&b.overflow)
+}
+
+// Wait 等待异步队列中的事件处理完成，处理过程中发布的新事件也会等待
+func (b *Bus) Wait(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for atomic.LoadInt64(&b.pending) > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
+
+func (b *Bus) start() {
+	for _, queue := range b.queues {
+		go func(queue chan *asyncTask) {
+			for task := range queue {
+				b.run(task)
+			}
+		}(queue)
+	}
+}
+
+func (b *Bus) run(task *asyncTask) {
+	for _, h := range task.handlers {
+		h.call(task.event)
+	}
+	atomic.AddInt64(&b.pending, -1)
+}
+
+func (b *Bus) queueIndex(key string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(b.queues)))
+}
+
+func (h handler) call(e Event) {
+	defer func() {
+		if err := recover(); err != nil {
+			logrus.Errorf("event handler %s panic: %v\n%s", h.name, err, debug.Stack())
+		}
+	}()
+	h.fn.Call([]reflect.Value{reflect.ValueOf(e)})
+}
+
+// newHandler 校验处理函数的格式，格式错误属于编码错误，直接panic
+func newHandler(fn interface{}) (reflect.Type, handler) {
+	v := reflect.ValueOf(fn)
+	t := v.Type()
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 || !t.In(0).Implements(eventType) ||
+		t.In(0).Kind() == reflect.Interface {
+		panic(fmt.Sprintf("event handler must be func(*XxxEvent), got %s", t))
+	}
+	return t.In(0), handler{
+		name: runtime.FuncForPC(v.Pointer()).Name(),
+		fn:   v,
+	}
+}
@@ -0,0 +1,157 @@
+package event
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	key string
+	n   int
+}
+
+func (e *testEvent) Key() string {
+	return e.key
+}
+
+// recorder 记录处理过的事件，按Key分组
+type recorder struct {
+	mutex  sync.Mutex
+	events map[string][]int
+}
+
+func (r *recorder) handle(e *testEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.events == nil {
+		r.events = make(map[string][]int)
+	}
+	r.events[e.key] = append(r.events[e.key], e.n)
+}
+
+func (r *recorder) count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	count := 0
+	for _, events := range r.events {
+		count += len(events)
+	}
+	return count
+}
+
+func TestPublish(t *testing.T) {
+	b := NewBus(4, 128)
+	var calls []string
+	b.Subscribe(func(e *testEvent) { calls = append(calls, "first") })
+	b.Subscribe(func(e *testEvent) {
+		if e.n == 0 {
+			panic("broken handler")
+		}
+	})
+	b.Subscribe(func(e *testEvent) { calls = append(calls, "second") })
+	r := &recorder{}
+	b.SubscribeAsync(r.handle)
+
+	for i := 0; i < 100; i++ {
+		b.Publish(&testEvent{key: "k" + strconv.Itoa(i%3), n: i})
+	}
+	if !b.Wait(5 * time.Second) {
+		t.Fatal("wait timeout")
+	}
+	// 同步处理函数按订阅顺序执行，panic 不影响其他处理函数
+	if len(calls) != 200 || calls[0] != "first" || calls[1] != "second" {
+		t.Fatalf("unexpected sync calls: %d %v", len(calls), calls[:2])
+	}
+	// 队列未满时同一Key的事件按发布顺序处理
+	if r.count() != 100 || b.Overflow() != 0 {
+		t.Fatalf("unexpected async result: count=%d overflow=%d", r.count(), b.Overflow())
+	}
+	for key, events := range r.events {
+		for i := 1; i < len(events); i++ {
+			if events[i] < events[i-1] {
+				t.Fatalf("events of %s out of order: %v", key, events)
+			}
+		}
+	}
+}
+
+func TestPublishWhenQueueFull(t *testing.T) {
+	b := NewBus(1, 1)
+	release := make(chan struct{})
+	r := &recorder{}
+	b.SubscribeAsync(func(e *testEvent) {
+		if e.n == 0 {
+			<-release
+		}
+		r.handle(e)
+	})
+
+	// 第一个事件阻塞处理协程，队列已满后发布方不阻塞
+	published := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			b.Publish(&testEvent{key: "k", n: i})
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("publish blocked when queue is full")
+	}
+	if b.Overflow() == 0 {
+		t.Fatal("overflow should be counted")
+	}
+	if b.Wait(100 * time.Millisecond) {
+		t.Fatal("wait should time out while handler is blocked")
+	}
+
+	close(release)
+	if !b.Wait(5 * time.Second) {
+		t.Fatal("wait timeout")
+	}
+	if r.count() != 10 {
+		t.Fatalf("expected 10 events handled, got %d", r.count())
+	}
+}
+
+// 异步处理函数向自己所在的队列发布事件不会死锁
+func TestPublishFromAsyncHandler(t *testing.T) {
+	b := NewBus(1, 1)
+	r := &recorder{}
+	b.SubscribeAsync(func(e *testEvent) {
+		r.handle(e)
+		if e.n == 0 {
+			for i := 1; i <= 10; i++ {
+				b.Publish(&testEvent{key: e.key, n: i})
+			}
+		}
+	})
+	b.Publish(&testEvent{key: "k", n: 0})
+	if !b.Wait(5 * time.Second) {
+		t.Fatal("wait timeout, handler may be deadlocked")
+	}
+	if r.count() != 11 {
+		t.Fatalf("expected 11 events handled, got %d", r.count())
+	}
+}
+
+func TestInvalidHandler(t *testing.T) {
+	for _, fn := range []interface{}{
+		func(e testEvent) {},
+		func(e Event) {},
+		func(e *testEvent) error { return nil },
+		"handler",
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%T should be rejected", fn)
+				}
+			}()
+			NewBus(1, 1).Subscribe(fn)
+		}()
+	}
+}
@@ -0,0 +1,137 @@
+package event
+
+import (
+	"strconv"
+
+	"bbs-go/model"
+	"bbs-go/model/constants"
+)
+
+// TopicPublished 发表话题
+type TopicPublished struct {
+	Topic *model.Topic
+	Tags  []string
+}
+
+func (e *TopicPublished) Key() string {
+	return entityKey(constants.EntityTopic, e.Topic.Id)
+}
+
+// ArticlePublished 发表文章
+type ArticlePublished struct {
+	Article *model.Article
+	Tags    []string
+}
+
+func (e *ArticlePublished) Key() string {
+	return entityKey(constants.EntityArticle, e.Article.Id)
+}
+
+// TweetPublished 发表动态
+type TweetPublished struct {
+	Tweet *model.Tweet
+}
+
+func (e *TweetPublished) Key() string {
+	return entityKey(constants.EntityTweet, e.Tweet.Id)
+}
+
+// CommentCreated 发表评论，同一内容下的评论按顺序处理
+type CommentCreated struct {
+	Comment *model.Comment
+}
+
+func (e *CommentCreated) Key() string {
+	return entityKey(e.Comment.EntityType, e.Comment.EntityId)
+}
+
+// ContentDeleted 删除话题、文章、评论、动态
+type ContentDeleted struct {
+	EntityType string
+	EntityId   int64
+	OperatorId int64 // 操作人
+}
+
+func (e *ContentDeleted) Key() string {
+	return entityKey(e.EntityType, e.EntityId)
+}
+
+// ContentRestored 恢复已删除的内容
+type ContentRestored struct {
+	EntityType string
+	EntityId   int64
+	OperatorId int64 // 操作人
+}
+
+func (e *ContentRestored) Key() string {
+	return entityKey(e.EntityType, e.EntityId)
+}
+
+// TopicRecommended 推荐、取消推荐话题
+type TopicRecommended struct {
+	TopicId    int64
+	Recommend  bool
+	OperatorId int64 // 操作人
+}
+
+func (e *TopicRecommended) Key() string {
+	return entityKey(constants.EntityTopic, e.TopicId)
+}
+
+// UserSignedUp 用户注册
+type UserSignedUp struct {
+	User *model.User
+}
+
+func (e *UserSignedUp) Key() string {
+	return entityKey(constants.EntityUser, e.User.Id)
+}
+
+// UserForbidden 禁言
+type UserForbidden struct {
+	UserId           int64
+	Days             int
+	ForbiddenEndTime int64
+	Reason           string
+	OperatorId       int64 // 操作人
+}
+
+func (e *UserForbidden) Key() string {
+	return entityKey(constants.EntityUser, e.UserId)
+}
+
+// UserForbiddenRemoved 解除禁言
+type UserForbiddenRemoved struct {
+	UserId     int64
+	OperatorId int64 // 操作人
+}
+
+func (e *UserForbiddenRemoved) Key() string {
+	return entityKey(constants.EntityUser, e.UserId)
+}
+
+// UsersMerged 合并账号，FromUserId 的内容已转移到 ToUserId
+type UsersMerged struct {
+	FromUserId int64
+	ToUserId   int64
+	OperatorId int64 // 操作人
+}
+
+func (e *UsersMerged) Key() string {
+	return entityKey(constants.EntityUser, e.ToUserId)
+}
+
+// UserDeleted 注销账号，用户资料已匿名化
+type UserDeleted struct {
+	UserId     int64
+	Purge      bool  // 是否同时删除了发表的内容
+	OperatorId int64 // 操作人，用户自己申请时为用户编号
+}
+
+func (e *UserDeleted) Key() string {
+	return entityKey(constants.EntityUser, e.UserId)
+}
+
+func entityKey(entityType string, entityId int64) string {
+	return entityType + ":" + strconv.FormatInt(entityId, 10)
+}
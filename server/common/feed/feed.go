@@ -0,0 +1,139 @@
+package feed
+
+import (
+	"time"
+
+	"github.com/gorilla/feeds"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/common/urls"
+	"bbs-go/model"
+)
+
+// 订阅格式
+const (
+	FormatRss  = "rss"
+	FormatAtom = "atom"
+	FormatJson = "json"
+)
+
+// Feed 订阅源
+type Feed struct {
+	Title       string
+	Link        string
+	Description string
+	Items       []*Item
+}
+
+// Item 订阅条目
+type Item struct {
+	Title       string
+	Link        string
+	Description string
+	Author      *model.User
+	Created     int64 // 发布时间，毫秒
+	Updated     int64 // 更新时间，毫秒，为0时使用发布时间
+}
+
+// New 创建订阅源
+func New(title, link, description string) *Feed {
+	return &Feed{Title: title, Link: link, Description: description}
+}
+
+// Add 添加条目
+func (f *Feed) Add(item *Item) {
+	f.Items = append(f.Items, item)
+}
+
+// LastModified 所有条目中最后的更新时间，毫秒
+func (f *Feed) LastModified() (ret int64) {
+	for _, item := range f.Items {
+		if t := item.updated(); t > ret {
+			ret = t
+		}
+	}
+	return
+}
+
+// ParseFormat 解析订阅格式，不支持的格式返回rss
+func ParseFormat(format string) string {
+	switch format {
+	case FormatAtom, FormatJson:
+		return format
+	default:
+		return FormatRss
+	}
+}
+
+// Render 按格式输出，返回 Content-Type 和内容
+func (f *Feed) Render(format string) (contentType string, body string, err error) {
+	switch ParseFormat(format) {
+	case FormatAtom:
+		body, err = f.toFeeds().ToAtom()
+		contentType = "application/atom+xml; charset=utf-8"
+	case FormatJson:
+		body, err = f.toJson()
+		contentType = "application/feed+json; charset=utf-8"
+	default:
+		body, err = f.toFeeds().ToRss()
+		contentType = "application/rss+xml; charset=utf-8"
+	}
+	return
+}
+
+// ToAtom 生成Atom
+func (f *Feed) ToAtom() (string, error) {
+	return f.toFeeds().ToAtom()
+}
+
+// ToRss 生成RSS 2.0
+func (f *Feed) ToRss() (string, error) {
+	return f.toFeeds().ToRss()
+}
+
+func (f *Feed) toFeeds() *feeds.Feed {
+	ret := &feeds.Feed{
+		Title:       f.Title,
+		Link:        &feeds.Link{Href: f.Link},
+		Description: f.Description,
+		Author:      &feeds.Author{Name: f.Title},
+		Created:     time.Now(),
+	}
+	if lastModified := f.LastModified(); lastModified > 0 {
+		ret.Updated = simple.TimeFromTimestamp(lastModified)
+	}
+	for _, item := range f.Items {
+		ret.Items = append(ret.Items, &feeds.Item{
+			Id:          item.Link,
+			Title:       item.Title,
+			Link:        &feeds.Link{Href: item.Link},
+			Description: item.Description,
+			Author:      author(item.Author),
+			Created:     simple.TimeFromTimestamp(item.Created),
+			Updated:     simple.TimeFromTimestamp(item.updated()),
+		})
+	}
+	return ret
+}
+
+func (item *Item) updated() int64 {
+	if item.Updated > item.Created {
+		return item.Updated
+	}
+	return item.Created
+}
+
+// author 作者使用昵称，不公开邮箱
+func author(user *model.User) *feeds.Author {
+	if user == nil {
+		return nil
+	}
+	return &feeds.Author{Name: user.Nickname}
+}
+
+func authorUrl(user *model.User) string {
+	if user == nil {
+		return ""
+	}
+	return urls.UserUrl(user.Id)
+}
@@ -0,0 +1,77 @@
+package feed
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/mlogclub/simple"
+)
+
+// JSON Feed 1.1，文档：https://www.jsonfeed.org/version/1.1/
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+type jsonFeed struct {
+	Version     string     `json:"version"`
+	Title       string     `json:"title"`
+	HomePageUrl string     `json:"home_page_url,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Items       []jsonItem `json:"items"`
+}
+
+type jsonItem struct {
+	Id            string       `json:"id"`
+	Url           string       `json:"url,omitempty"`
+	Title         string       `json:"title,omitempty"`
+	ContentText   string       `json:"content_text"`
+	DatePublished string       `json:"date_published,omitempty"`
+	DateModified  string       `json:"date_modified,omitempty"`
+	Authors       []jsonAuthor `json:"authors,omitempty"`
+}
+
+type jsonAuthor struct {
+	Name   string `json:"name,omitempty"`
+	Url    string `json:"url,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+func (f *Feed) toJson() (string, error) {
+	ret := &jsonFeed{
+		Version:     jsonFeedVersion,
+		Title:       f.Title,
+		HomePageUrl: f.Link,
+		Description: f.Description,
+		Items:       []jsonItem{},
+	}
+	for _, item := range f.Items {
+		ji := jsonItem{
+			Id:            item.Link,
+			Url:           item.Link,
+			Title:         item.Title,
+			ContentText:   item.Description,
+			DatePublished: formatTime(item.Created),
+		}
+		if item.updated() > item.Created {
+			ji.DateModified = formatTime(item.updated())
+		}
+		if item.Author != nil {
+			ji.Authors = []jsonAuthor{{
+				Name:   item.Author.Nickname,
+				Url:    authorUrl(item.Author),
+				Avatar: item.Author.Avatar,
+			}}
+		}
+		ret.Items = append(ret.Items, ji)
+	}
+	data, err := json.Marshal(ret)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func formatTime(timestamp int64) string {
+	if timestamp <= 0 {
+		return ""
+	}
+	return simple.TimeFromTimestamp(timestamp).Format(time.RFC3339)
+}
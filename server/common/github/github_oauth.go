@@ -11,6 +11,7 @@ import (
 	"golang.org/x/oauth2"
 
 	"bbs-go/common"
+	"bbs-go/common/tracing"
 	"bbs-go/config"
 )
 
@@ -56,7 +57,7 @@ func AuthCodeURL(params map[string]string) string {
 
 // 根据code获取用户信息
 // 流程为先使用code换取accessToken，然后根据accessToken获取用户信息
-func GetUserInfoByCode(code, state string) (*UserInfo, error) {
+func GetUserInfoByCode(ctx context.Context, code, state string) (*UserInfo, error) {
 	// 从上下文中获取跳转地址
 	val, found := ctxCache.GetIfPresent(state)
 	var redirectUrl string
@@ -64,18 +65,20 @@ func GetUserInfoByCode(code, state string) (*UserInfo, error) {
 		redirectUrl = val.(string)
 	}
 
-	token, err := newOauthConfig(redirectUrl).Exchange(context.TODO(), code)
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, tracing.Client(10*time.Second))
+	token, err := newOauthConfig(redirectUrl).Exchange(ctx, code)
 	if err != nil {
 		return nil, err
 	}
-	return GetUserInfo(token.AccessToken)
+	return GetUserInfo(ctx, token.AccessToken)
 }
 
 // 根据accessToken获取用户信息
-func GetUserInfo(accessToken string) (*UserInfo, error) {
-	response, err := resty.New().R().SetQueryParam("access_token", accessToken).Get("https://api.github.com/user")
+func GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	response, err := resty.New().SetTransport(tracing.Transport(nil)).R().SetContext(ctx).
+		SetQueryParam("access_token", accessToken).Get("https://api.github.com/user")
 	if err != nil {
-		logrus.Errorf("Get user info error %s", err)
+		logrus.WithContext(ctx).Errorf("Get user info error %s", err)
 		return nil, err
 	}
 	content := string(response.Body())
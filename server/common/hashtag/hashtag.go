@@ -0,0 +1,54 @@
+package hashtag
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 匹配 #话题，名称由文字、数字、_ 组成，不能全是数字
+var hashtagRegexp = regexp.MustCompile(`#([\p{L}\p{N}_]{1,32})`)
+
+// Extract 提取文本中的话题标签，去重（不区分大小写）并保持出现顺序，最多返回 max 个
+func Extract(text string, max int) []string {
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+	for _, loc := range hashtagRegexp.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > 0 {
+			// 排除网址中的锚点、HTML实体等 # 前面是字母、数字的情况
+			prev, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+			if prev < utf8.RuneSelf && (isAlnum(prev) || strings.ContainsRune("_&/#", prev)) {
+				continue
+			}
+		}
+		name := text[loc[2]:loc[3]]
+		if isDigits(name) {
+			continue
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+		if max > 0 && len(names) >= max {
+			break
+		}
+	}
+	return names
+}
+
+func isAlnum(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
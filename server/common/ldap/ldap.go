@@ -0,0 +1,185 @@
+package ldap
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"bbs-go/config"
+)
+
+var (
+	// ErrUserNotFound 目录中不存在该用户
+	ErrUserNotFound = errors.New("用户不存在")
+	// ErrInvalidCredentials 用户存在但密码错误
+	ErrInvalidCredentials = errors.New("密码错误")
+)
+
+// 连接、查询超时时间
+const timeout = 10 * time.Second
+
+type UserInfo struct {
+	DN       string   `json:"dn"`
+	Username string   `json:"username"`
+	Nickname string   `json:"nickname"`
+	Email    string   `json:"email"`
+	Groups   []string `json:"groups"` // 所属组DN
+}
+
+// IsEnabled 是否开启了LDAP登录
+func IsEnabled() bool {
+	return config.Instance.Ldap.Enabled && len(config.Instance.Ldap.Url) > 0
+}
+
+// Authenticate 使用用户名和密码登录，用户不存在时返回 ErrUserNotFound，密码错误时返回 ErrInvalidCredentials，
+// 其他错误（连接失败、超时、查询账号配置错误等）表示无法确定登录结果
+// 流程为先使用查询账号查找用户，然后使用用户的DN和密码进行绑定校验密码
+func Authenticate(username, password string) (*UserInfo, error) {
+	if len(username) == 0 || len(password) == 0 {
+		// 空密码会被服务器当作匿名绑定处理，必须拒绝
+		return nil, errors.New("用户名或密码不能为空")
+	}
+	c := &config.Instance.Ldap
+
+	conn, err := dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if len(c.BindDN) > 0 {
+		if err := conn.Bind(c.BindDN, c.BindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP查询账号登录失败：%v", err)
+		}
+	}
+
+	entry, err := findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	userInfo := &UserInfo{
+		DN:       entry.DN,
+		Username: entry.GetAttributeValue(attribute(c.UsernameAttribute, "uid")),
+		Nickname: entry.GetAttributeValue(attribute(c.NicknameAttribute, "cn")),
+		Email:    entry.GetAttributeValue(attribute(c.EmailAttribute, "mail")),
+	}
+	if len(userInfo.Username) == 0 {
+		userInfo.Username = username
+	}
+	if len(c.GroupAttribute) > 0 {
+		userInfo.Groups = entry.GetAttributeValues(c.GroupAttribute)
+	}
+	if len(c.GroupFilter) > 0 {
+		// 重新使用查询账号绑定，用户自己可能没有查询组的权限
+		if len(c.BindDN) > 0 {
+			if err := conn.Bind(c.BindDN, c.BindPassword); err != nil {
+				return nil, err
+			}
+		}
+		groups, err := findGroups(conn, entry.DN)
+		if err != nil {
+			return nil, err
+		}
+		userInfo.Groups = append(userInfo.Groups, groups...)
+	}
+	return userInfo, nil
+}
+
+func findUser(conn *goldap.Conn, username string) (*goldap.Entry, error) {
+	c := &config.Instance.Ldap
+	userFilter := c.UserFilter
+	if len(userFilter) == 0 {
+		userFilter = "(uid=%s)"
+	}
+
+	attributes := []string{
+		attribute(c.UsernameAttribute, "uid"),
+		attribute(c.NicknameAttribute, "cn"),
+		attribute(c.EmailAttribute, "mail"),
+	}
+	if len(c.GroupAttribute) > 0 {
+		attributes = append(attributes, c.GroupAttribute)
+	}
+	result, err := conn.Search(goldap.NewSearchRequest(
+		c.BaseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 2, int(timeout.Seconds()), false,
+		strings.ReplaceAll(userFilter, "%s", goldap.EscapeFilter(username)), attributes, nil,
+	))
+	if err != nil {
+		if goldap.IsErrorWithCode(err, goldap.LDAPResultNoSuchObject) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if len(result.Entries) == 0 {
+		return nil, ErrUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, errors.New("LDAP中存在多个同名用户")
+	}
+	return result.Entries[0], nil
+}
+
+func findGroups(conn *goldap.Conn, userDN string) ([]string, error) {
+	c := &config.Instance.Ldap
+	baseDN := c.GroupBaseDN
+	if len(baseDN) == 0 {
+		baseDN = c.BaseDN
+	}
+	result, err := conn.Search(goldap.NewSearchRequest(
+		baseDN, goldap.ScopeWholeSubtree, goldap.NeverDerefAliases, 0, int(timeout.Seconds()), false,
+		strings.ReplaceAll(c.GroupFilter, "%s", goldap.EscapeFilter(userDN)), []string{"dn"}, nil,
+	))
+	if err != nil {
+		return nil, err
+	}
+	var groups []string
+	for _, entry := range result.Entries {
+		groups = append(groups, entry.DN)
+	}
+	return groups, nil
+}
+
+func dial() (*goldap.Conn, error) {
+	c := &config.Instance.Ldap
+	u, err := url.Parse(c.Url)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+	conn, err := goldap.DialURL(c.Url, goldap.DialWithDialer(&net.Dialer{Timeout: timeout}), goldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+	if c.StartTLS && u.Scheme == "ldap" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func attribute(name, defaultName string) string {
+	if len(name) > 0 {
+		return name
+	}
+	return defaultName
+}
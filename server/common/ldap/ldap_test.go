@@ -0,0 +1,190 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	goldap "github.com/go-ldap/ldap/v3"
+
+	"bbs-go/config"
+)
+
+// testUser 测试目录中的用户
+type testUser struct {
+	dn       string
+	uid      string
+	password string
+	cn       string
+	mail     string
+}
+
+// testServer 只实现了 Bind 和 Search 的LDAP服务器，Search 只按过滤条件中的第一个等值条件匹配 uid
+type testServer struct {
+	listener net.Listener
+	users    []testUser
+}
+
+func newTestServer(t *testing.T, users ...testUser) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testServer{listener: listener, users: users}
+	go s.serve()
+	return s
+}
+
+func (s *testServer) Url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *testServer) Close() {
+	_ = s.listener.Close()
+}
+
+func (s *testServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *testServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		packet, err := ber.ReadPacket(reader)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageId := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case goldap.ApplicationBindRequest:
+			dn := op.Children[1].Value.(string)
+			password := op.Children[2].Data.String()
+			code := uint16(goldap.LDAPResultInvalidCredentials)
+			for _, user := range s.users {
+				if user.dn == dn && user.password == password {
+					code = goldap.LDAPResultSuccess
+				}
+			}
+			s.write(conn, messageId, result(goldap.ApplicationBindResponse, code))
+		case goldap.ApplicationSearchRequest:
+			uid := equalityValue(op.Children[6])
+			for _, user := range s.users {
+				if user.uid == uid {
+					s.write(conn, messageId, entry(user))
+				}
+			}
+			s.write(conn, messageId, result(goldap.ApplicationSearchResultDone, goldap.LDAPResultSuccess))
+		default: // Unbind
+			return
+		}
+	}
+}
+
+func (s *testServer) write(conn net.Conn, messageId int64, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "MessageID"))
+	packet.AppendChild(op)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func result(tag ber.Tag, code uint16) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "resultCode"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return packet
+}
+
+func entry(user testUser) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, goldap.ApplicationSearchResultEntry, nil, "Entry")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, user.dn, "objectName"))
+	attributes := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, value := range map[string]string{"uid": user.uid, "cn": user.cn, "mail": user.mail} {
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		values := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		values.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "value"))
+		attribute.AppendChild(values)
+		attributes.AppendChild(attribute)
+	}
+	packet.AppendChild(attributes)
+	return packet
+}
+
+// equalityValue 过滤条件中第一个等值条件的值
+func equalityValue(filter *ber.Packet) string {
+	if filter.ClassType == ber.ClassContext && filter.Tag == goldap.FilterEqualityMatch && len(filter.Children) == 2 {
+		return filter.Children[1].Data.String()
+	}
+	for _, child := range filter.Children {
+		if value := equalityValue(child); len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
+
+func setConfig(url string) {
+	config.Instance = &config.Config{}
+	config.Instance.Ldap.Enabled = true
+	config.Instance.Ldap.Url = url
+	config.Instance.Ldap.BindDN = "cn=readonly,dc=example,dc=com"
+	config.Instance.Ldap.BindPassword = "readonly"
+	config.Instance.Ldap.BaseDN = "ou=people,dc=example,dc=com"
+}
+
+func TestAuthenticate(t *testing.T) {
+	server := newTestServer(t,
+		testUser{dn: "cn=readonly,dc=example,dc=com", password: "readonly"},
+		testUser{dn: "uid=alice,ou=people,dc=example,dc=com", uid: "alice", password: "secret", cn: "Alice", mail: "alice@example.com"},
+	)
+	defer server.Close()
+	setConfig(server.Url())
+
+	userInfo, err := Authenticate("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if userInfo.DN != "uid=alice,ou=people,dc=example,dc=com" || userInfo.Username != "alice" ||
+		userInfo.Nickname != "Alice" || userInfo.Email != "alice@example.com" {
+		t.Fatalf("unexpected user info: %+v", userInfo)
+	}
+
+	if _, err := Authenticate("alice", "wrong"); err != ErrInvalidCredentials {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := Authenticate("bob", "secret"); err != ErrUserNotFound {
+		t.Fatalf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+// 服务不可用时返回的错误不能被当作密码错误或用户不存在，调用方据此回退到本地账号登录
+func TestAuthenticateUnavailable(t *testing.T) {
+	server := newTestServer(t)
+	url := server.Url()
+	server.Close()
+	setConfig(url)
+
+	_, err := Authenticate("alice", "secret")
+	if err == nil || err == ErrInvalidCredentials || err == ErrUserNotFound {
+		t.Fatalf("expected connection error, got %v", err)
+	}
+
+	// 查询账号密码错误同样无法确定用户的登录结果
+	server = newTestServer(t)
+	defer server.Close()
+	setConfig(server.Url())
+	_, err = Authenticate("alice", "secret")
+	if err == nil || err == ErrInvalidCredentials || err == ErrUserNotFound {
+		t.Fatalf("expected bind error, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package logging
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/tracing"
+	"bbs-go/config"
+)
+
+// Init 初始化日志，console 为 true 时（执行子命令）输出到控制台，否则输出到 LogFile 并按配置切分
+func Init(console bool) {
+	conf := config.Instance.Log
+	level, err := logrus.ParseLevel(conf.Level)
+	if err != nil {
+		level = logrus.InfoLevel
+	}
+	logrus.SetLevel(level)
+	if conf.Format == "text" {
+		logrus.SetFormatter(&logrus.TextFormatter{})
+	} else {
+		logrus.SetFormatter(&logrus.JSONFormatter{TimestampFormat: time.RFC3339Nano})
+	}
+	logrus.AddHook(contextHook{})
+
+	if console || len(config.Instance.LogFile) == 0 {
+		logrus.SetOutput(os.Stdout)
+		return
+	}
+	maxSize := conf.MaxSize
+	if maxSize == 0 {
+		maxSize = 100
+	}
+	maxBackups := conf.MaxBackups
+	if maxBackups == 0 {
+		maxBackups = 7
+	}
+	writer, err := newRotateWriter(config.Instance.LogFile, maxSize, maxBackups, conf.MaxAge, conf.Daily)
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+	logrus.SetOutput(writer)
+}
+
+type contextKey struct{}
+
+// requestInfo 请求相关的日志字段，用户编号在登录校验后才能确定，所以使用指针保存在上下文中
+type requestInfo struct {
+	userId    int64
+	requestId string
+}
+
+// NewContext 创建携带请求编号的上下文
+func NewContext(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestInfo{requestId: requestId})
+}
+
+// RequestId 上下文中的请求编号
+func RequestId(ctx context.Context) string {
+	if info := getRequestInfo(ctx); info != nil {
+		return info.requestId
+	}
+	return ""
+}
+
+// SetUserId 设置当前登录用户，之后输出的日志都会带上用户编号
+func SetUserId(ctx context.Context, userId int64) {
+	if info := getRequestInfo(ctx); info != nil {
+		atomic.StoreInt64(&info.userId, userId)
+	}
+}
+
+// UserId 上下文中的当前登录用户编号，未登录时为0
+func UserId(ctx context.Context) int64 {
+	if info := getRequestInfo(ctx); info != nil {
+		return atomic.LoadInt64(&info.userId)
+	}
+	return 0
+}
+
+func getRequestInfo(ctx context.Context) *requestInfo {
+	if ctx == nil {
+		return nil
+	}
+	info, _ := ctx.Value(contextKey{}).(*requestInfo)
+	return info
+}
+
+// contextHook 通过 logrus.WithContext(ctx) 输出日志时，添加上下文中的请求编号、用户编号和 trace id
+type contextHook struct{}
+
+func (contextHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (contextHook) Fire(entry *logrus.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+	fields := logrus.Fields{}
+	if info := getRequestInfo(entry.Context); info != nil {
+		fields["request_id"] = info.requestId
+		if userId := atomic.LoadInt64(&info.userId); userId > 0 {
+			fields["user_id"] = userId
+		}
+	}
+	if span := tracing.SpanFromContext(entry.Context); span != nil {
+		fields["trace_id"] = span.TraceIdString()
+		fields["span_id"] = span.SpanIdString()
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	// Data 可能被多条日志共用，复制后再添加
+	data := make(logrus.Fields, len(entry.Data)+len(fields))
+	for k, v := range fields {
+		data[k] = v
+	}
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	entry.Data = data
+	return nil
+}
@@ -0,0 +1,71 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/tracing"
+)
+
+func newTestLogger() (*logrus.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(contextHook{})
+	return logger, buf
+}
+
+func decode(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	return fields
+}
+
+func TestContextFields(t *testing.T) {
+	tracing.Init(tracing.Options{Exporter: tracing.NewInMemoryExporter(), Synchronous: true})
+	logger, buf := newTestLogger()
+
+	ctx := NewContext(context.Background(), "req-1")
+	logger.WithContext(ctx).Info("anonymous")
+	fields := decode(t, buf)
+	if fields["request_id"] != "req-1" {
+		t.Fatalf("unexpected request_id: %v", fields["request_id"])
+	}
+	if _, ok := fields["user_id"]; ok {
+		t.Fatal("user_id should be omitted before sign in")
+	}
+
+	// 用户编号在登录校验后设置，之前创建的上下文同样生效
+	ctx, span := tracing.Start(ctx, "request", tracing.KindServer)
+	defer span.End()
+	SetUserId(ctx, 7)
+	logger.WithContext(ctx).WithField("foo", "bar").Warn("signed in")
+	fields = decode(t, buf)
+	if fields["request_id"] != "req-1" || fields["user_id"] != float64(7) || fields["foo"] != "bar" {
+		t.Fatalf("unexpected fields: %v", fields)
+	}
+	if fields["trace_id"] != span.TraceIdString() || fields["span_id"] != span.SpanIdString() {
+		t.Fatalf("unexpected trace fields: %v", fields)
+	}
+	if RequestId(ctx) != "req-1" || UserId(ctx) != 7 {
+		t.Fatal("context values not readable")
+	}
+
+	// 没有上下文的日志不添加字段
+	logger.Info("plain")
+	fields = decode(t, buf)
+	for _, key := range []string{"request_id", "user_id", "trace_id"} {
+		if _, ok := fields[key]; ok {
+			t.Fatalf("%s should be omitted without context", key)
+		}
+	}
+}
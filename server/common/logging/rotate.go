@@ -0,0 +1,139 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const backupTimeFormat = "20060102-150405.000"
+
+// rotateWriter 按大小或日期切分日志文件，切分后的文件名为：{文件名}-{时间}{扩展名}，例如：bbs-go-20200102-150405.000.log
+type rotateWriter struct {
+	size       int64
+	filename   string
+	maxSize    int64         // 单个文件的最大字节数，为0时不按大小切分
+	maxBackups int           // 保留的历史文件数，为0时不限制
+	maxAge     time.Duration // 历史文件的最长保留时间，为0时不限制
+	daily      bool
+	mutex      sync.Mutex
+	file       *os.File
+	day        string // 当前文件的日期
+}
+
+// newRotateWriter maxSizeMB、maxBackups、maxAgeDays 小于等于0时不限制
+func newRotateWriter(filename string, maxSizeMB, maxBackups, maxAgeDays int, daily bool) (*rotateWriter, error) {
+	w := &rotateWriter{filename: filename, daily: daily}
+	if maxSizeMB > 0 {
+		w.maxSize = int64(maxSizeMB) * 1024 * 1024
+	}
+	if maxBackups > 0 {
+		w.maxBackups = maxBackups
+	}
+	if maxAgeDays > 0 {
+		w.maxAge = time.Duration(maxAgeDays) * 24 * time.Hour
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size > 0 && ((w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize) ||
+		(w.daily && time.Now().Format("20060102") != w.day)) {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	w.day = info.ModTime().Format("20060102")
+	if w.size == 0 {
+		w.day = time.Now().Format("20060102")
+	}
+	return nil
+}
+
+func (w *rotateWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+	if err := os.Rename(w.filename, w.backupPrefix()+time.Now().Format(backupTimeFormat)+filepath.Ext(w.filename)); err != nil {
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	go w.removeBackups()
+	return nil
+}
+
+func (w *rotateWriter) backupPrefix() string {
+	return strings.TrimSuffix(w.filename, filepath.Ext(w.filename)) + "-"
+}
+
+// removeBackups 删除超过数量或保留时间的历史文件
+func (w *rotateWriter) removeBackups() {
+	if w.maxBackups == 0 && w.maxAge == 0 {
+		return
+	}
+	prefix, ext := w.backupPrefix(), filepath.Ext(w.filename)
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return
+	}
+	var backups []string
+	for _, name := range matches {
+		if _, err := time.Parse(backupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext)); err == nil {
+			backups = append(backups, name)
+		}
+	}
+	// 文件名中的时间格式按字符串排序即按时间排序，最新的在前
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, name := range backups {
+		remove := w.maxBackups > 0 && i >= w.maxBackups
+		if !remove && w.maxAge > 0 {
+			if info, err := os.Stat(name); err == nil && time.Since(info.ModTime()) > w.maxAge {
+				remove = true
+			}
+		}
+		if remove {
+			if err := os.Remove(name); err != nil {
+				logrus.Warn(err)
+			}
+		}
+	}
+}
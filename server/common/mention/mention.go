@@ -0,0 +1,108 @@
+package mention
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// 匹配 @用户名 或 @昵称，名称由文字、数字、_、- 组成
+var mentionRegexp = regexp.MustCompile(`@([\p{L}\p{N}_-]{1,32})`)
+
+// 不解析这些标签中的内容
+var skipTags = map[string]bool{
+	"a":      true,
+	"code":   true,
+	"pre":    true,
+	"script": true,
+	"style":  true,
+}
+
+// Extract 提取HTML内容中提及的名称，去重并保持出现顺序，链接、代码中的内容不会被提取
+func Extract(htmlContent string) []string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
+	if err != nil {
+		return nil
+	}
+	var (
+		names []string
+		seen  = make(map[string]bool)
+	)
+	eachText(doc.Selection, func(text *goquery.Selection) {
+		for _, m := range find(text.Text()) {
+			if !seen[m.name] {
+				seen[m.name] = true
+				names = append(names, m.name)
+			}
+		}
+	})
+	return names
+}
+
+// Link 将 @名称 替换为链接，resolve 返回名称对应的用户主页，返回空时保持原样
+func Link(sel *goquery.Selection, resolve func(name string) string) {
+	eachText(sel, func(text *goquery.Selection) {
+		str := text.Text()
+		matches := find(str)
+		if len(matches) == 0 {
+			return
+		}
+		var (
+			b       strings.Builder
+			last    = 0
+			changed = false
+		)
+		for _, m := range matches {
+			url := resolve(m.name)
+			if len(url) == 0 {
+				continue
+			}
+			b.WriteString(html.EscapeString(str[last:m.start]))
+			b.WriteString(`<a href="` + html.EscapeString(url) + `" class="mention">@` + html.EscapeString(m.name) + `</a>`)
+			last = m.end
+			changed = true
+		}
+		if changed {
+			b.WriteString(html.EscapeString(str[last:]))
+			text.ReplaceWithHtml(b.String())
+		}
+	})
+}
+
+type match struct {
+	name       string
+	start, end int
+}
+
+func find(text string) []match {
+	var ret []match
+	for _, loc := range mentionRegexp.FindAllStringSubmatchIndex(text, -1) {
+		if loc[0] > 0 {
+			// 排除邮箱等 @ 前面是字母、数字的情况，中文等非ASCII文字后面可以直接提及
+			prev, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+			if prev < utf8.RuneSelf && (isAlnum(prev) || strings.ContainsRune("_-.@/+", prev)) {
+				continue
+			}
+		}
+		ret = append(ret, match{name: text[loc[2]:loc[3]], start: loc[0], end: loc[1]})
+	}
+	return ret
+}
+
+func eachText(sel *goquery.Selection, fn func(text *goquery.Selection)) {
+	sel.Contents().Each(func(i int, child *goquery.Selection) {
+		name := goquery.NodeName(child)
+		if name == "#text" {
+			fn(child)
+		} else if !skipTags[name] {
+			eachText(child, fn)
+		}
+	})
+}
+
+func isAlnum(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
@@ -0,0 +1,244 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 指标使用 Prometheus 文本格式输出，文档：https://prometheus.io/docs/instrumenting/exposition_formats/
+//
+// 没有使用 github.com/prometheus/client_golang：只需要计数器、采集时取值的 gauge 和直方图，文本格式是稳定的协议，
+// 实现只有几百行；client_golang 会引入 protobuf、procfs、prometheus/common 等依赖，并且新版本不再支持 Go 1.14。
+// 指标名称、类型和标签与 client_golang 的输出一致，以后替换时 Prometheus 抓取配置和看板不需要修改
+
+// DefaultBuckets 默认的耗时分布区间（秒）
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Sample 一个采样值，Labels 与指标定义的标签名一一对应
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+var (
+	mutex      sync.Mutex
+	collectors []collector
+)
+
+func register(c collector) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	collectors = append(collectors, c)
+}
+
+// WriteTo 输出所有指标
+func WriteTo(w io.Writer) error {
+	mutex.Lock()
+	list := append([]collector{}, collectors...)
+	mutex.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range list {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// CounterVec 计数器
+type CounterVec struct {
+	name, help string
+	labels     []string
+	mutex      sync.Mutex
+	values     map[string]*Sample
+}
+
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*Sample)}
+	register(c)
+	return c
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) Add(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	s, ok := c.values[key]
+	if !ok {
+		s = &Sample{Labels: labelValues}
+		c.values[key] = s
+	}
+	s.Value += v
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mutex.Lock()
+	samples := make([]Sample, 0, len(c.values))
+	for _, s := range c.values {
+		samples = append(samples, *s)
+	}
+	c.mutex.Unlock()
+
+	writeHeader(w, c.name, c.help, "counter")
+	for _, s := range sortSamples(samples) {
+		writeSample(w, c.name, c.labels, s.Labels, "", "", s.Value)
+	}
+}
+
+// NewGaugeFunc 采集时调用 fn 获取当前值，用于连接池、队列长度等状态
+func NewGaugeFunc(name, help string, labels []string, fn func() []Sample) {
+	register(&funcCollector{name: name, help: help, typ: "gauge", labels: labels, fn: fn})
+}
+
+// NewCounterFunc 采集时调用 fn 获取累计值，用于其他组件自身维护的计数，例如缓存命中数
+func NewCounterFunc(name, help string, labels []string, fn func() []Sample) {
+	register(&funcCollector{name: name, help: help, typ: "counter", labels: labels, fn: fn})
+}
+
+type funcCollector struct {
+	name, help, typ string
+	labels          []string
+	fn              func() []Sample
+}
+
+func (f *funcCollector) write(w *bufio.Writer) {
+	writeHeader(w, f.name, f.help, f.typ)
+	for _, s := range sortSamples(f.fn()) {
+		writeSample(w, f.name, f.labels, s.Labels, "", "", s.Value)
+	}
+}
+
+// HistogramVec 分布统计，例如请求耗时
+type HistogramVec struct {
+	name, help string
+	labels     []string
+	buckets    []float64
+	mutex      sync.Mutex
+	values     map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // 各区间的数量，不累加
+	count  uint64
+	sum    float64
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: buckets, values: make(map[string]*histogramValue)}
+	register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labels: labelValues, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mutex.Lock()
+	values := make([]histogramValue, 0, len(h.values))
+	for _, hv := range h.values {
+		values = append(values, histogramValue{
+			labels: hv.labels,
+			counts: append([]uint64{}, hv.counts...),
+			count:  hv.count,
+			sum:    hv.sum,
+		})
+	}
+	h.mutex.Unlock()
+	sort.Slice(values, func(i, j int) bool {
+		return strings.Join(values[i].labels, "\xff") < strings.Join(values[j].labels, "\xff")
+	})
+
+	writeHeader(w, h.name, h.help, "histogram")
+	for _, hv := range values {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hv.counts[i]
+			writeSample(w, h.name+"_bucket", h.labels, hv.labels, "le", formatFloat(upper), float64(cumulative))
+		}
+		writeSample(w, h.name+"_bucket", h.labels, hv.labels, "le", "+Inf", float64(hv.count))
+		writeSample(w, h.name+"_sum", h.labels, hv.labels, "", "", hv.sum)
+		writeSample(w, h.name+"_count", h.labels, hv.labels, "", "", float64(hv.count))
+	}
+}
+
+func sortSamples(samples []Sample) []Sample {
+	sort.Slice(samples, func(i, j int) bool {
+		return strings.Join(samples[i].Labels, "\xff") < strings.Join(samples[j].Labels, "\xff")
+	})
+	return samples
+}
+
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	w.WriteString("# HELP " + name + " " + strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(help) + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// writeSample 输出一行采样值，extraName 不为空时追加一个标签（用于 histogram 的 le）
+func writeSample(w *bufio.Writer, name string, labelNames, labelValues []string, extraName, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || len(extraName) > 0 {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			labelValue := ""
+			if i < len(labelValues) {
+				labelValue = labelValues[i]
+			}
+			w.WriteString(labelName + `="` + escapeLabel(labelValue) + `"`)
+		}
+		if len(extraName) > 0 {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var labelReplacer = strings.NewReplacer("\\", `\\`, "\"", `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelReplacer.Replace(s)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
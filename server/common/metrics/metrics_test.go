@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func output(c collector) string {
+	buf := &bytes.Buffer{}
+	w := bufio.NewWriter(buf)
+	c.write(w)
+	_ = w.Flush()
+	return buf.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	c := NewCounterVec("test_logins_total", "登录次数\n按方式统计", "provider")
+	c.Inc("password")
+	c.Add(2, "oidc \"corp\"")
+	c.Inc("password")
+
+	expected := `# HELP test_logins_total 登录次数\n按方式统计
+# TYPE test_logins_total counter
+test_logins_total{provider="oidc \"corp\""} 2
+test_logins_total{provider="password"} 2
+`
+	if actual := output(c); actual != expected {
+		t.Fatalf("unexpected output:\n%s", actual)
+	}
+}
+
+func TestHistogramVec(t *testing.T) {
+	h := NewHistogramVec("test_duration_seconds", "耗时", []float64{0.1, 1}, "route")
+	h.Observe(0.05, "/topics")
+	h.Observe(0.1, "/topics") // 等于上限时计入该区间
+	h.Observe(0.5, "/topics")
+	h.Observe(3, "/topics")
+
+	expected := `# HELP test_duration_seconds 耗时
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{route="/topics",le="0.1"} 2
+test_duration_seconds_bucket{route="/topics",le="1"} 3
+test_duration_seconds_bucket{route="/topics",le="+Inf"} 4
+test_duration_seconds_sum{route="/topics"} 3.65
+test_duration_seconds_count{route="/topics"} 4
+`
+	if actual := output(h); actual != expected {
+		t.Fatalf("unexpected output:\n%s", actual)
+	}
+}
+
+func TestFuncCollector(t *testing.T) {
+	c := &funcCollector{name: "test_queue_depth", help: "队列长度", typ: "gauge", labels: []string{"type", "status"},
+		fn: func() []Sample {
+			return []Sample{
+				{Labels: []string{"email", "pending"}, Value: 3},
+				{Labels: []string{"email", "failed"}, Value: 1},
+			}
+		}}
+
+	expected := `# HELP test_queue_depth 队列长度
+# TYPE test_queue_depth gauge
+test_queue_depth{type="email",status="failed"} 1
+test_queue_depth{type="email",status="pending"} 3
+`
+	if actual := output(c); actual != expected {
+		t.Fatalf("unexpected output:\n%s", actual)
+	}
+
+	// 没有标签时不输出大括号
+	c = &funcCollector{name: "test_up", help: "up", typ: "gauge", fn: func() []Sample {
+		return []Sample{{Value: 1}}
+	}}
+	if actual := output(c); !strings.HasSuffix(actual, "\ntest_up 1\n") {
+		t.Fatalf("unexpected output:\n%s", actual)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	Logins.Inc("password")
+	buf := &bytes.Buffer{}
+	if err := WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	// 每个注册的指标都输出 HELP 和 TYPE，采样行的指标名需要与 TYPE 对应
+	types := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			types[fields[2]] = fields[3]
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := line[:strings.IndexAny(line, "{ ")]
+		if _, ok := types[name]; ok {
+			continue
+		}
+		for _, suffix := range []string{"_bucket", "_sum", "_count"} {
+			if strings.HasSuffix(name, suffix) && types[strings.TrimSuffix(name, suffix)] == "histogram" {
+				name = ""
+			}
+		}
+		if len(name) > 0 {
+			t.Fatalf("sample without TYPE: %s", line)
+		}
+	}
+	if types["bbs_http_request_duration_seconds"] != "histogram" || types["bbs_logins_total"] != "counter" {
+		t.Fatalf("unexpected types: %v", types)
+	}
+	if !strings.Contains(buf.String(), "bbs_logins_total{provider=\"password\"} 1\n") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,15 @@
+package metrics
+
+// 业务指标，连接池、队列长度、缓存等状态类指标在启动时通过 NewGaugeFunc 注册
+var (
+	HttpRequests = NewHistogramVec("bbs_http_request_duration_seconds", "HTTP请求耗时",
+		DefaultBuckets, "method", "route", "status")
+	ContentCreated = NewCounterVec("bbs_content_created_total", "发表的内容数，type：topic、article、tweet、comment",
+		"type")
+	Signups = NewCounterVec("bbs_signups_total", "注册用户数，provider 同登录方式",
+		"provider")
+	Logins = NewCounterVec("bbs_logins_total", "登录次数，provider：password、ldap、github、qq 或 OIDC 名称",
+		"provider")
+	EmailsSent = NewCounterVec("bbs_emails_sent_total", "发送邮件数，status：sent、failed",
+		"status")
+)
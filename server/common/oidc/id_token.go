@@ -0,0 +1,253 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// 允许的时钟误差
+const clockSkew = time.Minute
+
+// 重新获取公钥的最小间隔，避免伪造的kid导致频繁请求
+const keysRefreshInterval = time.Minute
+
+// 签名公钥
+type keySet struct {
+	keys      map[string]crypto.PublicKey
+	fetchTime time.Time
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// verifyIdToken 校验id_token的签名、颁发者、受众、有效期和nonce，返回id_token中的字段
+func (p *Provider) verifyIdToken(d *discovery, rawIdToken, nonce string) (map[string]interface{}, error) {
+	parts := strings.Split(rawIdToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("id_token格式错误")
+	}
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("id_token格式错误")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("id_token格式错误")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("id_token格式错误")
+	}
+	header := &jwtHeader{}
+	if err := json.Unmarshal(headerData, header); err != nil {
+		return nil, errors.New("id_token格式错误")
+	}
+
+	key, err := p.getKey(d, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	claimsJson := string(payload)
+	if gjson.Get(claimsJson, "iss").String() != d.Issuer {
+		return nil, errors.New("id_token颁发者不匹配")
+	}
+	if !containsAudience(gjson.Get(claimsJson, "aud"), p.ClientID) {
+		return nil, errors.New("id_token受众不匹配")
+	}
+	now := time.Now()
+	if exp := gjson.Get(claimsJson, "exp"); !exp.Exists() || now.After(time.Unix(exp.Int(), 0).Add(clockSkew)) {
+		return nil, errors.New("id_token已过期")
+	}
+	if nbf := gjson.Get(claimsJson, "nbf"); nbf.Exists() && now.Add(clockSkew).Before(time.Unix(nbf.Int(), 0)) {
+		return nil, errors.New("id_token尚未生效")
+	}
+	if gjson.Get(claimsJson, "nonce").String() != nonce {
+		return nil, errors.New("id_token nonce校验失败")
+	}
+
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// getKey 获取签名公钥，未找到时重新获取一次公钥（颁发者可能已轮换密钥）
+func (p *Provider) getKey(d *discovery, kid string) (crypto.PublicKey, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.keys != nil {
+		if key := p.keys.find(kid); key != nil {
+			return key, nil
+		}
+		if time.Since(p.keys.fetchTime) < keysRefreshInterval {
+			return nil, errors.New("id_token签名公钥不存在")
+		}
+	}
+	keys, err := fetchKeySet(d.JwksURL)
+	if err != nil {
+		return nil, err
+	}
+	p.keys = keys
+	if key := p.keys.find(kid); key != nil {
+		return key, nil
+	}
+	return nil, errors.New("id_token签名公钥不存在")
+}
+
+// find 根据kid查找公钥，id_token未指定kid且只有一个公钥时使用该公钥
+func (k *keySet) find(kid string) crypto.PublicKey {
+	if len(kid) == 0 && len(k.keys) == 1 {
+		for _, key := range k.keys {
+			return key
+		}
+	}
+	return k.keys[kid]
+}
+
+func fetchKeySet(jwksUrl string) (*keySet, error) {
+	if len(jwksUrl) == 0 {
+		return nil, errors.New("未配置jwks_uri")
+	}
+	resp, err := newHttpClient().R().Get(jwksUrl)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, errors.New("获取签名公钥失败：" + resp.Status())
+	}
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(resp.Body(), &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := &keySet{keys: make(map[string]crypto.PublicKey), fetchTime: time.Now()}
+	for _, jwk := range jwks.Keys {
+		if len(jwk.Use) > 0 && jwk.Use != "sig" {
+			continue
+		}
+		if key := jwk.publicKey(); key != nil {
+			keys.keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey 解析公钥，目前支持RSA和EC，不支持的类型返回nil
+func (k *jsonWebKey) publicKey() crypto.PublicKey {
+	switch k.Kty {
+	case "RSA":
+		n, e := decodeBigInt(k.N), decodeBigInt(k.E)
+		if n == nil || e == nil {
+			return nil
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil
+		}
+		x, y := decodeBigInt(k.X), decodeBigInt(k.Y)
+		if x == nil || y == nil {
+			return nil
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+	}
+	return nil
+}
+
+// verifySignature 校验签名，支持RS256、RS384、RS512、ES256、ES384、ES512
+func verifySignature(alg string, key crypto.PublicKey, signed string, signature []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return errors.New("不支持的id_token签名算法：" + alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") || rsa.VerifyPKCS1v15(pub, hash, digest, signature) != nil {
+			return errors.New("id_token签名校验失败")
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(alg, "ES") || len(signature) != 2*size {
+			return errors.New("id_token签名校验失败")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("id_token签名校验失败")
+		}
+	default:
+		return errors.New("id_token签名校验失败")
+	}
+	return nil
+}
+
+func containsAudience(aud gjson.Result, clientId string) bool {
+	if aud.IsArray() {
+		for _, item := range aud.Array() {
+			if item.String() == clientId {
+				return true
+			}
+		}
+		return false
+	}
+	return aud.String() == clientId
+}
+
+func decodeBigInt(s string) *big.Int {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+	return new(big.Int).SetBytes(data)
+}
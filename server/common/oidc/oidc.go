@@ -0,0 +1,331 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/goburrow/cache"
+	"github.com/mlogclub/simple"
+	"github.com/tidwall/gjson"
+	"golang.org/x/oauth2"
+
+	"bbs-go/common"
+	"bbs-go/common/tracing"
+	"bbs-go/config"
+	"bbs-go/model/constants"
+)
+
+// 授权上下文，key为state，一次有效
+var ctxCache = cache.New(cache.WithMaximumSize(1000), cache.WithExpireAfterAccess(10*time.Minute))
+
+var (
+	providers     map[string]*Provider
+	providerNames []string
+	providersOnce sync.Once
+)
+
+// 授权上下文
+type authState struct {
+	Provider     string
+	RedirectUrl  string
+	CodeVerifier string
+	Nonce        string
+}
+
+// 第三方用户信息
+type UserInfo struct {
+	Provider string          `json:"provider"`
+	Subject  string          `json:"subject"`
+	Nickname string          `json:"nickname"`
+	Email    string          `json:"email"`
+	Avatar   string          `json:"avatar"`
+	Claims   json.RawMessage `json:"claims"` // id_token与用户信息接口返回的全部字段
+}
+
+// 服务发现文档
+type discovery struct {
+	Issuer      string `json:"issuer"`
+	AuthURL     string `json:"authorization_endpoint"`
+	TokenURL    string `json:"token_endpoint"`
+	UserInfoURL string `json:"userinfo_endpoint"`
+	JwksURL     string `json:"jwks_uri"`
+}
+
+type Provider struct {
+	config.OidcProvider
+	mutex     sync.Mutex
+	discovery *discovery
+	keys      *keySet
+}
+
+// GetProvider 根据名称获取登录配置，不存在时返回nil
+func GetProvider(name string) *Provider {
+	initProviders()
+	return providers[name]
+}
+
+// GetProviders 所有的登录配置，按配置文件中的顺序返回
+func GetProviders() []*Provider {
+	initProviders()
+	var ret []*Provider
+	for _, name := range providerNames {
+		ret = append(ret, providers[name])
+	}
+	return ret
+}
+
+func initProviders() {
+	providersOnce.Do(func() {
+		providers = make(map[string]*Provider)
+		for _, c := range config.Instance.Oidc {
+			name := strings.TrimSpace(c.Name)
+			// 名称作为第三方账号类型，不能与内置的登录方式冲突
+			if len(name) == 0 || len(name) > 32 || name == constants.ThirdAccountTypeGithub ||
+				name == constants.ThirdAccountTypeQQ || name == constants.ThirdAccountTypeLdap || providers[name] != nil {
+				continue
+			}
+			c.Name = name
+			if len(c.Title) == 0 {
+				c.Title = name
+			}
+			providers[name] = &Provider{OidcProvider: c}
+			providerNames = append(providerNames, name)
+		}
+	})
+}
+
+// AuthCodeURL 获取授权地址
+func (p *Provider) AuthCodeURL(params map[string]string) (string, error) {
+	d, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	state := &authState{
+		Provider:    p.Name,
+		RedirectUrl: p.getRedirectUrl(params),
+	}
+	var opts []oauth2.AuthCodeOption
+	if !p.DisablePKCE {
+		state.CodeVerifier = randomString()
+		sum := sha256.Sum256([]byte(state.CodeVerifier))
+		opts = append(opts,
+			oauth2.SetAuthURLParam("code_challenge", base64.RawURLEncoding.EncodeToString(sum[:])),
+			oauth2.SetAuthURLParam("code_challenge_method", "S256"))
+	}
+	if p.isOidc() {
+		state.Nonce = randomString()
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", state.Nonce))
+	}
+
+	stateId := simple.UUID()
+	ctxCache.Put(stateId, state)
+	return p.newOauthConfig(d, state.RedirectUrl).AuthCodeURL(stateId, opts...), nil
+}
+
+// GetUserInfoByCode 根据code获取用户信息
+// 流程为先校验state，然后使用code换取令牌，校验id_token并获取用户信息
+func (p *Provider) GetUserInfoByCode(ctx context.Context, code, stateId string) (*UserInfo, error) {
+	val, found := ctxCache.GetIfPresent(stateId)
+	if !found {
+		return nil, errors.New("登录已过期，请重新登录")
+	}
+	ctxCache.Invalidate(stateId)
+	state := val.(*authState)
+	if state.Provider != p.Name {
+		return nil, errors.New("登录状态校验失败")
+	}
+
+	d, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []oauth2.AuthCodeOption
+	if len(state.CodeVerifier) > 0 {
+		opts = append(opts, oauth2.SetAuthURLParam("code_verifier", state.CodeVerifier))
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, tracing.Client(10*time.Second))
+	token, err := p.newOauthConfig(d, state.RedirectUrl).Exchange(ctx, code, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(map[string]interface{})
+	if p.isOidc() {
+		rawIdToken, _ := token.Extra("id_token").(string)
+		if len(rawIdToken) == 0 {
+			return nil, errors.New("未获取到id_token")
+		}
+		if claims, err = p.verifyIdToken(d, rawIdToken, state.Nonce); err != nil {
+			return nil, err
+		}
+	}
+	if len(d.UserInfoURL) > 0 {
+		userInfoClaims, err := p.getUserInfoClaims(ctx, d.UserInfoURL, token.AccessToken)
+		if err != nil {
+			return nil, err
+		}
+		// 用户信息接口返回的sub必须与id_token一致
+		if sub, ok := claims["sub"]; ok && userInfoClaims["sub"] != nil && userInfoClaims["sub"] != sub {
+			return nil, errors.New("用户信息校验失败")
+		}
+		for k, v := range userInfoClaims {
+			claims[k] = v
+		}
+	}
+	return p.buildUserInfo(claims)
+}
+
+// buildUserInfo 按配置的字段映射提取用户信息
+func (p *Provider) buildUserInfo(claims map[string]interface{}) (*UserInfo, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	claimsJson := string(data)
+	userInfo := &UserInfo{
+		Provider: p.Name,
+		Subject:  getClaim(claimsJson, p.SubjectClaim, "sub"),
+		Nickname: getClaim(claimsJson, p.NicknameClaim, "name", "preferred_username"),
+		Avatar:   getClaim(claimsJson, p.AvatarClaim, "picture"),
+		Claims:   data,
+	}
+	if len(userInfo.Subject) == 0 {
+		return nil, errors.New("未获取到用户唯一标识")
+	}
+	// 只使用服务端明确返回 email_verified 为 true 的邮箱，否则第三方用户可以填写他人的邮箱
+	if gjson.Get(claimsJson, "email_verified").Bool() {
+		userInfo.Email = getClaim(claimsJson, p.EmailClaim, "email")
+	}
+	return userInfo, nil
+}
+
+// getDiscovery 获取端点配置，配置了Issuer时通过服务发现获取
+func (p *Provider) getDiscovery() (*discovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	d := &discovery{}
+	if p.isOidc() {
+		issuer := strings.TrimSuffix(p.Issuer, "/")
+		resp, err := newHttpClient().R().Get(issuer + "/.well-known/openid-configuration")
+		if err != nil {
+			return nil, err
+		}
+		if !resp.IsSuccess() {
+			return nil, errors.New("获取OIDC配置失败：" + resp.Status())
+		}
+		if err := json.Unmarshal(resp.Body(), d); err != nil {
+			return nil, err
+		}
+		if strings.TrimSuffix(d.Issuer, "/") != issuer {
+			return nil, errors.New("OIDC颁发者不匹配：" + d.Issuer)
+		}
+	}
+	// 配置文件中的端点优先
+	if len(p.AuthURL) > 0 {
+		d.AuthURL = p.AuthURL
+	}
+	if len(p.TokenURL) > 0 {
+		d.TokenURL = p.TokenURL
+	}
+	if len(p.UserInfoURL) > 0 {
+		d.UserInfoURL = p.UserInfoURL
+	}
+	if len(d.AuthURL) == 0 || len(d.TokenURL) == 0 {
+		return nil, errors.New("登录配置错误：" + p.Name)
+	}
+	if !p.isOidc() && len(d.UserInfoURL) == 0 {
+		return nil, errors.New("登录配置错误，未配置用户信息地址：" + p.Name)
+	}
+	p.discovery = d
+	return d, nil
+}
+
+func (p *Provider) getUserInfoClaims(ctx context.Context, userInfoUrl, accessToken string) (map[string]interface{}, error) {
+	resp, err := newHttpClient().R().SetContext(ctx).SetAuthToken(accessToken).SetHeader("Accept", "application/json").Get(userInfoUrl)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, errors.New("获取用户信息失败：" + resp.Status())
+	}
+	claims := make(map[string]interface{})
+	if err := json.Unmarshal(resp.Body(), &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (p *Provider) newOauthConfig(d *discovery, redirectUrl string) *oauth2.Config {
+	scopes := p.Scopes
+	if len(scopes) == 0 && p.isOidc() {
+		scopes = []string{"openid", "profile", "email"}
+	}
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  redirectUrl,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  d.AuthURL,
+			TokenURL: d.TokenURL,
+		},
+	}
+}
+
+// isOidc 是否为OpenID Connect，否则按普通OAuth2处理
+func (p *Provider) isOidc() bool {
+	return len(p.Issuer) > 0
+}
+
+// 获取回调跳转地址
+func (p *Provider) getRedirectUrl(params map[string]string) string {
+	redirectUrl := config.Instance.BaseUrl + "/user/oidc/callback"
+	if !common.IsProd() {
+		redirectUrl = "http://localhost:3000/user/oidc/callback"
+	}
+	ub := simple.ParseUrl(redirectUrl)
+	ub.AddQuery("provider", p.Name)
+	for k, v := range params {
+		ub.AddQuery(k, v)
+	}
+	return ub.BuildStr()
+}
+
+// getClaim 获取字段值，支持 a.b 形式的嵌套字段，未配置字段时依次尝试默认字段
+func getClaim(claimsJson string, claim string, defaults ...string) string {
+	names := defaults
+	if len(claim) > 0 {
+		names = []string{claim}
+	}
+	for _, name := range names {
+		if value := strings.TrimSpace(gjson.Get(claimsJson, name).String()); len(value) > 0 {
+			return value
+		}
+	}
+	return ""
+}
+
+// randomString 生成随机字符串，用于PKCE code_verifier和nonce
+func randomString() string {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func newHttpClient() *resty.Client {
+	return resty.New().SetTimeout(10 * time.Second).SetTransport(tracing.Transport(nil))
+}
@@ -0,0 +1,154 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"bbs-go/config"
+)
+
+// fakeIssuer 模拟的OIDC颁发者，id_token使用RSA密钥签名，claims为id_token中除标准字段外的其他字段
+type fakeIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	nonce  string
+	claims map[string]interface{}
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &fakeIssuer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, map[string]string{
+			"issuer":                 issuer.server.URL,
+			"authorization_endpoint": issuer.server.URL + "/auth",
+			"token_endpoint":         issuer.server.URL + "/token",
+			"jwks_uri":               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		writeJson(w, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("code") != "code" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeJson(w, map[string]interface{}{
+			"access_token": "access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     issuer.idToken(t),
+		})
+	})
+	issuer.server = httptest.NewServer(mux)
+	return issuer
+}
+
+func (f *fakeIssuer) idToken(t *testing.T) string {
+	claims := map[string]interface{}{
+		"iss":   f.server.URL,
+		"aud":   "client",
+		"sub":   "user-1",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": f.nonce,
+		"name":  "Alice",
+	}
+	for k, v := range f.claims {
+		claims[k] = v
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func writeJson(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// signIn 走一遍授权流程：获取授权地址，颁发者使用授权地址中的nonce签发id_token，然后使用code获取用户信息
+func signIn(t *testing.T, issuer *fakeIssuer, claims map[string]interface{}) (*UserInfo, error) {
+	config.Instance = &config.Config{BaseUrl: "https://bbs.example.com", Env: "prod"}
+	p := &Provider{OidcProvider: config.OidcProvider{Name: "test", Issuer: issuer.server.URL, ClientID: "client", ClientSecret: "secret"}}
+	authUrl, err := p.AuthCodeURL(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer.nonce = u.Query().Get("nonce")
+	issuer.claims = claims
+	return p.GetUserInfoByCode(context.Background(), "code", u.Query().Get("state"))
+}
+
+func TestEmailVerified(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	defer issuer.server.Close()
+
+	cases := []struct {
+		name   string
+		claims map[string]interface{}
+		email  string
+	}{
+		{"verified", map[string]interface{}{"email": "alice@example.com", "email_verified": true}, "alice@example.com"},
+		{"unverified", map[string]interface{}{"email": "alice@example.com", "email_verified": false}, ""},
+		{"missing", map[string]interface{}{"email": "alice@example.com"}, ""},
+	}
+	for _, c := range cases {
+		userInfo, err := signIn(t, issuer, c.claims)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if userInfo.Subject != "user-1" || userInfo.Nickname != "Alice" {
+			t.Fatalf("%s: unexpected user info: %+v", c.name, userInfo)
+		}
+		if userInfo.Email != c.email {
+			t.Errorf("%s: expected email %q, got %q", c.name, c.email, userInfo.Email)
+		}
+	}
+}
+
+func TestIdTokenRejected(t *testing.T) {
+	issuer := newFakeIssuer(t)
+	defer issuer.server.Close()
+
+	if _, err := signIn(t, issuer, map[string]interface{}{"aud": "other"}); err == nil {
+		t.Error("id_token for other audience should be rejected")
+	}
+	if _, err := signIn(t, issuer, map[string]interface{}{"nonce": "other"}); err == nil {
+		t.Error("id_token with wrong nonce should be rejected")
+	}
+	if _, err := signIn(t, issuer, map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()}); err == nil {
+		t.Error("expired id_token should be rejected")
+	}
+}
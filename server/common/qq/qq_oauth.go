@@ -1,6 +1,7 @@
 package qq
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
@@ -14,6 +15,7 @@ import (
 	"github.com/tidwall/gjson"
 
 	"bbs-go/common"
+	"bbs-go/common/tracing"
 	"bbs-go/config"
 )
 
@@ -60,7 +62,7 @@ func AuthorizeUrl(params map[string]string) string {
 // code -> accessToken
 // 文档：https://wiki.connect.qq.com/%E4%BD%BF%E7%94%A8authorization_code%E8%8E%B7%E5%8F%96access_token
 // 接口：https://graph.qq.com/oauth2.0/token
-func AuthorizationCode(code, state string) (*AccessToken, error) {
+func AuthorizationCode(ctx context.Context, code, state string) (*AccessToken, error) {
 	// 从上下文中获取跳转地址
 	val, found := ctxCache.GetIfPresent(state)
 	var redirectUrl string
@@ -68,7 +70,7 @@ func AuthorizationCode(code, state string) (*AccessToken, error) {
 		redirectUrl = val.(string)
 	}
 
-	resp, err := resty.New().R().
+	resp, err := newRequest(ctx).
 		SetQueryParam("grant_type", "authorization_code").
 		SetQueryParam("client_id", config.Instance.QQConnect.AppId).
 		SetQueryParam("client_secret", config.Instance.QQConnect.AppKey).
@@ -98,19 +100,19 @@ func AuthorizationCode(code, state string) (*AccessToken, error) {
 
 // 文档：https://wiki.connect.qq.com/%E8%8E%B7%E5%8F%96%E7%94%A8%E6%88%B7openid_oauth2-0
 // 接口：https://graph.qq.com/oauth2.0/me
-func GetOpenid(accessToken string) (string, string, error) {
-	resp, err := resty.New().R().
+func GetOpenid(ctx context.Context, accessToken string) (string, string, error) {
+	resp, err := newRequest(ctx).
 		SetQueryParam("access_token", accessToken).
 		SetQueryParam("unionid", "1"). // 申请unionId，0：不申请，1：申请
 		Get("https://graph.qq.com/oauth2.0/me")
 	if err != nil {
-		logrus.Errorf("QQ: Get openid error", err)
+		logrus.WithContext(ctx).Errorf("QQ: Get openid error", err)
 		return "", "", err
 	}
 	content := string(resp.Body())
 	content = removeCallback(content)
 
-	logrus.Info("me:" + content)
+	logrus.WithContext(ctx).Info("me:" + content)
 
 	return gjson.Get(content, "openid").String(), gjson.Get(content, "unionid").String(), nil
 }
@@ -118,12 +120,12 @@ func GetOpenid(accessToken string) (string, string, error) {
 // 获取用户信息
 // 文档：https://wiki.connect.qq.com/get_user_info
 // 接口：https://graph.qq.com/user/get_user_info
-func GetUserInfo(accessToken string) (*UserInfo, error) {
-	openid, unionid, err := GetOpenid(accessToken)
+func GetUserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	openid, unionid, err := GetOpenid(ctx, accessToken)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := resty.New().R().
+	resp, err := newRequest(ctx).
 		SetQueryParam("access_token", accessToken).
 		SetQueryParam("oauth_consumer_key", config.Instance.QQConnect.AppId).
 		SetQueryParam("openid", openid).
@@ -133,7 +135,7 @@ func GetUserInfo(accessToken string) (*UserInfo, error) {
 	}
 	content := string(resp.Body())
 
-	logrus.Info("get_user_info:" + content)
+	logrus.WithContext(ctx).Info("get_user_info:" + content)
 
 	ret := gjson.Get(content, "ret").Int()
 	msg := gjson.Get(content, "msg").String()
@@ -155,12 +157,16 @@ func GetUserInfo(accessToken string) (*UserInfo, error) {
 
 // 根据code获取用户信息
 // 流程为先使用code换取accessToken，然后根据accessToken获取用户信息
-func GetUserInfoByCode(code, state string) (*UserInfo, error) {
-	token, err := AuthorizationCode(code, state)
+func GetUserInfoByCode(ctx context.Context, code, state string) (*UserInfo, error) {
+	token, err := AuthorizationCode(ctx, code, state)
 	if err != nil {
 		return nil, err
 	}
-	return GetUserInfo(token.AccessToken)
+	return GetUserInfo(ctx, token.AccessToken)
+}
+
+func newRequest(ctx context.Context) *resty.Request {
+	return resty.New().SetTransport(tracing.Transport(nil)).R().SetContext(ctx)
 }
 
 // 获取回调跳转地址
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/goburrow/cache"
+)
+
+// bucket 令牌桶
+type bucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Limiter 基于令牌桶的限流器，每个key对应一个令牌桶，长时间未访问的令牌桶会被回收
+type Limiter struct {
+	rate    float64 // 每秒产生的令牌数
+	burst   float64 // 令牌桶容量
+	mu      sync.Mutex
+	buckets cache.Cache
+}
+
+// NewLimiter 创建限流器
+// ratePerMinute: 每分钟允许的请求数；burst: 允许的突发请求数
+func NewLimiter(ratePerMinute float64, burst int) *Limiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:    ratePerMinute / 60,
+		burst:   float64(burst),
+		buckets: cache.New(cache.WithMaximumSize(100000), cache.WithExpireAfterAccess(30*time.Minute)),
+	}
+}
+
+// Allow 消耗key对应令牌桶中的一个令牌，没有可用令牌时返回false
+func (l *Limiter) Allow(key string) bool {
+	return l.AllowAt(key, time.Now())
+}
+
+// AllowAt 同Allow，指定当前时间
+func (l *Limiter) AllowAt(key string, now time.Time) bool {
+	b := l.getBucket(key, now)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (l *Limiter) getBucket(key string, now time.Time) *bucket {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if val, found := l.buckets.GetIfPresent(key); found {
+		return val.(*bucket)
+	}
+	b := &bucket{tokens: l.burst, last: now}
+	l.buckets.Put(key, b)
+	return b
+}
+
+// IntervalLimiter 限制同一key两次操作之间的最小间隔
+type IntervalLimiter struct {
+	last cache.Cache
+}
+
+// NewIntervalLimiter 创建间隔限制器
+func NewIntervalLimiter() *IntervalLimiter {
+	return &IntervalLimiter{
+		last: cache.New(cache.WithMaximumSize(100000), cache.WithExpireAfterAccess(24*time.Hour)),
+	}
+}
+
+// Remaining 距离下一次允许操作还需等待的时长，返回0表示允许操作
+func (l *IntervalLimiter) Remaining(key string, interval time.Duration) time.Duration {
+	val, found := l.last.GetIfPresent(key)
+	if !found {
+		return 0
+	}
+	remaining := val.(time.Time).Add(interval).Sub(time.Now())
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Mark 记录key的操作时间
+func (l *IntervalLimiter) Mark(key string) {
+	l.last.Put(key, time.Now())
+}
@@ -0,0 +1,300 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Exporter 导出已结束的 span
+type Exporter interface {
+	Export(spans []*Span) error
+}
+
+type processor interface {
+	onEnd(span *Span)
+	shutdown(timeout time.Duration) bool
+}
+
+// syncProcessor 结束时立即导出
+type syncProcessor struct {
+	exporter Exporter
+}
+
+func (p *syncProcessor) onEnd(span *Span) {
+	if err := p.exporter.Export([]*Span{span}); err != nil {
+		logrus.Warn("export spans error: ", err)
+	}
+}
+
+func (p *syncProcessor) shutdown(time.Duration) bool {
+	return true
+}
+
+const (
+	batchQueueSize = 2048
+	batchMaxSize   = 512
+	batchInterval  = 5 * time.Second
+)
+
+// batchProcessor 在后台批量导出，队列满时丢弃，不阻塞请求
+type batchProcessor struct {
+	dropped  int64
+	closed   int32
+	exporter Exporter
+	queue    chan *Span
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newBatchProcessor(exporter Exporter) *batchProcessor {
+	p := &batchProcessor{
+		exporter: exporter,
+		queue:    make(chan *Span, batchQueueSize),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+func (p *batchProcessor) onEnd(span *Span) {
+	if atomic.LoadInt32(&p.closed) == 1 {
+		return
+	}
+	select {
+	case p.queue <- span:
+	default:
+		if atomic.AddInt64(&p.dropped, 1)%1000 == 1 {
+			logrus.Warn("span queue is full, dropped: ", atomic.LoadInt64(&p.dropped))
+		}
+	}
+}
+
+func (p *batchProcessor) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(batchInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchMaxSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.exporter.Export(batch); err != nil {
+			logrus.Warn("export spans error: ", err)
+		}
+		batch = make([]*Span, 0, batchMaxSize)
+	}
+	for {
+		select {
+		case span := <-p.queue:
+			batch = append(batch, span)
+			if len(batch) >= batchMaxSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-p.stop:
+			for {
+				select {
+				case span := <-p.queue:
+					batch = append(batch, span)
+					if len(batch) >= batchMaxSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (p *batchProcessor) shutdown(timeout time.Duration) bool {
+	p.once.Do(func() {
+		atomic.StoreInt32(&p.closed, 1)
+		close(p.stop)
+	})
+	select {
+	case <-p.done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// InMemoryExporter 将 span 保存在内存中，用于测试
+type InMemoryExporter struct {
+	mutex sync.Mutex
+	spans []*Span
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+func (e *InMemoryExporter) Export(spans []*Span) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Spans 已导出的 span，按结束顺序排列
+func (e *InMemoryExporter) Spans() []*Span {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]*Span{}, e.spans...)
+}
+
+// Reset 清空已导出的 span
+func (e *InMemoryExporter) Reset() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.spans = nil
+}
+
+// OtlpExporter 使用 OTLP/HTTP 的 JSON 格式导出，文档：https://opentelemetry.io/docs/specs/otlp/#otlphttp
+type OtlpExporter struct {
+	endpoint    string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+}
+
+// NewOtlpExporter endpoint 例如：http://127.0.0.1:4318/v1/traces
+func NewOtlpExporter(endpoint string, headers map[string]string, serviceName string) *OtlpExporter {
+	return &OtlpExporter{
+		endpoint:    endpoint,
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (e *OtlpExporter) Export(spans []*Span) error {
+	body, err := json.Marshal(e.buildRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return errors.New("otlp export failed, status: " + resp.Status + ", body: " + string(msg))
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+type otlpKeyValue struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+func (e *OtlpExporter) buildRequest(spans []*Span) map[string]interface{} {
+	list := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceId:           span.TraceIdString(),
+			SpanId:            span.SpanIdString(),
+			Name:              span.Name,
+			Kind:              int(span.Kind),
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        otlpAttributes(span.Attributes),
+		}
+		if span.HasParent() {
+			s.ParentSpanId = hex.EncodeToString(span.ParentSpanId[:])
+		}
+		if span.Error {
+			s.Status.Code = 2
+			s.Status.Message = span.StatusMessage
+		}
+		list = append(list, s)
+	}
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": otlpAttributes(map[string]interface{}{"service.name": e.serviceName}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]interface{}{"name": "bbs-go"},
+						"spans": list,
+					},
+				},
+			},
+		},
+	}
+}
+
+func otlpAttributes(attributes map[string]interface{}) []otlpKeyValue {
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	list := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		var value map[string]interface{}
+		switch v := attributes[k].(type) {
+		case string:
+			value = map[string]interface{}{"stringValue": v}
+		case bool:
+			value = map[string]interface{}{"boolValue": v}
+		case int:
+			value = map[string]interface{}{"intValue": strconv.Itoa(v)}
+		case int64:
+			value = map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}
+		case float64:
+			value = map[string]interface{}{"doubleValue": v}
+		default:
+			continue
+		}
+		list = append(list, otlpKeyValue{Key: k, Value: value})
+	}
+	return list
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+)
+
+const (
+	gormContextKey = "tracing:context"
+	gormSpanKey    = "tracing:span"
+)
+
+// DB 返回携带上下文的数据库连接，通过该连接（包括在其上开启的事务）执行的操作会作为上下文中 span 的子节点
+func DB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if ctx == nil || SpanFromContext(ctx) == nil {
+		return db
+	}
+	return db.Set(gormContextKey, ctx)
+}
+
+// RegisterGormCallbacks 为数据库操作创建 span，没有通过 DB 传入上下文的操作不会创建
+func RegisterGormCallbacks(db *gorm.DB) {
+	callback := db.Callback()
+	callback.Create().Before("gorm:create").Register("tracing:before_create", gormBefore("create"))
+	callback.Create().After("gorm:create").Register("tracing:after_create", gormAfter)
+	callback.Query().Before("gorm:query").Register("tracing:before_query", gormBefore("query"))
+	callback.Query().After("gorm:query").Register("tracing:after_query", gormAfter)
+	callback.Update().Before("gorm:update").Register("tracing:before_update", gormBefore("update"))
+	callback.Update().After("gorm:update").Register("tracing:after_update", gormAfter)
+	callback.Delete().Before("gorm:delete").Register("tracing:before_delete", gormBefore("delete"))
+	callback.Delete().After("gorm:delete").Register("tracing:after_delete", gormAfter)
+	callback.RowQuery().Before("gorm:row_query").Register("tracing:before_row_query", gormBefore("row_query"))
+	callback.RowQuery().After("gorm:row_query").Register("tracing:after_row_query", gormAfter)
+}
+
+func gormBefore(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		value, ok := scope.Get(gormContextKey)
+		if !ok {
+			return
+		}
+		ctx, ok := value.(context.Context)
+		if !ok || SpanFromContext(ctx) == nil {
+			return
+		}
+		_, span := Start(ctx, "gorm."+operation, KindClient)
+		if span == nil {
+			return
+		}
+		span.SetAttribute("db.system", scope.Dialect().GetName())
+		span.SetAttribute("db.operation", operation)
+		if scope.Value != nil {
+			span.SetAttribute("db.sql.table", scope.TableName())
+		}
+		scope.InstanceSet(gormSpanKey, span)
+	}
+}
+
+func gormAfter(scope *gorm.Scope) {
+	value, ok := scope.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	span, ok := value.(*Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("db.statement", scope.SQL)
+	span.SetAttribute("db.rows_affected", scope.DB().RowsAffected)
+	if err := scope.DB().Error; err != nil && !gorm.IsRecordNotFoundError(err) {
+		span.RecordError(err)
+	}
+	span.End()
+}
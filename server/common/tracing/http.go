@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Transport 为调用外部接口的请求创建 span 并写入 traceparent 请求头，请求的上下文中没有 span 时不创建；base 为空时使用 http.DefaultTransport
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+// Client 使用 Transport 的 http.Client
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: Transport(nil), Timeout: timeout}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if SpanFromContext(req.Context()) == nil {
+		return t.base.RoundTrip(req)
+	}
+	ctx, span := Start(req.Context(), "HTTP "+req.Method+" "+req.URL.Host, KindClient)
+	if span == nil {
+		return t.base.RoundTrip(req)
+	}
+	defer span.End()
+
+	// 地址中可能带有 access_token 等参数，不记录查询参数
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+	span.SetAttribute("net.peer.name", req.URL.Hostname())
+
+	req = req.WithContext(ctx)
+	req.Header = req.Header.Clone()
+	Inject(ctx, req.Header)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= 500 {
+		span.RecordError(errors.New(resp.Status))
+	}
+	return resp, nil
+}
@@ -0,0 +1,272 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 链路追踪，数据结构和导出格式遵循 OpenTelemetry，上下文传播使用 W3C Trace Context：https://www.w3.org/TR/trace-context/
+//
+// 没有使用 go.opentelemetry.io/otel：项目仍需使用 Go 1.14 构建，otel 1.x 要求更高的 Go 版本，支持 Go 1.14 的
+// 只有 API 不稳定的 0.x 版本，并且会引入 gRPC、protobuf 等较大的依赖；这里只需要 span、采样、traceparent 传播和
+// OTLP/HTTP JSON 导出，协议本身是稳定的，导出的数据可以直接被 Collector、Jaeger、Tempo 接收。升级 Go 版本后可以换成 otel SDK，
+// 调用方只依赖 Start、DB、Transport 等函数，不需要修改
+
+// SpanKind 取值与 OTLP 一致
+type SpanKind int
+
+const (
+	KindInternal SpanKind = 1
+	KindServer   SpanKind = 2
+	KindClient   SpanKind = 3
+)
+
+// Span 一次操作的耗时记录，未开启追踪时 Start 返回 nil，nil 上的方法调用都会被忽略
+type Span struct {
+	TraceId       [16]byte
+	SpanId        [8]byte
+	ParentSpanId  [8]byte
+	Name          string
+	Kind          SpanKind
+	StartTime     time.Time
+	EndTime       time.Time
+	Attributes    map[string]interface{}
+	Error         bool   // 是否失败
+	StatusMessage string // 失败原因
+
+	sampled bool
+	remote  bool // 从请求头中解析的上游 span，只用作父节点
+	mutex   sync.Mutex
+	ended   bool
+}
+
+// SetAttribute 设置属性，value 支持 string、bool、int、int64、float64
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil || !s.sampled {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// SetName 修改名称，例如请求匹配到路由后使用路由模板作为名称
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Name = name
+}
+
+// RecordError 标记为失败
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Error = true
+	s.StatusMessage = err.Error()
+}
+
+// End 结束并交给导出器，重复调用时只有第一次生效
+func (s *Span) End() {
+	if s == nil || s.remote {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+	s.mutex.Unlock()
+
+	if t := getTracer(); t != nil && s.sampled {
+		t.processor.onEnd(s)
+	}
+}
+
+// TraceIdString 十六进制的 trace id
+func (s *Span) TraceIdString() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.TraceId[:])
+}
+
+// SpanIdString 十六进制的 span id
+func (s *Span) SpanIdString() string {
+	if s == nil {
+		return ""
+	}
+	return hex.EncodeToString(s.SpanId[:])
+}
+
+// HasParent 是否有父节点
+func (s *Span) HasParent() bool {
+	return s != nil && s.ParentSpanId != [8]byte{}
+}
+
+// Options 追踪配置
+type Options struct {
+	ServiceName string   // 服务名称
+	SampleRatio float64  // 采样比例，只对没有上游 span 的请求生效，有上游时跟随上游的采样结果；不在(0,1)之间时全部采样
+	Exporter    Exporter // 导出器
+	Synchronous bool     // 结束时立即导出，不经过批量队列，用于测试
+}
+
+type tracer struct {
+	options   Options
+	processor processor
+}
+
+var current atomic.Value // *tracer
+
+func getTracer() *tracer {
+	t, _ := current.Load().(*tracer)
+	return t
+}
+
+// Init 开启追踪，重复调用时替换之前的配置，之前未导出的 span 会先导出
+func Init(options Options) {
+	var p processor
+	if options.Synchronous {
+		p = &syncProcessor{exporter: options.Exporter}
+	} else {
+		p = newBatchProcessor(options.Exporter)
+	}
+	old := getTracer()
+	current.Store(&tracer{options: options, processor: p})
+	if old != nil {
+		old.processor.shutdown(5 * time.Second)
+	}
+}
+
+// Enabled 是否开启了追踪
+func Enabled() bool {
+	return getTracer() != nil
+}
+
+// ServiceName 服务名称
+func ServiceName() string {
+	if t := getTracer(); t != nil {
+		return t.options.ServiceName
+	}
+	return ""
+}
+
+// Shutdown 导出队列中剩余的 span，等待时间超过 timeout 时返回false
+func Shutdown(timeout time.Duration) bool {
+	t := getTracer()
+	if t == nil {
+		return true
+	}
+	return t.processor.shutdown(timeout)
+}
+
+type contextKey struct{}
+
+// Start 创建 span，上下文中有 span 时作为其子节点；未开启追踪时返回 nil
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	t := getTracer()
+	if t == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{Name: name, Kind: kind, StartTime: time.Now()}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceId = parent.TraceId
+		span.ParentSpanId = parent.SpanId
+		span.sampled = parent.sampled
+	} else {
+		_, _ = rand.Read(span.TraceId[:])
+		span.sampled = sample(span.TraceId, t.options.SampleRatio)
+	}
+	_, _ = rand.Read(span.SpanId[:])
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample 使用 trace id 的前8个字节决定是否采样，同一个 trace 的结果总是相同
+func sample(traceId [16]byte, ratio float64) bool {
+	if ratio <= 0 || ratio >= 1 {
+		return true
+	}
+	var v uint64
+	for _, b := range traceId[:8] {
+		v = v<<8 | uint64(b)
+	}
+	return float64(v>>11)/float64(1<<53) < ratio
+}
+
+// ContextWithSpan 将 span 保存到上下文中
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, contextKey{}, span)
+}
+
+// SpanFromContext 获取上下文中的 span，没有时返回 nil
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(contextKey{}).(*Span)
+	return span
+}
+
+// TraceparentHeader W3C Trace Context 请求头
+const TraceparentHeader = "traceparent"
+
+// Inject 将上下文中的 span 写入请求头，用于调用其他服务
+func Inject(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	flags := "00"
+	if span.sampled {
+		flags = "01"
+	}
+	header.Set(TraceparentHeader, fmt.Sprintf("00-%s-%s-%s", span.TraceIdString(), span.SpanIdString(), flags))
+}
+
+// Extract 解析请求头中的上游 span，解析成功时作为之后创建的 span 的父节点
+func Extract(ctx context.Context, header http.Header) context.Context {
+	if getTracer() == nil {
+		return ctx
+	}
+	parts := strings.Split(strings.TrimSpace(header.Get(TraceparentHeader)), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return ctx
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return ctx
+	}
+	span := &Span{remote: true}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return ctx
+	}
+	if _, err := hex.Decode(span.TraceId[:], []byte(parts[1])); err != nil || span.TraceId == [16]byte{} {
+		return ctx
+	}
+	if _, err := hex.Decode(span.SpanId[:], []byte(parts[2])); err != nil || span.SpanId == [8]byte{} {
+		return ctx
+	}
+	span.sampled = flags[0]&1 == 1
+	return ContextWithSpan(ctx, span)
+}
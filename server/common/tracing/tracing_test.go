@@ -0,0 +1,200 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+func initTest() *InMemoryExporter {
+	exporter := NewInMemoryExporter()
+	Init(Options{ServiceName: "test", Exporter: exporter, Synchronous: true})
+	return exporter
+}
+
+func findSpan(spans []*Span, prefix string) *Span {
+	for _, span := range spans {
+		if strings.HasPrefix(span.Name, prefix) {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestPropagation(t *testing.T) {
+	exporter := initTest()
+
+	ctx, root := Start(context.Background(), "root", KindServer)
+	header := http.Header{}
+	Inject(ctx, header)
+	if !strings.HasPrefix(header.Get(TraceparentHeader), "00-"+root.TraceIdString()+"-"+root.SpanIdString()+"-01") {
+		t.Fatalf("unexpected traceparent: %s", header.Get(TraceparentHeader))
+	}
+
+	// 下游服务解析请求头后创建的 span 是上游 span 的子节点
+	_, child := Start(Extract(context.Background(), header), "child", KindServer)
+	child.End()
+	root.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[0].TraceId != root.TraceId || spans[0].ParentSpanId != root.SpanId {
+		t.Fatal("child span should belong to the upstream trace")
+	}
+	if spans[1].HasParent() {
+		t.Fatal("root span should not have parent")
+	}
+
+	// 不合法的请求头被忽略
+	header.Set(TraceparentHeader, "00-00000000000000000000000000000000-0000000000000000-01")
+	if SpanFromContext(Extract(context.Background(), header)) != nil {
+		t.Fatal("invalid traceparent should be ignored")
+	}
+}
+
+func TestTransport(t *testing.T) {
+	exporter := initTest()
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get(TraceparentHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	client := Client(0)
+
+	// 请求上下文中没有 span 时不创建
+	resp, err := client.Get(server.URL + "/api?access_token=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if len(exporter.Spans()) != 0 || len(traceparent) > 0 {
+		t.Fatal("request without span should not be traced")
+	}
+
+	ctx, parent := Start(context.Background(), "parent", KindServer)
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api?access_token=secret", nil)
+	resp, err = client.Do(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	span := findSpan(exporter.Spans(), "HTTP GET")
+	if span == nil {
+		t.Fatal("client span not exported")
+	}
+	if span.Kind != KindClient || span.ParentSpanId != parent.SpanId {
+		t.Fatal("client span should be child of the request span")
+	}
+	if span.Attributes["http.status_code"] != http.StatusNoContent {
+		t.Fatalf("unexpected status: %v", span.Attributes["http.status_code"])
+	}
+	if url := span.Attributes["http.url"].(string); strings.Contains(url, "secret") {
+		t.Fatalf("query should not be recorded: %s", url)
+	}
+	if !strings.Contains(traceparent, span.SpanIdString()) {
+		t.Fatalf("traceparent should carry the client span: %s", traceparent)
+	}
+}
+
+type testRow struct {
+	Id   int64
+	Name string
+}
+
+func TestGormCallbacks(t *testing.T) {
+	exporter := initTest()
+
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	RegisterGormCallbacks(db)
+	if err := db.AutoMigrate(&testRow{}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有传入上下文的操作不创建 span
+	if err := db.Create(&testRow{Name: "a"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(exporter.Spans()) != 0 {
+		t.Fatal("operation without context should not be traced")
+	}
+
+	ctx, parent := Start(context.Background(), "parent", KindServer)
+	err = DB(ctx, db).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&testRow{Name: "b"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []testRow
+	if err := DB(ctx, db).Where("name = ?", "b").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exporter.Spans()
+	for _, name := range []string{"gorm.create", "gorm.query"} {
+		span := findSpan(spans, name)
+		if span == nil {
+			t.Fatalf("%s span not exported", name)
+		}
+		if span.ParentSpanId != parent.SpanId {
+			t.Fatalf("%s span should be child of the request span", name)
+		}
+		if span.Attributes["db.sql.table"] != "test_rows" || len(span.Attributes["db.statement"].(string)) == 0 {
+			t.Fatalf("unexpected attributes: %v", span.Attributes)
+		}
+	}
+}
+
+func TestOtlpExporter(t *testing.T) {
+	var body map[string]interface{}
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		data, _ := ioutil.ReadAll(r.Body)
+		_ = json.Unmarshal(data, &body)
+	}))
+	defer server.Close()
+
+	exporter := NewOtlpExporter(server.URL, map[string]string{"Authorization": "Bearer token"}, "bbs-go")
+	Init(Options{Exporter: exporter, Synchronous: true})
+	ctx, parent := Start(context.Background(), "parent", KindServer)
+	_, span := Start(ctx, "child", KindInternal)
+	span.SetAttribute("count", 1)
+	span.RecordError(context.Canceled)
+	span.End()
+
+	if authorization != "Bearer token" {
+		t.Fatalf("headers not sent: %q", authorization)
+	}
+	resourceSpans := body["resourceSpans"].([]interface{})[0].(map[string]interface{})
+	scopeSpans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})
+	exported := scopeSpans["spans"].([]interface{})[0].(map[string]interface{})
+	if exported["traceId"] != parent.TraceIdString() || exported["parentSpanId"] != parent.SpanIdString() || exported["name"] != "child" {
+		t.Fatalf("unexpected span: %v", exported)
+	}
+	if status := exported["status"].(map[string]interface{}); status["code"].(float64) != 2 {
+		t.Fatalf("unexpected status: %v", status)
+	}
+	attribute := exported["attributes"].([]interface{})[0].(map[string]interface{})
+	if attribute["key"] != "count" || attribute["value"].(map[string]interface{})["intValue"] != "1" {
+		t.Fatalf("unexpected attribute: %v", attribute)
+	}
+}
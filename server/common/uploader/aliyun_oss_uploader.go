@@ -2,6 +2,8 @@ package uploader
 
 import (
 	"bytes"
+	"errors"
+	"io/ioutil"
 	"sync"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
@@ -39,6 +41,29 @@ func (aliyun *aliyunOssUploader) CopyImage(originUrl string) (string, error) {
 	return aliyun.PutImage(data)
 }
 
+func (aliyun *aliyunOssUploader) GetObject(key string) ([]byte, error) {
+	body, err := aliyun.getBucket().GetObject(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+func (aliyun *aliyunOssUploader) Host() string {
+	return config.Instance.Uploader.AliyunOss.Host
+}
+
+// Ping 查询一个不存在的文件，只要求读取权限，能够检查网络和密钥是否可用
+func (aliyun *aliyunOssUploader) Ping() error {
+	bucket := aliyun.getBucket()
+	if bucket == nil {
+		return errors.New("阿里云OSS配置错误")
+	}
+	_, err := bucket.IsObjectExist(".bbs-go-ping")
+	return err
+}
+
 func (aliyun *aliyunOssUploader) getBucket() *oss.Bucket {
 	aliyun.once.Do(func() {
 		c := config.Instance.Uploader.AliyunOss
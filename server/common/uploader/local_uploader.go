@@ -1,6 +1,7 @@
 package uploader
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -39,3 +40,26 @@ func (local *localUploader) CopyImage(originUrl string) (string, error) {
 	}
 	return local.PutImage(data)
 }
+
+func (local *localUploader) GetObject(key string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(config.Instance.Uploader.Local.Path, key))
+}
+
+func (local *localUploader) Host() string {
+	return config.Instance.Uploader.Local.Host
+}
+
+func (local *localUploader) Ping() error {
+	path := config.Instance.Uploader.Local.Path
+	if err := os.MkdirAll(path, os.ModePerm); err != nil {
+		return err
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return errors.New(path + " 不是目录")
+	}
+	return nil
+}
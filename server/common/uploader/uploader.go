@@ -1,6 +1,7 @@
 package uploader
 
 import (
+	"strings"
 	"sync"
 
 	"github.com/mlogclub/simple"
@@ -12,6 +13,9 @@ type uploader interface {
 	PutImage(data []byte) (string, error)
 	PutObject(key string, data []byte) (string, error)
 	CopyImage(originUrl string) (string, error)
+	GetObject(key string) ([]byte, error)
+	Host() string
+	Ping() error
 }
 
 var (
@@ -34,6 +38,37 @@ func CopyImage(originUrl string) (string, error) {
 	return getUploader().CopyImage(originUrl)
 }
 
+// GetObject 读取已上传的文件
+func GetObject(key string) ([]byte, error) {
+	return getUploader().GetObject(key)
+}
+
+// Ping 检查存储是否可用，用于就绪检查
+func Ping() error {
+	return getUploader().Ping()
+}
+
+// ObjectKey 解析上传文件地址中的key，不是当前上传方式的地址时返回false
+func ObjectKey(url string) (string, bool) {
+	host := strings.TrimSuffix(getUploader().Host(), "/")
+	if len(host) == 0 || !strings.HasPrefix(url, host+"/") {
+		return "", false
+	}
+	key := strings.TrimPrefix(url, host+"/")
+	if i := strings.IndexAny(key, "?#"); i >= 0 {
+		key = key[:i]
+	}
+	if sep := config.Instance.Uploader.AliyunOss.StyleSplitter; len(sep) > 0 && getUploader() == aliyun {
+		if i := strings.Index(key, sep); i >= 0 {
+			key = key[:i]
+		}
+	}
+	if len(key) == 0 || strings.Contains(key, "..") {
+		return "", false
+	}
+	return key, true
+}
+
 func getUploader() uploader {
 	enable := config.Instance.Uploader.Enable
 	if simple.EqualsIgnoreCase(enable, "aliyun") || simple.EqualsIgnoreCase(enable, "oss") ||
@@ -47,11 +47,26 @@ func TagArticlesUrl(tagId int64) string {
 	return AbsUrl("/articles/" + strconv.FormatInt(tagId, 10))
 }
 
+// 标签话题列表
+func TagTopicsUrl(tagId int64) string {
+	return AbsUrl("/topics/tag/" + strconv.FormatInt(tagId, 10))
+}
+
 // 话题详情
 func TopicUrl(topicId int64) string {
 	return AbsUrl("/topic/" + strconv.FormatInt(topicId, 10))
 }
 
+// 节点话题列表
+func TopicNodeUrl(nodeId int64) string {
+	return AbsUrl("/topics/node/" + strconv.FormatInt(nodeId, 10))
+}
+
+// 评论锚点
+func CommentUrl(entityUrl string, commentId int64) string {
+	return entityUrl + "#comment-" + strconv.FormatInt(commentId, 10)
+}
+
 // 动态详情
 func TweetUrl(tweetId int64) string {
 	return AbsUrl("/tweet/" + strconv.FormatInt(tweetId, 10))
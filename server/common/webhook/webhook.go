@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	HeaderEvent     = "X-Bbs-Event"     // 事件类型
+	HeaderDelivery  = "X-Bbs-Delivery"  // 投递编号
+	HeaderSignature = "X-Bbs-Signature" // 签名，格式为：sha256=<hex>
+)
+
+// 推送超时时间
+const timeout = 10 * time.Second
+
+// 保存的响应内容最大长度
+const maxResponseBodyLen = 2048
+
+// 投递失败后的重试间隔，超过次数后不再重试
+var retryBackoff = []time.Duration{
+	time.Minute,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	6 * time.Hour,
+}
+
+// NextRetry 已投递 attempts 次仍失败时，下次重试的间隔；超过重试次数时返回false
+func NextRetry(attempts int) (time.Duration, bool) {
+	if attempts <= 0 || attempts > len(retryBackoff) {
+		return 0, false
+	}
+	return retryBackoff[attempts-1], true
+}
+
+// 推送结果
+type Result struct {
+	StatusCode   int    // 响应码，请求失败时为0
+	ResponseBody string // 响应内容，超长时截断
+	Error        string // 错误信息
+	Duration     int64  // 耗时（毫秒）
+}
+
+// Success 响应码为2xx时认为推送成功
+func (r *Result) Success() bool {
+	return r.StatusCode >= 200 && r.StatusCode < 300
+}
+
+// Sign 使用密钥对推送内容计算HMAC-SHA256签名，接收方使用同样的方式计算后比对 X-Bbs-Signature
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send 推送，未配置密钥时不签名
+func Send(url, secret, event, deliveryId string, payload []byte) *Result {
+	req := resty.New().SetTimeout(timeout).SetRedirectPolicy(resty.NoRedirectPolicy()).R().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("User-Agent", "bbs-go-webhook").
+		SetHeader(HeaderEvent, event).
+		SetHeader(HeaderDelivery, deliveryId).
+		SetBody(payload)
+	if len(secret) > 0 {
+		req.SetHeader(HeaderSignature, Sign(secret, payload))
+	}
+
+	start := time.Now()
+	resp, err := req.Post(url)
+	result := &Result{Duration: time.Since(start).Milliseconds()}
+	if resp != nil && resp.RawResponse != nil {
+		result.StatusCode = resp.StatusCode()
+		result.ResponseBody = truncate(string(resp.Body()), maxResponseBodyLen)
+	}
+	if err != nil {
+		result.Error = err.Error()
+	} else if !result.Success() {
+		result.Error = resp.Status()
+	}
+	return result
+}
+
+// truncate 按字节截断，不截断多字节字符
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver 模拟的接收方，校验签名，前 failures 次返回500
+type receiver struct {
+	mutex      sync.Mutex
+	secret     string
+	failures   int
+	requests   int
+	deliveries []string
+	signatures []string
+	invalid    []string // 签名校验失败的原因
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.requests++
+	body, _ := ioutil.ReadAll(req.Body)
+	if req.Header.Get(HeaderEvent) != "topic.create" || req.Header.Get("Content-Type") != "application/json" {
+		r.invalid = append(r.invalid, "unexpected headers")
+	}
+	if len(r.secret) > 0 && !hmac.Equal([]byte(req.Header.Get(HeaderSignature)), []byte(Sign(r.secret, body))) {
+		r.invalid = append(r.invalid, "bad signature: "+req.Header.Get(HeaderSignature))
+	}
+	r.deliveries = append(r.deliveries, req.Header.Get(HeaderDelivery))
+	r.signatures = append(r.signatures, req.Header.Get(HeaderSignature))
+	if r.requests <= r.failures {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("busy"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestSendSigned(t *testing.T) {
+	r := &receiver{secret: "secret"}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	result := Send(server.URL, "secret", "topic.create", "d-1", []byte(`{"id":1}`))
+	if !result.Success() || result.StatusCode != http.StatusNoContent || len(result.Error) > 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if len(r.invalid) > 0 {
+		t.Fatal(r.invalid)
+	}
+
+	// 密钥不一致时接收方校验失败
+	r.invalid = nil
+	Send(server.URL, "other", "topic.create", "d-2", []byte(`{"id":1}`))
+	if len(r.invalid) != 1 {
+		t.Fatal("signature with other secret should not match")
+	}
+	if Sign("secret", []byte("a")) == Sign("secret", []byte("b")) {
+		t.Fatal("signature should depend on payload")
+	}
+
+	// 未配置密钥时不签名
+	r.secret = ""
+	Send(server.URL, "", "topic.create", "d-3", []byte(`{"id":1}`))
+	if signature := r.signatures[len(r.signatures)-1]; len(signature) > 0 {
+		t.Fatalf("signature should be omitted without secret: %s", signature)
+	}
+}
+
+// 模拟定时任务的重试：失败时按 NextRetry 计算间隔后重新投递，投递编号和签名保持不变
+func TestRetry(t *testing.T) {
+	r := &receiver{secret: "secret", failures: 2}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	var (
+		attempts int
+		backoffs []time.Duration
+		result   *Result
+	)
+	for {
+		result = Send(server.URL, "secret", "topic.create", "d-1", []byte(`{"id":1}`))
+		attempts++
+		if result.Success() {
+			break
+		}
+		if result.StatusCode != http.StatusInternalServerError || result.ResponseBody != "busy" || len(result.Error) == 0 {
+			t.Fatalf("unexpected failure result: %+v", result)
+		}
+		backoff, ok := NextRetry(attempts)
+		if !ok {
+			t.Fatal("should retry")
+		}
+		backoffs = append(backoffs, backoff)
+	}
+	if attempts != 3 || len(backoffs) != 2 || backoffs[0] != time.Minute || backoffs[1] != 5*time.Minute {
+		t.Fatalf("unexpected retries: attempts=%d backoffs=%v", attempts, backoffs)
+	}
+	if len(r.invalid) > 0 {
+		t.Fatal(r.invalid)
+	}
+	for i := range r.deliveries {
+		if r.deliveries[i] != "d-1" || r.signatures[i] != r.signatures[0] {
+			t.Fatalf("delivery id and signature should be kept on retry: %v %v", r.deliveries, r.signatures)
+		}
+	}
+
+	// 超过重试次数后不再重试
+	if _, ok := NextRetry(len(retryBackoff) + 1); ok {
+		t.Fatal("should give up after max attempts")
+	}
+}
+
+func TestSendFailure(t *testing.T) {
+	// 不跟随跳转，避免推送到其他地址
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, "http://127.0.0.1:1/", http.StatusFound)
+	}))
+	defer redirect.Close()
+	if result := Send(redirect.URL, "", "topic.create", "d-1", []byte(`{}`)); result.Success() {
+		t.Fatalf("redirect should not be followed: %+v", result)
+	}
+
+	// 连接失败
+	closed := httptest.NewServer(http.NotFoundHandler())
+	url := closed.URL
+	closed.Close()
+	result := Send(url, "", "topic.create", "d-1", []byte(`{}`))
+	if result.Success() || result.StatusCode != 0 || len(result.Error) == 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	// 响应内容按字节截断，不截断多字节字符
+	long := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte("a" + strings.Repeat("中", maxResponseBodyLen)))
+	}))
+	defer long.Close()
+	result = Send(long.URL, "", "topic.create", "d-1", []byte(`{}`))
+	if len(result.ResponseBody) > maxResponseBodyLen || strings.ContainsRune(result.ResponseBody, '�') {
+		t.Fatalf("unexpected response body length: %d", len(result.ResponseBody))
+	}
+}
@@ -19,6 +19,8 @@ type Config struct {
 
 	MySqlUrl string `yaml:"MySqlUrl"` // 数据库连接地址
 
+	Secret string `yaml:"Secret"` // 签名密钥，用于生成退订链接等，未配置时自动生成并保存在系统配置中
+
 	// Github
 	Github struct {
 		ClientID     string `yaml:"ClientID"`
@@ -31,6 +33,121 @@ type Config struct {
 		AppKey string `yaml:"AppKey"`
 	} `yaml:"QQConnect"`
 
+	// OpenID Connect / OAuth2 登录，支持配置多个
+	Oidc []OidcProvider `yaml:"Oidc"`
+
+	// LDAP登录
+	Ldap struct {
+		Enabled              bool            `yaml:"Enabled"`              // 是否开启LDAP登录
+		Url                  string          `yaml:"Url"`                  // 服务器地址，例如：ldap://ldap.example.com:389、ldaps://ldap.example.com:636
+		StartTLS             bool            `yaml:"StartTLS"`             // 是否使用StartTLS
+		InsecureSkipVerify   bool            `yaml:"InsecureSkipVerify"`   // 是否跳过证书校验
+		BindDN               string          `yaml:"BindDN"`               // 查询用户时使用的账号，为空时匿名查询
+		BindPassword         string          `yaml:"BindPassword"`         // 查询用户时使用的账号密码
+		BaseDN               string          `yaml:"BaseDN"`               // 查询用户的根节点
+		UserFilter           string          `yaml:"UserFilter"`           // 查询用户的过滤条件，%s 为登录用户名，默认：(uid=%s)
+		UsernameAttribute    string          `yaml:"UsernameAttribute"`    // 用户名属性，默认：uid
+		NicknameAttribute    string          `yaml:"NicknameAttribute"`    // 昵称属性，默认：cn
+		EmailAttribute       string          `yaml:"EmailAttribute"`       // 邮箱属性，默认：mail
+		GroupAttribute       string          `yaml:"GroupAttribute"`       // 用户所属组属性，例如：memberOf
+		GroupBaseDN          string          `yaml:"GroupBaseDN"`          // 查询用户所属组的根节点，为空时使用BaseDN
+		GroupFilter          string          `yaml:"GroupFilter"`          // 查询用户所属组的过滤条件，%s 为用户DN，例如：(&(objectClass=groupOfNames)(member=%s))
+		GroupRoles           []LdapGroupRole `yaml:"GroupRoles"`           // 组与角色的对应关系
+		DisablePasswordLogin bool            `yaml:"DisablePasswordLogin"` // 是否关闭本地账号密码登录和注册
+	} `yaml:"Ldap"`
+
+	// 评论
+	Comment struct {
+		MaxDepth    int `yaml:"MaxDepth"`    // 楼中楼最大层级，超过后回复挂在上一层并引用被回复的评论，默认：3，为0时使用默认值
+		EditMinutes int `yaml:"EditMinutes"` // 发表后允许作者编辑的分钟数，默认：30，小于0时不允许编辑
+	} `yaml:"Comment"`
+
+	// 动态
+	Tweet struct {
+		TrendingHours int `yaml:"TrendingHours"` // 统计热门话题标签的时间范围（小时），默认：24
+	} `yaml:"Tweet"`
+
+	// 表情回应
+	Reaction struct {
+		Emojis []string `yaml:"Emojis"` // 可用的表情，不配置时使用默认表情
+	} `yaml:"Reaction"`
+
+	// 站点地图
+	Sitemap struct {
+		Enabled   bool   `yaml:"Enabled"`   // 是否定时生成站点地图
+		Storage   string `yaml:"Storage"`   // 存储方式：uploader（使用Uploader上传）、static（写入StaticPath），默认：uploader
+		Path      string `yaml:"Path"`      // 存放目录，默认：sitemap
+		ShardSize int    `yaml:"ShardSize"` // 每个分片的最大链接数，默认：10000，最大：50000
+	} `yaml:"Sitemap"`
+
+	// 订阅源
+	Feed struct {
+		Size         int `yaml:"Size"`         // 每个订阅源的条目数，默认：50
+		CacheSeconds int `yaml:"CacheSeconds"` // 订阅源缓存时长（秒），默认：300
+	} `yaml:"Feed"`
+
+	// ActivityPub联邦
+	ActivityPub struct {
+		Enabled           bool     `yaml:"Enabled"`           // 是否开启，开启后其他实例可以关注本站用户，关注后推送用户发表的话题、文章、动态
+		BlockedDomains    []string `yaml:"BlockedDomains"`    // 屏蔽的实例域名，不接收来自这些实例的活动
+		AllowPrivateHosts bool     `yaml:"AllowPrivateHosts"` // 允许访问内网地址和 http 地址，仅用于在本机启动两个实例测试联邦，Env 为 prod 时不生效
+	} `yaml:"ActivityPub"`
+
+	// 用户数据导出
+	Export struct {
+		Path        string `yaml:"Path"`        // 导出文件存放目录，不要配置在静态文件目录下，默认：exports
+		ExpireHours int    `yaml:"ExpireHours"` // 下载链接有效时长（小时），过期后删除导出文件，默认：72
+	} `yaml:"Export"`
+
+	// 注销账号
+	AccountDeletion struct {
+		GraceDays int    `yaml:"GraceDays"` // 申请注销后的冷静期（天），期间可以撤销，默认：15
+		Nickname  string `yaml:"Nickname"`  // 注销后显示的昵称，默认：已注销用户
+	} `yaml:"AccountDeletion"`
+
+	// 后台任务队列
+	Job struct {
+		Workers     int `yaml:"Workers"`     // 执行任务的协程数，默认：4
+		MaxAttempts int `yaml:"MaxAttempts"` // 任务最大执行次数，默认：5
+	} `yaml:"Job"`
+
+	// Prometheus监控指标
+	Metrics struct {
+		Enabled bool   `yaml:"Enabled"` // 是否开启 /metrics
+		Token   string `yaml:"Token"`   // 访问令牌，请求时携带 Authorization: Bearer {Token}，未配置Addr时必须配置
+		Addr    string `yaml:"Addr"`    // 单独监听的地址，例如：127.0.0.1:9100，配置后只在该地址提供 /metrics
+	} `yaml:"Metrics"`
+
+	// 健康检查
+	Health struct {
+		CheckSmtp bool `yaml:"CheckSmtp"` // /readyz 是否检查SMTP服务器连接，使用smtp发送方式时生效
+	} `yaml:"Health"`
+
+	// 停机
+	Shutdown struct {
+		Delay   int `yaml:"Delay"`   // 收到停止信号后 /readyz 返回503，等待该时长（秒）后再停止接收请求，便于负载均衡摘除实例，默认：0
+		Timeout int `yaml:"Timeout"` // 等待执行中的请求、定时任务、后台任务完成的最长时间（秒），默认：30
+	} `yaml:"Shutdown"`
+
+	// 日志，输出到 LogFile
+	Log struct {
+		Level      string `yaml:"Level"`      // 日志级别：debug、info、warn、error，默认：info
+		Format     string `yaml:"Format"`     // 日志格式：json、text，默认：json
+		MaxSize    int    `yaml:"MaxSize"`    // 单个日志文件的最大大小（MB），超过后切分，默认：100，小于0时不按大小切分
+		MaxBackups int    `yaml:"MaxBackups"` // 保留的历史日志文件数，默认：7，小于0时不限制
+		MaxAge     int    `yaml:"MaxAge"`     // 历史日志文件的保留天数，默认：0，不限制
+		Daily      bool   `yaml:"Daily"`      // 是否每天切分
+	} `yaml:"Log"`
+
+	// 链路追踪，使用 OTLP/HTTP 协议导出，可以接入 OpenTelemetry Collector、Jaeger、Tempo 等
+	Tracing struct {
+		Enabled     bool              `yaml:"Enabled"`     // 是否开启
+		Endpoint    string            `yaml:"Endpoint"`    // 导出地址，默认：http://127.0.0.1:4318/v1/traces
+		Headers     map[string]string `yaml:"Headers"`     // 导出时附加的请求头，例如鉴权信息
+		ServiceName string            `yaml:"ServiceName"` // 服务名称，默认：bbs-go
+		SampleRatio float64           `yaml:"SampleRatio"` // 采样比例，0~1，只对没有上游调用方的请求生效，默认：1
+	} `yaml:"Tracing"`
+
 	// 阿里云oss配置
 	Uploader struct {
 		Enable    string `yaml:"Enable"`
@@ -73,11 +190,59 @@ type Config struct {
 		SSL      bool   `yaml:"SSL"`
 	} `yaml:"Smtp"`
 
+	// 邮件发送
+	Mail struct {
+		Transport string `yaml:"Transport"` // 发送方式：smtp、file（写入本地目录，用于开发和测试）、log（仅打印日志），不配置时配置了Smtp则使用smtp
+		From      string `yaml:"From"`      // 发件人，不配置时使用Smtp.Username
+		FileDir   string `yaml:"FileDir"`   // file方式保存邮件的目录，使用Maildir格式，默认：mails
+	} `yaml:"Mail"`
+
+	// 限流配置
+	RateLimit struct {
+		Disabled                   bool              `yaml:"Disabled"`                   // 是否关闭限流
+		PostIntervalSeconds        int               `yaml:"PostIntervalSeconds"`        // 同一用户两次发表内容的最小间隔
+		ObservePostIntervalSeconds int               `yaml:"ObservePostIntervalSeconds"` // 观察期用户两次发表内容的最小间隔
+		ObserveSeconds             int               `yaml:"ObserveSeconds"`             // 观察期时长，为0时使用系统配置中的新用户观察期
+		Policies                   []RateLimitPolicy `yaml:"Policies"`                   // 限流策略，不配置时使用默认策略
+	} `yaml:"RateLimit"`
+
 	Analyze struct {
 		SignupAPI string `yaml:"SignupAPI"`
 	} `yaml:"Analyze"`
 }
 
+// 限流策略
+type RateLimitPolicy struct {
+	Pattern string  `yaml:"Pattern"` // 路径，ant风格
+	Method  string  `yaml:"Method"`  // 请求方法，为空时匹配所有方法
+	Rate    float64 `yaml:"Rate"`    // 每分钟允许的请求数
+	Burst   int     `yaml:"Burst"`   // 允许的突发请求数
+}
+
+// LDAP组与角色的对应关系
+type LdapGroupRole struct {
+	Group string `yaml:"Group"` // 组DN
+	Role  string `yaml:"Role"`  // 角色，例如：admin
+}
+
+// OpenID Connect / OAuth2 登录配置
+type OidcProvider struct {
+	Name          string   `yaml:"Name"`          // 唯一名称，同时作为第三方账号类型，例如：keycloak
+	Title         string   `yaml:"Title"`         // 登录按钮上显示的名称
+	Issuer        string   `yaml:"Issuer"`        // OIDC颁发者地址，配置后通过 /.well-known/openid-configuration 自动发现端点
+	AuthURL       string   `yaml:"AuthURL"`       // 授权地址，未配置Issuer时必填
+	TokenURL      string   `yaml:"TokenURL"`      // 令牌地址，未配置Issuer时必填
+	UserInfoURL   string   `yaml:"UserInfoURL"`   // 用户信息地址
+	ClientID      string   `yaml:"ClientID"`      // 客户端编号
+	ClientSecret  string   `yaml:"ClientSecret"`  // 客户端密钥
+	Scopes        []string `yaml:"Scopes"`        // 授权范围，配置了Issuer时默认为：openid profile email
+	DisablePKCE   bool     `yaml:"DisablePKCE"`   // 是否关闭PKCE
+	SubjectClaim  string   `yaml:"SubjectClaim"`  // 用户唯一标识字段，默认：sub
+	NicknameClaim string   `yaml:"NicknameClaim"` // 昵称字段，默认：name，为空时使用 preferred_username
+	EmailClaim    string   `yaml:"EmailClaim"`    // 邮箱字段，默认：email，只有 email_verified 为 true 时使用
+	AvatarClaim   string   `yaml:"AvatarClaim"`   // 头像字段，默认：picture
+}
+
 func Init(filename string) {
 	Instance = &Config{}
 	if yamlFile, err := ioutil.ReadFile(filename); err != nil {
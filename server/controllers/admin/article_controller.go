@@ -12,6 +12,7 @@ import (
 
 	"bbs-go/cache"
 	"bbs-go/common"
+	"bbs-go/common/event"
 	"bbs-go/controllers/render"
 	"bbs-go/services"
 )
@@ -141,10 +142,11 @@ func (c *ArticleController) PostDelete() *simple.JsonResult {
 	if id <= 0 {
 		return simple.JsonErrorMsg("id is required")
 	}
-	err := services.ArticleService.Delete(id)
+	err := services.ArticleService.Delete(c.Ctx.Request().Context(), id)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
+	event.Publish(&event.ContentDeleted{EntityType: constants.EntityArticle, EntityId: id, OperatorId: operatorId(c.Ctx)})
 	return simple.JsonSuccess()
 }
 
@@ -10,6 +10,8 @@ import (
 	"github.com/kataras/iris/v12"
 	"github.com/mlogclub/simple"
 
+	"bbs-go/common/event"
+	"bbs-go/model/constants"
 	"bbs-go/services"
 )
 
@@ -74,6 +76,7 @@ func (c *CommentController) PostDeleteBy(id int64) *simple.JsonResult {
 	if err := services.CommentService.Delete(id); err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	} else {
+		event.Publish(&event.ContentDeleted{EntityType: constants.EntityComment, EntityId: id, OperatorId: operatorId(c.Ctx)})
 		return simple.JsonSuccess()
 	}
 }
@@ -6,6 +6,8 @@ import (
 
 	"github.com/kataras/iris/v12"
 	"github.com/mlogclub/simple"
+
+	"bbs-go/services"
 )
 
 type CommonController struct {
@@ -22,3 +24,11 @@ func (c *CommonController) GetSysteminfo() *simple.JsonResult {
 		Put("hostname", hostname).
 		JsonResult()
 }
+
+// operatorId 当前操作人编号
+func operatorId(ctx iris.Context) int64 {
+	if user := services.UserTokenService.GetCurrent(ctx); user != nil {
+		return user.Id
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/services"
+)
+
+type EmailOutboxController struct {
+	Ctx iris.Context
+}
+
+func (c *EmailOutboxController) GetBy(id int64) *simple.JsonResult {
+	t := services.EmailOutboxService.Get(id)
+	if t == nil {
+		return simple.JsonErrorMsg("Not found, id=" + strconv.FormatInt(id, 10))
+	}
+	services.EmailOutboxService.Redact(t)
+	return simple.JsonData(t)
+}
+
+func (c *EmailOutboxController) AnyList() *simple.JsonResult {
+	list, paging := services.EmailOutboxService.FindPageByParams(simple.NewQueryParams(c.Ctx).
+		EqByReq("email").EqByReq("template").EqByReq("status").PageByReq().Desc("id"))
+	for i := range list {
+		services.EmailOutboxService.Redact(&list[i])
+	}
+	return simple.JsonData(&simple.PageResult{Results: list, Page: paging})
+}
+
+// 重新发送
+func (c *EmailOutboxController) PostResendBy(id int64) *simple.JsonResult {
+	if err := services.EmailOutboxService.Resend(id); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
@@ -0,0 +1,89 @@
+package admin
+
+import (
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/common/email"
+	"bbs-go/services"
+)
+
+type EmailTemplateController struct {
+	Ctx iris.Context
+}
+
+// 所有模板
+func (c *EmailTemplateController) AnyList() *simple.JsonResult {
+	var list []map[string]interface{}
+	for _, t := range services.EmailTemplateService.GetTemplates() {
+		list = append(list, map[string]interface{}{
+			"name":        t.Name,
+			"description": t.Description,
+			"variables":   t.Variables,
+			"subject":     t.Subject,
+			"html":        t.Html,
+			"text":        t.Text,
+			"custom":      services.EmailTemplateService.GetByName(t.Name) != nil,
+		})
+	}
+	return simple.JsonData(list)
+}
+
+func (c *EmailTemplateController) PostUpdate() *simple.JsonResult {
+	var (
+		name    = simple.FormValue(c.Ctx, "name")
+		subject = simple.FormValue(c.Ctx, "subject")
+		html    = simple.FormValue(c.Ctx, "html")
+		text    = simple.FormValue(c.Ctx, "text")
+	)
+	if err := services.EmailTemplateService.Save(name, subject, html, text); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
+
+// 恢复为内置模板
+func (c *EmailTemplateController) PostReset() *simple.JsonResult {
+	name := simple.FormValue(c.Ctx, "name")
+	if email.GetDefaultTemplate(name) == nil {
+		return simple.JsonErrorMsg("模板不存在")
+	}
+	services.EmailTemplateService.Reset(name)
+	return simple.JsonSuccess()
+}
+
+// 使用示例数据预览，提交的内容为编辑中的模板，不保存
+func (c *EmailTemplateController) PostPreview() *simple.JsonResult {
+	t := email.GetDefaultTemplate(simple.FormValue(c.Ctx, "name"))
+	if t == nil {
+		return simple.JsonErrorMsg("模板不存在")
+	}
+	t.Subject = simple.FormValue(c.Ctx, "subject")
+	t.Html = simple.FormValue(c.Ctx, "html")
+	t.Text = simple.FormValue(c.Ctx, "text")
+	if err := t.Validate(); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	rendered, err := services.EmailTemplateService.Preview(t)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(rendered)
+}
+
+// 使用示例数据给指定邮箱发送测试邮件
+func (c *EmailTemplateController) PostTest() *simple.JsonResult {
+	var (
+		name = simple.FormValue(c.Ctx, "name")
+		to   = simple.FormValue(c.Ctx, "email")
+	)
+	t := email.GetDefaultTemplate(name)
+	if t == nil {
+		return simple.JsonErrorMsg("模板不存在")
+	}
+	outbox, err := services.EmailOutboxService.Send(to, name, t.Sample, nil)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(outbox)
+}
@@ -0,0 +1,50 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/model/constants"
+	"bbs-go/services"
+)
+
+type JobController struct {
+	Ctx iris.Context
+}
+
+func (c *JobController) GetBy(id int64) *simple.JsonResult {
+	t := services.JobService.Get(id)
+	if t == nil {
+		return simple.JsonErrorMsg("Not found, id=" + strconv.FormatInt(id, 10))
+	}
+	return simple.JsonData(t)
+}
+
+func (c *JobController) AnyList() *simple.JsonResult {
+	list, paging := services.JobService.FindPageByParams(simple.NewQueryParams(c.Ctx).
+		EqByReq("id").EqByReq("type").EqByReq("status").PageByReq().Desc("id"))
+	return simple.JsonData(&simple.PageResult{Results: list, Page: paging})
+}
+
+// 各状态的任务数量
+func (c *JobController) GetStats() *simple.JsonResult {
+	count := func(status int) int {
+		return services.JobService.Count(simple.NewSqlCnd().Eq("status", status))
+	}
+	return simple.NewEmptyRspBuilder().
+		Put("pending", count(constants.JobStatusPending)).
+		Put("running", count(constants.JobStatusRunning)).
+		Put("success", count(constants.JobStatusSuccess)).
+		Put("dead", count(constants.JobStatusDead)).
+		JsonResult()
+}
+
+// 重新执行死信任务
+func (c *JobController) PostRetryBy(id int64) *simple.JsonResult {
+	if err := services.JobService.Retry(id); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
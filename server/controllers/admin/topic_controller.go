@@ -7,8 +7,10 @@ import (
 	"github.com/mlogclub/simple"
 	"github.com/mlogclub/simple/markdown"
 
+	"bbs-go/common/event"
 	"bbs-go/controllers/render"
 	"bbs-go/model"
+	"bbs-go/model/constants"
 	"bbs-go/services"
 )
 
@@ -63,6 +65,7 @@ func (c *TopicController) PostRecommend() *simple.JsonResult {
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
+	event.Publish(&event.TopicRecommended{TopicId: id, Recommend: true, OperatorId: operatorId(c.Ctx)})
 	return simple.JsonSuccess()
 }
 
@@ -76,6 +79,7 @@ func (c *TopicController) DeleteRecommend() *simple.JsonResult {
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
+	event.Publish(&event.TopicRecommended{TopicId: id, Recommend: false, OperatorId: operatorId(c.Ctx)})
 	return simple.JsonSuccess()
 }
 
@@ -121,10 +125,11 @@ func (c *TopicController) PostDelete() *simple.JsonResult {
 		return simple.JsonErrorMsg(err.Error())
 	}
 
-	err = services.TopicService.Delete(id)
+	err = services.TopicService.Delete(c.Ctx.Request().Context(), id)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
+	event.Publish(&event.ContentDeleted{EntityType: constants.EntityTopic, EntityId: id, OperatorId: operatorId(c.Ctx)})
 	return simple.JsonSuccess()
 }
 
@@ -137,5 +142,6 @@ func (c *TopicController) PostUndelete() *simple.JsonResult {
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
+	event.Publish(&event.ContentRestored{EntityType: constants.EntityTopic, EntityId: id, OperatorId: operatorId(c.Ctx)})
 	return simple.JsonSuccess()
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/kataras/iris/v12"
 	"github.com/mlogclub/simple"
 
+	"bbs-go/common/event"
 	"bbs-go/controllers/render"
 	"bbs-go/services"
 )
@@ -38,10 +39,11 @@ func (c *TweetController) PostDelete() *simple.JsonResult {
 		return simple.JsonErrorMsg(err.Error())
 	}
 
-	err = services.TweetService.UpdateColumn(id, "status", constants.StatusDeleted)
+	err = services.TweetService.Delete(id)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
+	event.Publish(&event.ContentDeleted{EntityType: constants.EntityTweet, EntityId: id, OperatorId: operatorId(c.Ctx)})
 	return simple.JsonSuccess()
 }
 
@@ -51,7 +53,7 @@ func (c *TweetController) PostUndelete() *simple.JsonResult {
 		return simple.JsonErrorMsg(err.Error())
 	}
 
-	err = services.TweetService.UpdateColumn(id, "status", constants.StatusOk)
+	err = services.TweetService.Undelete(id)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -18,11 +18,13 @@ type UserController struct {
 	Ctx iris.Context
 }
 
+// 同步用户计数，返回后台任务，可通过 /api/admin/job/{id} 查询执行状态
 func (c *UserController) GetSynccount() *simple.JsonResult {
-	go func() {
-		services.UserService.SyncUserCount()
-	}()
-	return simple.JsonSuccess()
+	job, err := services.JobService.Enqueue(constants.JobTypeSyncUserCount, nil)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(job)
 }
 
 func (c *UserController) GetBy(id int64) *simple.JsonResult {
@@ -116,6 +118,51 @@ func (c *UserController) PostForbidden() *simple.JsonResult {
 	return simple.JsonSuccess()
 }
 
+// 合并重复账号，将 fromUserId 的内容转移到 toUserId 并删除 fromUserId
+func (c *UserController) PostMerge() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	if !user.HasAnyRole(constants.RoleOwner, constants.RoleAdmin) {
+		return simple.JsonErrorMsg("无权限")
+	}
+	var (
+		fromUserId = simple.FormValueInt64Default(c.Ctx, "fromUserId", 0)
+		toUserId   = simple.FormValueInt64Default(c.Ctx, "toUserId", 0)
+	)
+	if fromUserId <= 0 || toUserId <= 0 {
+		return simple.JsonErrorMsg("请传入：fromUserId、toUserId")
+	}
+	if err := services.UserService.Merge(user.Id, fromUserId, toUserId, c.Ctx.Request()); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
+
+// 注销账号，立即匿名化用户资料，purge 为 true 时同时删除发表的内容
+func (c *UserController) PostDelete() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	if !user.HasAnyRole(constants.RoleOwner, constants.RoleAdmin) {
+		return simple.JsonErrorMsg("无权限")
+	}
+	var (
+		userId = simple.FormValueInt64Default(c.Ctx, "userId", 0)
+		purge  = simple.FormValue(c.Ctx, "purge") == "true"
+		reason = simple.FormValue(c.Ctx, "reason")
+	)
+	if userId <= 0 {
+		return simple.JsonErrorMsg("请传入：userId")
+	}
+	if err := services.UserDeletionService.Delete(user.Id, userId, purge, reason, c.Ctx.Request()); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
+
 func (c *UserController) buildUserItem(user *model.User) map[string]interface{} {
 	score := cache.UserCache.GetScore(user.Id)
 	return simple.NewRspBuilder(user).
@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"strconv"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/model"
+	"bbs-go/services"
+)
+
+type WebhookController struct {
+	Ctx iris.Context
+}
+
+func (c *WebhookController) GetBy(id int64) *simple.JsonResult {
+	t := services.WebhookService.Get(id)
+	if t == nil {
+		return simple.JsonErrorMsg("Not found, id=" + strconv.FormatInt(id, 10))
+	}
+	return simple.JsonData(t)
+}
+
+func (c *WebhookController) AnyList() *simple.JsonResult {
+	list, paging := services.WebhookService.FindPageByParams(simple.NewQueryParams(c.Ctx).
+		EqByReq("status").LikeByReq("name").LikeByReq("url").PageByReq().Desc("id"))
+	return simple.JsonData(&simple.PageResult{Results: list, Page: paging})
+}
+
+// 可订阅的事件
+func (c *WebhookController) GetEvents() *simple.JsonResult {
+	return simple.JsonData(services.WebhookEvents)
+}
+
+func (c *WebhookController) PostCreate() *simple.JsonResult {
+	t := &model.Webhook{}
+	err := simple.ReadForm(c.Ctx, t)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	if err := services.WebhookService.Check(t); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	t.CreateTime = simple.NowTimestamp()
+	t.UpdateTime = simple.NowTimestamp()
+
+	err = services.WebhookService.Create(t)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(t)
+}
+
+func (c *WebhookController) PostUpdate() *simple.JsonResult {
+	id, err := simple.FormValueInt64(c.Ctx, "id")
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	t := services.WebhookService.Get(id)
+	if t == nil {
+		return simple.JsonErrorMsg("entity not found")
+	}
+
+	err = simple.ReadForm(c.Ctx, t)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	if err := services.WebhookService.Check(t); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	t.UpdateTime = simple.NowTimestamp()
+
+	err = services.WebhookService.Update(t)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(t)
+}
+
+// 投递记录
+func (c *WebhookController) AnyDeliveries() *simple.JsonResult {
+	list, paging := services.WebhookDeliveryService.FindPageByParams(simple.NewQueryParams(c.Ctx).
+		EqByReq("webhook_id").EqByReq("delivery_id").EqByReq("event").EqByReq("status").PageByReq().Desc("id"))
+	return simple.JsonData(&simple.PageResult{Results: list, Page: paging})
+}
+
+// 重新投递
+func (c *WebhookController) PostRedeliverBy(deliveryId int64) *simple.JsonResult {
+	delivery, err := services.WebhookDeliveryService.Redeliver(deliveryId)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(delivery)
+}
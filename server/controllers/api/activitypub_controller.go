@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/activitypub"
+	"bbs-go/common/urls"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/services"
+)
+
+// 收件箱请求内容最大长度
+const maxInboxBodyLen = 1 << 20
+
+// ActivityPubController ActivityPub用户、内容和收件箱，未开启时返回404
+type ActivityPubController struct {
+	Ctx iris.Context
+}
+
+// 用户
+func (c *ActivityPubController) GetUsersBy(userId int64) {
+	user := c.getUser(userId)
+	if user == nil {
+		return
+	}
+	// 浏览器访问时跳转到用户主页
+	if accept := c.Ctx.GetHeader("Accept"); strings.Contains(accept, "text/html") && !strings.Contains(accept, "json") {
+		c.Ctx.Redirect(urls.UserUrl(user.Id), http.StatusFound)
+		return
+	}
+	actor, err := services.ActivityPubService.BuildActor(user)
+	if err != nil {
+		logrus.Error(err)
+		c.Ctx.StatusCode(http.StatusInternalServerError)
+		return
+	}
+	writeJson(c.Ctx, actor, activitypub.ContentType)
+}
+
+// 发件箱
+func (c *ActivityPubController) GetUsersByOutbox(userId int64) {
+	user := c.getUser(userId)
+	if user == nil {
+		return
+	}
+	outbox, err := services.ActivityPubService.Outbox(user)
+	if err != nil {
+		logrus.Error(err)
+		c.Ctx.StatusCode(http.StatusInternalServerError)
+		return
+	}
+	writeJson(c.Ctx, outbox, activitypub.ContentType)
+}
+
+// 关注者
+func (c *ActivityPubController) GetUsersByFollowers(userId int64) {
+	user := c.getUser(userId)
+	if user == nil {
+		return
+	}
+	writeJson(c.Ctx, services.ActivityPubService.Followers(user), activitypub.ContentType)
+}
+
+// 用户收件箱
+func (c *ActivityPubController) PostUsersByInbox(userId int64) {
+	if c.getUser(userId) == nil {
+		return
+	}
+	c.handleInbox()
+}
+
+// 共享收件箱
+func (c *ActivityPubController) PostInbox() {
+	if !services.ActivityPubService.Enabled() {
+		c.Ctx.StatusCode(http.StatusNotFound)
+		return
+	}
+	c.handleInbox()
+}
+
+// 话题
+func (c *ActivityPubController) GetTopicsBy(topicId int64) {
+	c.writeObject(constants.EntityTopic, topicId)
+}
+
+// 文章
+func (c *ActivityPubController) GetArticlesBy(articleId int64) {
+	c.writeObject(constants.EntityArticle, articleId)
+}
+
+// 动态
+func (c *ActivityPubController) GetTweetsBy(tweetId int64) {
+	c.writeObject(constants.EntityTweet, tweetId)
+}
+
+func (c *ActivityPubController) handleInbox() {
+	body, err := ioutil.ReadAll(io.LimitReader(c.Ctx.Request().Body, maxInboxBodyLen))
+	if err != nil {
+		c.Ctx.StatusCode(http.StatusBadRequest)
+		return
+	}
+	if err := services.ActivityPubService.HandleInbox(c.Ctx.Request(), body); err != nil {
+		logrus.Warn("ActivityPub inbox error: ", err)
+		if errors.Is(err, services.ErrApSignature) {
+			c.Ctx.StatusCode(http.StatusUnauthorized)
+		} else {
+			c.Ctx.StatusCode(http.StatusBadRequest)
+		}
+		return
+	}
+	c.Ctx.StatusCode(http.StatusAccepted)
+}
+
+func (c *ActivityPubController) writeObject(entityType string, entityId int64) {
+	if !services.ActivityPubService.Enabled() {
+		c.Ctx.StatusCode(http.StatusNotFound)
+		return
+	}
+	object := services.ActivityPubService.BuildObject(entityType, entityId)
+	if object == nil {
+		c.Ctx.StatusCode(http.StatusNotFound)
+		return
+	}
+	object.Context = activitypub.ContextActivityStreams
+	writeJson(c.Ctx, object, activitypub.ContentType)
+}
+
+// getUser 可以被关注的本地用户，不存在或者未开启时返回404
+func (c *ActivityPubController) getUser(userId int64) *model.User {
+	if !services.ActivityPubService.Enabled() {
+		c.Ctx.StatusCode(http.StatusNotFound)
+		return nil
+	}
+	user := services.ActivityPubService.GetLocalUser(userId)
+	if user == nil {
+		c.Ctx.StatusCode(http.StatusNotFound)
+	}
+	return user
+}
+
+// WellKnownController /.well-known 下的发现接口
+type WellKnownController struct {
+	Ctx iris.Context
+}
+
+// WebFinger，其他实例通过 @用户名@域名 查找用户
+func (c *WellKnownController) GetWebfinger() {
+	if !services.ActivityPubService.Enabled() {
+		c.Ctx.StatusCode(http.StatusNotFound)
+		return
+	}
+	webFinger := services.ActivityPubService.WebFinger(c.Ctx.URLParam("resource"))
+	if webFinger == nil {
+		c.Ctx.StatusCode(http.StatusNotFound)
+		return
+	}
+	writeJson(c.Ctx, webFinger, activitypub.JrdType)
+}
+
+func writeJson(ctx iris.Context, v interface{}, contentType string) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		logrus.Error(err)
+		ctx.StatusCode(http.StatusInternalServerError)
+		return
+	}
+	ctx.Header("Content-Type", contentType+"; charset=utf-8")
+	if _, err := ctx.Write(data); err != nil {
+		logrus.Error(err)
+	}
+}
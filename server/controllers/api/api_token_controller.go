@@ -0,0 +1,57 @@
+package api
+
+import (
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/controllers/render"
+	"bbs-go/services"
+)
+
+// ApiTokenController 个人访问令牌
+type ApiTokenController struct {
+	Ctx iris.Context
+}
+
+// 令牌列表
+func (c *ApiTokenController) Get() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	tokens := services.ApiTokenService.GetUserTokens(user.Id)
+	return simple.JsonData(render.BuildApiTokens(tokens))
+}
+
+// 创建令牌，原始令牌只在这里返回一次
+func (c *ApiTokenController) Post() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	var (
+		name       = simple.FormValue(c.Ctx, "name")
+		scopes     = simple.FormValueStringArray(c.Ctx, "scopes")
+		expireDays = simple.FormValueIntDefault(c.Ctx, "expireDays", 0)
+	)
+	apiToken, token, err := services.ApiTokenService.Create(user, name, scopes, expireDays)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.NewEmptyRspBuilder().
+		Put("token", token).
+		Put("apiToken", render.BuildApiToken(apiToken)).
+		JsonResult()
+}
+
+// 吊销令牌
+func (c *ApiTokenController) PostDeleteBy(id int64) *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	if err := services.ApiTokenService.Revoke(user.Id, id); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/mlogclub/simple"
 
 	"bbs-go/cache"
+	"bbs-go/common/event"
 	"bbs-go/common/urls"
 	"bbs-go/controllers/render"
 	"bbs-go/model"
@@ -54,7 +55,7 @@ func (c *ArticleController) PostCreate() *simple.JsonResult {
 		content = c.Ctx.PostValue("content")
 	)
 
-	article, err := services.ArticleService.Publish(user.Id, title, summary, content,
+	article, err := services.ArticleService.Publish(c.Ctx.Request().Context(), user.Id, title, summary, content,
 		constants.ContentTypeMarkdown, tags, "")
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
@@ -118,7 +119,7 @@ func (c *ArticleController) PostEditBy(articleId int64) *simple.JsonResult {
 		return simple.JsonErrorMsg("无权限")
 	}
 
-	if err := services.ArticleService.Edit(articleId, tags, title, content); err != nil {
+	if err := services.ArticleService.Edit(c.Ctx.Request().Context(), articleId, tags, title, content); err != nil {
 		return simple.JsonError(err)
 	}
 	// 操作日志
@@ -144,12 +145,13 @@ func (c *ArticleController) PostDeleteBy(articleId int64) *simple.JsonResult {
 		return simple.JsonErrorMsg("无权限")
 	}
 
-	if err := services.ArticleService.Delete(articleId); err != nil {
+	if err := services.ArticleService.Delete(c.Ctx.Request().Context(), articleId); err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
 	// 操作日志
 	services.OperateLogService.AddOperateLog(user.Id, constants.OpTypeDelete, constants.EntityArticle, articleId,
 		"", c.Ctx.Request())
+	event.Publish(&event.ContentDeleted{EntityType: constants.EntityArticle, EntityId: articleId, OperatorId: user.Id})
 	return simple.JsonSuccess()
 }
 
@@ -159,7 +161,7 @@ func (c *ArticleController) PostFavoriteBy(articleId int64) *simple.JsonResult {
 	if user == nil {
 		return simple.JsonError(simple.ErrorNotLogin)
 	}
-	err := services.FavoriteService.AddArticleFavorite(user.Id, articleId)
+	err := services.FavoriteService.AddArticleFavorite(c.Ctx.Request().Context(), user.Id, articleId)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -211,7 +213,7 @@ func (c *ArticleController) GetUserArticles() *simple.JsonResult {
 // 文章列表
 func (c *ArticleController) GetArticles() *simple.JsonResult {
 	cursor := simple.FormValueInt64Default(c.Ctx, "cursor", 0)
-	articles, cursor := services.ArticleService.GetArticles(cursor)
+	articles, cursor := services.ArticleService.GetArticles(c.Ctx.Request().Context(), cursor)
 	return simple.JsonCursorData(render.BuildSimpleArticles(articles), strconv.FormatInt(cursor, 10))
 }
 
@@ -6,8 +6,10 @@ import (
 	"github.com/kataras/iris/v12"
 	"github.com/mlogclub/simple"
 
+	"bbs-go/common/event"
 	"bbs-go/controllers/render"
 	"bbs-go/model"
+	"bbs-go/model/constants"
 	"bbs-go/services"
 )
 
@@ -31,8 +33,24 @@ func (c *CommentController) GetList() *simple.JsonResult {
 		return simple.JsonErrorMsg(err.Error())
 	}
 
-	comments, cursor := services.CommentService.GetComments(entityType, entityId, cursor)
-	return simple.JsonCursorData(render.BuildComments(comments), strconv.FormatInt(cursor, 10))
+	sort := simple.FormValue(c.Ctx, "sort")
+
+	comments, cursor := services.CommentService.GetComments(c.Ctx.Request().Context(), entityType, entityId, sort, cursor)
+	currentUser := services.UserTokenService.GetCurrent(c.Ctx)
+	return simple.JsonCursorData(render.BuildComments(comments, currentUser), strconv.FormatInt(cursor, 10))
+}
+
+// 评论的回复列表，用于展开楼中楼
+func (c *CommentController) GetReplies() *simple.JsonResult {
+	commentId, err := simple.FormValueInt64(c.Ctx, "commentId")
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	cursor := simple.FormValueInt64Default(c.Ctx, "cursor", 0)
+
+	comments, cursor := services.CommentService.GetReplies(c.Ctx.Request().Context(), commentId, cursor)
+	currentUser := services.UserTokenService.GetCurrent(c.Ctx)
+	return simple.JsonCursorData(render.BuildComments(comments, currentUser), strconv.FormatInt(cursor, 10))
 }
 
 func (c *CommentController) PostCreate() *simple.JsonResult {
@@ -40,6 +58,9 @@ func (c *CommentController) PostCreate() *simple.JsonResult {
 	if err := services.UserService.CheckPostStatus(user); err != nil {
 		return simple.JsonError(err)
 	}
+	if err := services.UserService.CheckPostInterval(user); err != nil {
+		return simple.JsonError(err)
+	}
 
 	form := &model.CreateCommentForm{}
 	err := simple.ReadForm(c.Ctx, form)
@@ -47,10 +68,72 @@ func (c *CommentController) PostCreate() *simple.JsonResult {
 		return simple.JsonErrorMsg(err.Error())
 	}
 
-	comment, err := services.CommentService.Publish(user.Id, form)
+	comment, err := services.CommentService.Publish(c.Ctx.Request().Context(), user.Id, form)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	services.UserService.OnPosted(user.Id)
+
+	return simple.JsonData(render.BuildComment(*comment, user))
+}
+
+// 编辑评论，只有作者可以在发表后的一段时间内编辑
+func (c *CommentController) PostEditBy(commentId int64) *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if err := services.UserService.CheckPostStatus(user); err != nil {
+		return simple.JsonError(err)
+	}
+	content := simple.FormValue(c.Ctx, "content")
+	comment, err := services.CommentService.Edit(c.Ctx.Request().Context(), user.Id, commentId, content)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
+	return simple.JsonData(render.BuildComment(*comment, user))
+}
 
-	return simple.JsonData(render.BuildComment(*comment))
+// 评论编辑历史
+func (c *CommentController) GetHistoryBy(commentId int64) *simple.JsonResult {
+	comment := services.CommentService.Get(commentId)
+	if comment == nil || comment.Status != constants.StatusOk || comment.DeleteTime > 0 {
+		return simple.JsonErrorMsg("评论不存在")
+	}
+	histories := services.CommentService.GetHistories(commentId)
+	return simple.JsonData(render.BuildCommentHistories(histories))
+}
+
+// 作者删除评论
+func (c *CommentController) PostDeleteBy(commentId int64) *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	if err := services.CommentService.DeleteByAuthor(c.Ctx.Request().Context(), user.Id, commentId); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	event.Publish(&event.ContentDeleted{EntityType: constants.EntityComment, EntityId: commentId, OperatorId: user.Id})
+	return simple.JsonSuccess()
+}
+
+// 点赞
+func (c *CommentController) PostLikeBy(commentId int64) *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	if err := services.UserLikeService.CommentLike(c.Ctx.Request().Context(), user.Id, commentId); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
+
+// 取消点赞
+func (c *CommentController) PostUnlikeBy(commentId int64) *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	if err := services.UserLikeService.CommentUnlike(c.Ctx.Request().Context(), user.Id, commentId); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
 }
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/services"
+)
+
+// FeedController 订阅源，通过 format 参数指定格式：rss（默认）、atom、json
+type FeedController struct {
+	Ctx iris.Context
+}
+
+// 节点订阅
+func (c *FeedController) GetNodeBy(nodeId int64) {
+	c.write(services.FeedTypeNode, nodeId)
+}
+
+// 标签订阅
+func (c *FeedController) GetTagBy(tagId int64) {
+	c.write(services.FeedTypeTag, tagId)
+}
+
+// 用户订阅
+func (c *FeedController) GetUserBy(userId int64) {
+	c.write(services.FeedTypeUser, userId)
+}
+
+// 话题评论订阅
+func (c *FeedController) GetTopicByComments(topicId int64) {
+	c.write(services.FeedTypeTopicComments, topicId)
+}
+
+func (c *FeedController) write(feedType string, id int64) {
+	content, err := services.FeedService.Get(feedType, id, c.Ctx.URLParam("format"))
+	if err != nil {
+		logrus.Error(err)
+		c.Ctx.StatusCode(http.StatusInternalServerError)
+		return
+	}
+	if content == nil {
+		c.Ctx.StatusCode(http.StatusNotFound)
+		return
+	}
+
+	maxAge := int(services.FeedService.CacheDuration() / time.Second)
+	c.Ctx.Header("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	c.Ctx.Header("ETag", content.ETag)
+	var lastModified time.Time
+	if content.LastModified > 0 {
+		lastModified = simple.TimeFromTimestamp(content.LastModified).UTC()
+		c.Ctx.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+	if c.notModified(content.ETag, lastModified) {
+		c.Ctx.StatusCode(http.StatusNotModified)
+		return
+	}
+	c.Ctx.Header("Content-Type", content.ContentType)
+	if _, err := c.Ctx.WriteString(content.Body); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// notModified 校验条件请求，有 If-None-Match 时忽略 If-Modified-Since
+func (c *FeedController) notModified(etag string, lastModified time.Time) bool {
+	if ifNoneMatch := c.Ctx.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+		return false
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(c.Ctx.GetHeader("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
@@ -7,6 +7,7 @@ import (
 
 	"bbs-go/common"
 	"bbs-go/common/github"
+	"bbs-go/common/oidc"
 	"bbs-go/common/qq"
 	"bbs-go/controllers/render"
 	"bbs-go/model"
@@ -113,7 +114,7 @@ func (c *LoginController) GetGithubCallback() *simple.JsonResult {
 	code := c.Ctx.FormValue("code")
 	state := c.Ctx.FormValue("state")
 	flag := c.Ctx.URLParam("flag")
-	thirdAccount, err := services.ThirdAccountService.GetOrCreateByGithub(code, state)
+	thirdAccount, err := services.ThirdAccountService.GetOrCreateByGithub(c.Ctx.Request().Context(), code, state)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -139,7 +140,53 @@ func (c *LoginController) GetQqCallback() *simple.JsonResult {
 	state := c.Ctx.FormValue("state")
 	flag := c.Ctx.URLParam("flag")
 
-	thirdAccount, err := services.ThirdAccountService.GetOrCreateByQQ(code, state)
+	thirdAccount, err := services.ThirdAccountService.GetOrCreateByQQ(c.Ctx.Request().Context(), code, state)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+
+	user, codeErr := services.UserService.SignInByThirdAccount(thirdAccount, flag)
+	if codeErr != nil {
+		return simple.JsonError(codeErr)
+	} else {
+		return c.GenerateLoginResult(user, "")
+	}
+}
+
+// 获取OpenID Connect / OAuth2 登录方式列表
+func (c *LoginController) GetOidcProviders() *simple.JsonResult {
+	var results []map[string]interface{}
+	for _, provider := range oidc.GetProviders() {
+		results = append(results, map[string]interface{}{
+			"name":  provider.Name,
+			"title": provider.Title,
+		})
+	}
+	return simple.JsonData(results)
+}
+
+// 获取OpenID Connect / OAuth2 登录授权地址
+func (c *LoginController) GetOidcAuthorize() *simple.JsonResult {
+	provider := oidc.GetProvider(c.Ctx.FormValue("provider"))
+	if provider == nil {
+		return simple.JsonErrorMsg("登录方式不存在")
+	}
+	ref := c.Ctx.FormValue("ref")
+	url, err := provider.AuthCodeURL(map[string]string{"ref": ref})
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.NewEmptyRspBuilder().Put("url", url).JsonResult()
+}
+
+// 获取OpenID Connect / OAuth2 回调信息获取
+func (c *LoginController) GetOidcCallback() *simple.JsonResult {
+	provider := c.Ctx.FormValue("provider")
+	code := c.Ctx.FormValue("code")
+	state := c.Ctx.FormValue("state")
+	flag := c.Ctx.URLParam("flag")
+
+	thirdAccount, err := services.ThirdAccountService.GetOrCreateByOidc(c.Ctx.Request().Context(), provider, code, state)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/services"
+)
+
+// NotificationController 通知设置
+type NotificationController struct {
+	Ctx iris.Context
+}
+
+// 获取通知设置
+func (c *NotificationController) GetSettings() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	return c.buildSettings(services.NotificationSettingService.GetByUserId(user.Id))
+}
+
+// 保存通知设置，preferences 为各消息类型的通知方式（JSON），digestNodeIds 为逗号分隔的节点编号
+func (c *NotificationController) PostSettings() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	var (
+		preferences   = simple.FormValue(c.Ctx, "preferences")
+		topicDigest   = simple.FormValue(c.Ctx, "topicDigest")
+		digestNodeIds = simple.FormValue(c.Ctx, "digestNodeIds")
+	)
+	prefs := make(map[int]string)
+	if len(preferences) > 0 {
+		if err := json.Unmarshal([]byte(preferences), &prefs); err != nil {
+			return simple.JsonErrorMsg("通知设置格式错误")
+		}
+	}
+	var nodeIds []int64
+	for _, str := range strings.Split(digestNodeIds, ",") {
+		if str = strings.TrimSpace(str); len(str) == 0 {
+			continue
+		}
+		nodeId, err := strconv.ParseInt(str, 10, 64)
+		if err != nil {
+			return simple.JsonErrorMsg("节点编号错误")
+		}
+		nodeIds = append(nodeIds, nodeId)
+	}
+	setting, err := services.NotificationSettingService.Save(user.Id, prefs, topicDigest, nodeIds)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return c.buildSettings(setting)
+}
+
+// 退订邮件通知，使用邮件中的签名令牌，无需登录；同时支持邮件客户端的一键退订（RFC 8058）
+func (c *NotificationController) PostUnsubscribe() *simple.JsonResult {
+	token := c.Ctx.URLParam("token")
+	if len(token) == 0 {
+		token = simple.FormValue(c.Ctx, "token")
+	}
+	userId, err := services.NotificationSettingService.ParseUnsubscribeToken(token)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	if err := services.NotificationSettingService.Unsubscribe(userId); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
+
+func (c *NotificationController) buildSettings(setting *model.NotificationSetting) *simple.JsonResult {
+	topicDigest := constants.NotifyModeOff
+	if setting != nil && len(setting.TopicDigest) > 0 {
+		topicDigest = setting.TopicDigest
+	}
+	return simple.NewEmptyRspBuilder().
+		Put("preferences", services.NotificationSettingService.GetPreferences(setting)).
+		Put("topicDigest", topicDigest).
+		Put("digestNodeIds", services.NotificationSettingService.GetDigestNodeIds(setting)).
+		Put("msgTypes", services.NotifyMsgTypes).
+		Put("modes", services.NotifyModes).
+		JsonResult()
+}
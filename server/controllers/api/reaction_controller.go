@@ -0,0 +1,95 @@
+package api
+
+import (
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/controllers/render"
+	"bbs-go/services"
+)
+
+// ReactionController 表情回应
+type ReactionController struct {
+	Ctx iris.Context
+}
+
+// 可用的表情
+func (c *ReactionController) GetEmojis() *simple.JsonResult {
+	return simple.JsonData(services.ReactionService.GetEmojis())
+}
+
+// 回应数量和当前用户使用过的表情
+func (c *ReactionController) GetList() *simple.JsonResult {
+	entityType, entityId, err := c.readEntity()
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	var emojis []string
+	if user := services.UserTokenService.GetCurrent(c.Ctx); user != nil {
+		emojis = services.ReactionService.GetUserEmojis(user.Id, entityType, entityId)
+	}
+	return simple.NewEmptyRspBuilder().
+		Put("reactions", services.ReactionService.GetCounts(entityType, entityId)).
+		Put("emojis", emojis).
+		JsonResult()
+}
+
+// 最近回应的用户，emoji 为空时查询所有表情
+func (c *ReactionController) GetUsers() *simple.JsonResult {
+	entityType, entityId, err := c.readEntity()
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	emoji := simple.FormValue(c.Ctx, "emoji")
+	reactions := services.ReactionService.Recent(entityType, entityId, emoji, 20)
+	var results []map[string]interface{}
+	for _, reaction := range reactions {
+		userInfo := render.BuildUserById(reaction.UserId)
+		if userInfo == nil {
+			continue
+		}
+		results = append(results, map[string]interface{}{
+			"user":       userInfo,
+			"emoji":      reaction.Emoji,
+			"createTime": reaction.CreateTime,
+		})
+	}
+	return simple.JsonData(results)
+}
+
+// 添加表情回应
+func (c *ReactionController) PostAdd() *simple.JsonResult {
+	return c.react(services.ReactionService.Add)
+}
+
+// 取消表情回应
+func (c *ReactionController) PostRemove() *simple.JsonResult {
+	return c.react(services.ReactionService.Remove)
+}
+
+func (c *ReactionController) react(fn func(userId int64, entityType string, entityId int64, emoji string) error) *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	entityType, entityId, err := c.readEntity()
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	emoji, err := simple.FormValueRequired(c.Ctx, "emoji")
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	if err := fn(user.Id, entityType, entityId, emoji); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(services.ReactionService.GetCounts(entityType, entityId))
+}
+
+func (c *ReactionController) readEntity() (entityType string, entityId int64, err error) {
+	if entityType, err = simple.FormValueRequired(c.Ctx, "entityType"); err != nil {
+		return
+	}
+	entityId, err = simple.FormValueInt64(c.Ctx, "entityId")
+	return
+}
@@ -3,9 +3,7 @@ package api
 import (
 	"bbs-go/model/constants"
 	"errors"
-	"io/ioutil"
 	"strconv"
-	"strings"
 
 	"github.com/kataras/iris/v12"
 	"github.com/mlogclub/simple"
@@ -29,7 +27,7 @@ func (c *SpiderController) PostWxPublish() *simple.JsonResult {
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
-	t, err := collect.NewWxbotApi().Publish(article)
+	t, err := collect.NewWxbotApi().Publish(c.Ctx.Request().Context(), article)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -49,7 +47,7 @@ func (c *SpiderController) PostArticlePublish() *simple.JsonResult {
 		return simple.JsonErrorMsg(err.Error())
 	}
 
-	articleId, err := collect.NewSpiderApi().Publish(article)
+	articleId, err := collect.NewSpiderApi().Publish(c.Ctx.Request().Context(), article)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -68,7 +66,7 @@ func (c *SpiderController) PostCommentPublish() *simple.JsonResult {
 		return simple.JsonErrorMsg(err.Error())
 	}
 
-	commentId, err := collect.NewSpiderApi().PublishComment(comment)
+	commentId, err := collect.NewSpiderApi().PublishComment(c.Ctx.Request().Context(), comment)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -110,15 +108,16 @@ func (c *SpiderController) PostProjectPublish() *simple.JsonResult {
 	return simple.NewEmptyRspBuilder().Put("id", p.Id).JsonResult()
 }
 
+// checkToken 校验采集令牌，令牌需要拥有spider授权范围，可通过 Authorization: Bearer 或 token 参数传递
 func (c *SpiderController) checkToken() error {
-	token := c.Ctx.FormValue("token")
-	data, err := ioutil.ReadFile("/data/publish_token")
-	if err != nil {
-		return err
+	token := services.ApiTokenService.GetBearerToken(c.Ctx)
+	if len(token) == 0 {
+		token = c.Ctx.FormValue("token")
 	}
-	token2 := strings.TrimSpace(string(data))
-	if token != token2 {
+	apiToken := services.ApiTokenService.GetByToken(token)
+	if apiToken == nil || !apiToken.HasScope(constants.ApiScopeSpider) {
 		return errors.New("token invalidate")
 	}
+	services.ApiTokenService.MarkUsed(apiToken)
 	return nil
 }
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/controllers/render"
+	"bbs-go/services"
+)
+
+// ThirdAccountController 绑定的第三方账号
+type ThirdAccountController struct {
+	Ctx iris.Context
+}
+
+// 已绑定的第三方账号列表
+func (c *ThirdAccountController) Get() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	thirdAccounts := services.ThirdAccountService.GetUserThirdAccounts(user.Id)
+	return simple.JsonData(render.BuildThirdAccounts(thirdAccounts))
+}
+
+// 获取绑定第三方账号的授权地址
+func (c *ThirdAccountController) GetLinkAuthorize() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	var (
+		thirdType = c.Ctx.FormValue("thirdType")
+		ref       = c.Ctx.FormValue("ref")
+	)
+	url, err := services.ThirdAccountService.GetLinkAuthorizeUrl(user.Id, thirdType, ref)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.NewEmptyRspBuilder().Put("url", url).JsonResult()
+}
+
+// 绑定第三方账号，授权回调后调用
+func (c *ThirdAccountController) PostLink() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	var (
+		thirdType = simple.FormValue(c.Ctx, "thirdType")
+		code      = simple.FormValue(c.Ctx, "code")
+		state     = simple.FormValue(c.Ctx, "state")
+	)
+	thirdAccount, err := services.ThirdAccountService.Link(user.Id, thirdType, code, state)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(render.BuildThirdAccount(thirdAccount))
+}
+
+// 解除绑定第三方账号
+func (c *ThirdAccountController) PostUnlink() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	thirdType := simple.FormValue(c.Ctx, "thirdType")
+	if err := services.ThirdAccountService.Unlink(user, thirdType); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/mlogclub/simple"
 
 	"bbs-go/cache"
+	"bbs-go/common/event"
 	"bbs-go/controllers/render"
 	"bbs-go/model"
 	"bbs-go/services"
@@ -61,6 +62,9 @@ func (c *TopicController) PostCreate() *simple.JsonResult {
 	if err := services.UserService.CheckPostStatus(user); err != nil {
 		return simple.JsonError(err)
 	}
+	if err := services.UserService.CheckPostInterval(user); err != nil {
+		return simple.JsonError(err)
+	}
 
 	var (
 		captchaId   = simple.FormValue(c.Ctx, "captchaId")
@@ -79,10 +83,11 @@ func (c *TopicController) PostCreate() *simple.JsonResult {
 		return simple.JsonErrorMsg("无权限")
 	}
 
-	topic, err := services.TopicService.Publish(user.Id, nodeId, tags, title, content)
+	topic, err := services.TopicService.Publish(c.Ctx.Request().Context(), user.Id, nodeId, tags, title, content)
 	if err != nil {
 		return simple.JsonError(err)
 	}
+	services.UserService.OnPosted(user.Id)
 	return simple.JsonData(render.BuildSimpleTopic(topic))
 }
 
@@ -145,7 +150,7 @@ func (c *TopicController) PostEditBy(topicId int64) *simple.JsonResult {
 	if !checkNodeRole(user, nodeId) {
 		return simple.JsonErrorMsg("无权限")
 	}
-	err := services.TopicService.Edit(topicId, nodeId, tags, title, content)
+	err := services.TopicService.Edit(c.Ctx.Request().Context(), topicId, nodeId, tags, title, content)
 	if err != nil {
 		return simple.JsonError(err)
 	}
@@ -172,12 +177,13 @@ func (c *TopicController) PostDeleteBy(topicId int64) *simple.JsonResult {
 		return simple.JsonErrorMsg("无权限")
 	}
 
-	if err := services.TopicService.Delete(topicId); err != nil {
+	if err := services.TopicService.Delete(c.Ctx.Request().Context(), topicId); err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
 	// 操作日志
 	services.OperateLogService.AddOperateLog(user.Id, constants.OpTypeDelete, constants.EntityTopic, topicId,
 		"", c.Ctx.Request())
+	event.Publish(&event.ContentDeleted{EntityType: constants.EntityTopic, EntityId: topicId, OperatorId: user.Id})
 	return simple.JsonSuccess()
 }
 
@@ -197,7 +203,7 @@ func (c *TopicController) PostLikeBy(topicId int64) *simple.JsonResult {
 	if user == nil {
 		return simple.JsonError(simple.ErrorNotLogin)
 	}
-	err := services.UserLikeService.TopicLike(user.Id, topicId)
+	err := services.UserLikeService.TopicLike(c.Ctx.Request().Context(), user.Id, topicId)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -309,7 +315,7 @@ func (c *TopicController) GetFavoriteBy(topicId int64) *simple.JsonResult {
 	if user == nil {
 		return simple.JsonError(simple.ErrorNotLogin)
 	}
-	err := services.FavoriteService.AddTopicFavorite(user.Id, topicId)
+	err := services.FavoriteService.AddTopicFavorite(c.Ctx.Request().Context(), user.Id, topicId)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -20,18 +20,23 @@ func (c *TweetController) PostCreate() *simple.JsonResult {
 	if err := services.UserService.CheckPostStatus(user); err != nil {
 		return simple.JsonError(err)
 	}
+	if err := services.UserService.CheckPostInterval(user); err != nil {
+		return simple.JsonError(err)
+	}
 	content := strings.TrimSpace(simple.FormValue(c.Ctx, "content"))
 	imageList := simple.FormValue(c.Ctx, "imageList")
-	tweets, err := services.TweetService.Publish(user.Id, content, imageList)
+	repostId := simple.FormValueInt64Default(c.Ctx, "repostId", 0)
+	tweets, err := services.TweetService.Publish(c.Ctx.Request().Context(), user.Id, content, imageList, repostId)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
+	services.UserService.OnPosted(user.Id)
 	return simple.JsonData(render.BuildTweet(tweets))
 }
 
 func (c *TweetController) GetList() *simple.JsonResult {
 	cursor := simple.FormValueInt64Default(c.Ctx, "cursor", 0)
-	tweets, cursor := services.TweetService.GetTweets(cursor)
+	tweets, cursor := services.TweetService.GetTweets(c.Ctx.Request().Context(), cursor)
 	return simple.JsonCursorData(render.BuildTweets(tweets), strconv.FormatInt(cursor, 10))
 }
 
@@ -45,7 +50,7 @@ func (c *TweetController) PostLikeBy(tweetId int64) *simple.JsonResult {
 	if user == nil {
 		return simple.JsonError(simple.ErrorNotLogin)
 	}
-	err := services.UserLikeService.TweetLike(user.Id, tweetId)
+	err := services.UserLikeService.TweetLike(c.Ctx.Request().Context(), user.Id, tweetId)
 	if err != nil {
 		return simple.JsonErrorMsg(err.Error())
 	}
@@ -56,3 +61,15 @@ func (c *TweetController) GetNewest() *simple.JsonResult {
 	tweets := services.TweetService.GetNewest()
 	return simple.JsonData(render.BuildTweets(tweets))
 }
+
+// 话题标签下的动态
+func (c *TweetController) GetHashtagBy(name string) *simple.JsonResult {
+	cursor := simple.FormValueInt64Default(c.Ctx, "cursor", 0)
+	tweets, cursor := services.HashtagService.GetTweets(name, cursor)
+	return simple.JsonCursorData(render.BuildTweets(tweets), strconv.FormatInt(cursor, 10))
+}
+
+// 热门话题标签
+func (c *TweetController) GetHashtagsTrending() *simple.JsonResult {
+	return simple.JsonData(services.HashtagService.GetTrending(10))
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/kataras/iris/v12"
 	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
 
 	"bbs-go/cache"
 	"bbs-go/controllers/render"
@@ -38,6 +39,22 @@ func (c *UserController) GetBy(userId int64) *simple.JsonResult {
 	return simple.JsonErrorMsg("用户不存在")
 }
 
+// @提及自动补全，按用户名、昵称前缀匹配
+func (c *UserController) GetMentionSearch() *simple.JsonResult {
+	users := services.UserService.SearchForMention(c.Ctx.URLParam("keyword"), 10)
+	var results []map[string]interface{}
+	for _, user := range users {
+		info := render.BuildUser(&user)
+		results = append(results, map[string]interface{}{
+			"id":          info.Id,
+			"username":    info.Username,
+			"nickname":    info.Nickname,
+			"smallAvatar": info.SmallAvatar,
+		})
+	}
+	return simple.JsonData(results)
+}
+
 // 用户积分
 func (c *UserController) GetScoreBy(userId int64) *simple.JsonResult {
 	score := cache.UserCache.GetScore(userId)
@@ -334,3 +351,90 @@ func (c *UserController) GetCheckin() *simple.JsonResult {
 	}
 	return simple.JsonSuccess()
 }
+
+// PostExport 申请导出个人数据，后台生成完成后通过消息通知下载链接
+func (c *UserController) PostExport() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	export, err := services.UserExportService.Request(user.Id)
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(export)
+}
+
+// GetExport 最近一次导出的状态
+func (c *UserController) GetExport() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	export := services.UserExportService.GetLatest(user.Id)
+	if export == nil {
+		return simple.JsonSuccess()
+	}
+	builder := simple.NewRspBuilder(export)
+	if export.Status == constants.UserExportSuccess {
+		builder.Put("downloadUrl", services.UserExportService.DownloadUrl(export.Id))
+	}
+	return builder.JsonResult()
+}
+
+// GetExportDownload 下载导出的数据，通过签名链接访问，无需登录
+func (c *UserController) GetExportDownload() {
+	export, filename, err := services.UserExportService.GetDownloadFile(c.Ctx.URLParam("token"))
+	if err != nil {
+		c.Ctx.StatusCode(iris.StatusNotFound)
+		_, _ = c.Ctx.WriteString(err.Error())
+		return
+	}
+	c.Ctx.Header("Cache-Control", "private, no-store")
+	if err := c.Ctx.SendFile(filename, "bbs-go-export-"+strconv.FormatInt(export.UserId, 10)+".zip"); err != nil {
+		logrus.Error(err)
+		c.Ctx.StatusCode(iris.StatusNotFound)
+	}
+}
+
+// GetDeletion 处于冷静期的注销申请
+func (c *UserController) GetDeletion() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	if deletion := services.UserDeletionService.GetPending(user.Id); deletion != nil {
+		return simple.JsonData(deletion)
+	}
+	return simple.JsonSuccess()
+}
+
+// PostDeletion 申请注销账号，冷静期结束后执行
+func (c *UserController) PostDeletion() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	var (
+		password = simple.FormValue(c.Ctx, "password")
+		reason   = simple.FormValue(c.Ctx, "reason")
+		purge    = simple.FormValue(c.Ctx, "purge") == "true"
+	)
+	deletion, err := services.UserDeletionService.Request(user, password, reason, purge, c.Ctx.Request())
+	if err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonData(deletion)
+}
+
+// PostDeletionCancel 撤销注销申请
+func (c *UserController) PostDeletionCancel() *simple.JsonResult {
+	user := services.UserTokenService.GetCurrent(c.Ctx)
+	if user == nil {
+		return simple.JsonError(simple.ErrorNotLogin)
+	}
+	if err := services.UserDeletionService.Cancel(user.Id, c.Ctx.Request()); err != nil {
+		return simple.JsonErrorMsg(err.Error())
+	}
+	return simple.JsonSuccess()
+}
@@ -16,6 +16,7 @@ import (
 	"bbs-go/cache"
 	"bbs-go/common"
 	"bbs-go/common/avatar"
+	"bbs-go/common/mention"
 	"bbs-go/common/urls"
 	"bbs-go/config"
 	"bbs-go/model"
@@ -209,6 +210,7 @@ func BuildTopic(topic *model.Topic) *model.TopicResponse {
 	rsp.ViewCount = topic.ViewCount
 	rsp.CommentCount = topic.CommentCount
 	rsp.LikeCount = topic.LikeCount
+	rsp.Reactions = services.ReactionService.GetCounts(constants.EntityTopic, topic.Id)
 
 	if topic.NodeId > 0 {
 		node := services.TopicNodeService.Get(topic.NodeId)
@@ -265,6 +267,25 @@ func BuildTweet(tweet *model.Tweet) *model.TweetResponse {
 	if tweet == nil {
 		return nil
 	}
+	return _buildTweet(tweet, true, getTweetReactions(*tweet))
+}
+
+// getTweetReactions 批量查询动态及其转发的动态的表情回应数量
+func getTweetReactions(tweets ...model.Tweet) map[int64][]model.ReactionCountResponse {
+	var ids []int64
+	for _, tweet := range tweets {
+		ids = append(ids, tweet.Id)
+		if tweet.RepostId > 0 {
+			ids = append(ids, tweet.RepostId)
+		}
+	}
+	return services.ReactionService.GetCountsByEntityIds(constants.EntityTweet, ids)
+}
+
+func _buildTweet(tweet *model.Tweet, buildRepost bool, reactions map[int64][]model.ReactionCountResponse) *model.TweetResponse {
+	if tweet == nil {
+		return nil
+	}
 
 	rsp := &model.TweetResponse{
 		TweetId:      tweet.Id,
@@ -272,6 +293,9 @@ func BuildTweet(tweet *model.Tweet) *model.TweetResponse {
 		Content:      tweet.Content,
 		CommentCount: tweet.CommentCount,
 		LikeCount:    tweet.LikeCount,
+		Reactions:    reactions[tweet.Id],
+		RepostId:     tweet.RepostId,
+		RepostCount:  tweet.RepostCount,
 		Status:       tweet.Status,
 		CreateTime:   tweet.CreateTime,
 	}
@@ -292,13 +316,22 @@ func BuildTweet(tweet *model.Tweet) *model.TweetResponse {
 			logrus.Error(err)
 		}
 	}
+	if buildRepost && tweet.RepostId > 0 {
+		original := services.TweetService.Get(tweet.RepostId)
+		if original != nil && original.Status == constants.StatusOk {
+			rsp.Repost = _buildTweet(original, false, reactions)
+		} else {
+			rsp.RepostRemoved = true
+		}
+	}
 	return rsp
 }
 
 func BuildTweets(tweets []model.Tweet) []model.TweetResponse {
+	reactions := getTweetReactions(tweets...)
 	var ret []model.TweetResponse
 	for _, tweet := range tweets {
-		ret = append(ret, *BuildTweet(&tweet))
+		ret = append(ret, *_buildTweet(&tweet, true, reactions))
 	}
 	return ret
 }
@@ -365,19 +398,48 @@ func BuildSimpleProject(project *model.Project) *model.ProjectSimpleResponse {
 	return rsp
 }
 
-func BuildComments(comments []model.Comment) []model.CommentResponse {
+// BuildComments 渲染评论列表，currentUser为当前登录用户，未登录时为nil
+func BuildComments(comments []model.Comment, currentUser *model.User) []model.CommentResponse {
+	reactions := getCommentReactions(comments...)
+	liked := getCommentLiked(currentUser, comments...)
 	var ret []model.CommentResponse
 	for _, comment := range comments {
-		ret = append(ret, *BuildComment(comment))
+		ret = append(ret, *_buildComment(&comment, true, currentUser, reactions, liked))
 	}
 	return ret
 }
 
-func BuildComment(comment model.Comment) *model.CommentResponse {
-	return _buildComment(&comment, true)
+// BuildComment 渲染评论，currentUser为当前登录用户，未登录时为nil
+func BuildComment(comment model.Comment, currentUser *model.User) *model.CommentResponse {
+	return _buildComment(&comment, true, currentUser, getCommentReactions(comment), getCommentLiked(currentUser, comment))
+}
+
+// getCommentReactions 批量查询评论及其引用的评论的表情回应数量
+func getCommentReactions(comments ...model.Comment) map[int64][]model.ReactionCountResponse {
+	return services.ReactionService.GetCountsByEntityIds(constants.EntityComment, getCommentIds(comments...))
 }
 
-func _buildComment(comment *model.Comment, buildQuote bool) *model.CommentResponse {
+// getCommentLiked 批量查询当前用户点赞过的评论及其引用的评论
+func getCommentLiked(currentUser *model.User, comments ...model.Comment) map[int64]bool {
+	if currentUser == nil {
+		return nil
+	}
+	return services.UserLikeService.LikedIds(currentUser.Id, constants.EntityComment, getCommentIds(comments...))
+}
+
+func getCommentIds(comments ...model.Comment) []int64 {
+	var ids []int64
+	for _, comment := range comments {
+		ids = append(ids, comment.Id)
+		if comment.QuoteId > 0 {
+			ids = append(ids, comment.QuoteId)
+		}
+	}
+	return ids
+}
+
+func _buildComment(comment *model.Comment, buildQuote bool, currentUser *model.User,
+	reactions map[int64][]model.ReactionCountResponse, liked map[int64]bool) *model.CommentResponse {
 	if comment == nil {
 		return nil
 	}
@@ -388,21 +450,33 @@ func _buildComment(comment *model.Comment, buildQuote bool) *model.CommentRespon
 		EntityType: comment.EntityType,
 		EntityId:   comment.EntityId,
 		QuoteId:    comment.QuoteId,
+		ParentId:   comment.ParentId,
+		RootId:     comment.RootId,
+		Depth:      comment.Depth,
+		ReplyCount: comment.ReplyCount,
+		LikeCount:  comment.LikeCount,
+		Liked:      liked[comment.Id],
+		Reactions:  reactions[comment.Id],
+		Edited:     comment.UpdateTime > 0,
+		Deleted:    comment.DeleteTime > 0,
 		Status:     comment.Status,
 		CreateTime: comment.CreateTime,
+		UpdateTime: comment.UpdateTime,
 	}
 
-	if comment.ContentType == constants.ContentTypeMarkdown {
-		content, _ := markdown.New().Run(comment.Content)
-		ret.Content = BuildHtmlContent(content)
-	} else if comment.ContentType == constants.ContentTypeHtml {
-		ret.Content = BuildHtmlContent(comment.Content)
+	// 原始内容只返回给作者
+	if currentUser != nil && currentUser.Id == comment.UserId && services.CommentService.CanEdit(comment) {
+		ret.Editable = true
+		ret.Source = comment.Content
+	}
+	if ret.Deleted { // 作者已删除，不再展示内容
+		ret.Content = "该评论已删除"
 	} else {
-		ret.Content = html.EscapeString(comment.Content)
+		ret.Content = buildCommentContent(comment.ContentType, comment.Content)
 	}
 
 	if buildQuote && comment.QuoteId > 0 {
-		quote := _buildComment(services.CommentService.Get(comment.QuoteId), false)
+		quote := _buildComment(services.CommentService.Get(comment.QuoteId), false, currentUser, reactions, liked)
 		if quote != nil {
 			ret.Quote = quote
 			ret.QuoteContent = quote.User.Nickname + "：" + quote.Content
@@ -411,6 +485,30 @@ func _buildComment(comment *model.Comment, buildQuote bool) *model.CommentRespon
 	return ret
 }
 
+func buildCommentContent(contentType, content string) string {
+	if contentType == constants.ContentTypeMarkdown {
+		str, _ := markdown.New().Run(content)
+		return BuildHtmlContent(str)
+	} else if contentType == constants.ContentTypeHtml {
+		return BuildHtmlContent(content)
+	} else if strings.Contains(content, "@") {
+		return BuildHtmlContent(html.EscapeString(content))
+	}
+	return html.EscapeString(content)
+}
+
+func BuildCommentHistories(histories []model.CommentHistory) []model.CommentHistoryResponse {
+	var ret []model.CommentHistoryResponse
+	for _, history := range histories {
+		ret = append(ret, model.CommentHistoryResponse{
+			Id:         history.Id,
+			Content:    buildCommentContent(history.ContentType, history.Content),
+			CreateTime: history.CreateTime,
+		})
+	}
+	return ret
+}
+
 func BuildTag(tag *model.Tag) *model.TagResponse {
 	if tag == nil {
 		return nil
@@ -494,6 +592,8 @@ func BuildMessage(message *model.Message) *model.MessageResponse {
 		} else if entityType.String() == constants.EntityTweet {
 			detailUrl = urls.TweetUrl(entityId.Int())
 		}
+	} else if message.Type == constants.MsgTypeSystem {
+		detailUrl = gjson.Get(message.ExtraData, "url").String()
 	}
 	from := BuildUserDefaultIfNull(message.FromId)
 	if message.FromId <= 0 {
@@ -525,12 +625,70 @@ func BuildMessages(messages []model.Message) []model.MessageResponse {
 	return responses
 }
 
+func BuildApiToken(apiToken *model.ApiToken) *model.ApiTokenResponse {
+	if apiToken == nil {
+		return nil
+	}
+	return &model.ApiTokenResponse{
+		ApiTokenId: apiToken.Id,
+		Name:       apiToken.Name,
+		TokenTail:  apiToken.TokenTail,
+		Scopes:     apiToken.GetScopes(),
+		ExpiredAt:  apiToken.ExpiredAt,
+		LastUsedAt: apiToken.LastUsedAt,
+		CreateTime: apiToken.CreateTime,
+	}
+}
+
+func BuildApiTokens(apiTokens []model.ApiToken) []model.ApiTokenResponse {
+	if len(apiTokens) == 0 {
+		return nil
+	}
+	var responses []model.ApiTokenResponse
+	for _, apiToken := range apiTokens {
+		responses = append(responses, *BuildApiToken(&apiToken))
+	}
+	return responses
+}
+
+func BuildThirdAccount(thirdAccount *model.ThirdAccount) *model.ThirdAccountResponse {
+	if thirdAccount == nil {
+		return nil
+	}
+	return &model.ThirdAccountResponse{
+		ThirdAccountId: thirdAccount.Id,
+		ThirdType:      thirdAccount.ThirdType,
+		Nickname:       thirdAccount.Nickname,
+		Avatar:         thirdAccount.Avatar,
+		CreateTime:     thirdAccount.CreateTime,
+	}
+}
+
+func BuildThirdAccounts(thirdAccounts []model.ThirdAccount) []model.ThirdAccountResponse {
+	if len(thirdAccounts) == 0 {
+		return nil
+	}
+	var responses []model.ThirdAccountResponse
+	for _, thirdAccount := range thirdAccounts {
+		responses = append(responses, *BuildThirdAccount(&thirdAccount))
+	}
+	return responses
+}
+
 func BuildHtmlContent(htmlContent string) string {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlContent))
 	if err != nil {
 		return htmlContent
 	}
 
+	// @提及的用户链接到用户主页
+	mention.Link(doc.Find("body"), func(name string) string {
+		if user := cache.UserCache.GetByName(name); user != nil {
+			return urls.UserUrl(user.Id)
+		}
+		return ""
+	})
+
 	doc.Find("a").Each(func(i int, selection *goquery.Selection) {
 		href := selection.AttrOr("href", "")
 
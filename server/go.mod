@@ -14,12 +14,13 @@ require (
 	github.com/dchest/captcha v0.0.0-20170622155422-6a29415a8364
 	github.com/emirpasic/gods v1.12.0
 	github.com/fasthttp-contrib/websocket v0.0.0-20160511215533-1f3b11f56072 // indirect
+	github.com/go-asn1-ber/asn1-ber v1.5.1
+	github.com/go-ldap/ldap/v3 v3.3.0
 	github.com/go-resty/resty/v2 v2.1.0
 	github.com/goburrow/cache v0.1.0
 	github.com/google/go-querystring v1.0.0 // indirect
 	github.com/gorilla/feeds v1.1.1
 	github.com/graphql-go/graphql v0.7.9
-	github.com/ikeikeikeike/go-sitemap-generator/v2 v2.0.2
 	github.com/imkira/go-interpol v1.1.0 // indirect
 	github.com/iris-contrib/middleware/cors v0.0.0-20191219204441-78279b78a367
 	github.com/issue9/identicon v1.0.1
@@ -14,6 +14,7 @@ const (
 	CtxCurrentUser  ContextKey = "current-user"
 	CtxTopicType    string     = "topic-content-type"
 	UserCache       string     = "user-cache"
+	RepliesCache    string     = "replies-cache"
 )
 
 type RequestOptions struct {
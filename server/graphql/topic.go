@@ -19,6 +19,9 @@ var (
 	ForumSchema *graphql.Schema
 )
 
+// 每条评论随评论列表返回的回复数，更多回复通过 repliesCursor 调用 /api/comment/replies 加载
+const repliesLimit = 5
+
 type CommentsMap map[int64]model.Comment
 type UsersMap map[int64]model.User
 type RootType map[string]interface{}
@@ -125,6 +128,42 @@ func (query *LazyQuery) Get(ids ...int64) []interface{} {
 	return result
 }
 
+// shareComments 记录已查询的评论用于解析引用，并登记评论作者和回复的延迟查询
+func shareComments(params *graphql.ResolveParams, topicId int64, comments []model.Comment) {
+	root := params.Info.RootValue.(map[string]interface{})
+	key := fmt.Sprintf("%s-%d", CtxCommentsType, topicId)
+	commentsMap, ok := root[key].(CommentsMap)
+	if !ok {
+		commentsMap = make(CommentsMap)
+		root[key] = commentsMap
+	}
+	users := make([]int64, 0)
+	parents := make([]int64, 0)
+	for _, item := range comments {
+		commentsMap[item.Id] = item
+		users = append(users, item.UserId)
+		if item.ReplyCount > 0 {
+			parents = append(parents, item.Id)
+		}
+	}
+	setKeysToCache(params, UserCache, users...)
+	setKeysToCache(params, RepliesCache, parents...)
+}
+
+// queryReplies 评论的回复，没有回复时返回nil
+func queryReplies(params *graphql.ResolveParams) *services.Replies {
+	data, ok := params.Source.(model.Comment)
+	if !ok || data.ReplyCount == 0 {
+		return nil
+	}
+	if result, ok := queryDataFromCache(params, RepliesCache, data.Id); ok {
+		if replies := result.(*services.Replies); len(replies.Comments) > 0 {
+			return replies
+		}
+	}
+	return nil
+}
+
 func initLazyQuery(query LazyQueryFn) QueryCache {
 	lq := LazyQuery{
 		cache:        make(map[int64]interface{}),
@@ -210,6 +249,9 @@ func InitTopicType() {
 				Type: graphql.String,
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					if model, ok := p.Source.(model.Comment); ok {
+						if model.DeleteTime > 0 {
+							return "该评论已删除", nil
+						}
 						return chooseContentType(&p, model.Content), nil
 					}
 					return nil, nil
@@ -246,6 +288,45 @@ func InitTopicType() {
 			return nil, nil
 		},
 	})
+	commentType.AddFieldConfig("replyCount", &graphql.Field{
+		Type:    graphql.Int,
+		Resolve: modelFieldResolver("ReplyCount"),
+	})
+	commentType.AddFieldConfig("likeCount", &graphql.Field{
+		Type:    graphql.Int,
+		Resolve: modelFieldResolver("LikeCount"),
+	})
+	commentType.AddFieldConfig("replies", &graphql.Field{
+		Type:        graphql.NewList(commentType),
+		Description: "First direct replies of the comment, use repliesCursor to load more",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if replies := queryReplies(&p); replies != nil {
+				shareComments(&p, replies.Comments[0].EntityId, replies.Comments)
+				return replies.Comments, nil
+			}
+			return nil, nil
+		},
+	})
+	commentType.AddFieldConfig("hasMoreReplies", &graphql.Field{
+		Type:        graphql.Boolean,
+		Description: "Whether the comment has more replies than returned in replies",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if replies := queryReplies(&p); replies != nil {
+				return replies.HasMore, nil
+			}
+			return false, nil
+		},
+	})
+	commentType.AddFieldConfig("repliesCursor", &graphql.Field{
+		Type:        graphql.Int,
+		Description: "Cursor for loading more replies from /api/comment/replies",
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if replies := queryReplies(&p); replies != nil {
+				return replies.Cursor, nil
+			}
+			return nil, nil
+		},
+	})
 	TopicType = graphql.NewObject(graphql.ObjectConfig{
 		Name:        "Topic",
 		Description: "Topic",
@@ -310,22 +391,26 @@ func InitTopicType() {
 				Type: graphql.NewList(commentType),
 				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
 					if data, ok := p.Source.(model.Topic); ok {
-						comments := services.CommentService.Find(simple.NewSqlCnd().Eq("entity_id", data.Id))
+						comments := services.CommentService.Find(simple.NewSqlCnd().
+							Eq("entity_type", constants.EntityTopic).
+							Eq("entity_id", data.Id).
+							Eq("parent_id", 0).
+							Eq("status", constants.StatusOk))
 						if len(comments) == 0 {
 							return nil, nil
 						}
-						commentsMap := make(CommentsMap)
-						users := make([]int64, 0)
-						linq.From(comments).
-							SelectT(func(item model.Comment) linq.KeyValue {
-								users = append(users, item.UserId)
-								return linq.KeyValue{Key: item.Id, Value: item}
-							}).
-							ToMap(&commentsMap)
 						root := p.Info.RootValue.(map[string]interface{})
-						key := fmt.Sprintf("%s-%d", CtxCommentsType, data.Id)
-						root[key] = commentsMap
-						setKeysToCache(&p, UserCache, users...)
+						if _, ok := root[RepliesCache]; !ok {
+							ctx := p.Context
+							root[RepliesCache] = initLazyQuery(func(ids ...int64) map[int64]interface{} {
+								replies := make(map[int64]interface{})
+								for parentId, items := range services.CommentService.FindReplies(ctx, repliesLimit, ids...) {
+									replies[parentId] = items
+								}
+								return replies
+							})
+						}
+						shareComments(&p, data.Id, comments)
 						return comments, nil
 					}
 					return nil, nil
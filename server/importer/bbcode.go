@@ -0,0 +1,57 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// BBCode 转 Markdown 的规则，按顺序替换，未识别的标签最后统一去掉
+var bbcodeRules = []struct {
+	regexp  *regexp.Regexp
+	replace string
+}{
+	{regexp.MustCompile(`(?is)\[code\](.*?)\[/code\]`), "\n```\n$1\n```\n"},
+	{regexp.MustCompile(`(?is)\[img(?:=[^\]]*)?\]\s*(.*?)\s*\[/img\]`), "![]($1)"},
+	{regexp.MustCompile(`(?is)\[url=([^\]]+)\](.*?)\[/url\]`), "[$2]($1)"},
+	{regexp.MustCompile(`(?is)\[url\](.*?)\[/url\]`), "<$1>"},
+	{regexp.MustCompile(`(?is)\[email=([^\]]+)\](.*?)\[/email\]`), "[$2](mailto:$1)"},
+	{regexp.MustCompile(`(?is)\[b\](.*?)\[/b\]`), "**$1**"},
+	{regexp.MustCompile(`(?is)\[i(?:=s)?\](.*?)\[/i\]`), "*$1*"},
+	{regexp.MustCompile(`(?is)\[s\](.*?)\[/s\]`), "~~$1~~"},
+	{regexp.MustCompile(`(?is)\[\*\]`), "\n- "},
+	{regexp.MustCompile(`(?is)\[hr\]`), "\n---\n"},
+	{regexp.MustCompile(`(?is)\[attach(?:img)?\]\d+\[/attach(?:img)?\]`), ""},
+	{regexp.MustCompile(`(?is)\[/?(?:size|color|font|align|u|list|p|table|tr|td|backcolor|indent|float|hide|media|flash|audio|free|sup|sub)(?:=[^\]]*)?\]`), ""},
+}
+
+var (
+	quoteStartRegexp = regexp.MustCompile(`(?i)\[quote\]`)
+	quoteEndRegexp   = regexp.MustCompile(`(?i)\[/quote\]`)
+)
+
+// BBCodeToMarkdown 将 Discuz 的 BBCode 转换为 Markdown，仅处理常用标签
+func BBCodeToMarkdown(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = replaceQuotes(s)
+	for _, rule := range bbcodeRules {
+		s = rule.regexp.ReplaceAllString(s, rule.replace)
+	}
+	return strings.TrimSpace(s)
+}
+
+// replaceQuotes 由内向外替换引用，引用内容的每行都加上 "> "，嵌套的引用会加上多层
+func replaceQuotes(s string) string {
+	for {
+		starts := quoteStartRegexp.FindAllStringIndex(s, -1)
+		if len(starts) == 0 {
+			return s
+		}
+		start := starts[len(starts)-1]
+		end := quoteEndRegexp.FindStringIndex(s[start[1]:])
+		if end == nil {
+			return s
+		}
+		content := strings.TrimSpace(s[start[1] : start[1]+end[0]])
+		s = s[:start[0]] + "\n> " + strings.ReplaceAll(content, "\n", "\n> ") + "\n\n" + s[start[1]+end[1]:]
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"bbs-go/common/uploader"
+	"bbs-go/common/urls"
+	"bbs-go/model/constants"
+)
+
+var (
+	markdownImageRegexp = regexp.MustCompile(`!\[([^\]]*)\]\(\s*([^)\s]+)([^)]*)\)`)
+	htmlImageRegexp     = regexp.MustCompile(`(?i)(<img[^>]+src=["'])([^"']+)(["'])`)
+)
+
+// rewrite 替换内容中的原论坛图片和话题链接
+func (im *Importer) rewrite(content string) string {
+	content = markdownImageRegexp.ReplaceAllStringFunc(content, func(s string) string {
+		m := markdownImageRegexp.FindStringSubmatch(s)
+		if copied := im.copyContentImage(m[2]); len(copied) > 0 {
+			return "![" + m[1] + "](" + copied + m[3] + ")"
+		}
+		return s
+	})
+	content = htmlImageRegexp.ReplaceAllStringFunc(content, func(s string) string {
+		m := htmlImageRegexp.FindStringSubmatch(s)
+		if copied := im.copyContentImage(m[2]); len(copied) > 0 {
+			return m[1] + copied + m[3]
+		}
+		return s
+	})
+	return im.topicLinkRegexp().ReplaceAllStringFunc(content, func(s string) string {
+		m := im.topicLinkRegexp().FindStringSubmatch(s)
+		sourceId := m[2]
+		if len(sourceId) == 0 {
+			sourceId = m[3]
+		}
+		if topicId := im.getMapping(constants.EntityTopic, sourceId); topicId > 0 {
+			return m[1] + urls.TopicUrl(topicId)
+		}
+		return s
+	})
+}
+
+// copyContentImage 仅复制原论坛的图片（相对地址或以 -base-url 开头），外站图片和已复制的图片保持不变
+func (im *Importer) copyContentImage(src string) string {
+	if _, ok := uploader.ObjectKey(src); ok {
+		return ""
+	}
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") || strings.HasPrefix(src, "//") {
+		if len(im.baseUrl) == 0 || !strings.HasPrefix(im.absUrl(src), im.baseUrl+"/") {
+			return ""
+		}
+	}
+	return im.copyImage(src)
+}
+
+// topicLinkRegexp 原论坛话题链接：
+// Discourse：/t/slug/123、/t/123，可带楼层 /t/slug/123/4；
+// Discuz：forum.php?mod=viewthread&tid=123、thread-123-1-1.html
+func (im *Importer) topicLinkRegexp() *regexp.Regexp {
+	if im.linkRegexp != nil {
+		return im.linkRegexp
+	}
+	prefix := `(\(|"|'|\s|^)`
+	host := `/`
+	if len(im.baseUrl) > 0 {
+		host = `(?:` + regexp.QuoteMeta(im.baseUrl) + `)?/`
+	}
+	var pattern string
+	if im.source == SourceDiscourse {
+		pattern = prefix + host + `t/(?:[^/\s)"']*[^/\s)"'\d][^/\s)"']*/)?(\d+)(?:/\d+)?()`
+	} else {
+		pattern = prefix + host + `?(?:forum\.php\?mod=viewthread&(?:amp;)?tid=(\d+)[^\s)"']*|thread-(\d+)-\d+-\d+\.html)`
+	}
+	im.linkRegexp = regexp.MustCompile(pattern)
+	return im.linkRegexp
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlogclub/simple"
+)
+
+// Discourse 导出数据，目录下包含以下JSON文件，每个文件为对应接口返回的对象数组（字段名与Discourse接口一致）：
+//
+//	categories.json：id、name、description、position
+//	users.json：id、username、name、email、avatar_template、created_at
+//	topics.json：id、category_id、user_id、title、views、pinned、tags、created_at、deleted_at
+//	posts.json：id、topic_id、user_id、post_number、raw、reply_to_post_number、created_at、deleted_at
+//
+// 可以通过 Data Explorer 插件按上述字段导出
+type discourseCategory struct {
+	Id          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Position    int    `json:"position"`
+}
+
+type discourseUser struct {
+	Id             int64  `json:"id"`
+	Username       string `json:"username"`
+	Name           string `json:"name"`
+	Email          string `json:"email"`
+	AvatarTemplate string `json:"avatar_template"`
+	CreatedAt      string `json:"created_at"`
+}
+
+type discourseTopic struct {
+	Id         int64           `json:"id"`
+	CategoryId int64           `json:"category_id"`
+	UserId     int64           `json:"user_id"`
+	Title      string          `json:"title"`
+	Views      int64           `json:"views"`
+	Pinned     bool            `json:"pinned"`
+	Tags       json.RawMessage `json:"tags"`
+	CreatedAt  string          `json:"created_at"`
+	DeletedAt  string          `json:"deleted_at"`
+}
+
+type discoursePost struct {
+	Id                int64  `json:"id"`
+	TopicId           int64  `json:"topic_id"`
+	UserId            int64  `json:"user_id"`
+	PostNumber        int    `json:"post_number"`
+	Raw               string `json:"raw"`
+	ReplyToPostNumber int    `json:"reply_to_post_number"`
+	CreatedAt         string `json:"created_at"`
+	DeletedAt         string `json:"deleted_at"`
+}
+
+// ReadDiscourse 读取 Discourse 导出数据
+func ReadDiscourse(dir string) (*Dump, error) {
+	var (
+		categories []discourseCategory
+		users      []discourseUser
+		topics     []discourseTopic
+		posts      []discoursePost
+	)
+	files := map[string]interface{}{
+		"categories.json": &categories,
+		"users.json":      &users,
+		"topics.json":     &topics,
+		"posts.json":      &posts,
+	}
+	for name, v := range files {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, v); err != nil {
+			return nil, err
+		}
+	}
+
+	dump := &Dump{}
+	for _, c := range categories {
+		dump.Nodes = append(dump.Nodes, Node{
+			Id:          discourseId(c.Id),
+			Name:        c.Name,
+			Description: c.Description,
+			SortNo:      c.Position,
+		})
+	}
+	for _, u := range users {
+		if u.Id <= 0 { // system、discobot 等内置用户
+			continue
+		}
+		avatar := ""
+		if len(u.AvatarTemplate) > 0 {
+			avatar = strings.Replace(u.AvatarTemplate, "{size}", "240", 1)
+		}
+		dump.Users = append(dump.Users, User{
+			Id:         discourseId(u.Id),
+			Username:   u.Username,
+			Nickname:   u.Name,
+			Email:      u.Email,
+			Avatar:     avatar,
+			CreateTime: discourseTime(u.CreatedAt),
+		})
+	}
+
+	// 按主题整理帖子，1楼为主题内容
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].TopicId != posts[j].TopicId {
+			return posts[i].TopicId < posts[j].TopicId
+		}
+		return posts[i].PostNumber < posts[j].PostNumber
+	})
+	var (
+		firstPosts  = make(map[int64]discoursePost)
+		postNumbers = make(map[string]int64) // 主题编号:楼层 -> 帖子编号
+	)
+	for _, p := range posts {
+		postNumbers[discourseId(p.TopicId)+":"+strconv.Itoa(p.PostNumber)] = p.Id
+		if p.PostNumber == 1 {
+			firstPosts[p.TopicId] = p
+		}
+	}
+
+	for _, t := range topics {
+		first, ok := firstPosts[t.Id]
+		if !ok {
+			continue
+		}
+		dump.Topics = append(dump.Topics, Topic{
+			Id:         discourseId(t.Id),
+			NodeId:     discourseId(t.CategoryId),
+			UserId:     discourseId(t.UserId),
+			Title:      t.Title,
+			Content:    first.Raw,
+			Tags:       discourseTags(t.Tags),
+			ViewCount:  t.Views,
+			Pinned:     t.Pinned,
+			Deleted:    len(t.DeletedAt) > 0,
+			CreateTime: discourseTime(t.CreatedAt),
+		})
+	}
+	for _, p := range posts {
+		if p.PostNumber == 1 {
+			continue
+		}
+		post := Post{
+			Id:         discourseId(p.Id),
+			TopicId:    discourseId(p.TopicId),
+			UserId:     discourseId(p.UserId),
+			Content:    p.Raw,
+			Deleted:    len(p.DeletedAt) > 0,
+			CreateTime: discourseTime(p.CreatedAt),
+		}
+		if p.ReplyToPostNumber > 1 {
+			if replyTo, ok := postNumbers[post.TopicId+":"+strconv.Itoa(p.ReplyToPostNumber)]; ok {
+				post.ReplyToId = discourseId(replyTo)
+			}
+		}
+		dump.Posts = append(dump.Posts, post)
+	}
+	return dump, nil
+}
+
+func discourseId(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+func discourseTime(s string) int64 {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0
+	}
+	return simple.Timestamp(t)
+}
+
+// discourseTags 标签，新版本接口返回对象数组
+func discourseTags(data json.RawMessage) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		return names
+	}
+	var objects []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(data, &objects); err == nil {
+		for _, o := range objects {
+			names = append(names, o.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ReadDiscuz 读取 Discuz! X 的 mysqldump 导出文件，prefix 为数据表前缀，默认为 pre_；
+// 附件（[attach]）不导入，头像使用 UCenter 地址，需要配置 -base-url 才能复制
+func ReadDiscuz(filename, prefix string) (*Dump, error) {
+	var (
+		forums       = make(map[string]map[string]string)
+		descriptions = make(map[string]string)
+		members      []map[string]string
+		threads      []map[string]string
+		posts        []map[string]string
+		tagNames     = make(map[string]string)
+		threadTags   = make(map[string][]string)
+		tables       = map[string]bool{}
+	)
+	for _, name := range []string{"forum_forum", "forum_forumfield", "common_member", "forum_thread",
+		"forum_post", "common_tag", "common_tagitem"} {
+		tables[prefix+name] = true
+	}
+	var tagItems []map[string]string
+	err := ReadSqlDump(filename, tables, func(table string, row map[string]string) {
+		switch strings.TrimPrefix(table, prefix) {
+		case "forum_forum":
+			forums[row["fid"]] = row
+		case "forum_forumfield":
+			descriptions[row["fid"]] = row["description"]
+		case "common_member":
+			members = append(members, row)
+		case "forum_thread":
+			threads = append(threads, row)
+		case "forum_post":
+			posts = append(posts, row)
+		case "common_tag":
+			tagNames[row["tagid"]] = row["tagname"]
+		case "common_tagitem":
+			tagItems = append(tagItems, row)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range tagItems {
+		if item["idtype"] == "tid" || item["idtype"] == "" {
+			if name := tagNames[item["tagid"]]; len(name) > 0 {
+				threadTags[item["itemid"]] = append(threadTags[item["itemid"]], name)
+			}
+		}
+	}
+
+	dump := &Dump{}
+	for fid, forum := range forums {
+		if forum["type"] == "group" || forum["status"] == "0" { // 分区和已关闭的版块
+			continue
+		}
+		dump.Nodes = append(dump.Nodes, Node{
+			Id:          fid,
+			Name:        forum["name"],
+			Description: descriptions[fid],
+			SortNo:      discuzInt(forum["displayorder"]),
+		})
+	}
+	sort.Slice(dump.Nodes, func(i, j int) bool {
+		return discuzInt(dump.Nodes[i].Id) < discuzInt(dump.Nodes[j].Id)
+	})
+
+	for _, m := range members {
+		dump.Users = append(dump.Users, User{
+			Id:         m["uid"],
+			Username:   m["username"],
+			Nickname:   m["username"],
+			Email:      m["email"],
+			Avatar:     "uc_server/avatar.php?uid=" + m["uid"] + "&size=big",
+			CreateTime: discuzTime(m["regdate"]),
+		})
+	}
+
+	firstPosts := make(map[string]map[string]string)
+	for _, p := range posts {
+		if p["first"] == "1" {
+			firstPosts[p["tid"]] = p
+		}
+	}
+	for _, t := range threads {
+		first, ok := firstPosts[t["tid"]]
+		if !ok {
+			continue
+		}
+		displayOrder := discuzInt(t["displayorder"])
+		dump.Topics = append(dump.Topics, Topic{
+			Id:         t["tid"],
+			NodeId:     t["fid"],
+			UserId:     t["authorid"],
+			Title:      t["subject"],
+			Content:    BBCodeToMarkdown(first["message"]),
+			Tags:       threadTags[t["tid"]],
+			ViewCount:  int64(discuzInt(t["views"])),
+			Pinned:     displayOrder > 0,
+			Deleted:    displayOrder < 0,
+			CreateTime: discuzTime(t["dateline"]),
+		})
+	}
+	sort.Slice(posts, func(i, j int) bool {
+		return discuzInt(posts[i]["pid"]) < discuzInt(posts[j]["pid"])
+	})
+	for _, p := range posts {
+		if p["first"] == "1" {
+			continue
+		}
+		dump.Posts = append(dump.Posts, Post{
+			Id:         p["pid"],
+			TopicId:    p["tid"],
+			UserId:     p["authorid"],
+			Content:    BBCodeToMarkdown(p["message"]),
+			Deleted:    discuzInt(p["invisible"]) < 0,
+			CreateTime: discuzTime(p["dateline"]),
+		})
+	}
+	return dump, nil
+}
+
+func discuzInt(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
+
+// discuzTime Discuz 使用秒级时间戳
+func discuzTime(s string) int64 {
+	t, _ := strconv.ParseInt(s, 10, 64)
+	return t * 1000
+}
@@ -0,0 +1,58 @@
+package main
+
+// 数据来源
+const (
+	SourceDiscourse = "discourse"
+	SourceDiscuz    = "discuz"
+)
+
+// Dump 从导出文件中读取的数据，编号均为原论坛编号
+type Dump struct {
+	Nodes  []Node
+	Users  []User
+	Topics []Topic
+	Posts  []Post
+}
+
+// Node 分类、版块
+type Node struct {
+	Id          string
+	Name        string
+	Description string
+	SortNo      int
+}
+
+// User 用户
+type User struct {
+	Id         string
+	Username   string
+	Nickname   string
+	Email      string
+	Avatar     string // 头像地址
+	CreateTime int64  // 毫秒时间戳
+}
+
+// Topic 主题，Content 为首帖内容
+type Topic struct {
+	Id         string
+	NodeId     string
+	UserId     string
+	Title      string
+	Content    string
+	Tags       []string
+	ViewCount  int64
+	Pinned     bool
+	Deleted    bool
+	CreateTime int64
+}
+
+// Post 回帖
+type Post struct {
+	Id         string
+	TopicId    string
+	UserId     string
+	Content    string
+	ReplyToId  string // 回复的回帖编号
+	Deleted    bool
+	CreateTime int64
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/uploader"
+	"bbs-go/common/validate"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+	"bbs-go/services"
+)
+
+// 对应关系中的实体类型，话题、评论、用户使用 constants 中的实体类型
+const (
+	entityNode = "node"
+	ghostId    = "ghost" // 作者不存在时使用的占位用户
+)
+
+// Importer 将读取到的数据写入数据库，每条数据与对应关系在同一个事务中写入，重复执行时跳过已导入的数据
+type Importer struct {
+	source       string
+	baseUrl      string
+	copyImages   bool
+	mergeByEmail bool                        // 邮箱已注册时是否合并到已有账号
+	mappings     map[string]map[string]int64 // 实体类型 -> 原编号 -> 本站编号
+	images       map[string]string           // 原图片地址 -> 复制后的地址
+	linkRegexp   *regexp.Regexp              // 原论坛话题链接
+}
+
+func NewImporter(source, baseUrl string, copyImages, mergeByEmail bool) *Importer {
+	return &Importer{
+		source:       source,
+		baseUrl:      strings.TrimSuffix(baseUrl, "/"),
+		copyImages:   copyImages,
+		mergeByEmail: mergeByEmail,
+		mappings:     make(map[string]map[string]int64),
+		images:       make(map[string]string),
+	}
+}
+
+func (im *Importer) Run(dump *Dump) error {
+	if err := im.loadMappings(); err != nil {
+		return err
+	}
+	steps := []struct {
+		name string
+		fn   func(dump *Dump) error
+	}{
+		{"节点", im.importNodes},
+		{"用户", im.importUsers},
+		{"话题", im.importTopics},
+		{"评论", im.importPosts},
+	}
+	for _, step := range steps {
+		logrus.Info("开始导入", step.name)
+		if err := step.fn(dump); err != nil {
+			return errors.New("导入" + step.name + "失败：" + err.Error())
+		}
+	}
+	logrus.Info("开始替换内容中的链接和图片")
+	if err := im.rewriteContents(); err != nil {
+		return err
+	}
+	logrus.Info("开始更新计数")
+	return im.recount()
+}
+
+// loadMappings 加载已导入数据的对应关系
+func (im *Importer) loadMappings() error {
+	var cursor int64
+	for {
+		list := repositories.ImportMappingRepository.Find(simple.DB(), simple.NewSqlCnd().
+			Where("source = ? and id > ?", im.source, cursor).Asc("id").Limit(10000))
+		if len(list) == 0 {
+			return nil
+		}
+		for _, m := range list {
+			im.setMapping(m.EntityType, m.SourceId, m.TargetId)
+		}
+		cursor = list[len(list)-1].Id
+	}
+}
+
+func (im *Importer) getMapping(entityType, sourceId string) int64 {
+	return im.mappings[entityType][sourceId]
+}
+
+func (im *Importer) setMapping(entityType, sourceId string, targetId int64) {
+	if im.mappings[entityType] == nil {
+		im.mappings[entityType] = make(map[string]int64)
+	}
+	im.mappings[entityType][sourceId] = targetId
+}
+
+// addMapping 在事务中写入对应关系，事务提交后再调用 setMapping
+func (im *Importer) addMapping(tx *gorm.DB, entityType, sourceId string, targetId int64) error {
+	return repositories.ImportMappingRepository.Create(tx, &model.ImportMapping{
+		Source:     im.source,
+		EntityType: entityType,
+		SourceId:   sourceId,
+		TargetId:   targetId,
+		CreateTime: simple.NowTimestamp(),
+	})
+}
+
+// importNodes 导入节点，已存在同名节点时合并到该节点
+func (im *Importer) importNodes(dump *Dump) error {
+	for _, n := range dump.Nodes {
+		if im.getMapping(entityNode, n.Id) > 0 {
+			continue
+		}
+		name := truncate(strings.TrimSpace(n.Name), 32)
+		if len(name) == 0 {
+			continue
+		}
+		var nodeId int64
+		err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+			node := repositories.TopicNodeRepository.Take(tx, "name = ?", name)
+			if node == nil {
+				node = &model.TopicNode{
+					Name:        name,
+					Description: n.Description,
+					SortNo:      n.SortNo,
+					Status:      constants.StatusOk,
+					CreateTime:  simple.NowTimestamp(),
+				}
+				if err := repositories.TopicNodeRepository.Create(tx, node); err != nil {
+					return err
+				}
+			}
+			nodeId = node.Id
+			return im.addMapping(tx, entityNode, n.Id, node.Id)
+		})
+		if err != nil {
+			return err
+		}
+		im.setMapping(entityNode, n.Id, nodeId)
+	}
+	return nil
+}
+
+// importUsers 导入用户，每个原用户新建账号，开启 mergeByEmail 时邮箱已注册的用户合并到已有账号；
+// 导入的用户没有密码，需要通过邮箱找回密码后登录，同时绑定类型为数据来源的第三方账号
+func (im *Importer) importUsers(dump *Dump) error {
+	for i, u := range dump.Users {
+		if im.getMapping(constants.EntityUser, u.Id) > 0 {
+			continue
+		}
+		userId, err := im.importUser(u)
+		if err != nil {
+			return err
+		}
+		im.setMapping(constants.EntityUser, u.Id, userId)
+		if (i+1)%1000 == 0 {
+			logrus.Info("已导入用户：", i+1, "/", len(dump.Users))
+		}
+	}
+	return nil
+}
+
+func (im *Importer) importUser(u User) (int64, error) {
+	var (
+		email    = strings.TrimSpace(u.Email)
+		username = strings.TrimSpace(u.Username)
+		nickname = strings.TrimSpace(u.Nickname)
+		avatar   = im.copyImage(u.Avatar)
+	)
+	if validate.IsEmail(email) != nil {
+		email = ""
+	}
+	if validate.IsUsername(username) != nil || repositories.UserRepository.GetByUsername(simple.DB(), username) != nil {
+		username = ""
+	}
+	if len(nickname) == 0 {
+		nickname = u.Username
+	}
+	if len(nickname) == 0 {
+		nickname = "用户" + u.Id
+	}
+
+	var (
+		userId  int64
+		merged  bool
+		dropped bool // 邮箱已被占用，新建的账号不设置邮箱
+	)
+	err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		var user *model.User
+		merged, dropped = false, false
+		userEmail := email
+		if len(userEmail) > 0 {
+			if existing := repositories.UserRepository.GetByEmail(tx, userEmail); existing != nil {
+				// 原论坛的邮箱可能未经验证，默认不合并，否则原论坛的用户可以通过相同邮箱接管本站账号
+				if im.mergeByEmail {
+					user, merged = existing, true
+				} else {
+					userEmail, dropped = "", true
+				}
+			}
+		}
+		if user == nil {
+			createTime := u.CreateTime
+			if createTime <= 0 {
+				createTime = simple.NowTimestamp()
+			}
+			user = &model.User{
+				Username:   simple.SqlNullString(username),
+				Email:      simple.SqlNullString(userEmail),
+				Nickname:   truncate(nickname, 16),
+				Avatar:     avatar,
+				Status:     constants.StatusOk,
+				Type:       constants.UserTypeNormal,
+				CreateTime: createTime,
+				UpdateTime: simple.NowTimestamp(),
+			}
+			if err := repositories.UserRepository.Create(tx, user); err != nil {
+				return err
+			}
+		}
+		userId = user.Id
+
+		// 多个原用户合并到同一账号时只绑定第一个
+		if repositories.ThirdAccountRepository.Take(tx, "third_type = ? and (third_id = ? or user_id = ?)", im.source, u.Id, user.Id) == nil {
+			if err := repositories.ThirdAccountRepository.Create(tx, &model.ThirdAccount{
+				UserId:     sql.NullInt64{Int64: user.Id, Valid: true},
+				Avatar:     avatar,
+				Nickname:   truncate(nickname, 32),
+				ThirdType:  im.source,
+				ThirdId:    u.Id,
+				CreateTime: simple.NowTimestamp(),
+				UpdateTime: simple.NowTimestamp(),
+			}); err != nil {
+				return err
+			}
+		}
+		return im.addMapping(tx, constants.EntityUser, u.Id, user.Id)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if merged {
+		logrus.Info("原用户 ", u.Id, " 的邮箱 ", email, " 已注册，合并到用户 ", userId)
+	} else if dropped {
+		logrus.Warn("原用户 ", u.Id, " 的邮箱 ", email, " 已注册，新建的用户 ", userId, " 不设置邮箱")
+	}
+	return userId, nil
+}
+
+// getUserId 作者对应的本站用户，作者不存在（已删除）时使用占位用户
+func (im *Importer) getUserId(sourceUserId string) (int64, error) {
+	if userId := im.getMapping(constants.EntityUser, sourceUserId); userId > 0 {
+		return userId, nil
+	}
+	if userId := im.getMapping(constants.EntityUser, ghostId); userId > 0 {
+		return userId, nil
+	}
+	userId, err := im.importUser(User{Id: ghostId, Nickname: "已删除用户"})
+	if err != nil {
+		return 0, err
+	}
+	im.setMapping(constants.EntityUser, ghostId, userId)
+	return userId, nil
+}
+
+// importTopics 导入话题和标签，节点不存在时使用默认节点
+func (im *Importer) importTopics(dump *Dump) error {
+	defaultNodeId := services.SysConfigService.GetConfig().DefaultNodeId
+	for i, t := range dump.Topics {
+		if im.getMapping(constants.EntityTopic, t.Id) > 0 {
+			continue
+		}
+		nodeId := im.getMapping(entityNode, t.NodeId)
+		if nodeId <= 0 {
+			nodeId = defaultNodeId
+		}
+		if nodeId <= 0 {
+			return errors.New("话题 " + t.Id + " 的节点不存在，请先在后台配置默认节点")
+		}
+		userId, err := im.getUserId(t.UserId)
+		if err != nil {
+			return err
+		}
+		title := truncate(strings.TrimSpace(t.Title), 128)
+		if len(title) == 0 {
+			title = "无标题"
+		}
+		status := constants.StatusOk
+		if t.Deleted {
+			status = constants.StatusDeleted
+		}
+		topic := &model.Topic{
+			NodeId:          nodeId,
+			UserId:          userId,
+			Title:           title,
+			Content:         t.Content,
+			ViewCount:       t.ViewCount,
+			IsPin:           t.Pinned,
+			Status:          status,
+			LastCommentTime: t.CreateTime,
+			CreateTime:      t.CreateTime,
+		}
+		err = simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+			if err := repositories.TopicRepository.Create(tx, topic); err != nil {
+				return err
+			}
+			repositories.TopicTagRepository.AddTopicTags(tx, topic.Id, repositories.TagRepository.GetOrCreates(tx, t.Tags))
+			return im.addMapping(tx, constants.EntityTopic, t.Id, topic.Id)
+		})
+		if err != nil {
+			return err
+		}
+		im.setMapping(constants.EntityTopic, t.Id, topic.Id)
+		if (i+1)%1000 == 0 {
+			logrus.Info("已导入话题：", i+1, "/", len(dump.Topics))
+		}
+	}
+	return nil
+}
+
+// importPosts 导入回帖为话题评论，保留发表时间，回复关系保存为引用
+func (im *Importer) importPosts(dump *Dump) error {
+	for i, p := range dump.Posts {
+		if im.getMapping(constants.EntityComment, p.Id) > 0 {
+			continue
+		}
+		topicId := im.getMapping(constants.EntityTopic, p.TopicId)
+		if topicId <= 0 {
+			logrus.Warn("回帖 ", p.Id, " 所属的话题 ", p.TopicId, " 未导入，跳过")
+			continue
+		}
+		if len(strings.TrimSpace(p.Content)) == 0 {
+			continue
+		}
+		userId, err := im.getUserId(p.UserId)
+		if err != nil {
+			return err
+		}
+		status := constants.StatusOk
+		if p.Deleted {
+			status = constants.StatusDeleted
+		}
+		comment := &model.Comment{
+			UserId:      userId,
+			EntityType:  constants.EntityTopic,
+			EntityId:    topicId,
+			Content:     p.Content,
+			ContentType: constants.ContentTypeMarkdown,
+			QuoteId:     im.getMapping(constants.EntityComment, p.ReplyToId),
+			Status:      status,
+			CreateTime:  p.CreateTime,
+		}
+		err = simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+			if err := repositories.CommentRepository.Create(tx, comment); err != nil {
+				return err
+			}
+			return im.addMapping(tx, constants.EntityComment, p.Id, comment.Id)
+		})
+		if err != nil {
+			return err
+		}
+		im.setMapping(constants.EntityComment, p.Id, comment.Id)
+		if (i+1)%1000 == 0 {
+			logrus.Info("已导入评论：", i+1, "/", len(dump.Posts))
+		}
+	}
+	return nil
+}
+
+// rewriteContents 替换已导入的话题和评论中的站内链接和图片，替换后的内容不再匹配，因此可以重复执行
+func (im *Importer) rewriteContents() error {
+	for topicSourceId, topicId := range im.mappings[constants.EntityTopic] {
+		topic := repositories.TopicRepository.Get(simple.DB(), topicId)
+		if topic == nil {
+			continue
+		}
+		if content := im.rewrite(topic.Content); content != topic.Content {
+			if err := repositories.TopicRepository.UpdateColumn(simple.DB(), topicId, "content", content); err != nil {
+				return errors.New("话题 " + topicSourceId + "：" + err.Error())
+			}
+		}
+	}
+	for commentSourceId, commentId := range im.mappings[constants.EntityComment] {
+		comment := repositories.CommentRepository.Get(simple.DB(), commentId)
+		if comment == nil {
+			continue
+		}
+		if content := im.rewrite(comment.Content); content != comment.Content {
+			if err := repositories.CommentRepository.UpdateColumn(simple.DB(), commentId, "content", content); err != nil {
+				return errors.New("评论 " + commentSourceId + "：" + err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// recount 更新已导入话题的评论数、最后回复时间，以及用户的话题数、评论数
+func (im *Importer) recount() error {
+	topicIds := "select target_id from t_import_mapping where source = ? and entity_type = ?"
+	if err := simple.DB().Exec("update t_topic set "+
+		"comment_count = (select count(*) from t_comment where entity_type = ? and entity_id = t_topic.id and status = ?), "+
+		"last_comment_time = coalesce((select max(create_time) from t_comment where entity_type = ? and entity_id = t_topic.id and status = ?), create_time) "+
+		"where id in ("+topicIds+")",
+		constants.EntityTopic, constants.StatusOk, constants.EntityTopic, constants.StatusOk,
+		im.source, constants.EntityTopic).Error; err != nil {
+		return err
+	}
+	if err := simple.DB().Exec("update t_topic_tag set "+
+		"status = (select status from t_topic where t_topic.id = t_topic_tag.topic_id), "+
+		"last_comment_time = (select last_comment_time from t_topic where t_topic.id = t_topic_tag.topic_id) "+
+		"where topic_id in ("+topicIds+")",
+		im.source, constants.EntityTopic).Error; err != nil {
+		return err
+	}
+	services.UserService.SyncUserCount()
+	return nil
+}
+
+// copyImage 复制原论坛的图片，相对地址使用 -base-url 补全，复制失败时返回空
+func (im *Importer) copyImage(src string) string {
+	if len(src) == 0 || !im.copyImages {
+		return ""
+	}
+	src = im.absUrl(src)
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		return ""
+	}
+	if copied, ok := im.images[src]; ok {
+		return copied
+	}
+	copied, err := uploader.CopyImage(src)
+	if err != nil {
+		logrus.Warn("复制图片失败：", src, " ", err)
+		copied = ""
+	}
+	im.images[src] = copied
+	return copied
+}
+
+func (im *Importer) absUrl(u string) string {
+	if strings.HasPrefix(u, "//") {
+		return "https:" + u
+	}
+	if strings.HasPrefix(u, "http://") || strings.HasPrefix(u, "https://") || len(im.baseUrl) == 0 {
+		return u
+	}
+	return im.baseUrl + "/" + strings.TrimPrefix(u, "/")
+}
+
+// truncate 按字符截断
+func truncate(s string, length int) string {
+	if simple.RuneLen(s) <= length {
+		return s
+	}
+	return string([]rune(s)[:length])
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/config"
+	"bbs-go/model"
+)
+
+// 从其他论坛导入数据，例如：
+//
+//	go run ./importer -config ./bbs-go.yaml -source discourse -path ./discourse-export -base-url https://old.example.com
+//	go run ./importer -config ./bbs-go.yaml -source discuz -path ./discuz.sql -prefix pre_ -base-url https://old.example.com
+//
+// 已导入的数据记录在 t_import_mapping 中，中断后重新执行会跳过已导入的数据
+var (
+	configFile   = flag.String("config", "./bbs-go.yaml", "配置文件路径")
+	source       = flag.String("source", "", "数据来源：discourse、discuz")
+	dataPath     = flag.String("path", "", "导出数据路径，discourse 为JSON文件所在目录，discuz 为SQL文件")
+	prefix       = flag.String("prefix", "pre_", "discuz 数据表前缀")
+	baseUrl      = flag.String("base-url", "", "原论坛地址，用于替换内容中的站内链接和复制图片")
+	skipImages   = flag.Bool("skip-images", false, "不复制内容中的图片")
+	mergeByEmail = flag.Bool("merge-by-email", false, "邮箱已注册的用户合并到已有账号，只有确认原论坛的邮箱都经过验证时才开启")
+)
+
+func main() {
+	flag.Parse()
+	if len(*source) == 0 || len(*dataPath) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config.Init(*configFile)
+	if err := simple.OpenMySql(config.Instance.MySqlUrl, 10, 20, config.Instance.ShowSql, model.Models...); err != nil {
+		logrus.Fatal(err)
+	}
+	if config.Instance.Uploader.Enable == "local" {
+		if len(config.Instance.Uploader.Local.Path) == 0 {
+			config.Instance.Uploader.Local.Path = config.Instance.StaticPath
+		} else {
+			config.Instance.Uploader.Local.Path = fmt.Sprintf("%s/%s", config.Instance.StaticPath, config.Instance.Uploader.Local.Path)
+		}
+	}
+
+	var (
+		dump *Dump
+		err  error
+	)
+	switch *source {
+	case SourceDiscourse:
+		dump, err = ReadDiscourse(*dataPath)
+	case SourceDiscuz:
+		dump, err = ReadDiscuz(*dataPath, *prefix)
+	default:
+		err = fmt.Errorf("不支持的数据来源：%s", *source)
+	}
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	importer := NewImporter(*source, *baseUrl, !*skipImages, *mergeByEmail)
+	if err := importer.Run(dump); err != nil {
+		logrus.Fatal(err)
+	}
+}
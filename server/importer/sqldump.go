@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	createTableRegexp = regexp.MustCompile("(?is)^CREATE TABLE\\s+(?:IF NOT EXISTS\\s+)?`?([\\w]+)`?\\s*\\((.*)\\)")
+	columnRegexp      = regexp.MustCompile("(?m)^\\s*`(\\w+)`\\s")
+	insertRegexp      = regexp.MustCompile("(?is)^(?:INSERT|REPLACE)(?:\\s+IGNORE)?\\s+INTO\\s+`?([\\w]+)`?\\s*(\\([^)]*\\))?\\s*VALUES\\s*")
+)
+
+// ReadSqlDump 读取 mysqldump 导出的SQL文件，按行回调 tables 中的数据表，
+// 列名优先使用 INSERT 语句中的列名，其次使用 CREATE TABLE 语句中的列顺序
+func ReadSqlDump(filename string, tables map[string]bool, fn func(table string, row map[string]string)) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	columns := make(map[string][]string)
+	reader := bufio.NewReaderSize(file, 1<<20)
+	for {
+		stmt, err := readStatement(reader)
+		if len(stmt) > 0 {
+			if m := createTableRegexp.FindStringSubmatch(stmt); m != nil && tables[m[1]] {
+				for _, c := range columnRegexp.FindAllStringSubmatch(m[2], -1) {
+					columns[m[1]] = append(columns[m[1]], c[1])
+				}
+			} else if m := insertRegexp.FindStringSubmatchIndex(stmt); m != nil {
+				table := stmt[m[2]:m[3]]
+				if tables[table] {
+					cols := columns[table]
+					if m[4] >= 0 {
+						cols = nil
+						for _, c := range strings.Split(strings.Trim(stmt[m[4]:m[5]], "()"), ",") {
+							cols = append(cols, strings.Trim(strings.TrimSpace(c), "`"))
+						}
+					}
+					if len(cols) == 0 {
+						return errors.New("数据表 " + table + " 缺少列定义")
+					}
+					if err := parseValues(stmt[m[1]:], func(values []string) {
+						row := make(map[string]string, len(cols))
+						for i, col := range cols {
+							if i < len(values) {
+								row[col] = values[i]
+							}
+						}
+						fn(table, row)
+					}); err != nil {
+						return errors.New("数据表 " + table + " 解析失败：" + err.Error())
+					}
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// readStatement 读取一条以分号结尾的语句，跳过注释
+func readStatement(reader *bufio.Reader) (string, error) {
+	var (
+		sb    strings.Builder
+		quote rune // 当前所在的引号
+	)
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return strings.TrimSpace(sb.String()), err
+		}
+		if quote != 0 {
+			sb.WriteRune(r)
+			if r == '\\' {
+				if next, _, err := reader.ReadRune(); err == nil {
+					sb.WriteRune(next)
+				}
+			} else if r == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch r {
+		case '\'', '"', '`':
+			quote = r
+			sb.WriteRune(r)
+		case '-', '#':
+			// 行首的 -- 和 # 注释
+			if strings.TrimSpace(sb.String()) == "" {
+				if r == '#' || peekRune(reader) == '-' {
+					if _, err := reader.ReadString('\n'); err != nil {
+						return "", err
+					}
+					sb.Reset()
+					continue
+				}
+			}
+			sb.WriteRune(r)
+		case '/':
+			// /* */ 注释和 /*!40101 ... */ 条件语句，均跳过
+			if peekRune(reader) == '*' {
+				if err := skipBlockComment(reader); err != nil {
+					return "", err
+				}
+				continue
+			}
+			sb.WriteRune(r)
+		case ';':
+			return strings.TrimSpace(sb.String()), nil
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+func peekRune(reader *bufio.Reader) rune {
+	r, _, err := reader.ReadRune()
+	if err != nil {
+		return 0
+	}
+	_ = reader.UnreadRune()
+	return r
+}
+
+func skipBlockComment(reader *bufio.Reader) error {
+	var prev rune
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return err
+		}
+		if prev == '*' && r == '/' {
+			return nil
+		}
+		prev = r
+	}
+}
+
+// parseValues 解析 VALUES 后的 (...),(...) 数据，NULL 解析为空字符串
+func parseValues(s string, fn func(values []string)) error {
+	i := 0
+	for i < len(s) {
+		for i < len(s) && (s[i] == ',' || s[i] == ' ' || s[i] == '\n' || s[i] == '\r' || s[i] == '\t') {
+			i++
+		}
+		if i >= len(s) {
+			break
+		}
+		if s[i] != '(' {
+			return errors.New("格式错误")
+		}
+		i++
+		var values []string
+		for {
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			if i >= len(s) {
+				return errors.New("格式错误")
+			}
+			if s[i] == '\'' {
+				var (
+					sb  strings.Builder
+					end = false
+				)
+				for i++; i < len(s); i++ {
+					c := s[i]
+					if c == '\\' && i+1 < len(s) {
+						i++
+						sb.WriteByte(unescape(s[i]))
+					} else if c == '\'' {
+						if i+1 < len(s) && s[i+1] == '\'' {
+							sb.WriteByte('\'')
+							i++
+						} else {
+							end = true
+							i++
+							break
+						}
+					} else {
+						sb.WriteByte(c)
+					}
+				}
+				if !end {
+					return errors.New("字符串未结束")
+				}
+				values = append(values, sb.String())
+			} else {
+				start := i
+				for i < len(s) && s[i] != ',' && s[i] != ')' {
+					i++
+				}
+				value := strings.TrimSpace(s[start:i])
+				if strings.EqualFold(value, "NULL") {
+					value = ""
+				}
+				values = append(values, value)
+			}
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			if i >= len(s) {
+				return errors.New("格式错误")
+			}
+			if s[i] == ',' {
+				i++
+				continue
+			}
+			if s[i] == ')' {
+				i++
+				break
+			}
+			return errors.New("格式错误")
+		}
+		fn(values)
+	}
+	return nil
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case '0':
+		return 0
+	case 'b':
+		return '\b'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'Z':
+		return 26
+	default:
+		return c
+	}
+}
@@ -3,7 +3,6 @@ package main
 import (
 	"flag"
 	"fmt"
-	"os"
 
 	_ "github.com/jinzhu/gorm/dialects/mysql"
 	"github.com/mlogclub/simple"
@@ -11,6 +10,7 @@ import (
 
 	"bbs-go/app"
 	"bbs-go/common/avatar"
+	"bbs-go/common/logging"
 	"bbs-go/config"
 	"bbs-go/model"
 )
@@ -23,16 +23,14 @@ func init() {
 	// 初始化配置
 	config.Init(*configFile)
 
-	// 初始化日志
-	if file, err := os.OpenFile(config.Instance.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666); err == nil {
-		logrus.SetOutput(file)
-	} else {
-		logrus.Error(err)
-	}
+	// 初始化日志，执行子命令时输出到控制台
+	logging.Init(flag.NArg() > 0)
 
-	// 连接数据库
-	if err := simple.OpenMySql(config.Instance.MySqlUrl, 10, 20, config.Instance.ShowSql, model.Models...); err != nil {
-		logrus.Error(err)
+	// 连接数据库，子命令自行连接，避免对备份或恢复以外的数据库执行迁移
+	if flag.NArg() == 0 {
+		if err := simple.OpenMySql(config.Instance.MySqlUrl, 10, 20, config.Instance.ShowSql, model.Models...); err != nil {
+			logrus.Error(err)
+		}
 	}
 	avatar.SetAvatarHost(config.Instance.BaseUrl)
 	if config.Instance.Uploader.Enable == "local" {
@@ -47,6 +45,10 @@ func init() {
 }
 
 func main() {
+	if flag.NArg() > 0 {
+		app.RunCommand(flag.Args())
+		return
+	}
 	app.StartOn()
 	app.InitIris()
 }
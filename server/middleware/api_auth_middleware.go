@@ -13,6 +13,9 @@ var (
 		{Pattern: "/api/admin/sys-config/**", Roles: []string{constants.RoleOwner}},
 		{Pattern: "/api/admin/user/create", Roles: []string{constants.RoleOwner}},
 		{Pattern: "/api/admin/user/update", Roles: []string{constants.RoleOwner}},
+		{Pattern: "/api/admin/user/merge", Roles: []string{constants.RoleOwner}},
+		{Pattern: "/api/admin/user/delete", Roles: []string{constants.RoleOwner}},
+		{Pattern: "/api/admin/email-outbox/**", Roles: []string{constants.RoleOwner}},
 		{Pattern: "/api/admin/topic-node/create", Roles: []string{constants.RoleOwner}},
 		{Pattern: "/api/admin/topic-node/update", Roles: []string{constants.RoleOwner}},
 		{Pattern: "/api/admin/tag/create", Roles: []string{constants.RoleOwner}},
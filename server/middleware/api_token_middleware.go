@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bbs-go/model/constants"
+	"bbs-go/services"
+	"github.com/kataras/iris/v12"
+)
+
+var (
+	// 个人访问令牌可以访问的路径及所需的授权范围，按顺序匹配，未匹配到的路径不允许使用令牌访问
+	scopeConfig = []PathScope{
+		// "/**" 不匹配路径本身，需要同时配置
+		{Pattern: "/api/user/api-tokens"}, // 不允许使用令牌管理令牌
+		{Pattern: "/api/user/api-tokens/**"},
+		{Pattern: "/api/user/third-accounts"}, // 不允许使用令牌绑定、解绑第三方账号
+		{Pattern: "/api/user/third-accounts/**"},
+		{Pattern: "/api/user/export"}, // 不允许使用令牌导出个人数据
+		{Pattern: "/api/user/export/**"},
+		{Pattern: "/api/user/deletion"}, // 不允许使用令牌注销账号
+		{Pattern: "/api/user/deletion/**"},
+		{Pattern: "/api/admin/**", Scope: constants.ApiScopeAdmin},
+		{Pattern: "/api/spider/**", Scope: constants.ApiScopeSpider},
+		{Pattern: "/api/topic/create", Method: iris.MethodPost, Scope: constants.ApiScopeTopic},
+		{Pattern: "/api/topic/edit/*", Method: iris.MethodPost, Scope: constants.ApiScopeTopic},
+		{Pattern: "/api/topic/delete/*", Method: iris.MethodPost, Scope: constants.ApiScopeTopic},
+		{Pattern: "/api/comment/create", Method: iris.MethodPost, Scope: constants.ApiScopeComment},
+		{Pattern: "/api/comment/edit/*", Method: iris.MethodPost, Scope: constants.ApiScopeComment},
+		{Pattern: "/api/comment/delete/*", Method: iris.MethodPost, Scope: constants.ApiScopeComment},
+		{Pattern: "/api/tweet/create", Method: iris.MethodPost, Scope: constants.ApiScopeTweet},
+		{Pattern: "/api/**", Method: iris.MethodGet, Scope: constants.ApiScopeRead},
+	}
+)
+
+// ApiTokenAuth 个人访问令牌权限，只对携带了 Authorization: Bearer 的请求生效；监控指标使用单独的令牌
+func ApiTokenAuth(ctx iris.Context) {
+	token := services.ApiTokenService.GetBearerToken(ctx)
+	if len(token) == 0 || ctx.Path() == MetricsPath {
+		ctx.Next()
+		return
+	}
+
+	apiToken := services.ApiTokenService.GetByToken(token)
+	if apiToken == nil {
+		notLogin(ctx)
+		return
+	}
+
+	scope := getPathScope(ctx)
+	if len(scope) == 0 || !apiToken.HasScope(scope) {
+		noPermission(ctx)
+		return
+	}
+	services.ApiTokenService.MarkUsed(apiToken)
+
+	ctx.Next()
+}
+
+// getPathScope 获取请求该路径所需的授权范围
+func getPathScope(ctx iris.Context) string {
+	return matchPathScope(ctx.Method(), ctx.Path())
+}
+
+func matchPathScope(method, path string) string {
+	for _, pathScope := range scopeConfig {
+		if len(pathScope.Method) > 0 && pathScope.Method != method {
+			continue
+		}
+		if antPathMatcher.Match(pathScope.Pattern, path) {
+			return pathScope.Scope
+		}
+	}
+	return ""
+}
+
+type PathScope struct {
+	Pattern string // path pattern
+	Method  string // http method, empty means any
+	Scope   string // required scope, empty means forbidden
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"testing"
+
+	"github.com/kataras/iris/v12"
+
+	"bbs-go/model/constants"
+)
+
+func TestMatchPathScope(t *testing.T) {
+	cases := []struct {
+		method, path, scope string
+	}{
+		// 账号安全相关的接口不允许使用令牌访问，包括路径本身
+		{iris.MethodGet, "/api/user/api-tokens", ""},
+		{iris.MethodPost, "/api/user/api-tokens", ""},
+		{iris.MethodPost, "/api/user/api-tokens/delete/1", ""},
+		{iris.MethodGet, "/api/user/third-accounts", ""},
+		{iris.MethodGet, "/api/user/third-accounts/link/authorize", ""},
+		{iris.MethodGet, "/api/user/export", ""},
+		{iris.MethodGet, "/api/user/export/download", ""},
+		{iris.MethodPost, "/api/user/export", ""},
+		{iris.MethodGet, "/api/user/deletion", ""},
+		{iris.MethodPost, "/api/user/deletion/cancel", ""},
+
+		{iris.MethodGet, "/api/admin/user/list", constants.ApiScopeAdmin},
+		{iris.MethodPost, "/api/admin/user/update", constants.ApiScopeAdmin},
+		{iris.MethodGet, "/api/spider/topics", constants.ApiScopeSpider},
+		{iris.MethodPost, "/api/topic/create", constants.ApiScopeTopic},
+		{iris.MethodPost, "/api/topic/edit/1", constants.ApiScopeTopic},
+		{iris.MethodPost, "/api/comment/create", constants.ApiScopeComment},
+		{iris.MethodPost, "/api/comment/delete/1", constants.ApiScopeComment},
+		{iris.MethodPost, "/api/tweet/create", constants.ApiScopeTweet},
+		{iris.MethodGet, "/api/topic/1", constants.ApiScopeRead},
+		{iris.MethodGet, "/api/user/current", constants.ApiScopeRead},
+
+		// 未配置的写操作不允许使用令牌
+		{iris.MethodGet, "/api/topic/create", constants.ApiScopeRead},
+		{iris.MethodPost, "/api/user/set/password", ""},
+		{iris.MethodPost, "/api/topic/like/1", ""},
+	}
+	for _, c := range cases {
+		if scope := matchPathScope(c.method, c.path); scope != c.scope {
+			t.Errorf("%s %s: expected scope %q, got %q", c.method, c.path, c.scope, scope)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris/v12"
+
+	"bbs-go/common/metrics"
+	appconfig "bbs-go/config"
+)
+
+// Metrics 统计请求耗时，按路由模板（例如：/api/topic/{id:long}）和状态码区分，避免路径参数导致标签数量过多
+func Metrics(ctx iris.Context) {
+	start := time.Now()
+	defer func() {
+		err := recover()
+		status := ctx.GetStatusCode()
+		if err != nil {
+			status = iris.StatusInternalServerError
+		}
+		route := "unmatched"
+		if r := ctx.GetCurrentRoute(); r != nil {
+			route = r.Path()
+		}
+		metrics.HttpRequests.Observe(time.Since(start).Seconds(), ctx.Method(), route, strconv.Itoa(status))
+		if err != nil {
+			panic(err) // 交给 recover 中间件处理
+		}
+	}()
+	ctx.Next()
+}
+
+// MetricsPath 监控指标地址
+const MetricsPath = "/metrics"
+
+// MetricsHandler 在站点端口输出监控指标，需要携带 Authorization: Bearer {Token}
+func MetricsHandler(ctx iris.Context) {
+	if !MetricsTokenValid(ctx.GetHeader("Authorization")) {
+		ctx.StatusCode(iris.StatusNotFound)
+		return
+	}
+	ctx.ContentType("text/plain; version=0.0.4; charset=utf-8")
+	_ = metrics.WriteTo(ctx.ResponseWriter())
+}
+
+// MetricsTokenValid 校验访问令牌，未配置令牌时校验通过（仅用于单独监听的地址）
+func MetricsTokenValid(authorization string) bool {
+	token := appconfig.Instance.Metrics.Token
+	if len(token) == 0 {
+		return true
+	}
+	value := strings.TrimSpace(strings.TrimPrefix(authorization, "Bearer "))
+	return subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+}
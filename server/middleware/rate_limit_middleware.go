@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/common"
+	"bbs-go/common/ratelimit"
+	appconfig "bbs-go/config"
+	"bbs-go/services"
+)
+
+var (
+	// 默认限流策略，未在配置文件中配置限流策略时使用
+	defaultRateLimitPolicies = []appconfig.RateLimitPolicy{
+		{Pattern: "/api/topic/create", Method: iris.MethodPost, Rate: 5, Burst: 2},
+		{Pattern: "/api/comment/create", Method: iris.MethodPost, Rate: 10, Burst: 5},
+		{Pattern: "/api/tweet/create", Method: iris.MethodPost, Rate: 10, Burst: 5},
+		{Pattern: "/api/login/signin", Method: iris.MethodPost, Rate: 10, Burst: 5},
+		{Pattern: "/api/login/forgot/password", Method: iris.MethodPost, Rate: 2, Burst: 3},
+	}
+	rateLimitRules     []rateLimitRule
+	rateLimitRulesOnce sync.Once
+)
+
+type rateLimitRule struct {
+	appconfig.RateLimitPolicy
+	limiter *ratelimit.Limiter
+}
+
+// RateLimit 接口限流，按用户编号限流，未登录时按客户端IP限流
+func RateLimit(ctx iris.Context) {
+	if appconfig.Instance.RateLimit.Disabled {
+		ctx.Next()
+		return
+	}
+	rule := getPathRateLimitRule(ctx)
+	if rule == nil {
+		ctx.Next()
+		return
+	}
+	if !rule.limiter.Allow(rateLimitKey(ctx)) {
+		tooManyRequests(ctx)
+		return
+	}
+	ctx.Next()
+}
+
+// getPathRateLimitRule 获取请求该路径的限流规则
+func getPathRateLimitRule(ctx iris.Context) *rateLimitRule {
+	rateLimitRulesOnce.Do(func() {
+		policies := appconfig.Instance.RateLimit.Policies
+		if len(policies) == 0 {
+			policies = defaultRateLimitPolicies
+		}
+		for _, policy := range policies {
+			rateLimitRules = append(rateLimitRules, rateLimitRule{
+				RateLimitPolicy: policy,
+				limiter:         ratelimit.NewLimiter(policy.Rate, policy.Burst),
+			})
+		}
+	})
+
+	p := ctx.Path()
+	for i := range rateLimitRules {
+		rule := &rateLimitRules[i]
+		if len(rule.Method) > 0 && !strings.EqualFold(rule.Method, ctx.Method()) {
+			continue
+		}
+		if antPathMatcher.Match(rule.Pattern, p) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// rateLimitKey 限流key，登录用户使用用户编号，未登录用户使用客户端IP
+func rateLimitKey(ctx iris.Context) string {
+	if userId := services.UserTokenService.GetCurrentUserId(ctx); userId > 0 {
+		return "user:" + strconv.FormatInt(userId, 10)
+	}
+	return "ip:" + services.ClientIP(ctx.Request())
+}
+
+// tooManyRequests 请求过于频繁返回
+func tooManyRequests(ctx iris.Context) {
+	_, _ = ctx.JSON(simple.JsonError(common.TooManyRequests))
+	ctx.StopExecution()
+}
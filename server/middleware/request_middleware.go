@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/logging"
+	"bbs-go/common/tracing"
+)
+
+// RequestIdHeader 请求编号，请求中携带时（例如由网关生成）沿用，否则生成新的编号，并在响应头中返回
+const RequestIdHeader = "X-Request-Id"
+
+// 健康检查、监控指标的访问日志使用 debug 级别输出，避免探针请求刷屏
+var quietPaths = map[string]bool{"/healthz": true, "/readyz": true, MetricsPath: true}
+
+// RequestContext 为请求生成请求编号、创建 span，并保存在 ctx.Request().Context() 中，
+// 之后通过 logrus.WithContext 输出的日志会带上请求编号、用户编号和 trace id；请求结束后输出访问日志
+func RequestContext(ctx iris.Context) {
+	start := time.Now()
+	requestId := ctx.GetHeader(RequestIdHeader)
+	if !isValidRequestId(requestId) {
+		requestId = newRequestId()
+	}
+	ctx.Header(RequestIdHeader, requestId)
+
+	req := ctx.Request()
+	c := logging.NewContext(req.Context(), requestId)
+	c = tracing.Extract(c, req.Header)
+	c, span := tracing.Start(c, "HTTP "+ctx.Method(), tracing.KindServer)
+	span.SetAttribute("http.method", ctx.Method())
+	span.SetAttribute("http.target", ctx.Path())
+	span.SetAttribute("http.client_ip", ctx.RemoteAddr())
+	span.SetAttribute("http.request_id", requestId)
+	ctx.ResetRequest(req.WithContext(c))
+
+	defer func() {
+		err := recover()
+		status := ctx.GetStatusCode()
+		if err != nil {
+			status = iris.StatusInternalServerError
+		}
+		route := "unmatched"
+		if r := ctx.GetCurrentRoute(); r != nil {
+			route = r.Path()
+			span.SetName(ctx.Method() + " " + route)
+		}
+		span.SetAttribute("http.route", route)
+		span.SetAttribute("http.status_code", status)
+		userId := logging.UserId(c)
+		if userId > 0 {
+			span.SetAttribute("enduser.id", userId)
+		}
+		if err != nil {
+			span.RecordError(fmt.Errorf("panic: %v", err))
+		} else if status >= iris.StatusInternalServerError {
+			span.RecordError(errors.New(http.StatusText(status)))
+		}
+		span.End()
+
+		entry := logrus.WithContext(c).WithFields(logrus.Fields{
+			"method":  ctx.Method(),
+			"path":    ctx.Path(),
+			"route":   route,
+			"status":  status,
+			"latency": time.Since(start).Milliseconds(),
+			"ip":      ctx.RemoteAddr(),
+		})
+		if quietPaths[ctx.Path()] {
+			entry.Debug("request")
+		} else {
+			entry.Info("request")
+		}
+		if err != nil {
+			panic(err) // 交给 recover 中间件处理
+		}
+	}()
+	ctx.Next()
+}
+
+// isValidRequestId 只接受长度和字符都在合理范围内的编号，避免日志注入
+func isValidRequestId(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
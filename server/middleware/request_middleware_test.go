@@ -0,0 +1,159 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/logging"
+	"bbs-go/common/tracing"
+	appconfig "bbs-go/config"
+)
+
+// syncBuffer 日志在服务端的协程中写入，测试中读取
+type syncBuffer struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) entries(t *testing.T) []map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var ret []map[string]interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(b.buf.Bytes()))
+	for scanner.Scan() {
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, entry)
+	}
+	return ret
+}
+
+func findEntry(entries []map[string]interface{}, msg string) map[string]interface{} {
+	for _, entry := range entries {
+		if entry["msg"] == msg {
+			return entry
+		}
+	}
+	return nil
+}
+
+func findSpan(spans []*tracing.Span, prefix string) *tracing.Span {
+	for _, span := range spans {
+		if strings.HasPrefix(span.Name, prefix) {
+			return span
+		}
+	}
+	return nil
+}
+
+// 请求经过 RequestContext 后：创建 HTTP span 并作为上游的子节点，处理函数中调用外部接口的 span 是其子节点，
+// 通过 logrus.WithContext 输出的日志和访问日志带上请求编号、用户编号和 trace id
+func TestRequestContext(t *testing.T) {
+	appconfig.Instance = &appconfig.Config{}
+	logging.Init(true)
+	logs := &syncBuffer{}
+	logrus.SetOutput(logs)
+	defer logrus.SetOutput(os.Stdout)
+
+	exporter := tracing.NewInMemoryExporter()
+	tracing.Init(tracing.Options{Exporter: exporter, Synchronous: true})
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer backend.Close()
+
+	app := iris.New()
+	app.Logger().SetLevel("disable")
+	app.Use(RequestContext)
+	app.Get("/users/{id:int64}", func(ctx iris.Context) {
+		c := ctx.Request().Context()
+		logging.SetUserId(c, 7)
+		req, _ := http.NewRequest(http.MethodGet, backend.URL, nil)
+		if resp, err := tracing.Client(time.Second).Do(req.WithContext(c)); err == nil {
+			resp.Body.Close()
+		}
+		logrus.WithContext(c).Info("handler")
+		ctx.StatusCode(http.StatusOK)
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(app)
+	defer server.Close()
+
+	upstreamTraceId := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/users/1", nil)
+	req.Header.Set(RequestIdHeader, "req-1")
+	req.Header.Set(tracing.TraceparentHeader, "00-"+upstreamTraceId+"-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.Header.Get(RequestIdHeader) != "req-1" {
+		t.Fatalf("request id not returned: %q", resp.Header.Get(RequestIdHeader))
+	}
+
+	spans := exporter.Spans()
+	serverSpan := findSpan(spans, "GET /users/")
+	if serverSpan == nil {
+		t.Fatalf("server span not exported: %v", spans)
+	}
+	if serverSpan.Kind != tracing.KindServer || serverSpan.TraceIdString() != upstreamTraceId {
+		t.Fatal("server span should continue the upstream trace")
+	}
+	if serverSpan.Attributes["http.status_code"] != http.StatusOK || serverSpan.Attributes["enduser.id"] != int64(7) ||
+		serverSpan.Attributes["http.request_id"] != "req-1" {
+		t.Fatalf("unexpected server span attributes: %v", serverSpan.Attributes)
+	}
+	clientSpan := findSpan(spans, "HTTP GET")
+	if clientSpan == nil || clientSpan.ParentSpanId != serverSpan.SpanId {
+		t.Fatal("outbound span should be child of the server span")
+	}
+
+	entries := logs.entries(t)
+	for _, msg := range []string{"handler", "request"} {
+		entry := findEntry(entries, msg)
+		if entry == nil {
+			t.Fatalf("log %q not found", msg)
+		}
+		if entry["request_id"] != "req-1" || entry["user_id"] != float64(7) || entry["trace_id"] != upstreamTraceId {
+			t.Fatalf("unexpected log fields: %v", entry)
+		}
+	}
+	if access := findEntry(entries, "request"); access["status"] != float64(http.StatusOK) {
+		t.Fatalf("unexpected access log: %v", access)
+	}
+
+	// 不合法的请求编号重新生成
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/users/1", nil)
+	req.Header.Set(RequestIdHeader, "bad id")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if id := resp.Header.Get(RequestIdHeader); len(id) != 32 {
+		t.Fatalf("request id should be regenerated: %q", id)
+	}
+}
@@ -17,6 +17,7 @@ const (
 	SysConfigTopicCaptcha       = "topicCaptcha"       // 是否开启发帖验证码
 	SysConfigUserObserveSeconds = "userObserveSeconds" // 新用户观察期
 	SysConfigTokenExpireDays    = "tokenExpireDays"    // 登录Token有效天数
+	SysConfigSecret             = "secret"             // 签名密钥，配置文件中未配置Secret时自动生成
 )
 
 // EntityType
@@ -36,6 +37,16 @@ const (
 	RoleUser  = "user"  // 用户
 )
 
+// 个人访问令牌授权范围
+const (
+	ApiScopeRead    = "read"    // 读取数据
+	ApiScopeTopic   = "topic"   // 发表、编辑话题
+	ApiScopeComment = "comment" // 发表评论
+	ApiScopeTweet   = "tweet"   // 发表动态
+	ApiScopeSpider  = "spider"  // 采集发布
+	ApiScopeAdmin   = "admin"   // 管理后台
+)
+
 // 操作类型
 const (
 	OpTypeCreate          = "create"
@@ -43,6 +54,8 @@ const (
 	OpTypeUpdate          = "update"
 	OpTypeForbidden       = "forbidden"
 	OpTypeRemoveForbidden = "removeForbidden"
+	OpTypeMerge           = "merge"
+	OpTypeCancelAccount   = "cancelAccount"
 )
 
 // 状态
@@ -52,10 +65,23 @@ const (
 	StatusPending = 2 // 待审核
 )
 
+// 用户状态，正常和黑名单使用 StatusOk、StatusDeleted
+const (
+	UserStatusCanceled = 3 // 已注销，资料已匿名化，发表的内容显示为已注销用户
+)
+
+// 注销账号申请状态
+const (
+	UserDeletionPending  = 0 // 冷静期，可以撤销
+	UserDeletionDone     = 1 // 已注销
+	UserDeletionCanceled = 2 // 已撤销
+)
+
 // 用户类型
 const (
 	UserTypeNormal = 0 // 普通用户
 	UserTypeGzh    = 1 // 公众号用户
+	UserTypeRemote = 2 // ActivityPub远程用户，回复本站内容时自动创建，不能登录
 )
 
 // 内容类型
@@ -74,12 +100,97 @@ const (
 // 消息类型
 const (
 	MsgTypeComment = 0 // 回复消息
+	MsgTypeMention = 1 // 提到我的
+	MsgTypeSystem  = 2 // 系统通知
 )
 
 // 第三方账号类型
 const (
 	ThirdAccountTypeGithub = "github"
 	ThirdAccountTypeQQ     = "qq"
+	ThirdAccountTypeLdap   = "ldap"
+)
+
+// Webhook事件类型
+const (
+	EventTopicPublish         = "topic.publish"          // 发表话题
+	EventArticlePublish       = "article.publish"        // 发表文章
+	EventCommentPublish       = "comment.publish"        // 发表评论
+	EventTweetPublish         = "tweet.publish"          // 发表动态
+	EventUserSignUp           = "user.signup"            // 用户注册
+	EventUserForbidden        = "user.forbidden"         // 禁言
+	EventUserRemoveForbidden  = "user.remove_forbidden"  // 解除禁言
+	EventUserMerge            = "user.merge"             // 合并账号
+	EventUserDelete           = "user.delete"            // 注销账号
+	EventTopicDelete          = "topic.delete"           // 删除话题
+	EventTopicUndelete        = "topic.undelete"         // 恢复话题
+	EventTopicRecommend       = "topic.recommend"        // 推荐话题
+	EventTopicRemoveRecommend = "topic.remove_recommend" // 取消推荐话题
+	EventArticleDelete        = "article.delete"         // 删除文章
+	EventCommentDelete        = "comment.delete"         // 删除评论
+	EventTweetDelete          = "tweet.delete"           // 删除动态
+)
+
+// Webhook投递状态
+const (
+	WebhookDeliveryPending = 0 // 待投递（含等待重试）
+	WebhookDeliverySuccess = 1 // 投递成功
+	WebhookDeliveryFailed  = 2 // 投递失败，已达到最大重试次数
+)
+
+// 后台任务状态
+const (
+	JobStatusPending = 0 // 待执行（含等待重试）
+	JobStatusRunning = 1 // 执行中
+	JobStatusSuccess = 2 // 执行成功
+	JobStatusDead    = 3 // 死信，已达到最大执行次数
+)
+
+// 用户数据导出状态
+const (
+	UserExportPending = 0 // 待生成
+	UserExportRunning = 1 // 生成中
+	UserExportSuccess = 2 // 已生成
+	UserExportFailed  = 3 // 失败
+	UserExportExpired = 4 // 已过期，压缩包已删除
+)
+
+// 后台任务类型
+const (
+	JobTypeMessageCreate = "message.create"  // 创建站内消息
+	JobTypeMessageEmail  = "message.email"   // 发送消息邮件提醒
+	JobTypeSyncUserCount = "user.sync_count" // 同步用户计数
+	JobTypeEmailSend     = "email.send"      // 发送发件箱中的邮件
+	JobTypeDigest        = "digest"          // 发送摘要邮件，按用户拆分为 digest.user 任务
+	JobTypeDigestUser    = "digest.user"     // 给单个用户发送摘要邮件
+	JobTypeApPublish     = "ap.publish"      // ActivityPub推送新发表的内容，按关注者拆分为 ap.deliver 任务
+	JobTypeApDeliver     = "ap.deliver"      // ActivityPub投递活动到远程收件箱
+	JobTypeUserExport    = "user.export"     // 生成用户数据导出压缩包
+	JobTypeUserDelete    = "user.delete"     // 执行到期的注销账号申请
+)
+
+// 邮件模板
+const (
+	EmailTemplateVerify        = "verify"         // 邮箱验证
+	EmailTemplateResetPassword = "reset_password" // 重置密码
+	EmailTemplateNotice        = "notice"         // 消息提醒
+	EmailTemplateDigest        = "digest"         // 摘要邮件
+)
+
+// 发件箱邮件状态
+const (
+	EmailStatusPending = 0 // 待发送
+	EmailStatusSent    = 1 // 已发送
+	EmailStatusFailed  = 2 // 发送失败，后台任务会按退避策略重试
+)
+
+// 通知方式
+const (
+	NotifyModeOff    = "off"    // 不通知
+	NotifyModeInApp  = "inapp"  // 仅站内消息
+	NotifyModeEmail  = "email"  // 站内消息并立即发送邮件
+	NotifyModeDaily  = "daily"  // 站内消息并加入每日摘要邮件
+	NotifyModeWeekly = "weekly" // 站内消息并加入每周摘要邮件
 )
 
 // 积分操作类型
@@ -87,3 +198,10 @@ const (
 	ScoreTypeIncr = 0 // 积分+
 	ScoreTypeDecr = 1 // 积分-
 )
+
+// 评论排序方式
+const (
+	CommentSortBest   = "best"   // 最热，按回复数
+	CommentSortNewest = "newest" // 最新
+	CommentSortOldest = "oldest" // 最早
+)
@@ -6,5 +6,6 @@ type CreateCommentForm struct {
 	EntityId    int64  `form:"entityId"`
 	Content     string `form:"content"`
 	QuoteId     int64  `form:"quoteId"`
+	ParentId    int64  `form:"parentId"`
 	ContentType string `form:"contentType"`
 }
@@ -68,3 +68,30 @@ func (u *User) InObservationPeriod(observeSeconds int) bool {
 	}
 	return simple.TimeFromTimestamp(u.CreateTime).Add(time.Second * time.Duration(observeSeconds)).After(time.Now())
 }
+
+// HasPasswordLogin 是否可以使用用户名或邮箱加密码登录
+func (u *User) HasPasswordLogin() bool {
+	return len(u.Password) > 0 && (len(u.Username.String) > 0 || len(u.Email.String) > 0)
+}
+
+// GetScopes 获取令牌授权范围
+func (t *ApiToken) GetScopes() []string {
+	var scopes []string
+	for _, s := range strings.Split(t.Scopes, ",") {
+		s = strings.TrimSpace(s)
+		if simple.IsNotBlank(s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// HasScope 令牌是否拥有指定授权范围
+func (t *ApiToken) HasScope(scope string) bool {
+	return simple.Contains(scope, t.GetScopes())
+}
+
+// IsExpired 令牌是否已过期
+func (t *ApiToken) IsExpired() bool {
+	return t.ExpiredAt > 0 && t.ExpiredAt <= simple.NowTimestamp()
+}
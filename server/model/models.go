@@ -7,7 +7,12 @@ import (
 var Models = []interface{}{
 	&User{}, &UserToken{}, &Tag{}, &Article{}, &ArticleTag{}, &Comment{}, &Favorite{}, &Topic{}, &TopicNode{},
 	&TopicTag{}, &UserLike{}, &Tweet{}, &Message{}, &SysConfig{}, &Project{}, &Link{}, &ThirdAccount{},
-	&UserScore{}, &UserScoreLog{}, &OperateLog{}, &EmailCode{}, &CheckIn{}, &SignupAnalyze{},
+	&UserScore{}, &UserScoreLog{}, &OperateLog{}, &EmailCode{}, &CheckIn{}, &SignupAnalyze{}, &ApiToken{},
+	&Webhook{}, &WebhookDelivery{}, &Job{}, &NotificationSetting{},
+	&EmailTemplate{}, &EmailOutbox{}, &CommentHistory{},
+	&UserReaction{}, &ReactionCount{}, &Hashtag{}, &TweetHashtag{}, &SitemapShard{},
+	&ApActorKey{}, &ApRemoteActor{}, &ApFollower{}, &ApRemoteObject{}, &UserExport{},
+	&UserDeletion{}, &ImportMapping{},
 }
 
 type Model struct {
@@ -43,6 +48,20 @@ type UserToken struct {
 	CreateTime int64  `gorm:"not null" json:"createTime" form:"createTime"`
 }
 
+// 个人访问令牌，用于机器人、第三方集成通过 Authorization: Bearer 调用接口
+type ApiToken struct {
+	Model
+	UserId     int64  `gorm:"not null;index:idx_api_token_user_id;" json:"userId" form:"userId"` // 用户编号
+	Name       string `gorm:"size:64;not null" json:"name" form:"name"`                          // 令牌名称
+	TokenHash  string `gorm:"size:64;unique;not null" json:"-" form:"-"`                         // 令牌sha256摘要，原始令牌只在创建时返回一次
+	TokenTail  string `gorm:"size:8" json:"tokenTail" form:"tokenTail"`                          // 令牌末尾字符，用于展示时辨认
+	Scopes     string `gorm:"size:512" json:"scopes" form:"scopes"`                              // 授权范围，多个用逗号分隔
+	ExpiredAt  int64  `gorm:"not null;default:0" json:"expiredAt" form:"expiredAt"`              // 过期时间，0表示永不过期
+	LastUsedAt int64  `gorm:"not null;default:0" json:"lastUsedAt" form:"lastUsedAt"`            // 最后使用时间
+	Status     int    `gorm:"not null;index:idx_api_token_status" json:"status" form:"status"`   // 状态
+	CreateTime int64  `gorm:"not null" json:"createTime" form:"createTime"`                      // 创建时间
+}
+
 type ThirdAccount struct {
 	Model
 	UserId     sql.NullInt64 `gorm:"unique_index:idx_user_id_third_type;" json:"userId" form:"userId"`                                  // 用户编号
@@ -93,14 +112,31 @@ type ArticleTag struct {
 // 评论
 type Comment struct {
 	Model
-	UserId      int64  `gorm:"index:idx_comment_user_id;not null" json:"userId" form:"userId"`             // 用户编号
-	EntityType  string `gorm:"index:idx_comment_entity_type;not null" json:"entityType" form:"entityType"` // 被评论实体类型
-	EntityId    int64  `gorm:"index:idx_comment_entity_id;not null" json:"entityId" form:"entityId"`       // 被评论实体编号
-	Content     string `gorm:"type:text;not null" json:"content" form:"content"`                           // 内容
-	ContentType string `gorm:"type:varchar(32);not null" json:"contentType" form:"contentType"`            // 内容类型：markdown、html
-	QuoteId     int64  `gorm:"not null"  json:"quoteId" form:"quoteId"`                                    // 引用的评论编号
-	Status      int    `gorm:"int;index:idx_comment_status" json:"status" form:"status"`                   // 状态：0：待审核、1：审核通过、2：审核失败、3：已发布
-	CreateTime  int64  `json:"createTime" form:"createTime"`                                               // 创建时间
+	UserId      int64  `gorm:"index:idx_comment_user_id;not null" json:"userId" form:"userId"`                 // 用户编号
+	EntityType  string `gorm:"index:idx_comment_entity_type;not null" json:"entityType" form:"entityType"`     // 被评论实体类型
+	EntityId    int64  `gorm:"index:idx_comment_entity_id;not null" json:"entityId" form:"entityId"`           // 被评论实体编号
+	Content     string `gorm:"type:text;not null" json:"content" form:"content"`                               // 内容
+	ContentType string `gorm:"type:varchar(32);not null" json:"contentType" form:"contentType"`                // 内容类型：markdown、html
+	QuoteId     int64  `gorm:"not null"  json:"quoteId" form:"quoteId"`                                        // 引用的评论编号
+	ParentId    int64  `gorm:"index:idx_comment_parent_id;not null;default:0" json:"parentId" form:"parentId"` // 上级评论编号，为0时表示顶层评论
+	RootId      int64  `gorm:"index:idx_comment_root_id;not null;default:0" json:"rootId" form:"rootId"`       // 所属顶层评论编号，顶层评论为0
+	Depth       int    `gorm:"not null;default:0" json:"depth" form:"depth"`                                   // 层级，顶层评论为0
+	ReplyCount  int64  `gorm:"not null;default:0" json:"replyCount" form:"replyCount"`                         // 直接回复数量
+	LikeCount   int64  `gorm:"not null;default:0" json:"likeCount" form:"likeCount"`                           // 点赞数量
+	Status      int    `gorm:"int;index:idx_comment_status" json:"status" form:"status"`                       // 状态：0：待审核、1：审核通过、2：审核失败、3：已发布
+	CreateTime  int64  `json:"createTime" form:"createTime"`                                                   // 创建时间
+	UpdateTime  int64  `json:"updateTime" form:"updateTime"`                                                   // 作者最后编辑时间，为0时表示未编辑过
+	DeleteTime  int64  `json:"deleteTime" form:"deleteTime"`                                                   // 作者删除时间，不为0时显示为已删除
+}
+
+// 评论编辑历史，每次编辑前保存一份旧内容
+type CommentHistory struct {
+	Model
+	CommentId   int64  `gorm:"index:idx_comment_history_comment_id;not null" json:"commentId" form:"commentId"` // 评论编号
+	UserId      int64  `gorm:"not null" json:"userId" form:"userId"`                                            // 编辑人
+	Content     string `gorm:"type:text;not null" json:"content" form:"content"`                                // 编辑前的内容
+	ContentType string `gorm:"type:varchar(32);not null" json:"contentType" form:"contentType"`                 // 内容类型
+	CreateTime  int64  `json:"createTime" form:"createTime"`                                                    // 编辑时间
 }
 
 // 收藏
@@ -160,16 +196,64 @@ type UserLike struct {
 	CreateTime int64  `json:"createTime" form:"createTime"`                                                                                       // 创建时间
 }
 
+// 表情回应，同一用户对同一实体可以使用多个不同的表情
+type UserReaction struct {
+	Model
+	UserId     int64  `gorm:"not null;unique_index:idx_user_reaction_unique;" json:"userId" form:"userId"`                                                // 用户
+	EntityType string `gorm:"not null;size:32;unique_index:idx_user_reaction_unique;index:idx_user_reaction_entity;" json:"entityType" form:"entityType"` // 实体类型
+	EntityId   int64  `gorm:"not null;unique_index:idx_user_reaction_unique;index:idx_user_reaction_entity;" json:"entityId" form:"entityId"`             // 实体编号
+	Emoji      string `gorm:"not null;size:32;unique_index:idx_user_reaction_unique;" json:"emoji" form:"emoji"`                                          // 表情
+	CreateTime int64  `json:"createTime" form:"createTime"`                                                                                               // 创建时间
+}
+
+// 表情回应数量，回应和取消回应时增量更新
+type ReactionCount struct {
+	Model
+	EntityType string `gorm:"not null;size:32;unique_index:idx_reaction_count_unique;" json:"entityType" form:"entityType"` // 实体类型
+	EntityId   int64  `gorm:"not null;unique_index:idx_reaction_count_unique;" json:"entityId" form:"entityId"`             // 实体编号
+	Emoji      string `gorm:"not null;size:32;unique_index:idx_reaction_count_unique;" json:"emoji" form:"emoji"`           // 表情
+	Count      int64  `gorm:"not null;default:0" json:"count" form:"count"`                                                 // 数量
+}
+
 // 动态
 type Tweet struct {
 	Model
-	UserId       int64  `gorm:"not null;index:idx_topic_like_user_id;" json:"userId" form:"userId"` // 用户
-	Content      string `gorm:"type:text;not null;" json:"content" form:"content"`                  // 内容
-	ImageList    string `gorm:"type:longtext" json:"imageList" form:"imageList"`                    // 图片
-	CommentCount int64  `gorm:"not null" json:"commentCount" form:"commentCount"`                   // 跟帖数量
-	LikeCount    int64  `gorm:"not null" json:"likeCount" form:"likeCount"`                         // 点赞数量
-	Status       int    `gorm:"index:idx_topic_status;" json:"status" form:"status"`                // 状态：0：正常、1：删除
-	CreateTime   int64  `json:"createTime" form:"createTime"`                                       // 创建时间
+	UserId       int64  `gorm:"not null;index:idx_topic_like_user_id;" json:"userId" form:"userId"`           // 用户
+	Content      string `gorm:"type:text;not null;" json:"content" form:"content"`                            // 内容
+	ImageList    string `gorm:"type:longtext" json:"imageList" form:"imageList"`                              // 图片
+	CommentCount int64  `gorm:"not null" json:"commentCount" form:"commentCount"`                             // 跟帖数量
+	LikeCount    int64  `gorm:"not null" json:"likeCount" form:"likeCount"`                                   // 点赞数量
+	RepostId     int64  `gorm:"not null;default:0;index:idx_tweet_repost_id" json:"repostId" form:"repostId"` // 转发的动态编号，内容为空时为直接转发，否则为引用转发
+	RepostCount  int64  `gorm:"not null;default:0" json:"repostCount" form:"repostCount"`                     // 转发数量
+	Status       int    `gorm:"index:idx_topic_status;" json:"status" form:"status"`                          // 状态：0：正常、1：删除
+	CreateTime   int64  `json:"createTime" form:"createTime"`                                                 // 创建时间
+}
+
+// 动态话题标签
+type Hashtag struct {
+	Model
+	Name          string `gorm:"size:64;unique;not null" json:"name" form:"name"`        // 名称
+	TweetCount    int64  `gorm:"not null;default:0" json:"tweetCount" form:"tweetCount"` // 动态数量
+	CreateTime    int64  `json:"createTime" form:"createTime"`                           // 创建时间
+	LastTweetTime int64  `json:"lastTweetTime" form:"lastTweetTime"`                     // 最后发表时间
+}
+
+// 动态与话题标签的关联
+type TweetHashtag struct {
+	Model
+	TweetId    int64 `gorm:"not null;index:idx_tweet_hashtag_tweet_id" json:"tweetId" form:"tweetId"`       // 动态编号
+	HashtagId  int64 `gorm:"not null;index:idx_tweet_hashtag_hashtag_id" json:"hashtagId" form:"hashtagId"` // 话题标签编号
+	CreateTime int64 `gorm:"index:idx_tweet_hashtag_create_time" json:"createTime" form:"createTime"`       // 创建时间
+}
+
+// 站点地图分片，用于增量生成站点地图
+type SitemapShard struct {
+	Model
+	Name        string `gorm:"size:64;unique;not null" json:"name" form:"name"` // 分片名称
+	Fingerprint string `gorm:"size:128" json:"fingerprint" form:"fingerprint"`  // 分片内容指纹，变化时重新生成
+	Url         string `gorm:"type:text" json:"url" form:"url"`                 // 分片地址
+	LastMod     int64  `json:"lastMod" form:"lastMod"`                          // 分片内容最后更新时间
+	UpdateTime  int64  `json:"updateTime" form:"updateTime"`                    // 生成时间
 }
 
 // 消息
@@ -257,6 +341,94 @@ type OperateLog struct {
 	CreateTime  int64  `json:"createTime" form:"createTime"`                                        // 创建时间
 }
 
+// Webhook订阅，事件发生时向Url推送签名后的JSON
+type Webhook struct {
+	Model
+	Name       string `gorm:"size:64;not null" json:"name" form:"name"`                      // 名称
+	Url        string `gorm:"size:1024;not null" json:"url" form:"url"`                      // 推送地址
+	Secret     string `gorm:"size:128" json:"secret" form:"secret"`                          // 签名密钥
+	Events     string `gorm:"type:text" json:"events" form:"events"`                         // 订阅的事件，多个用逗号分隔，为空表示全部事件
+	Status     int    `gorm:"not null;index:idx_webhook_status" json:"status" form:"status"` // 状态
+	CreateTime int64  `json:"createTime" form:"createTime"`                                  // 创建时间
+	UpdateTime int64  `json:"updateTime" form:"updateTime"`                                  // 更新时间
+}
+
+// Webhook投递记录
+type WebhookDelivery struct {
+	Model
+	WebhookId     int64  `gorm:"not null;index:idx_webhook_delivery_webhook_id" json:"webhookId" form:"webhookId"`               // Webhook编号
+	DeliveryId    string `gorm:"size:32;not null;index:idx_webhook_delivery_delivery_id" json:"deliveryId" form:"deliveryId"`    // 投递编号，重新投递时保持不变，接收方可用于去重
+	Event         string `gorm:"size:64;not null" json:"event" form:"event"`                                                     // 事件类型
+	Payload       string `gorm:"type:longtext" json:"payload" form:"payload"`                                                    // 推送内容
+	Status        int    `gorm:"not null;index:idx_webhook_delivery_status" json:"status" form:"status"`                         // 状态
+	Attempts      int    `gorm:"not null;default:0" json:"attempts" form:"attempts"`                                             // 已投递次数
+	ResponseCode  int    `gorm:"not null;default:0" json:"responseCode" form:"responseCode"`                                     // 最后一次投递的响应码
+	ResponseBody  string `gorm:"type:text" json:"responseBody" form:"responseBody"`                                              // 最后一次投递的响应内容
+	Error         string `gorm:"type:text" json:"error" form:"error"`                                                            // 最后一次投递的错误信息
+	Duration      int64  `gorm:"not null;default:0" json:"duration" form:"duration"`                                             // 最后一次投递耗时（毫秒）
+	NextRetryTime int64  `gorm:"not null;default:0;index:idx_webhook_delivery_status" json:"nextRetryTime" form:"nextRetryTime"` // 下次投递时间
+	CreateTime    int64  `json:"createTime" form:"createTime"`                                                                   // 创建时间
+	UpdateTime    int64  `json:"updateTime" form:"updateTime"`                                                                   // 更新时间
+}
+
+// 后台任务
+type Job struct {
+	Model
+	Type          string `gorm:"size:64;not null;index:idx_job_type" json:"type" form:"type"`           // 任务类型
+	Payload       string `gorm:"type:longtext" json:"payload" form:"payload"`                           // 任务参数（JSON）
+	Status        int    `gorm:"not null;index:idx_job_status" json:"status" form:"status"`             // 状态
+	Attempts      int    `gorm:"not null;default:0" json:"attempts" form:"attempts"`                    // 已执行次数
+	MaxAttempts   int    `gorm:"not null;default:0" json:"maxAttempts" form:"maxAttempts"`              // 最大执行次数，超过后进入死信状态
+	LastError     string `gorm:"type:text" json:"lastError" form:"lastError"`                           // 最后一次执行的错误信息
+	RunTime       int64  `gorm:"not null;default:0;index:idx_job_status" json:"runTime" form:"runTime"` // 下次执行时间
+	StartTime     int64  `gorm:"not null;default:0" json:"startTime" form:"startTime"`                  // 最后一次开始执行时间
+	HeartbeatTime int64  `gorm:"not null;default:0" json:"heartbeatTime" form:"heartbeatTime"`          // 执行中的任务定期更新，长时间未更新时认为执行任务的进程已退出
+	FinishTime    int64  `gorm:"not null;default:0" json:"finishTime" form:"finishTime"`                // 完成时间
+	CreateTime    int64  `json:"createTime" form:"createTime"`                                          // 创建时间
+	UpdateTime    int64  `json:"updateTime" form:"updateTime"`                                          // 更新时间
+}
+
+// 用户通知设置
+type NotificationSetting struct {
+	Model
+	UserId               int64  `gorm:"not null;unique" json:"userId" form:"userId"`                                // 用户编号
+	Preferences          string `gorm:"type:text" json:"preferences" form:"preferences"`                            // 各消息类型的通知方式（JSON），未设置的使用默认方式
+	TopicDigest          string `gorm:"size:16;not null;default:''" json:"topicDigest" form:"topicDigest"`          // 关注节点热门话题摘要：off、daily、weekly
+	DigestNodeIds        string `gorm:"size:512" json:"digestNodeIds" form:"digestNodeIds"`                         // 关注的节点，多个用逗号分隔
+	LastDailyDigestTime  int64  `gorm:"not null;default:0" json:"lastDailyDigestTime" form:"lastDailyDigestTime"`   // 最后一次发送每日摘要的时间
+	LastWeeklyDigestTime int64  `gorm:"not null;default:0" json:"lastWeeklyDigestTime" form:"lastWeeklyDigestTime"` // 最后一次发送每周摘要的时间
+	CreateTime           int64  `json:"createTime" form:"createTime"`                                               // 创建时间
+	UpdateTime           int64  `json:"updateTime" form:"updateTime"`                                               // 更新时间
+}
+
+// 邮件模板，只保存在管理后台修改过的模板，未修改的使用内置模板
+type EmailTemplate struct {
+	Model
+	Name       string `gorm:"size:32;not null;unique" json:"name" form:"name"` // 模板名称
+	Subject    string `gorm:"size:256" json:"subject" form:"subject"`          // 主题
+	Html       string `gorm:"type:longtext" json:"html" form:"html"`           // HTML内容
+	Text       string `gorm:"type:longtext" json:"text" form:"text"`           // 纯文本内容
+	CreateTime int64  `json:"createTime" form:"createTime"`                    // 创建时间
+	UpdateTime int64  `json:"updateTime" form:"updateTime"`                    // 更新时间
+}
+
+// 发件箱
+type EmailOutbox struct {
+	Model
+	Email      string `gorm:"size:128;not null;index:idx_email_outbox_email" json:"email" form:"email"` // 收件人
+	Template   string `gorm:"size:32;not null" json:"template" form:"template"`                         // 模板名称
+	Subject    string `gorm:"size:256" json:"subject" form:"subject"`                                   // 主题
+	Html       string `gorm:"type:longtext" json:"html" form:"html"`                                    // HTML内容
+	Text       string `gorm:"type:longtext" json:"text" form:"text"`                                    // 纯文本内容
+	Headers    string `gorm:"type:text" json:"headers" form:"headers"`                                  // 额外的邮件头（JSON）
+	Status     int    `gorm:"not null;index:idx_email_outbox_status" json:"status" form:"status"`       // 状态：0：待发送、1：已发送、2：发送失败
+	Attempts   int    `gorm:"not null;default:0" json:"attempts" form:"attempts"`                       // 发送次数
+	LastError  string `gorm:"type:text" json:"lastError" form:"lastError"`                              // 最后一次发送失败的原因
+	SendTime   int64  `json:"sendTime" form:"sendTime"`                                                 // 发送成功时间
+	CreateTime int64  `json:"createTime" form:"createTime"`                                             // 创建时间
+	UpdateTime int64  `json:"updateTime" form:"updateTime"`                                             // 更新时间
+}
+
 // 邮箱验证码
 type EmailCode struct {
 	Model
@@ -285,3 +457,85 @@ type SignupAnalyze struct {
 	UserId int64  `gorm:"not null;unique_index:idx_user_id"` // 用户编号
 	Source string `gorm:"not null;size:32"`                  //来源标识
 }
+
+// ActivityPub本地用户密钥，首次被访问时生成
+type ApActorKey struct {
+	Model
+	UserId     int64  `gorm:"not null;unique" json:"userId" form:"userId"`          // 用户编号
+	PublicKey  string `gorm:"type:text;not null" json:"publicKey" form:"publicKey"` // 公钥（PEM）
+	PrivateKey string `gorm:"type:text;not null" json:"-" form:"-"`                 // 私钥（PEM）
+	CreateTime int64  `json:"createTime" form:"createTime"`                         // 创建时间
+}
+
+// ActivityPub远程用户
+type ApRemoteActor struct {
+	Model
+	ActorId     string `gorm:"size:512;unique;not null" json:"actorId" form:"actorId"`                           // 远程用户地址
+	UserId      int64  `gorm:"not null;default:0;index:idx_ap_remote_actor_user_id" json:"userId" form:"userId"` // 对应的本地影子用户编号，首次回复时创建
+	Username    string `gorm:"size:128" json:"username" form:"username"`                                         // 用户名
+	Name        string `gorm:"size:128" json:"name" form:"name"`                                                 // 昵称
+	Domain      string `gorm:"size:128;index:idx_ap_remote_actor_domain" json:"domain" form:"domain"`            // 所属实例域名
+	Url         string `gorm:"size:1024" json:"url" form:"url"`                                                  // 主页
+	Avatar      string `gorm:"type:text" json:"avatar" form:"avatar"`                                            // 头像
+	Inbox       string `gorm:"size:1024;not null" json:"inbox" form:"inbox"`                                     // 收件箱
+	SharedInbox string `gorm:"size:1024" json:"sharedInbox" form:"sharedInbox"`                                  // 共享收件箱
+	PublicKeyId string `gorm:"size:512" json:"publicKeyId" form:"publicKeyId"`                                   // 公钥编号
+	PublicKey   string `gorm:"type:text" json:"publicKey" form:"publicKey"`                                      // 公钥（PEM）
+	CreateTime  int64  `json:"createTime" form:"createTime"`                                                     // 创建时间
+	UpdateTime  int64  `json:"updateTime" form:"updateTime"`                                                     // 最后获取时间
+}
+
+// ActivityPub远程关注者
+type ApFollower struct {
+	Model
+	UserId     int64  `gorm:"not null;unique_index:idx_ap_follower_unique" json:"userId" form:"userId"`            // 被关注的本地用户编号
+	ActorId    string `gorm:"size:512;not null;unique_index:idx_ap_follower_unique" json:"actorId" form:"actorId"` // 关注者地址
+	Inbox      string `gorm:"size:1024;not null" json:"inbox" form:"inbox"`                                        // 投递地址，优先使用共享收件箱
+	CreateTime int64  `json:"createTime" form:"createTime"`                                                        // 创建时间
+}
+
+// ActivityPub远程对象与本地内容的对应关系，用于去重和处理回复、删除
+type ApRemoteObject struct {
+	Model
+	ObjectId   string `gorm:"size:512;unique;not null" json:"objectId" form:"objectId"`                      // 远程对象地址
+	ActorId    string `gorm:"size:512;not null" json:"actorId" form:"actorId"`                               // 发布者地址
+	EntityType string `gorm:"size:32;not null" json:"entityType" form:"entityType"`                          // 本地内容类型
+	EntityId   int64  `gorm:"not null;index:idx_ap_remote_object_entity_id" json:"entityId" form:"entityId"` // 本地内容编号
+	CreateTime int64  `json:"createTime" form:"createTime"`                                                  // 创建时间
+}
+
+// 用户数据导出，后台生成压缩包后通过签名链接下载
+type UserExport struct {
+	Model
+	UserId       int64  `gorm:"not null;index:idx_user_export_user_id" json:"userId" form:"userId"` // 用户编号
+	Status       int    `gorm:"not null;index:idx_user_export_status" json:"status" form:"status"`  // 状态：0：待生成、1：生成中、2：已生成、3：失败、4：已过期
+	FileName     string `gorm:"size:128" json:"-" form:"-"`                                         // 压缩包文件名，保存在Export.Path目录下
+	FileSize     int64  `gorm:"not null;default:0" json:"fileSize" form:"fileSize"`                 // 压缩包大小
+	ErrorMessage string `gorm:"type:text" json:"errorMessage" form:"errorMessage"`                  // 失败原因
+	ExpiredAt    int64  `gorm:"not null;default:0" json:"expiredAt" form:"expiredAt"`               // 下载链接过期时间
+	CreateTime   int64  `json:"createTime" form:"createTime"`                                       // 创建时间
+	UpdateTime   int64  `json:"updateTime" form:"updateTime"`                                       // 更新时间
+}
+
+// 注销账号申请，冷静期结束后匿名化用户资料
+type UserDeletion struct {
+	Model
+	UserId      int64  `gorm:"not null;index:idx_user_deletion_user_id" json:"userId" form:"userId"`                // 用户编号
+	OperatorId  int64  `gorm:"not null" json:"operatorId" form:"operatorId"`                                        // 申请人，用户自己申请时为用户编号
+	Purge       bool   `gorm:"not null;default:false" json:"purge" form:"purge"`                                    // 是否同时删除发表的内容，否则内容保留并显示为已注销用户
+	Reason      string `gorm:"size:1024" json:"reason" form:"reason"`                                               // 注销原因
+	Status      int    `gorm:"not null;index:idx_user_deletion_status" json:"status" form:"status"`                 // 状态：0：冷静期、1：已注销、2：已撤销
+	ScheduledAt int64  `gorm:"not null;index:idx_user_deletion_scheduled_at" json:"scheduledAt" form:"scheduledAt"` // 执行注销的时间
+	CreateTime  int64  `json:"createTime" form:"createTime"`                                                        // 创建时间
+	UpdateTime  int64  `json:"updateTime" form:"updateTime"`                                                        // 更新时间
+}
+
+// 导入其他论坛数据时原编号与本站编号的对应关系，用于断点续传和重复导入时跳过已导入的数据
+type ImportMapping struct {
+	Model
+	Source     string `gorm:"size:32;not null;unique_index:idx_import_mapping_unique" json:"source" form:"source"`         // 数据来源：discourse、discuz
+	EntityType string `gorm:"size:32;not null;unique_index:idx_import_mapping_unique" json:"entityType" form:"entityType"` // 实体类型
+	SourceId   string `gorm:"size:64;not null;unique_index:idx_import_mapping_unique" json:"sourceId" form:"sourceId"`     // 原编号
+	TargetId   int64  `gorm:"not null;index:idx_import_mapping_target_id" json:"targetId" form:"targetId"`                 // 本站编号
+	CreateTime int64  `json:"createTime" form:"createTime"`                                                                // 创建时间
+}
@@ -67,19 +67,31 @@ type TopicSimpleResponse struct {
 // TopicResponse 帖子详情返回实体
 type TopicResponse struct {
 	TopicSimpleResponse
-	Content string `json:"content"`
+	Content   string                  `json:"content"`
+	Reactions []ReactionCountResponse `json:"reactions"`
+}
+
+// 表情回应数量
+type ReactionCountResponse struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
 }
 
 // TweetResponse 帖子列表返回实体
 type TweetResponse struct {
-	TweetId      int64       `json:"tweetId"`
-	User         *UserInfo   `json:"user"`
-	Content      string      `json:"content"`
-	ImageList    []ImageInfo `json:"imageList"`
-	CommentCount int64       `json:"commentCount"`
-	LikeCount    int64       `json:"likeCount"`
-	Status       int         `json:"status"`
-	CreateTime   int64       `json:"createTime"`
+	TweetId       int64                   `json:"tweetId"`
+	User          *UserInfo               `json:"user"`
+	Content       string                  `json:"content"`
+	ImageList     []ImageInfo             `json:"imageList"`
+	CommentCount  int64                   `json:"commentCount"`
+	LikeCount     int64                   `json:"likeCount"`
+	Reactions     []ReactionCountResponse `json:"reactions"`
+	RepostId      int64                   `json:"repostId"`
+	Repost        *TweetResponse          `json:"repost"`        // 转发的动态
+	RepostRemoved bool                    `json:"repostRemoved"` // 转发的动态是否已删除
+	RepostCount   int64                   `json:"repostCount"`
+	Status        int                     `json:"status"`
+	CreateTime    int64                   `json:"createTime"`
 }
 
 // ProjectSimpleResponse 项目简单返回
@@ -103,16 +115,34 @@ type ProjectResponse struct {
 }
 
 type CommentResponse struct {
-	CommentId    int64            `json:"commentId"`
-	User         *UserInfo        `json:"user"`
-	EntityType   string           `json:"entityType"`
-	EntityId     int64            `json:"entityId"`
-	Content      string           `json:"content"`
-	QuoteId      int64            `json:"quoteId"`
-	Quote        *CommentResponse `json:"quote"`
-	QuoteContent string           `json:"quoteContent"`
-	Status       int              `json:"status"`
-	CreateTime   int64            `json:"createTime"`
+	CommentId    int64                   `json:"commentId"`
+	User         *UserInfo               `json:"user"`
+	EntityType   string                  `json:"entityType"`
+	EntityId     int64                   `json:"entityId"`
+	Content      string                  `json:"content"`
+	QuoteId      int64                   `json:"quoteId"`
+	Quote        *CommentResponse        `json:"quote"`
+	QuoteContent string                  `json:"quoteContent"`
+	ParentId     int64                   `json:"parentId"`
+	RootId       int64                   `json:"rootId"`
+	Depth        int                     `json:"depth"`
+	ReplyCount   int64                   `json:"replyCount"`
+	LikeCount    int64                   `json:"likeCount"`
+	Liked        bool                    `json:"liked"` // 当前用户是否已点赞
+	Reactions    []ReactionCountResponse `json:"reactions"`
+	Edited       bool                    `json:"edited"`   // 是否编辑过
+	Editable     bool                    `json:"editable"` // 当前用户是作者且还在可编辑时间内
+	Source       string                  `json:"source"`   // 原始内容，仅在可编辑时返回
+	Deleted      bool                    `json:"deleted"`  // 是否已被作者删除
+	UpdateTime   int64                   `json:"updateTime"`
+	Status       int                     `json:"status"`
+	CreateTime   int64                   `json:"createTime"`
+}
+
+type CommentHistoryResponse struct {
+	Id         int64  `json:"id"`
+	Content    string `json:"content"`
+	CreateTime int64  `json:"createTime"`
 }
 
 type FavoriteResponse struct {
@@ -141,6 +171,26 @@ type MessageResponse struct {
 	CreateTime   int64     `json:"createTime"`
 }
 
+// 个人访问令牌
+type ApiTokenResponse struct {
+	ApiTokenId int64    `json:"apiTokenId"`
+	Name       string   `json:"name"`
+	TokenTail  string   `json:"tokenTail"` // 令牌末尾字符
+	Scopes     []string `json:"scopes"`
+	ExpiredAt  int64    `json:"expiredAt"`
+	LastUsedAt int64    `json:"lastUsedAt"`
+	CreateTime int64    `json:"createTime"`
+}
+
+// 绑定的第三方账号
+type ThirdAccountResponse struct {
+	ThirdAccountId int64  `json:"thirdAccountId"`
+	ThirdType      string `json:"thirdType"`
+	Nickname       string `json:"nickname"`
+	Avatar         string `json:"avatar"`
+	CreateTime     int64  `json:"createTime"`
+}
+
 type ImageInfo struct {
 	Url     string `json:"url"`
 	Preview string `json:"preview"`
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var ApActorKeyRepository = newApActorKeyRepository()
+
+func newApActorKeyRepository() *apActorKeyRepository {
+	return &apActorKeyRepository{}
+}
+
+type apActorKeyRepository struct {
+}
+
+func (r *apActorKeyRepository) Get(db *gorm.DB, id int64) *model.ApActorKey {
+	ret := &model.ApActorKey{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apActorKeyRepository) Take(db *gorm.DB, where ...interface{}) *model.ApActorKey {
+	ret := &model.ApActorKey{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apActorKeyRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApActorKey) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *apActorKeyRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.ApActorKey {
+	ret := &model.ApActorKey{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apActorKeyRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.ApActorKey, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *apActorKeyRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApActorKey, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.ApActorKey{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *apActorKeyRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.ApActorKey{})
+}
+
+func (r *apActorKeyRepository) Create(db *gorm.DB, t *model.ApActorKey) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *apActorKeyRepository) Update(db *gorm.DB, t *model.ApActorKey) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *apActorKeyRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.ApActorKey{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *apActorKeyRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.ApActorKey{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *apActorKeyRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.ApActorKey{}, "id = ?", id)
+}
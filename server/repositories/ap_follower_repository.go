@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var ApFollowerRepository = newApFollowerRepository()
+
+func newApFollowerRepository() *apFollowerRepository {
+	return &apFollowerRepository{}
+}
+
+type apFollowerRepository struct {
+}
+
+func (r *apFollowerRepository) Get(db *gorm.DB, id int64) *model.ApFollower {
+	ret := &model.ApFollower{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apFollowerRepository) Take(db *gorm.DB, where ...interface{}) *model.ApFollower {
+	ret := &model.ApFollower{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apFollowerRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApFollower) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *apFollowerRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.ApFollower {
+	ret := &model.ApFollower{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apFollowerRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.ApFollower, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *apFollowerRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApFollower, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.ApFollower{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *apFollowerRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.ApFollower{})
+}
+
+func (r *apFollowerRepository) Create(db *gorm.DB, t *model.ApFollower) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *apFollowerRepository) Update(db *gorm.DB, t *model.ApFollower) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *apFollowerRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.ApFollower{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *apFollowerRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.ApFollower{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *apFollowerRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.ApFollower{}, "id = ?", id)
+}
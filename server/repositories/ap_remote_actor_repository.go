@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var ApRemoteActorRepository = newApRemoteActorRepository()
+
+func newApRemoteActorRepository() *apRemoteActorRepository {
+	return &apRemoteActorRepository{}
+}
+
+type apRemoteActorRepository struct {
+}
+
+func (r *apRemoteActorRepository) Get(db *gorm.DB, id int64) *model.ApRemoteActor {
+	ret := &model.ApRemoteActor{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apRemoteActorRepository) Take(db *gorm.DB, where ...interface{}) *model.ApRemoteActor {
+	ret := &model.ApRemoteActor{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apRemoteActorRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApRemoteActor) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *apRemoteActorRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.ApRemoteActor {
+	ret := &model.ApRemoteActor{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apRemoteActorRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.ApRemoteActor, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *apRemoteActorRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApRemoteActor, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.ApRemoteActor{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *apRemoteActorRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.ApRemoteActor{})
+}
+
+func (r *apRemoteActorRepository) Create(db *gorm.DB, t *model.ApRemoteActor) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *apRemoteActorRepository) Update(db *gorm.DB, t *model.ApRemoteActor) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *apRemoteActorRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.ApRemoteActor{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *apRemoteActorRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.ApRemoteActor{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *apRemoteActorRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.ApRemoteActor{}, "id = ?", id)
+}
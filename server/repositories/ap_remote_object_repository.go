@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var ApRemoteObjectRepository = newApRemoteObjectRepository()
+
+func newApRemoteObjectRepository() *apRemoteObjectRepository {
+	return &apRemoteObjectRepository{}
+}
+
+type apRemoteObjectRepository struct {
+}
+
+func (r *apRemoteObjectRepository) Get(db *gorm.DB, id int64) *model.ApRemoteObject {
+	ret := &model.ApRemoteObject{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apRemoteObjectRepository) Take(db *gorm.DB, where ...interface{}) *model.ApRemoteObject {
+	ret := &model.ApRemoteObject{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apRemoteObjectRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApRemoteObject) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *apRemoteObjectRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.ApRemoteObject {
+	ret := &model.ApRemoteObject{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apRemoteObjectRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.ApRemoteObject, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *apRemoteObjectRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApRemoteObject, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.ApRemoteObject{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *apRemoteObjectRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.ApRemoteObject{})
+}
+
+func (r *apRemoteObjectRepository) Create(db *gorm.DB, t *model.ApRemoteObject) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *apRemoteObjectRepository) Update(db *gorm.DB, t *model.ApRemoteObject) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *apRemoteObjectRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.ApRemoteObject{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *apRemoteObjectRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.ApRemoteObject{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *apRemoteObjectRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.ApRemoteObject{}, "id = ?", id)
+}
@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var ApiTokenRepository = newApiTokenRepository()
+
+func newApiTokenRepository() *apiTokenRepository {
+	return &apiTokenRepository{}
+}
+
+type apiTokenRepository struct {
+}
+
+func (r *apiTokenRepository) Get(db *gorm.DB, id int64) *model.ApiToken {
+	ret := &model.ApiToken{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apiTokenRepository) Take(db *gorm.DB, where ...interface{}) *model.ApiToken {
+	ret := &model.ApiToken{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apiTokenRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApiToken) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *apiTokenRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.ApiToken {
+	ret := &model.ApiToken{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *apiTokenRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.ApiToken, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *apiTokenRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ApiToken, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.ApiToken{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *apiTokenRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.ApiToken{})
+}
+
+func (r *apiTokenRepository) Create(db *gorm.DB, t *model.ApiToken) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *apiTokenRepository) Update(db *gorm.DB, t *model.ApiToken) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *apiTokenRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.ApiToken{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *apiTokenRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.ApiToken{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *apiTokenRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.ApiToken{}, "id = ?", id)
+}
+
+func (r *apiTokenRepository) GetByTokenHash(db *gorm.DB, tokenHash string) *model.ApiToken {
+	if len(tokenHash) == 0 {
+		return nil
+	}
+	return r.Take(db, "token_hash = ?", tokenHash)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var CommentHistoryRepository = newCommentHistoryRepository()
+
+func newCommentHistoryRepository() *commentHistoryRepository {
+	return &commentHistoryRepository{}
+}
+
+type commentHistoryRepository struct {
+}
+
+func (r *commentHistoryRepository) Get(db *gorm.DB, id int64) *model.CommentHistory {
+	ret := &model.CommentHistory{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *commentHistoryRepository) Take(db *gorm.DB, where ...interface{}) *model.CommentHistory {
+	ret := &model.CommentHistory{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *commentHistoryRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.CommentHistory) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *commentHistoryRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.CommentHistory {
+	ret := &model.CommentHistory{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *commentHistoryRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.CommentHistory, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *commentHistoryRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.CommentHistory, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.CommentHistory{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *commentHistoryRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.CommentHistory{})
+}
+
+func (r *commentHistoryRepository) Create(db *gorm.DB, t *model.CommentHistory) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *commentHistoryRepository) Update(db *gorm.DB, t *model.CommentHistory) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *commentHistoryRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.CommentHistory{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *commentHistoryRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.CommentHistory{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *commentHistoryRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.CommentHistory{}, "id = ?", id)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var EmailOutboxRepository = newEmailOutboxRepository()
+
+func newEmailOutboxRepository() *emailOutboxRepository {
+	return &emailOutboxRepository{}
+}
+
+type emailOutboxRepository struct {
+}
+
+func (r *emailOutboxRepository) Get(db *gorm.DB, id int64) *model.EmailOutbox {
+	ret := &model.EmailOutbox{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *emailOutboxRepository) Take(db *gorm.DB, where ...interface{}) *model.EmailOutbox {
+	ret := &model.EmailOutbox{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *emailOutboxRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.EmailOutbox) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *emailOutboxRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.EmailOutbox {
+	ret := &model.EmailOutbox{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *emailOutboxRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.EmailOutbox, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *emailOutboxRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.EmailOutbox, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.EmailOutbox{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *emailOutboxRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.EmailOutbox{})
+}
+
+func (r *emailOutboxRepository) Create(db *gorm.DB, t *model.EmailOutbox) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *emailOutboxRepository) Update(db *gorm.DB, t *model.EmailOutbox) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *emailOutboxRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.EmailOutbox{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *emailOutboxRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.EmailOutbox{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *emailOutboxRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.EmailOutbox{}, "id = ?", id)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var EmailTemplateRepository = newEmailTemplateRepository()
+
+func newEmailTemplateRepository() *emailTemplateRepository {
+	return &emailTemplateRepository{}
+}
+
+type emailTemplateRepository struct {
+}
+
+func (r *emailTemplateRepository) Get(db *gorm.DB, id int64) *model.EmailTemplate {
+	ret := &model.EmailTemplate{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *emailTemplateRepository) Take(db *gorm.DB, where ...interface{}) *model.EmailTemplate {
+	ret := &model.EmailTemplate{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *emailTemplateRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.EmailTemplate) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *emailTemplateRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.EmailTemplate {
+	ret := &model.EmailTemplate{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *emailTemplateRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.EmailTemplate, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *emailTemplateRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.EmailTemplate, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.EmailTemplate{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *emailTemplateRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.EmailTemplate{})
+}
+
+func (r *emailTemplateRepository) Create(db *gorm.DB, t *model.EmailTemplate) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *emailTemplateRepository) Update(db *gorm.DB, t *model.EmailTemplate) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *emailTemplateRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.EmailTemplate{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *emailTemplateRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.EmailTemplate{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *emailTemplateRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.EmailTemplate{}, "id = ?", id)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var HashtagRepository = newHashtagRepository()
+
+func newHashtagRepository() *hashtagRepository {
+	return &hashtagRepository{}
+}
+
+type hashtagRepository struct {
+}
+
+func (r *hashtagRepository) Get(db *gorm.DB, id int64) *model.Hashtag {
+	ret := &model.Hashtag{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *hashtagRepository) Take(db *gorm.DB, where ...interface{}) *model.Hashtag {
+	ret := &model.Hashtag{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *hashtagRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.Hashtag) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *hashtagRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.Hashtag {
+	ret := &model.Hashtag{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *hashtagRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.Hashtag, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *hashtagRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.Hashtag, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.Hashtag{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *hashtagRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.Hashtag{})
+}
+
+func (r *hashtagRepository) Create(db *gorm.DB, t *model.Hashtag) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *hashtagRepository) Update(db *gorm.DB, t *model.Hashtag) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *hashtagRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.Hashtag{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *hashtagRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.Hashtag{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *hashtagRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.Hashtag{}, "id = ?", id)
+}
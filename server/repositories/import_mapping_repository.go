@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var ImportMappingRepository = newImportMappingRepository()
+
+func newImportMappingRepository() *importMappingRepository {
+	return &importMappingRepository{}
+}
+
+type importMappingRepository struct {
+}
+
+func (r *importMappingRepository) Get(db *gorm.DB, id int64) *model.ImportMapping {
+	ret := &model.ImportMapping{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *importMappingRepository) Take(db *gorm.DB, where ...interface{}) *model.ImportMapping {
+	ret := &model.ImportMapping{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *importMappingRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ImportMapping) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *importMappingRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.ImportMapping {
+	ret := &model.ImportMapping{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *importMappingRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.ImportMapping, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *importMappingRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ImportMapping, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.ImportMapping{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *importMappingRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.ImportMapping{})
+}
+
+func (r *importMappingRepository) Create(db *gorm.DB, t *model.ImportMapping) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *importMappingRepository) Update(db *gorm.DB, t *model.ImportMapping) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *importMappingRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.ImportMapping{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *importMappingRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.ImportMapping{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *importMappingRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.ImportMapping{}, "id = ?", id)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var JobRepository = newJobRepository()
+
+func newJobRepository() *jobRepository {
+	return &jobRepository{}
+}
+
+type jobRepository struct {
+}
+
+func (r *jobRepository) Get(db *gorm.DB, id int64) *model.Job {
+	ret := &model.Job{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *jobRepository) Take(db *gorm.DB, where ...interface{}) *model.Job {
+	ret := &model.Job{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *jobRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.Job) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *jobRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.Job {
+	ret := &model.Job{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *jobRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.Job, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *jobRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.Job, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.Job{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *jobRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.Job{})
+}
+
+func (r *jobRepository) Create(db *gorm.DB, t *model.Job) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *jobRepository) Update(db *gorm.DB, t *model.Job) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *jobRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.Job{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *jobRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.Job{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *jobRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.Job{}, "id = ?", id)
+}
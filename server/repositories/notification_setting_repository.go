@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var NotificationSettingRepository = newNotificationSettingRepository()
+
+func newNotificationSettingRepository() *notificationSettingRepository {
+	return &notificationSettingRepository{}
+}
+
+type notificationSettingRepository struct {
+}
+
+func (r *notificationSettingRepository) Get(db *gorm.DB, id int64) *model.NotificationSetting {
+	ret := &model.NotificationSetting{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *notificationSettingRepository) Take(db *gorm.DB, where ...interface{}) *model.NotificationSetting {
+	ret := &model.NotificationSetting{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *notificationSettingRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.NotificationSetting) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *notificationSettingRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.NotificationSetting {
+	ret := &model.NotificationSetting{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *notificationSettingRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.NotificationSetting, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *notificationSettingRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.NotificationSetting, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.NotificationSetting{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *notificationSettingRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.NotificationSetting{})
+}
+
+func (r *notificationSettingRepository) Create(db *gorm.DB, t *model.NotificationSetting) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *notificationSettingRepository) Update(db *gorm.DB, t *model.NotificationSetting) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *notificationSettingRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.NotificationSetting{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *notificationSettingRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.NotificationSetting{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *notificationSettingRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.NotificationSetting{}, "id = ?", id)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var ReactionCountRepository = newReactionCountRepository()
+
+func newReactionCountRepository() *reactionCountRepository {
+	return &reactionCountRepository{}
+}
+
+type reactionCountRepository struct {
+}
+
+func (r *reactionCountRepository) Get(db *gorm.DB, id int64) *model.ReactionCount {
+	ret := &model.ReactionCount{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *reactionCountRepository) Take(db *gorm.DB, where ...interface{}) *model.ReactionCount {
+	ret := &model.ReactionCount{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *reactionCountRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ReactionCount) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *reactionCountRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.ReactionCount {
+	ret := &model.ReactionCount{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *reactionCountRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.ReactionCount, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *reactionCountRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.ReactionCount, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.ReactionCount{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *reactionCountRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.ReactionCount{})
+}
+
+func (r *reactionCountRepository) Create(db *gorm.DB, t *model.ReactionCount) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *reactionCountRepository) Update(db *gorm.DB, t *model.ReactionCount) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *reactionCountRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.ReactionCount{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *reactionCountRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.ReactionCount{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *reactionCountRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.ReactionCount{}, "id = ?", id)
+}
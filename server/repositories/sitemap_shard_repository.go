@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var SitemapShardRepository = newSitemapShardRepository()
+
+func newSitemapShardRepository() *sitemapShardRepository {
+	return &sitemapShardRepository{}
+}
+
+type sitemapShardRepository struct {
+}
+
+func (r *sitemapShardRepository) Get(db *gorm.DB, id int64) *model.SitemapShard {
+	ret := &model.SitemapShard{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *sitemapShardRepository) Take(db *gorm.DB, where ...interface{}) *model.SitemapShard {
+	ret := &model.SitemapShard{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *sitemapShardRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.SitemapShard) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *sitemapShardRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.SitemapShard {
+	ret := &model.SitemapShard{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *sitemapShardRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.SitemapShard, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *sitemapShardRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.SitemapShard, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.SitemapShard{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *sitemapShardRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.SitemapShard{})
+}
+
+func (r *sitemapShardRepository) Create(db *gorm.DB, t *model.SitemapShard) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *sitemapShardRepository) Update(db *gorm.DB, t *model.SitemapShard) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *sitemapShardRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.SitemapShard{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *sitemapShardRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.SitemapShard{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *sitemapShardRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.SitemapShard{}, "id = ?", id)
+}
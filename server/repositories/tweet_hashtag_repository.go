@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var TweetHashtagRepository = newTweetHashtagRepository()
+
+func newTweetHashtagRepository() *tweetHashtagRepository {
+	return &tweetHashtagRepository{}
+}
+
+type tweetHashtagRepository struct {
+}
+
+func (r *tweetHashtagRepository) Get(db *gorm.DB, id int64) *model.TweetHashtag {
+	ret := &model.TweetHashtag{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *tweetHashtagRepository) Take(db *gorm.DB, where ...interface{}) *model.TweetHashtag {
+	ret := &model.TweetHashtag{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *tweetHashtagRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.TweetHashtag) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *tweetHashtagRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.TweetHashtag {
+	ret := &model.TweetHashtag{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *tweetHashtagRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.TweetHashtag, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *tweetHashtagRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.TweetHashtag, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.TweetHashtag{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *tweetHashtagRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.TweetHashtag{})
+}
+
+func (r *tweetHashtagRepository) Create(db *gorm.DB, t *model.TweetHashtag) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *tweetHashtagRepository) Update(db *gorm.DB, t *model.TweetHashtag) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *tweetHashtagRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.TweetHashtag{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *tweetHashtagRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.TweetHashtag{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *tweetHashtagRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.TweetHashtag{}, "id = ?", id)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var UserDeletionRepository = newUserDeletionRepository()
+
+func newUserDeletionRepository() *userDeletionRepository {
+	return &userDeletionRepository{}
+}
+
+type userDeletionRepository struct {
+}
+
+func (r *userDeletionRepository) Get(db *gorm.DB, id int64) *model.UserDeletion {
+	ret := &model.UserDeletion{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userDeletionRepository) Take(db *gorm.DB, where ...interface{}) *model.UserDeletion {
+	ret := &model.UserDeletion{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userDeletionRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.UserDeletion) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *userDeletionRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.UserDeletion {
+	ret := &model.UserDeletion{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userDeletionRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.UserDeletion, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *userDeletionRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.UserDeletion, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.UserDeletion{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *userDeletionRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.UserDeletion{})
+}
+
+func (r *userDeletionRepository) Create(db *gorm.DB, t *model.UserDeletion) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *userDeletionRepository) Update(db *gorm.DB, t *model.UserDeletion) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *userDeletionRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.UserDeletion{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *userDeletionRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.UserDeletion{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *userDeletionRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.UserDeletion{}, "id = ?", id)
+}
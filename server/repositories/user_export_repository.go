@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var UserExportRepository = newUserExportRepository()
+
+func newUserExportRepository() *userExportRepository {
+	return &userExportRepository{}
+}
+
+type userExportRepository struct {
+}
+
+func (r *userExportRepository) Get(db *gorm.DB, id int64) *model.UserExport {
+	ret := &model.UserExport{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userExportRepository) Take(db *gorm.DB, where ...interface{}) *model.UserExport {
+	ret := &model.UserExport{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userExportRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.UserExport) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *userExportRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.UserExport {
+	ret := &model.UserExport{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userExportRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.UserExport, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *userExportRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.UserExport, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.UserExport{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *userExportRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.UserExport{})
+}
+
+func (r *userExportRepository) Create(db *gorm.DB, t *model.UserExport) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *userExportRepository) Update(db *gorm.DB, t *model.UserExport) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *userExportRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.UserExport{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *userExportRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.UserExport{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *userExportRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.UserExport{}, "id = ?", id)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var UserReactionRepository = newUserReactionRepository()
+
+func newUserReactionRepository() *userReactionRepository {
+	return &userReactionRepository{}
+}
+
+type userReactionRepository struct {
+}
+
+func (r *userReactionRepository) Get(db *gorm.DB, id int64) *model.UserReaction {
+	ret := &model.UserReaction{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userReactionRepository) Take(db *gorm.DB, where ...interface{}) *model.UserReaction {
+	ret := &model.UserReaction{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userReactionRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.UserReaction) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *userReactionRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.UserReaction {
+	ret := &model.UserReaction{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *userReactionRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.UserReaction, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *userReactionRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.UserReaction, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.UserReaction{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *userReactionRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.UserReaction{})
+}
+
+func (r *userReactionRepository) Create(db *gorm.DB, t *model.UserReaction) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *userReactionRepository) Update(db *gorm.DB, t *model.UserReaction) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *userReactionRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.UserReaction{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *userReactionRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.UserReaction{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *userReactionRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.UserReaction{}, "id = ?", id)
+}
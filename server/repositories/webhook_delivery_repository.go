@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var WebhookDeliveryRepository = newWebhookDeliveryRepository()
+
+func newWebhookDeliveryRepository() *webhookDeliveryRepository {
+	return &webhookDeliveryRepository{}
+}
+
+type webhookDeliveryRepository struct {
+}
+
+func (r *webhookDeliveryRepository) Get(db *gorm.DB, id int64) *model.WebhookDelivery {
+	ret := &model.WebhookDelivery{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *webhookDeliveryRepository) Take(db *gorm.DB, where ...interface{}) *model.WebhookDelivery {
+	ret := &model.WebhookDelivery{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *webhookDeliveryRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.WebhookDelivery) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *webhookDeliveryRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.WebhookDelivery {
+	ret := &model.WebhookDelivery{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *webhookDeliveryRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.WebhookDelivery, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *webhookDeliveryRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.WebhookDelivery, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.WebhookDelivery{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *webhookDeliveryRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.WebhookDelivery{})
+}
+
+func (r *webhookDeliveryRepository) Create(db *gorm.DB, t *model.WebhookDelivery) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *webhookDeliveryRepository) Update(db *gorm.DB, t *model.WebhookDelivery) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *webhookDeliveryRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.WebhookDelivery{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *webhookDeliveryRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.WebhookDelivery{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *webhookDeliveryRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.WebhookDelivery{}, "id = ?", id)
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"bbs-go/model"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+)
+
+var WebhookRepository = newWebhookRepository()
+
+func newWebhookRepository() *webhookRepository {
+	return &webhookRepository{}
+}
+
+type webhookRepository struct {
+}
+
+func (r *webhookRepository) Get(db *gorm.DB, id int64) *model.Webhook {
+	ret := &model.Webhook{}
+	if err := db.First(ret, "id = ?", id).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *webhookRepository) Take(db *gorm.DB, where ...interface{}) *model.Webhook {
+	ret := &model.Webhook{}
+	if err := db.Take(ret, where...).Error; err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *webhookRepository) Find(db *gorm.DB, cnd *simple.SqlCnd) (list []model.Webhook) {
+	cnd.Find(db, &list)
+	return
+}
+
+func (r *webhookRepository) FindOne(db *gorm.DB, cnd *simple.SqlCnd) *model.Webhook {
+	ret := &model.Webhook{}
+	if err := cnd.FindOne(db, &ret); err != nil {
+		return nil
+	}
+	return ret
+}
+
+func (r *webhookRepository) FindPageByParams(db *gorm.DB, params *simple.QueryParams) (list []model.Webhook, paging *simple.Paging) {
+	return r.FindPageByCnd(db, &params.SqlCnd)
+}
+
+func (r *webhookRepository) FindPageByCnd(db *gorm.DB, cnd *simple.SqlCnd) (list []model.Webhook, paging *simple.Paging) {
+	cnd.Find(db, &list)
+	count := cnd.Count(db, &model.Webhook{})
+
+	paging = &simple.Paging{
+		Page:  cnd.Paging.Page,
+		Limit: cnd.Paging.Limit,
+		Total: count,
+	}
+	return
+}
+
+func (r *webhookRepository) Count(db *gorm.DB, cnd *simple.SqlCnd) int {
+	return cnd.Count(db, &model.Webhook{})
+}
+
+func (r *webhookRepository) Create(db *gorm.DB, t *model.Webhook) (err error) {
+	err = db.Create(t).Error
+	return
+}
+
+func (r *webhookRepository) Update(db *gorm.DB, t *model.Webhook) (err error) {
+	err = db.Save(t).Error
+	return
+}
+
+func (r *webhookRepository) Updates(db *gorm.DB, id int64, columns map[string]interface{}) (err error) {
+	err = db.Model(&model.Webhook{}).Where("id = ?", id).Updates(columns).Error
+	return
+}
+
+func (r *webhookRepository) UpdateColumn(db *gorm.DB, id int64, name string, value interface{}) (err error) {
+	err = db.Model(&model.Webhook{}).Where("id = ?", id).UpdateColumn(name, value).Error
+	return
+}
+
+func (r *webhookRepository) Delete(db *gorm.DB, id int64) {
+	db.Delete(&model.Webhook{}, "id = ?", id)
+}
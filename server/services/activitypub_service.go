@@ -0,0 +1,811 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+	"github.com/mlogclub/simple/markdown"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/cache"
+	"bbs-go/common/activitypub"
+	"bbs-go/common/urls"
+	"bbs-go/config"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+const (
+	apActorRefreshInterval = 24 * time.Hour // 远程用户信息的刷新间隔
+	apOutboxSize           = 20             // 发件箱中展示的最近内容数量
+	apNicknameMaxLen       = 16             // 影子用户昵称最大长度，与 User.Nickname 字段长度一致
+)
+
+// ErrApSignature 收件箱请求的签名校验失败
+var ErrApSignature = errors.New("activitypub: invalid signature")
+
+// 本地内容在ActivityPub地址中的路径
+var apEntityPaths = map[string]string{
+	constants.EntityTopic:   "topics",
+	constants.EntityArticle: "articles",
+	constants.EntityTweet:   "tweets",
+}
+
+var ActivityPubService = newActivityPubService()
+
+func newActivityPubService() *activityPubService {
+	return &activityPubService{}
+}
+
+type activityPubService struct {
+}
+
+// 推送内容任务参数
+type apPublishJob struct {
+	EntityType string `json:"entityType"`
+	EntityId   int64  `json:"entityId"`
+	Delete     bool   `json:"delete"` // 是否为删除
+}
+
+// 投递活动任务参数
+type apDeliverJob struct {
+	UserId   int64           `json:"userId"` // 发送者，使用其密钥签名
+	Inbox    string          `json:"inbox"`
+	Activity json.RawMessage `json:"activity"`
+}
+
+func (s *activityPubService) Enabled() bool {
+	return config.Instance.ActivityPub.Enabled
+}
+
+// ActorId 本地用户地址
+func (s *activityPubService) ActorId(userId int64) string {
+	return urls.AbsUrl("/ap/users/" + strconv.FormatInt(userId, 10))
+}
+
+// ObjectId 本地内容地址
+func (s *activityPubService) ObjectId(entityType string, entityId int64) string {
+	return urls.AbsUrl("/ap/" + apEntityPaths[entityType] + "/" + strconv.FormatInt(entityId, 10))
+}
+
+func (s *activityPubService) keyId(userId int64) string {
+	return s.ActorId(userId) + "#main-key"
+}
+
+// host 本站域名，用于WebFinger和校验签名
+func (s *activityPubService) host() string {
+	u, err := url.Parse(config.Instance.BaseUrl)
+	if err != nil {
+		logrus.Error(err)
+		return ""
+	}
+	return u.Host
+}
+
+// GetLocalUser 可以被关注的本地用户
+func (s *activityPubService) GetLocalUser(userId int64) *model.User {
+	user := cache.UserCache.Get(userId)
+	if user == nil || user.Status != constants.StatusOk || user.Type == constants.UserTypeRemote {
+		return nil
+	}
+	return user
+}
+
+// preferredUsername 用户名，没有设置用户名的用户使用编号
+func (s *activityPubService) preferredUsername(user *model.User) string {
+	if user.Username.Valid && len(user.Username.String) > 0 {
+		return user.Username.String
+	}
+	return strconv.FormatInt(user.Id, 10)
+}
+
+// WebFinger 查询本地用户，resource 格式为：acct:用户名@域名，或者用户地址
+func (s *activityPubService) WebFinger(resource string) *activitypub.WebFinger {
+	var user *model.User
+	if userId := s.parseLocalId(resource, "users"); userId > 0 {
+		user = s.GetLocalUser(userId)
+	} else {
+		acct := strings.TrimPrefix(resource, "acct:")
+		at := strings.LastIndex(acct, "@")
+		if at <= 0 || !strings.EqualFold(acct[at+1:], s.host()) {
+			return nil
+		}
+		name := acct[:at]
+		if u := UserService.GetByUsername(name); u != nil {
+			user = s.GetLocalUser(u.Id)
+		} else if userId, err := strconv.ParseInt(name, 10, 64); err == nil {
+			user = s.GetLocalUser(userId)
+		}
+	}
+	if user == nil {
+		return nil
+	}
+	actorId := s.ActorId(user.Id)
+	return &activitypub.WebFinger{
+		Subject: "acct:" + s.preferredUsername(user) + "@" + s.host(),
+		Aliases: []string{actorId, urls.UserUrl(user.Id)},
+		Links: []activitypub.WebFingerLink{
+			{Rel: "self", Type: activitypub.ContentType, Href: actorId},
+			{Rel: "http://webfinger.net/rel/profile-page", Type: "text/html", Href: urls.UserUrl(user.Id)},
+		},
+	}
+}
+
+// BuildActor 本地用户对应的Actor
+func (s *activityPubService) BuildActor(user *model.User) (*activitypub.Actor, error) {
+	key, err := s.getKey(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	actorId := s.ActorId(user.Id)
+	actor := &activitypub.Actor{
+		Context:           []string{activitypub.ContextActivityStreams, activitypub.ContextSecurity},
+		Id:                actorId,
+		Type:              activitypub.TypePerson,
+		PreferredUsername: s.preferredUsername(user),
+		Name:              user.Nickname,
+		Summary:           html.EscapeString(user.Description),
+		Url:               activitypub.Iri(urls.UserUrl(user.Id)),
+		Inbox:             actorId + "/inbox",
+		Outbox:            actorId + "/outbox",
+		Followers:         actorId + "/followers",
+		Endpoints:         &activitypub.Endpoints{SharedInbox: urls.AbsUrl("/ap/inbox")},
+		PublicKey: &activitypub.PublicKey{
+			Id:           s.keyId(user.Id),
+			Owner:        actorId,
+			PublicKeyPem: key.PublicKey,
+		},
+	}
+	if len(user.Avatar) > 0 {
+		actor.Icon = &activitypub.Image{Type: "Image", Url: user.Avatar}
+	}
+	return actor, nil
+}
+
+// getKey 获取用户密钥，不存在时生成
+func (s *activityPubService) getKey(userId int64) (*model.ApActorKey, error) {
+	if key := repositories.ApActorKeyRepository.Take(simple.DB(), "user_id = ?", userId); key != nil {
+		return key, nil
+	}
+	privateKey, publicKey, err := activitypub.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	key := &model.ApActorKey{
+		UserId:     userId,
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+		CreateTime: simple.NowTimestamp(),
+	}
+	if err := repositories.ApActorKeyRepository.Create(simple.DB(), key); err != nil {
+		// 并发生成时使用先保存的密钥
+		if exists := repositories.ApActorKeyRepository.Take(simple.DB(), "user_id = ?", userId); exists != nil {
+			return exists, nil
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// BuildObject 本地内容对应的对象，内容不存在、未公开或作者不能被关注时返回nil
+func (s *activityPubService) BuildObject(entityType string, entityId int64) *activitypub.Object {
+	var (
+		object *activitypub.Object
+		userId int64
+	)
+	switch entityType {
+	case constants.EntityTopic:
+		topic := TopicService.Get(entityId)
+		if topic == nil || topic.Status != constants.StatusOk {
+			return nil
+		}
+		topicUrl := urls.TopicUrl(topic.Id)
+		content, _ := markdown.New(markdown.SummaryLen(0)).Run(topic.Content)
+		userId = topic.UserId
+		object = &activitypub.Object{
+			Type:      activitypub.TypeNote,
+			Name:      topic.Title,
+			Content:   `<p><a href="` + topicUrl + `">` + html.EscapeString(topic.Title) + `</a></p>` + content,
+			Url:       activitypub.Iri(topicUrl),
+			Published: s.formatTime(topic.CreateTime),
+		}
+	case constants.EntityArticle:
+		article := ArticleService.Get(entityId)
+		if article == nil || article.Status != constants.StatusOk {
+			return nil
+		}
+		content := article.Content
+		if article.ContentType == constants.ContentTypeMarkdown {
+			content, _ = markdown.New(markdown.SummaryLen(0)).Run(article.Content)
+		}
+		userId = article.UserId
+		object = &activitypub.Object{
+			Type:      activitypub.TypeArticle,
+			Name:      article.Title,
+			Summary:   html.EscapeString(article.Summary),
+			Content:   content,
+			Url:       activitypub.Iri(urls.ArticleUrl(article.Id)),
+			Published: s.formatTime(article.CreateTime),
+		}
+		if article.UpdateTime > article.CreateTime {
+			object.Updated = s.formatTime(article.UpdateTime)
+		}
+	case constants.EntityTweet:
+		tweet := TweetService.Get(entityId)
+		if tweet == nil || tweet.Status != constants.StatusOk || simple.IsBlank(tweet.Content) {
+			return nil // 直接转发的动态没有内容，不推送
+		}
+		userId = tweet.UserId
+		object = &activitypub.Object{
+			Type:      activitypub.TypeNote,
+			Content:   "<p>" + strings.ReplaceAll(html.EscapeString(tweet.Content), "\n", "<br>") + "</p>",
+			Url:       activitypub.Iri(urls.TweetUrl(tweet.Id)),
+			Published: s.formatTime(tweet.CreateTime),
+		}
+	default:
+		return nil
+	}
+	if s.GetLocalUser(userId) == nil {
+		return nil
+	}
+	actorId := s.ActorId(userId)
+	object.Id = s.ObjectId(entityType, entityId)
+	object.AttributedTo = activitypub.Iri(actorId)
+	object.To = activitypub.IriList{activitypub.Public}
+	object.Cc = activitypub.IriList{actorId + "/followers"}
+	return object
+}
+
+// buildCreate 发表内容的活动
+func (s *activityPubService) buildCreate(object *activitypub.Object) (*activitypub.Activity, error) {
+	activity, err := activitypub.NewActivity(object.Id+"/activity", activitypub.TypeCreate, string(object.AttributedTo), object)
+	if err != nil {
+		return nil, err
+	}
+	activity.To = object.To
+	activity.Cc = object.Cc
+	activity.Published = object.Published
+	return activity, nil
+}
+
+// Outbox 用户最近发表的内容
+func (s *activityPubService) Outbox(user *model.User) (*activitypub.OrderedCollection, error) {
+	var objects []*activitypub.Object
+	cnd := func() *simple.SqlCnd {
+		return simple.NewSqlCnd("id").Eq("user_id", user.Id).Eq("status", constants.StatusOk).Desc("id").Limit(apOutboxSize)
+	}
+	for _, topic := range repositories.TopicRepository.Find(simple.DB(), cnd()) {
+		if object := s.BuildObject(constants.EntityTopic, topic.Id); object != nil {
+			objects = append(objects, object)
+		}
+	}
+	for _, article := range repositories.ArticleRepository.Find(simple.DB(), cnd()) {
+		if object := s.BuildObject(constants.EntityArticle, article.Id); object != nil {
+			objects = append(objects, object)
+		}
+	}
+	for _, tweet := range repositories.TweetRepository.Find(simple.DB(), cnd()) {
+		if object := s.BuildObject(constants.EntityTweet, tweet.Id); object != nil {
+			objects = append(objects, object)
+		}
+	}
+	// 发布时间均为UTC时间，可以直接按字符串排序
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Published > objects[j].Published
+	})
+	if len(objects) > apOutboxSize {
+		objects = objects[:apOutboxSize]
+	}
+
+	ret := &activitypub.OrderedCollection{
+		Context:    activitypub.ContextActivityStreams,
+		Id:         s.ActorId(user.Id) + "/outbox",
+		Type:       activitypub.TypeOrderedCollection,
+		TotalItems: len(objects),
+	}
+	for _, object := range objects {
+		activity, err := s.buildCreate(object)
+		if err != nil {
+			return nil, err
+		}
+		activity.Context = nil
+		ret.OrderedItems = append(ret.OrderedItems, activity)
+	}
+	return ret, nil
+}
+
+// Followers 关注者列表，只公开数量
+func (s *activityPubService) Followers(user *model.User) *activitypub.OrderedCollection {
+	return &activitypub.OrderedCollection{
+		Context:    activitypub.ContextActivityStreams,
+		Id:         s.ActorId(user.Id) + "/followers",
+		Type:       activitypub.TypeOrderedCollection,
+		TotalItems: repositories.ApFollowerRepository.Count(simple.DB(), simple.NewSqlCnd().Eq("user_id", user.Id)),
+	}
+}
+
+// OnPublished 内容发表后推送给作者的关注者
+func (s *activityPubService) OnPublished(entityType string, entityId int64) {
+	s.enqueuePublish(&apPublishJob{EntityType: entityType, EntityId: entityId})
+}
+
+// OnDeleted 内容删除后通知作者的关注者
+func (s *activityPubService) OnDeleted(entityType string, entityId int64) {
+	s.enqueuePublish(&apPublishJob{EntityType: entityType, EntityId: entityId, Delete: true})
+}
+
+func (s *activityPubService) enqueuePublish(job *apPublishJob) {
+	if !s.Enabled() || len(apEntityPaths[job.EntityType]) == 0 {
+		return
+	}
+	if _, err := JobService.Enqueue(constants.JobTypeApPublish, job); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// Publish 生成活动，并按关注者的收件箱拆分为投递任务
+func (s *activityPubService) Publish(job *apPublishJob) error {
+	var (
+		activity *activitypub.Activity
+		userId   int64
+		err      error
+	)
+	if job.Delete {
+		if userId = s.getEntityUserId(job.EntityType, job.EntityId); userId == 0 {
+			return nil
+		}
+		objectId := s.ObjectId(job.EntityType, job.EntityId)
+		activity, err = activitypub.NewActivity(objectId+"/delete", activitypub.TypeDelete, s.ActorId(userId),
+			&activitypub.Object{Id: objectId, Type: activitypub.TypeTombstone})
+		if err != nil {
+			return err
+		}
+		activity.To = activitypub.IriList{activitypub.Public}
+	} else {
+		object := s.BuildObject(job.EntityType, job.EntityId)
+		if object == nil {
+			return nil
+		}
+		if activity, err = s.buildCreate(object); err != nil {
+			return err
+		}
+		userId = s.getEntityUserId(job.EntityType, job.EntityId)
+	}
+
+	var inboxes []string
+	if err := simple.DB().Model(&model.ApFollower{}).Where("user_id = ?", userId).
+		Pluck("distinct inbox", &inboxes).Error; err != nil {
+		return err
+	}
+	for _, inbox := range inboxes {
+		if err := s.enqueueDeliver(userId, inbox, activity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *activityPubService) getEntityUserId(entityType string, entityId int64) int64 {
+	switch entityType {
+	case constants.EntityTopic:
+		if topic := TopicService.Get(entityId); topic != nil {
+			return topic.UserId
+		}
+	case constants.EntityArticle:
+		if article := ArticleService.Get(entityId); article != nil {
+			return article.UserId
+		}
+	case constants.EntityTweet:
+		if tweet := TweetService.Get(entityId); tweet != nil {
+			return tweet.UserId
+		}
+	}
+	return 0
+}
+
+func (s *activityPubService) enqueueDeliver(userId int64, inbox string, activity *activitypub.Activity) error {
+	if s.isBlocked(inbox) {
+		return nil
+	}
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return err
+	}
+	_, err = JobService.Enqueue(constants.JobTypeApDeliver, &apDeliverJob{
+		UserId:   userId,
+		Inbox:    inbox,
+		Activity: data,
+	})
+	return err
+}
+
+// Deliver 使用发送者的密钥签名后投递，失败时由任务队列重试
+func (s *activityPubService) Deliver(job *apDeliverJob) error {
+	// 注销时已删除密钥和关注者，不再投递，否则会重新生成密钥
+	if user := cache.UserCache.Get(job.UserId); user == nil || user.Status == constants.UserStatusCanceled {
+		return nil
+	}
+	key, err := s.getKey(job.UserId)
+	if err != nil {
+		return err
+	}
+	return activitypub.Post(job.Inbox, s.keyId(job.UserId), key.PrivateKey, job.Activity)
+}
+
+// HandleInbox 处理收件箱收到的活动，body 为原始请求内容，用于校验签名
+func (s *activityPubService) HandleInbox(r *http.Request, body []byte) error {
+	activity := &activitypub.Activity{}
+	if err := json.Unmarshal(body, activity); err != nil {
+		return errors.New("invalid activity")
+	}
+	actorId := string(activity.Actor)
+	if len(actorId) == 0 || s.isBlocked(actorId) {
+		return errors.New("actor not allowed")
+	}
+	actor, err := s.verify(r, body, actorId)
+	if err != nil {
+		return err
+	}
+
+	switch activity.Type {
+	case activitypub.TypeFollow:
+		return s.onFollow(actor, activity)
+	case activitypub.TypeUndo:
+		return s.onUndo(actor, activity)
+	case activitypub.TypeCreate:
+		return s.onCreate(r.Context(), actor, activity)
+	case activitypub.TypeDelete:
+		return s.onDelete(r.Context(), actor, activity)
+	case activitypub.TypeUpdate:
+		return s.onUpdate(actor, activity)
+	}
+	return nil // 其他活动暂不处理
+}
+
+// verify 校验请求签名，签名的密钥必须属于活动的发起者
+func (s *activityPubService) verify(r *http.Request, body []byte, actorId string) (*model.ApRemoteActor, error) {
+	signature, err := activitypub.ParseSignature(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrApSignature, err)
+	}
+	// 获取远程用户前先确认签名的密钥与发起者在同一个域名下，避免通过伪造的 actor 访问任意地址
+	if err := activitypub.CheckUrl(actorId); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrApSignature, err)
+	}
+	if !s.sameHost(signature.KeyId, actorId) {
+		return nil, fmt.Errorf("%w: key %s does not belong to %s", ErrApSignature, signature.KeyId, actorId)
+	}
+	actor, err := s.getRemoteActor(actorId, false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrApSignature, err)
+	}
+	if actor.PublicKeyId == signature.KeyId && signature.Verify(r, s.host(), actor.PublicKey, body) == nil {
+		return actor, nil
+	}
+
+	// 远程用户可能更换了密钥，重新获取后再校验一次
+	if actor, err = s.getRemoteActor(actorId, true); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrApSignature, err)
+	}
+	if actor.PublicKeyId != signature.KeyId {
+		return nil, fmt.Errorf("%w: key %s does not belong to %s", ErrApSignature, signature.KeyId, actorId)
+	}
+	if err := signature.Verify(r, s.host(), actor.PublicKey, body); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrApSignature, err)
+	}
+	return actor, nil
+}
+
+// getRemoteActor 获取远程用户，本地保存的信息超过刷新间隔或者 refresh 为true时重新获取
+func (s *activityPubService) getRemoteActor(actorId string, refresh bool) (*model.ApRemoteActor, error) {
+	actor := repositories.ApRemoteActorRepository.Take(simple.DB(), "actor_id = ?", actorId)
+	if actor != nil && !refresh &&
+		time.Since(simple.TimeFromTimestamp(actor.UpdateTime)) < apActorRefreshInterval {
+		return actor, nil
+	}
+
+	remote := &activitypub.Actor{}
+	if err := activitypub.Get(actorId, "", "", remote); err != nil {
+		if actor != nil && !refresh {
+			logrus.Warn("Refresh remote actor error: ", actorId, ", ", err)
+			return actor, nil
+		}
+		return nil, err
+	}
+	if remote.Id != actorId || activitypub.CheckUrl(remote.Inbox) != nil || remote.PublicKey == nil || remote.PublicKey.Owner != actorId {
+		return nil, errors.New("invalid actor: " + actorId)
+	}
+	if remote.Endpoints != nil && len(remote.Endpoints.SharedInbox) > 0 && activitypub.CheckUrl(remote.Endpoints.SharedInbox) != nil {
+		remote.Endpoints.SharedInbox = ""
+	}
+
+	u, err := url.Parse(actorId)
+	if err != nil {
+		return nil, err
+	}
+	now := simple.NowTimestamp()
+	if actor == nil {
+		actor = &model.ApRemoteActor{ActorId: actorId, CreateTime: now}
+	}
+	actor.Username = remote.PreferredUsername
+	actor.Name = remote.Name
+	actor.Domain = u.Host
+	actor.Url = string(remote.Url)
+	actor.Inbox = remote.Inbox
+	actor.SharedInbox = ""
+	if remote.Endpoints != nil {
+		actor.SharedInbox = remote.Endpoints.SharedInbox
+	}
+	actor.Avatar = ""
+	if remote.Icon != nil {
+		actor.Avatar = remote.Icon.Url
+	}
+	actor.PublicKeyId = remote.PublicKey.Id
+	actor.PublicKey = remote.PublicKey.PublicKeyPem
+	actor.UpdateTime = now
+
+	err = simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		if actor.Id > 0 {
+			if err := repositories.ApRemoteActorRepository.Update(tx, actor); err != nil {
+				return err
+			}
+		} else if err := repositories.ApRemoteActorRepository.Create(tx, actor); err != nil {
+			return err
+		}
+		if actor.UserId > 0 {
+			return repositories.UserRepository.Updates(tx, actor.UserId, map[string]interface{}{
+				"nickname":    s.shadowNickname(actor),
+				"avatar":      actor.Avatar,
+				"home_page":   s.shadowHomePage(actor),
+				"update_time": now,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if actor.UserId > 0 {
+		cache.UserCache.Invalidate(actor.UserId)
+	}
+	return actor, nil
+}
+
+// getShadowUser 远程用户对应的本地影子用户，不存在时创建
+func (s *activityPubService) getShadowUser(actor *model.ApRemoteActor) (*model.User, error) {
+	if actor.UserId > 0 {
+		if user := UserService.Get(actor.UserId); user != nil {
+			return user, nil
+		}
+	}
+	now := simple.NowTimestamp()
+	user := &model.User{
+		Nickname:    s.shadowNickname(actor),
+		Avatar:      actor.Avatar,
+		HomePage:    s.shadowHomePage(actor),
+		Description: "@" + actor.Username + "@" + actor.Domain,
+		Status:      constants.StatusOk,
+		Type:        constants.UserTypeRemote,
+		CreateTime:  now,
+		UpdateTime:  now,
+	}
+	err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		if err := repositories.UserRepository.Create(tx, user); err != nil {
+			return err
+		}
+		return repositories.ApRemoteActorRepository.UpdateColumn(tx, actor.Id, "user_id", user.Id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	actor.UserId = user.Id
+	return user, nil
+}
+
+func (s *activityPubService) shadowNickname(actor *model.ApRemoteActor) string {
+	nickname := []rune(simple.DefaultIfBlank(actor.Name, actor.Username))
+	if len(nickname) > apNicknameMaxLen {
+		nickname = nickname[:apNicknameMaxLen]
+	}
+	return string(nickname)
+}
+
+func (s *activityPubService) shadowHomePage(actor *model.ApRemoteActor) string {
+	return simple.DefaultIfBlank(actor.Url, actor.ActorId)
+}
+
+// onFollow 远程用户关注本地用户，自动接受
+func (s *activityPubService) onFollow(actor *model.ApRemoteActor, activity *activitypub.Activity) error {
+	user := s.GetLocalUser(s.parseLocalId(activity.ObjectId(), "users"))
+	if user == nil {
+		return errors.New("user not found")
+	}
+	inbox := simple.DefaultIfBlank(actor.SharedInbox, actor.Inbox)
+	follower := repositories.ApFollowerRepository.Take(simple.DB(), "user_id = ? and actor_id = ?", user.Id, actor.ActorId)
+	if follower == nil {
+		if err := repositories.ApFollowerRepository.Create(simple.DB(), &model.ApFollower{
+			UserId:     user.Id,
+			ActorId:    actor.ActorId,
+			Inbox:      inbox,
+			CreateTime: simple.NowTimestamp(),
+		}); err != nil {
+			return err
+		}
+	} else if follower.Inbox != inbox {
+		if err := repositories.ApFollowerRepository.UpdateColumn(simple.DB(), follower.Id, "inbox", inbox); err != nil {
+			return err
+		}
+	}
+
+	actorId := s.ActorId(user.Id)
+	accept, err := activitypub.NewActivity(actorId+"#accepts/"+simple.UUID(), activitypub.TypeAccept, actorId, activity)
+	if err != nil {
+		return err
+	}
+	accept.To = activitypub.IriList{actor.ActorId}
+	return s.enqueueDeliver(user.Id, actor.Inbox, accept)
+}
+
+// onUndo 目前只处理取消关注
+func (s *activityPubService) onUndo(actor *model.ApRemoteActor, activity *activitypub.Activity) error {
+	undo := &activitypub.Activity{}
+	if err := activity.DecodeObject(undo); err != nil || undo.Type != activitypub.TypeFollow ||
+		string(undo.Actor) != actor.ActorId {
+		return nil
+	}
+	userId := s.parseLocalId(undo.ObjectId(), "users")
+	if follower := repositories.ApFollowerRepository.Take(simple.DB(), "user_id = ? and actor_id = ?",
+		userId, actor.ActorId); follower != nil {
+		repositories.ApFollowerRepository.Delete(simple.DB(), follower.Id)
+	}
+	return nil
+}
+
+// onCreate 保存对本地内容的回复，其他内容忽略
+func (s *activityPubService) onCreate(ctx context.Context, actor *model.ApRemoteActor, activity *activitypub.Activity) error {
+	object := &activitypub.Object{}
+	if err := activity.DecodeObject(object); err != nil ||
+		(object.Type != activitypub.TypeNote && object.Type != activitypub.TypeArticle) {
+		return nil
+	}
+	if string(object.AttributedTo) != actor.ActorId || !s.sameHost(object.Id, actor.ActorId) {
+		return errors.New("object is not attributed to actor")
+	}
+	if len(object.InReplyTo) == 0 {
+		return nil
+	}
+	if repositories.ApRemoteObjectRepository.Take(simple.DB(), "object_id = ?", object.Id) != nil {
+		return nil // 重复投递
+	}
+	entityType, entityId, parentId := s.resolveReplyTarget(string(object.InReplyTo))
+	if len(entityType) == 0 {
+		return nil
+	}
+	content := strings.TrimSpace(simple.GetHtmlText(object.Content))
+	if len(content) == 0 {
+		return nil
+	}
+
+	user, err := s.getShadowUser(actor)
+	if err != nil {
+		return err
+	}
+	if user.Status != constants.StatusOk || user.IsForbidden() {
+		return nil
+	}
+	comment, err := CommentService.Publish(ctx, user.Id, &model.CreateCommentForm{
+		EntityType:  entityType,
+		EntityId:    entityId,
+		Content:     content,
+		ContentType: constants.ContentTypeText,
+		ParentId:    parentId,
+	})
+	if err != nil {
+		return err
+	}
+	return repositories.ApRemoteObjectRepository.Create(simple.DB(), &model.ApRemoteObject{
+		ObjectId:   object.Id,
+		ActorId:    actor.ActorId,
+		EntityType: constants.EntityComment,
+		EntityId:   comment.Id,
+		CreateTime: simple.NowTimestamp(),
+	})
+}
+
+// resolveReplyTarget 回复的本地内容，回复的是远程评论时挂在该评论下面
+func (s *activityPubService) resolveReplyTarget(inReplyTo string) (entityType string, entityId, parentId int64) {
+	for t, p := range apEntityPaths {
+		if id := s.parseLocalId(inReplyTo, p); id > 0 && s.BuildObject(t, id) != nil {
+			return t, id, 0
+		}
+	}
+	remoteObject := repositories.ApRemoteObjectRepository.Take(simple.DB(), "object_id = ?", inReplyTo)
+	if remoteObject == nil || remoteObject.EntityType != constants.EntityComment {
+		return
+	}
+	comment := CommentService.Get(remoteObject.EntityId)
+	if comment == nil || comment.Status != constants.StatusOk {
+		return
+	}
+	return comment.EntityType, comment.EntityId, comment.Id
+}
+
+// onDelete 远程用户删除回复时同步删除评论，注销账号时移除其关注
+func (s *activityPubService) onDelete(ctx context.Context, actor *model.ApRemoteActor, activity *activitypub.Activity) error {
+	objectId := activity.ObjectId()
+	if objectId == actor.ActorId {
+		simple.DB().Delete(&model.ApFollower{}, "actor_id = ?", actor.ActorId)
+		return nil
+	}
+	remoteObject := repositories.ApRemoteObjectRepository.Take(simple.DB(), "object_id = ?", objectId)
+	if remoteObject == nil || remoteObject.ActorId != actor.ActorId || remoteObject.EntityType != constants.EntityComment {
+		return nil
+	}
+	return CommentService.DeleteByAuthor(ctx, actor.UserId, remoteObject.EntityId)
+}
+
+// onUpdate 远程用户更新资料时重新获取
+func (s *activityPubService) onUpdate(actor *model.ApRemoteActor, activity *activitypub.Activity) error {
+	if activity.ObjectType() == activitypub.TypePerson && activity.ObjectId() == actor.ActorId {
+		_, err := s.getRemoteActor(actor.ActorId, true)
+		return err
+	}
+	return nil
+}
+
+// parseLocalId 从本地地址中解析编号，例如：{BaseUrl}/ap/users/1 中的 1，不是本地地址时返回0
+func (s *activityPubService) parseLocalId(iri, path string) int64 {
+	prefix := urls.AbsUrl("/ap/" + path + "/")
+	if !strings.HasPrefix(iri, prefix) {
+		return 0
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(iri, prefix), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// isBlocked 是否是屏蔽的实例，包含子域名
+func (s *activityPubService) isBlocked(iri string) bool {
+	u, err := url.Parse(iri)
+	if err != nil || len(u.Hostname()) == 0 {
+		return true
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range config.Instance.ActivityPub.BlockedDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if len(domain) > 0 && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *activityPubService) sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return len(ua.Host) > 0 && strings.EqualFold(ua.Host, ub.Host)
+}
+
+func (s *activityPubService) formatTime(timestamp int64) string {
+	return simple.TimeFromTimestamp(timestamp).UTC().Format(time.RFC3339)
+}
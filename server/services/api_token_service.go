@@ -0,0 +1,204 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/cache"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+// 令牌前缀，方便在日志、代码仓库中识别泄漏的令牌
+const apiTokenPrefix = "bbs_"
+
+// 每个用户最多可创建的令牌数量
+const maxApiTokensPerUser = 20
+
+var ApiTokenService = newApiTokenService()
+
+func newApiTokenService() *apiTokenService {
+	return &apiTokenService{}
+}
+
+type apiTokenService struct {
+}
+
+func (s *apiTokenService) Get(id int64) *model.ApiToken {
+	return repositories.ApiTokenRepository.Get(simple.DB(), id)
+}
+
+func (s *apiTokenService) Take(where ...interface{}) *model.ApiToken {
+	return repositories.ApiTokenRepository.Take(simple.DB(), where...)
+}
+
+func (s *apiTokenService) Find(cnd *simple.SqlCnd) []model.ApiToken {
+	return repositories.ApiTokenRepository.Find(simple.DB(), cnd)
+}
+
+func (s *apiTokenService) FindOne(cnd *simple.SqlCnd) *model.ApiToken {
+	return repositories.ApiTokenRepository.FindOne(simple.DB(), cnd)
+}
+
+func (s *apiTokenService) FindPageByParams(params *simple.QueryParams) (list []model.ApiToken, paging *simple.Paging) {
+	return repositories.ApiTokenRepository.FindPageByParams(simple.DB(), params)
+}
+
+func (s *apiTokenService) FindPageByCnd(cnd *simple.SqlCnd) (list []model.ApiToken, paging *simple.Paging) {
+	return repositories.ApiTokenRepository.FindPageByCnd(simple.DB(), cnd)
+}
+
+func (s *apiTokenService) Count(cnd *simple.SqlCnd) int {
+	return repositories.ApiTokenRepository.Count(simple.DB(), cnd)
+}
+
+func (s *apiTokenService) UpdateColumn(id int64, name string, value interface{}) error {
+	return repositories.ApiTokenRepository.UpdateColumn(simple.DB(), id, name, value)
+}
+
+// GetUserTokens 用户的有效令牌列表
+func (s *apiTokenService) GetUserTokens(userId int64) []model.ApiToken {
+	return s.Find(simple.NewSqlCnd().Eq("user_id", userId).Eq("status", constants.StatusOk).Desc("id"))
+}
+
+// Create 创建令牌，返回令牌实体和原始令牌，原始令牌只在创建时返回一次
+// expireDays: 有效天数，小于等于0表示永不过期
+func (s *apiTokenService) Create(user *model.User, name string, scopes []string, expireDays int) (*model.ApiToken, string, error) {
+	name = strings.TrimSpace(name)
+	if len(name) == 0 {
+		return nil, "", errors.New("请输入令牌名称")
+	}
+	if simple.RuneLen(name) > 64 {
+		return nil, "", errors.New("令牌名称长度不能超过64")
+	}
+	scopes, err := s.checkScopes(user, scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.Count(simple.NewSqlCnd().Eq("user_id", user.Id).Eq("status", constants.StatusOk)) >= maxApiTokensPerUser {
+		return nil, "", errors.New("令牌数量已达上限")
+	}
+
+	var expiredAt int64
+	if expireDays > 0 {
+		expiredAt = simple.Timestamp(time.Now().Add(time.Hour * 24 * time.Duration(expireDays)))
+	}
+
+	token := apiTokenPrefix + simple.UUID()
+	apiToken := &model.ApiToken{
+		UserId:     user.Id,
+		Name:       name,
+		TokenHash:  s.hash(token),
+		TokenTail:  token[len(token)-4:],
+		Scopes:     strings.Join(scopes, ","),
+		ExpiredAt:  expiredAt,
+		Status:     constants.StatusOk,
+		CreateTime: simple.NowTimestamp(),
+	}
+	if err := repositories.ApiTokenRepository.Create(simple.DB(), apiToken); err != nil {
+		return nil, "", err
+	}
+	return apiToken, token, nil
+}
+
+// Revoke 吊销令牌
+func (s *apiTokenService) Revoke(userId, id int64) error {
+	apiToken := s.Get(id)
+	if apiToken == nil || apiToken.UserId != userId {
+		return errors.New("令牌不存在")
+	}
+	if apiToken.Status == constants.StatusDeleted {
+		return nil
+	}
+	err := s.UpdateColumn(id, "status", constants.StatusDeleted)
+	if err == nil {
+		cache.ApiTokenCache.Invalidate(apiToken.TokenHash)
+	}
+	return err
+}
+
+// GetByToken 根据原始令牌获取有效的令牌，令牌不存在、已吊销或已过期时返回nil
+func (s *apiTokenService) GetByToken(token string) *model.ApiToken {
+	if len(token) == 0 {
+		return nil
+	}
+	apiToken := cache.ApiTokenCache.Get(s.hash(token))
+	if apiToken == nil || apiToken.Status != constants.StatusOk || apiToken.IsExpired() {
+		return nil
+	}
+	return apiToken
+}
+
+// GetCurrent 获取当前请求携带的令牌
+func (s *apiTokenService) GetCurrent(ctx iris.Context) *model.ApiToken {
+	return s.GetByToken(s.GetBearerToken(ctx))
+}
+
+// GetCurrentUser 获取当前请求令牌所属用户
+func (s *apiTokenService) GetCurrentUser(ctx iris.Context) *model.User {
+	apiToken := s.GetCurrent(ctx)
+	if apiToken == nil {
+		return nil
+	}
+	user := cache.UserCache.Get(apiToken.UserId)
+	if user == nil || user.Status != constants.StatusOk {
+		return nil
+	}
+	return user
+}
+
+// GetBearerToken 从请求头 Authorization: Bearer xxx 中获取令牌
+func (s *apiTokenService) GetBearerToken(ctx iris.Context) string {
+	authorization := strings.TrimSpace(ctx.GetHeader("Authorization"))
+	if len(authorization) < 7 || !strings.EqualFold(authorization[:7], "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(authorization[7:])
+}
+
+// MarkUsed 记录令牌最后使用时间，一分钟内只记录一次
+func (s *apiTokenService) MarkUsed(apiToken *model.ApiToken) {
+	now := simple.NowTimestamp()
+	if now-apiToken.LastUsedAt < 60*1000 {
+		return
+	}
+	apiToken.LastUsedAt = now
+	_ = s.UpdateColumn(apiToken.Id, "last_used_at", now)
+}
+
+// checkScopes 校验授权范围，管理类的授权范围只有管理员才能申请
+func (s *apiTokenService) checkScopes(user *model.User, scopes []string) ([]string, error) {
+	var ret []string
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if len(scope) == 0 || simple.Contains(scope, ret) {
+			continue
+		}
+		switch scope {
+		case constants.ApiScopeRead, constants.ApiScopeTopic, constants.ApiScopeComment, constants.ApiScopeTweet:
+		case constants.ApiScopeAdmin, constants.ApiScopeSpider:
+			if !user.HasAnyRole(constants.RoleOwner, constants.RoleAdmin) {
+				return nil, errors.New("无权限申请授权范围：" + scope)
+			}
+		default:
+			return nil, errors.New("授权范围不存在：" + scope)
+		}
+		ret = append(ret, scope)
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("请选择授权范围")
+	}
+	return ret, nil
+}
+
+func (s *apiTokenService) hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
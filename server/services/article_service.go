@@ -2,26 +2,21 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"context"
 	"errors"
 	"math"
-	"path"
 	"strings"
-	"time"
 
 	"github.com/emirpasic/gods/sets/hashset"
 
 	"bbs-go/cache"
-	"bbs-go/common/baiduseo"
-	"bbs-go/common/urls"
-	"bbs-go/config"
+	"bbs-go/common/event"
+	"bbs-go/common/tracing"
 	"bbs-go/repositories"
 
-	"github.com/gorilla/feeds"
 	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
-	"github.com/sirupsen/logrus"
 
-	"bbs-go/common"
 	"bbs-go/model"
 )
 
@@ -73,11 +68,12 @@ func (s *articleService) UpdateColumn(id int64, name string, value interface{})
 	return err
 }
 
-func (s *articleService) Delete(id int64) error {
-	err := repositories.ArticleRepository.UpdateColumn(simple.DB(), id, "status", constants.StatusDeleted)
+func (s *articleService) Delete(ctx context.Context, id int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	err := repositories.ArticleRepository.UpdateColumn(db, id, "status", constants.StatusDeleted)
 	if err == nil {
 		// 删掉标签文章
-		ArticleTagService.DeleteByArticleId(id)
+		ArticleTagService.DeleteByArticleId(db, id)
 	}
 	return err
 }
@@ -103,12 +99,12 @@ func (s *articleService) GetArticleTags(articleId int64) []model.Tag {
 }
 
 // 文章列表
-func (s *articleService) GetArticles(cursor int64) (articles []model.Article, nextCursor int64) {
+func (s *articleService) GetArticles(ctx context.Context, cursor int64) (articles []model.Article, nextCursor int64) {
 	cnd := simple.NewSqlCnd().Eq("status", constants.StatusOk).Desc("id").Limit(20)
 	if cursor > 0 {
 		cnd.Lt("id", cursor)
 	}
-	articles = repositories.ArticleRepository.Find(simple.DB(), cnd)
+	articles = repositories.ArticleRepository.Find(tracing.DB(ctx, simple.DB()), cnd)
 	if len(articles) > 0 {
 		nextCursor = articles[len(articles)-1].Id
 	} else {
@@ -137,7 +133,7 @@ func (s *articleService) GetTagArticles(tagId int64, cursor int64) (articles []m
 }
 
 // 发布文章
-func (s *articleService) Publish(userId int64, title, summary, content, contentType string, tags []string,
+func (s *articleService) Publish(ctx context.Context, userId int64, title, summary, content, contentType string, tags []string,
 	sourceUrl string) (article *model.Article, err error) {
 	title = strings.TrimSpace(title)
 	summary = strings.TrimSpace(summary)
@@ -169,7 +165,7 @@ func (s *articleService) Publish(userId int64, title, summary, content, contentT
 		UpdateTime:  simple.NowTimestamp(),
 	}
 
-	err = simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+	err = simple.Tx(tracing.DB(ctx, simple.DB()), func(tx *gorm.DB) error {
 		tagIds := repositories.TagRepository.GetOrCreates(tx, tags)
 		err := repositories.ArticleRepository.Create(tx, article)
 		if err != nil {
@@ -180,13 +176,13 @@ func (s *articleService) Publish(userId int64, title, summary, content, contentT
 	})
 
 	if err == nil {
-		baiduseo.PushUrl(urls.ArticleUrl(article.Id))
+		event.Publish(&event.ArticlePublished{Article: article, Tags: tags})
 	}
 	return
 }
 
 // 修改文章
-func (s *articleService) Edit(articleId int64, tags []string, title, content string) *simple.CodeError {
+func (s *articleService) Edit(ctx context.Context, articleId int64, tags []string, title, content string) *simple.CodeError {
 	if len(title) == 0 {
 		return simple.NewErrorMsg("请输入标题")
 	}
@@ -194,8 +190,8 @@ func (s *articleService) Edit(articleId int64, tags []string, title, content str
 		return simple.NewErrorMsg("请填写文章内容")
 	}
 
-	err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
-		err := repositories.ArticleRepository.Updates(simple.DB(), articleId, map[string]interface{}{
+	err := simple.Tx(tracing.DB(ctx, simple.DB()), func(tx *gorm.DB) error {
+		err := repositories.ArticleRepository.Updates(tx, articleId, map[string]interface{}{
 			"title":   title,
 			"content": content,
 		})
@@ -295,47 +291,11 @@ func (s *articleService) GenerateRss() {
 	articles := repositories.ArticleRepository.Find(simple.DB(),
 		simple.NewSqlCnd().Where("status = ?", constants.StatusOk).Desc("id").Limit(1000))
 
-	var items []*feeds.Item
-	for _, article := range articles {
-		articleUrl := urls.ArticleUrl(article.Id)
-		user := cache.UserCache.Get(article.UserId)
-		if user == nil {
-			continue
-		}
-		description := common.GetSummary(article.ContentType, article.Content)
-		item := &feeds.Item{
-			Title:       article.Title,
-			Link:        &feeds.Link{Href: articleUrl},
-			Description: description,
-			Author:      &feeds.Author{Name: user.Avatar, Email: user.Email.String},
-			Created:     simple.TimeFromTimestamp(article.CreateTime),
-		}
-		items = append(items, item)
-	}
-
-	siteTitle := cache.SysConfigCache.GetValue(constants.SysConfigSiteTitle)
-	siteDescription := cache.SysConfigCache.GetValue(constants.SysConfigSiteDescription)
-	feed := &feeds.Feed{
-		Title:       siteTitle,
-		Link:        &feeds.Link{Href: config.Instance.BaseUrl},
-		Description: siteDescription,
-		Author:      &feeds.Author{Name: siteTitle},
-		Created:     time.Now(),
-		Items:       items,
-	}
-	atom, err := feed.ToAtom()
-	if err != nil {
-		logrus.Error(err)
-	} else {
-		_ = simple.WriteString(path.Join(config.Instance.StaticPath, "atom.xml"), atom, false)
-	}
-
-	rss, err := feed.ToRss()
-	if err != nil {
-		logrus.Error(err)
-	} else {
-		_ = simple.WriteString(path.Join(config.Instance.StaticPath, "rss.xml"), rss, false)
+	f := FeedService.NewSiteFeed()
+	for i := range articles {
+		FeedService.addArticle(f, &articles[i])
 	}
+	FeedService.WriteStatic(f, "atom.xml", "rss.xml")
 }
 
 // 浏览数+1
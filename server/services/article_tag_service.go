@@ -2,6 +2,7 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
 
 	"bbs-go/model"
@@ -53,6 +54,6 @@ func (s *articleTagService) UpdateColumn(id int64, name string, value interface{
 	return repositories.ArticleTagRepository.UpdateColumn(simple.DB(), id, name, value)
 }
 
-func (s *articleTagService) DeleteByArticleId(topicId int64) {
-	simple.DB().Model(model.ArticleTag{}).Where("topic_id = ?", topicId).UpdateColumn("status", constants.StatusDeleted)
+func (s *articleTagService) DeleteByArticleId(db *gorm.DB, articleId int64) {
+	db.Model(model.ArticleTag{}).Where("article_id = ?", articleId).UpdateColumn("status", constants.StatusDeleted)
 }
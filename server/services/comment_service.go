@@ -1,12 +1,17 @@
 package services
 
 import (
+	"bbs-go/config"
 	"bbs-go/model/constants"
+	"context"
 	"errors"
 	"strings"
 
+	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
 
+	"bbs-go/common/event"
+	"bbs-go/common/tracing"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
@@ -65,11 +70,39 @@ func (s *commentService) UpdateColumn(id int64, name string, value interface{})
 }
 
 func (s *commentService) Delete(id int64) error {
-	return repositories.CommentRepository.UpdateColumn(simple.DB(), id, "status", constants.StatusDeleted)
+	comment := s.Get(id)
+	if comment == nil || comment.Status == constants.StatusDeleted {
+		return nil
+	}
+	return simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		return s.deleteTx(tx, comment)
+	})
+}
+
+// deleteTx 在调用方的事务中删除评论，同时更新上级评论的回复数量和被评论内容的评论数量
+func (s *commentService) deleteTx(tx *gorm.DB, comment *model.Comment) error {
+	if err := repositories.CommentRepository.UpdateColumn(tx, comment.Id, "status", constants.StatusDeleted); err != nil {
+		return err
+	}
+	if comment.ParentId > 0 { // 更新上级评论的回复数量
+		if err := tx.Model(&model.Comment{}).Where("id = ? and reply_count > 0", comment.ParentId).
+			UpdateColumn("reply_count", gorm.Expr("reply_count - 1")).Error; err != nil {
+			return err
+		}
+	}
+	switch comment.EntityType {
+	case constants.EntityTopic:
+		return tx.Exec("update t_topic set comment_count = comment_count - 1 where id = ? and comment_count > 0",
+			comment.EntityId).Error
+	case constants.EntityTweet:
+		return tx.Exec("update t_tweet set comment_count = comment_count - 1 where id = ? and comment_count > 0",
+			comment.EntityId).Error
+	}
+	return nil
 }
 
 // 发表评论
-func (s *commentService) Publish(userId int64, form *model.CreateCommentForm) (*model.Comment, error) {
+func (s *commentService) Publish(ctx context.Context, userId int64, form *model.CreateCommentForm) (*model.Comment, error) {
 	form.Content = strings.TrimSpace(form.Content)
 
 	if simple.IsBlank(form.EntityType) {
@@ -92,19 +125,43 @@ func (s *commentService) Publish(userId int64, form *model.CreateCommentForm) (*
 		Status:      constants.StatusOk,
 		CreateTime:  simple.NowTimestamp(),
 	}
-	if err := s.Create(comment); err != nil {
-		return nil, err
+	db := tracing.DB(ctx, simple.DB())
+	if form.ParentId > 0 {
+		parent := repositories.CommentRepository.Get(db, form.ParentId)
+		if parent == nil || parent.Status != constants.StatusOk ||
+			parent.EntityType != form.EntityType || parent.EntityId != form.EntityId {
+			return nil, errors.New("回复的评论不存在")
+		}
+		if parent.Depth+1 > s.maxDepth() {
+			// 超过最大层级时挂在上一层，并引用被回复的评论
+			comment.QuoteId = parent.Id
+			comment.ParentId = parent.ParentId
+			comment.RootId = parent.RootId
+			comment.Depth = parent.Depth
+		} else {
+			comment.ParentId = parent.Id
+			comment.RootId = parent.RootId
+			if comment.RootId == 0 {
+				comment.RootId = parent.Id
+			}
+			comment.Depth = parent.Depth + 1
+		}
 	}
-
-	if form.EntityType == constants.EntityTopic {
-		TopicService.OnComment(form.EntityId, simple.NowTimestamp())
-	} else if form.EntityType == constants.EntityTweet {
-		TweetService.OnComment(form.EntityId)
+	err := simple.Tx(db, func(tx *gorm.DB) error {
+		if err := repositories.CommentRepository.Create(tx, comment); err != nil {
+			return err
+		}
+		if comment.ParentId > 0 {
+			return tx.Model(&model.Comment{}).Where("id = ?", comment.ParentId).
+				UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	UserService.IncrCommentCount(userId)                // 用户跟帖计数
-	UserScoreService.IncrementPostCommentScore(comment) // 获得积分
-	MessageService.SendCommentMsg(comment)              // 发送消息
+	event.Publish(&event.CommentCreated{Comment: comment})
 
 	return comment, nil
 }
@@ -116,13 +173,139 @@ func (s *commentService) Publish(userId int64, form *model.CreateCommentForm) (*
 // 	return count
 // }
 
-// 列表
-func (s *commentService) GetComments(entityType string, entityId int64, cursor int64) (comments []model.Comment, nextCursor int64) {
-	cnd := simple.NewSqlCnd().Eq("entity_type", entityType).Eq("entity_id", entityId).Eq("status", constants.StatusOk).Desc("id").Limit(50)
-	if cursor > 0 {
-		cnd.Lt("id", cursor)
+// Edit 作者编辑评论，只能在发表后的一段时间内编辑，编辑前的内容保存到编辑历史
+func (s *commentService) Edit(ctx context.Context, userId, commentId int64, content string) (*model.Comment, error) {
+	content = strings.TrimSpace(content)
+	if simple.IsBlank(content) {
+		return nil, errors.New("请输入评论内容")
+	}
+	db := tracing.DB(ctx, simple.DB())
+	comment := repositories.CommentRepository.Get(db, commentId)
+	if comment == nil || comment.Status != constants.StatusOk || comment.DeleteTime > 0 {
+		return nil, errors.New("评论不存在")
+	}
+	if comment.UserId != userId {
+		return nil, errors.New("无权限")
+	}
+	if !s.CanEdit(comment) {
+		return nil, errors.New("已超过可编辑时间")
+	}
+	if comment.Content == content {
+		return comment, nil
+	}
+	now := simple.NowTimestamp()
+	err := simple.Tx(db, func(tx *gorm.DB) error {
+		if err := repositories.CommentHistoryRepository.Create(tx, &model.CommentHistory{
+			CommentId:   comment.Id,
+			UserId:      userId,
+			Content:     comment.Content,
+			ContentType: comment.ContentType,
+			CreateTime:  now,
+		}); err != nil {
+			return err
+		}
+		return repositories.CommentRepository.Updates(tx, comment.Id, map[string]interface{}{
+			"content":     content,
+			"update_time": now,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	comment.Content = content
+	comment.UpdateTime = now
+	return comment, nil
+}
+
+// GetHistories 评论的编辑历史，按编辑时间倒序
+func (s *commentService) GetHistories(commentId int64) []model.CommentHistory {
+	return repositories.CommentHistoryRepository.Find(simple.DB(), simple.NewSqlCnd().Eq("comment_id", commentId).Desc("id"))
+}
+
+// DeleteByAuthor 作者删除评论，评论保留在楼层中显示为已删除，回复不受影响
+func (s *commentService) DeleteByAuthor(ctx context.Context, userId, commentId int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	comment := repositories.CommentRepository.Get(db, commentId)
+	if comment == nil || comment.Status != constants.StatusOk {
+		return errors.New("评论不存在")
+	}
+	if comment.UserId != userId {
+		return errors.New("无权限")
+	}
+	if comment.DeleteTime > 0 {
+		return nil
+	}
+	return repositories.CommentRepository.UpdateColumn(db, commentId, "delete_time", simple.NowTimestamp())
+}
+
+// GetComments 顶层评论列表，sort 为排序方式；最新、最早排序时 cursor 为上一页最后一条评论的编号，
+// 最热排序时按点赞数和回复数排序，cursor 为已加载的页数
+func (s *commentService) GetComments(ctx context.Context, entityType string, entityId int64, sort string, cursor int64) (comments []model.Comment, nextCursor int64) {
+	db := tracing.DB(ctx, simple.DB())
+	cnd := simple.NewSqlCnd().Eq("entity_type", entityType).Eq("entity_id", entityId).Eq("parent_id", 0).
+		Eq("status", constants.StatusOk)
+	if sort == constants.CommentSortBest {
+		page := int(cursor) + 1
+		cnd.Desc("like_count").Desc("reply_count").Desc("id").Page(page, 50)
+		comments = repositories.CommentRepository.Find(db, cnd)
+		if len(comments) > 0 {
+			nextCursor = int64(page)
+		} else {
+			nextCursor = cursor
+		}
+		return
+	}
+	return s.findByCursor(db, cnd, sort == constants.CommentSortOldest, cursor, 50)
+}
+
+// GetReplies 评论的直接回复，按时间正序，cursor 为上一页最后一条回复的编号
+func (s *commentService) GetReplies(ctx context.Context, commentId int64, cursor int64) (comments []model.Comment, nextCursor int64) {
+	cnd := simple.NewSqlCnd().Eq("parent_id", commentId).Eq("status", constants.StatusOk)
+	return s.findByCursor(tracing.DB(ctx, simple.DB()), cnd, true, cursor, 20)
+}
+
+// Replies 评论的前几条直接回复，HasMore 为 true 时以 Cursor 调用 GetReplies 加载更多
+type Replies struct {
+	Comments []model.Comment
+	HasMore  bool
+	Cursor   int64 // 最后一条回复的编号
+}
+
+// FindReplies 批量查询多条评论的直接回复，每条评论最多查询 limit 条，按时间正序
+func (s *commentService) FindReplies(ctx context.Context, limit int, commentIds ...int64) map[int64]*Replies {
+	ret := make(map[int64]*Replies)
+	db := tracing.DB(ctx, simple.DB())
+	for _, commentId := range commentIds {
+		// 多查询一条用于判断是否还有更多回复
+		cnd := simple.NewSqlCnd().Eq("parent_id", commentId).Eq("status", constants.StatusOk)
+		comments, _ := s.findByCursor(db, cnd, true, 0, limit+1)
+		replies := &Replies{Comments: comments}
+		if len(comments) > limit {
+			replies.Comments = comments[:limit]
+			replies.HasMore = true
+		}
+		if len(replies.Comments) > 0 {
+			replies.Cursor = replies.Comments[len(replies.Comments)-1].Id
+		}
+		ret[commentId] = replies
 	}
-	comments = repositories.CommentRepository.Find(simple.DB(), cnd)
+	return ret
+}
+
+func (s *commentService) findByCursor(db *gorm.DB, cnd *simple.SqlCnd, asc bool, cursor int64, limit int) (comments []model.Comment, nextCursor int64) {
+	if asc {
+		cnd.Asc("id")
+		if cursor > 0 {
+			cnd.Gt("id", cursor)
+		}
+	} else {
+		cnd.Desc("id")
+		if cursor > 0 {
+			cnd.Lt("id", cursor)
+		}
+	}
+	cnd.Limit(limit)
+	comments = repositories.CommentRepository.Find(db, cnd)
 	if len(comments) > 0 {
 		nextCursor = comments[len(comments)-1].Id
 	} else {
@@ -130,3 +313,26 @@ func (s *commentService) GetComments(entityType string, entityId int64, cursor i
 	}
 	return
 }
+
+// editMinutes 发表后允许作者编辑的分钟数
+func (s *commentService) editMinutes() int {
+	if config.Instance.Comment.EditMinutes != 0 {
+		return config.Instance.Comment.EditMinutes
+	}
+	return 30
+}
+
+// CanEdit 是否还在可编辑时间内
+func (s *commentService) CanEdit(comment *model.Comment) bool {
+	editMinutes := s.editMinutes()
+	return editMinutes >= 0 && comment.DeleteTime == 0 &&
+		simple.NowTimestamp()-comment.CreateTime <= int64(editMinutes)*60*1000
+}
+
+// maxDepth 楼中楼最大层级
+func (s *commentService) maxDepth() int {
+	if config.Instance.Comment.MaxDepth > 0 {
+		return config.Instance.Comment.MaxDepth
+	}
+	return 3
+}
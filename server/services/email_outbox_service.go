@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/cache"
+	"bbs-go/common/email"
+	"bbs-go/common/metrics"
+	"bbs-go/common/tracing"
+	"bbs-go/config"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+var EmailOutboxService = newEmailOutboxService()
+
+// 内容中包含一次性链接（验证邮箱、重置密码）的模板，发送成功后清空内容，后台不展示内容
+var sensitiveEmailTemplates = map[string]bool{
+	constants.EmailTemplateVerify:        true,
+	constants.EmailTemplateResetPassword: true,
+}
+
+// 后台展示时替换敏感邮件的内容
+const redactedEmailContent = "（内容包含一次性链接，不予展示）"
+
+func newEmailOutboxService() *emailOutboxService {
+	return &emailOutboxService{}
+}
+
+type emailOutboxService struct {
+}
+
+func (s *emailOutboxService) Get(id int64) *model.EmailOutbox {
+	return repositories.EmailOutboxRepository.Get(simple.DB(), id)
+}
+
+func (s *emailOutboxService) Take(where ...interface{}) *model.EmailOutbox {
+	return repositories.EmailOutboxRepository.Take(simple.DB(), where...)
+}
+
+func (s *emailOutboxService) Find(cnd *simple.SqlCnd) []model.EmailOutbox {
+	return repositories.EmailOutboxRepository.Find(simple.DB(), cnd)
+}
+
+func (s *emailOutboxService) FindOne(cnd *simple.SqlCnd) *model.EmailOutbox {
+	return repositories.EmailOutboxRepository.FindOne(simple.DB(), cnd)
+}
+
+func (s *emailOutboxService) FindPageByParams(params *simple.QueryParams) (list []model.EmailOutbox, paging *simple.Paging) {
+	return repositories.EmailOutboxRepository.FindPageByParams(simple.DB(), params)
+}
+
+func (s *emailOutboxService) FindPageByCnd(cnd *simple.SqlCnd) (list []model.EmailOutbox, paging *simple.Paging) {
+	return repositories.EmailOutboxRepository.FindPageByCnd(simple.DB(), cnd)
+}
+
+func (s *emailOutboxService) Count(cnd *simple.SqlCnd) int {
+	return repositories.EmailOutboxRepository.Count(simple.DB(), cnd)
+}
+
+func (s *emailOutboxService) Create(t *model.EmailOutbox) error {
+	return repositories.EmailOutboxRepository.Create(simple.DB(), t)
+}
+
+func (s *emailOutboxService) Update(t *model.EmailOutbox) error {
+	return repositories.EmailOutboxRepository.Update(simple.DB(), t)
+}
+
+func (s *emailOutboxService) Updates(id int64, columns map[string]interface{}) error {
+	return repositories.EmailOutboxRepository.Updates(simple.DB(), id, columns)
+}
+
+func (s *emailOutboxService) UpdateColumn(id int64, name string, value interface{}) error {
+	return repositories.EmailOutboxRepository.UpdateColumn(simple.DB(), id, name, value)
+}
+
+func (s *emailOutboxService) Delete(id int64) {
+	repositories.EmailOutboxRepository.Delete(simple.DB(), id)
+}
+
+// Send 使用模板渲染邮件并写入发件箱，由后台任务异步发送，失败时自动重试
+func (s *emailOutboxService) Send(to, templateName string, data map[string]interface{}, headers map[string]string) (*model.EmailOutbox, error) {
+	var outbox *model.EmailOutbox
+	err := simple.Tx(simple.DB(), func(tx *gorm.DB) (err error) {
+		outbox, err = s.SendTx(tx, to, templateName, data, headers)
+		return
+	})
+	return outbox, err
+}
+
+// SendTx 同 Send，在调用方的事务中写入发件箱，事务提交后才会发送
+func (s *emailOutboxService) SendTx(tx *gorm.DB, to, templateName string, data map[string]interface{}, headers map[string]string) (*model.EmailOutbox, error) {
+	if !email.Enabled() {
+		return nil, errors.New("未配置邮件发送方式")
+	}
+	t := EmailTemplateService.GetTemplate(templateName)
+	if t == nil {
+		return nil, errors.New("邮件模板不存在：" + templateName)
+	}
+	rendered, err := t.Render(s.buildData(data))
+	if err != nil {
+		return nil, err
+	}
+	headersJson := ""
+	if len(headers) > 0 {
+		if headersJson, err = simple.FormatJson(headers); err != nil {
+			return nil, err
+		}
+	}
+	outbox := &model.EmailOutbox{
+		Email:      to,
+		Template:   templateName,
+		Subject:    rendered.Subject,
+		Html:       rendered.Html,
+		Text:       rendered.Text,
+		Headers:    headersJson,
+		Status:     constants.EmailStatusPending,
+		CreateTime: simple.NowTimestamp(),
+		UpdateTime: simple.NowTimestamp(),
+	}
+	if err := repositories.EmailOutboxRepository.Create(tx, outbox); err != nil {
+		return nil, err
+	}
+	if _, err := JobService.EnqueueTx(tx, constants.JobTypeEmailSend, outbox.Id); err != nil {
+		return nil, err
+	}
+	return outbox, nil
+}
+
+// Deliver 发送发件箱中的邮件，已发送的不会重复发送；返回错误时由后台任务重试
+func (s *emailOutboxService) Deliver(ctx context.Context, id int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	outbox := repositories.EmailOutboxRepository.Get(db, id)
+	if outbox == nil { // 收件人注销账号时已删除
+		logrus.WithContext(ctx).Warn("Email outbox not found: ", id)
+		return nil
+	}
+	if outbox.Status == constants.EmailStatusSent {
+		return nil
+	}
+	msg := &email.Message{
+		To:      outbox.Email,
+		Subject: outbox.Subject,
+		Html:    outbox.Html,
+		Text:    outbox.Text,
+	}
+	if len(outbox.Headers) > 0 {
+		if err := json.Unmarshal([]byte(outbox.Headers), &msg.Headers); err != nil {
+			logrus.WithContext(ctx).Error(err)
+		}
+	}
+
+	sendErr := email.Send(ctx, msg)
+	columns := map[string]interface{}{
+		"attempts":    gorm.Expr("attempts + 1"),
+		"update_time": simple.NowTimestamp(),
+	}
+	if sendErr == nil {
+		metrics.EmailsSent.Inc("sent")
+		columns["status"] = constants.EmailStatusSent
+		columns["send_time"] = simple.NowTimestamp()
+		columns["last_error"] = ""
+		if sensitiveEmailTemplates[outbox.Template] {
+			columns["html"] = ""
+			columns["text"] = ""
+		}
+	} else {
+		metrics.EmailsSent.Inc("failed")
+		columns["status"] = constants.EmailStatusFailed
+		columns["last_error"] = sendErr.Error()
+	}
+	if err := repositories.EmailOutboxRepository.Updates(db, id, columns); err != nil {
+		logrus.WithContext(ctx).Error(err)
+	}
+	return sendErr
+}
+
+// Resend 重新发送，用于后台任务重试次数用完后手动发送
+func (s *emailOutboxService) Resend(id int64) error {
+	outbox := s.Get(id)
+	if outbox == nil {
+		return errors.New("邮件不存在")
+	}
+	if outbox.Status == constants.EmailStatusSent {
+		return errors.New("邮件已发送")
+	}
+	_, err := JobService.Enqueue(constants.JobTypeEmailSend, outbox.Id)
+	return err
+}
+
+// Redact 后台展示前隐藏敏感邮件的内容，避免管理员通过发件箱获取其他用户的重置密码链接
+func (s *emailOutboxService) Redact(list ...*model.EmailOutbox) {
+	for _, outbox := range list {
+		if sensitiveEmailTemplates[outbox.Template] {
+			outbox.Html = redactedEmailContent
+			outbox.Text = redactedEmailContent
+		}
+	}
+}
+
+// buildData 模板数据，添加公共变量
+func (s *emailOutboxService) buildData(data map[string]interface{}) map[string]interface{} {
+	ret := map[string]interface{}{
+		"SiteTitle":      cache.SysConfigCache.GetValue(constants.SysConfigSiteTitle),
+		"BaseUrl":        config.Instance.BaseUrl,
+		"UnsubscribeUrl": "",
+	}
+	for k, v := range data {
+		ret[k] = v
+	}
+	return ret
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/mlogclub/simple"
+
+	"bbs-go/common/email"
+	"bbs-go/model"
+	"bbs-go/repositories"
+)
+
+var EmailTemplateService = newEmailTemplateService()
+
+func newEmailTemplateService() *emailTemplateService {
+	return &emailTemplateService{}
+}
+
+type emailTemplateService struct {
+}
+
+func (s *emailTemplateService) Get(id int64) *model.EmailTemplate {
+	return repositories.EmailTemplateRepository.Get(simple.DB(), id)
+}
+
+func (s *emailTemplateService) Take(where ...interface{}) *model.EmailTemplate {
+	return repositories.EmailTemplateRepository.Take(simple.DB(), where...)
+}
+
+func (s *emailTemplateService) Find(cnd *simple.SqlCnd) []model.EmailTemplate {
+	return repositories.EmailTemplateRepository.Find(simple.DB(), cnd)
+}
+
+func (s *emailTemplateService) FindOne(cnd *simple.SqlCnd) *model.EmailTemplate {
+	return repositories.EmailTemplateRepository.FindOne(simple.DB(), cnd)
+}
+
+func (s *emailTemplateService) FindPageByParams(params *simple.QueryParams) (list []model.EmailTemplate, paging *simple.Paging) {
+	return repositories.EmailTemplateRepository.FindPageByParams(simple.DB(), params)
+}
+
+func (s *emailTemplateService) FindPageByCnd(cnd *simple.SqlCnd) (list []model.EmailTemplate, paging *simple.Paging) {
+	return repositories.EmailTemplateRepository.FindPageByCnd(simple.DB(), cnd)
+}
+
+func (s *emailTemplateService) Count(cnd *simple.SqlCnd) int {
+	return repositories.EmailTemplateRepository.Count(simple.DB(), cnd)
+}
+
+func (s *emailTemplateService) Create(t *model.EmailTemplate) error {
+	return repositories.EmailTemplateRepository.Create(simple.DB(), t)
+}
+
+func (s *emailTemplateService) Update(t *model.EmailTemplate) error {
+	return repositories.EmailTemplateRepository.Update(simple.DB(), t)
+}
+
+func (s *emailTemplateService) Updates(id int64, columns map[string]interface{}) error {
+	return repositories.EmailTemplateRepository.Updates(simple.DB(), id, columns)
+}
+
+func (s *emailTemplateService) UpdateColumn(id int64, name string, value interface{}) error {
+	return repositories.EmailTemplateRepository.UpdateColumn(simple.DB(), id, name, value)
+}
+
+func (s *emailTemplateService) Delete(id int64) {
+	repositories.EmailTemplateRepository.Delete(simple.DB(), id)
+}
+
+// GetTemplate 获取模板，管理后台修改过的优先，否则使用内置模板；模板不存在时返回nil
+func (s *emailTemplateService) GetTemplate(name string) *email.Template {
+	t := email.GetDefaultTemplate(name)
+	if t == nil {
+		return nil
+	}
+	if custom := s.GetByName(name); custom != nil {
+		t.Subject = custom.Subject
+		t.Html = custom.Html
+		t.Text = custom.Text
+	}
+	return t
+}
+
+// GetTemplates 所有模板
+func (s *emailTemplateService) GetTemplates() []*email.Template {
+	var list []*email.Template
+	for _, t := range email.DefaultTemplates {
+		list = append(list, s.GetTemplate(t.Name))
+	}
+	return list
+}
+
+func (s *emailTemplateService) GetByName(name string) *model.EmailTemplate {
+	return repositories.EmailTemplateRepository.Take(simple.DB(), "name = ?", name)
+}
+
+// Save 保存修改后的模板
+func (s *emailTemplateService) Save(name, subject, html, text string) error {
+	t := email.GetDefaultTemplate(name)
+	if t == nil {
+		return errors.New("模板不存在")
+	}
+	t.Subject, t.Html, t.Text = subject, html, text
+	if err := s.Check(t); err != nil {
+		return err
+	}
+
+	custom := s.GetByName(name)
+	if custom == nil {
+		custom = &model.EmailTemplate{
+			Name:       name,
+			CreateTime: simple.NowTimestamp(),
+		}
+	}
+	custom.Subject = subject
+	custom.Html = html
+	custom.Text = text
+	custom.UpdateTime = simple.NowTimestamp()
+	if custom.Id > 0 {
+		return s.Update(custom)
+	}
+	return s.Create(custom)
+}
+
+// Reset 恢复为内置模板
+func (s *emailTemplateService) Reset(name string) {
+	if custom := s.GetByName(name); custom != nil {
+		s.Delete(custom.Id)
+	}
+}
+
+// Check 校验模板，使用示例数据渲染一次以发现使用了错误变量等问题
+func (s *emailTemplateService) Check(t *email.Template) error {
+	if len(t.Subject) == 0 {
+		return errors.New("主题不能为空")
+	}
+	if len(t.Html) == 0 {
+		return errors.New("HTML内容不能为空")
+	}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	_, err := s.Preview(t)
+	return err
+}
+
+// Preview 使用示例数据渲染模板
+func (s *emailTemplateService) Preview(t *email.Template) (*email.Rendered, error) {
+	return t.Render(EmailOutboxService.buildData(t.Sample))
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bbs-go/common/baiduseo"
+	"bbs-go/common/event"
+	"bbs-go/common/metrics"
+	"bbs-go/common/urls"
+	"bbs-go/model/constants"
+)
+
+// 注册事件处理函数：计数、积分等需要立即生效的使用同步处理，消息、推送等耗时操作使用异步处理
+func init() {
+	// 发表话题
+	event.Subscribe(func(e *event.TopicPublished) {
+		UserService.IncrTopicCount(e.Topic.UserId)        // 用户话题计数
+		UserScoreService.IncrementPostTopicScore(e.Topic) // 获得积分
+	})
+	event.SubscribeAsync(func(e *event.TopicPublished) {
+		baiduseo.PushUrl(urls.TopicUrl(e.Topic.Id)) // 百度链接推送
+	})
+	event.SubscribeAsync(func(e *event.TopicPublished) {
+		MentionService.SendTopicMentionMsg(e.Topic) // @提及消息
+	})
+	event.SubscribeAsync(func(e *event.TopicPublished) {
+		WebhookService.Dispatch(constants.EventTopicPublish, map[string]interface{}{
+			"id":         e.Topic.Id,
+			"userId":     e.Topic.UserId,
+			"nodeId":     e.Topic.NodeId,
+			"title":      e.Topic.Title,
+			"tags":       e.Tags,
+			"url":        urls.TopicUrl(e.Topic.Id),
+			"createTime": e.Topic.CreateTime,
+		})
+	})
+	event.SubscribeAsync(func(e *event.TopicPublished) {
+		ActivityPubService.OnPublished(constants.EntityTopic, e.Topic.Id) // 推送给ActivityPub关注者
+	})
+
+	// 发表文章
+	event.SubscribeAsync(func(e *event.ArticlePublished) {
+		baiduseo.PushUrl(urls.ArticleUrl(e.Article.Id)) // 百度链接推送
+	})
+	event.SubscribeAsync(func(e *event.ArticlePublished) {
+		MentionService.SendArticleMentionMsg(e.Article) // @提及消息
+	})
+	event.SubscribeAsync(func(e *event.ArticlePublished) {
+		WebhookService.Dispatch(constants.EventArticlePublish, map[string]interface{}{
+			"id":         e.Article.Id,
+			"userId":     e.Article.UserId,
+			"title":      e.Article.Title,
+			"summary":    e.Article.Summary,
+			"tags":       e.Tags,
+			"status":     e.Article.Status,
+			"sourceUrl":  e.Article.SourceUrl,
+			"url":        urls.ArticleUrl(e.Article.Id),
+			"createTime": e.Article.CreateTime,
+		})
+	})
+	event.SubscribeAsync(func(e *event.ArticlePublished) {
+		ActivityPubService.OnPublished(constants.EntityArticle, e.Article.Id) // 推送给ActivityPub关注者
+	})
+
+	// 发表动态
+	event.SubscribeAsync(func(e *event.TweetPublished) {
+		MentionService.SendTweetMentionMsg(e.Tweet) // @提及消息
+	})
+	event.SubscribeAsync(func(e *event.TweetPublished) {
+		WebhookService.Dispatch(constants.EventTweetPublish, map[string]interface{}{
+			"id":         e.Tweet.Id,
+			"userId":     e.Tweet.UserId,
+			"content":    e.Tweet.Content,
+			"imageList":  e.Tweet.ImageList,
+			"url":        urls.TweetUrl(e.Tweet.Id),
+			"createTime": e.Tweet.CreateTime,
+		})
+	})
+	event.SubscribeAsync(func(e *event.TweetPublished) {
+		ActivityPubService.OnPublished(constants.EntityTweet, e.Tweet.Id) // 推送给ActivityPub关注者
+	})
+
+	// 发表评论
+	event.Subscribe(func(e *event.CommentCreated) {
+		if e.Comment.EntityType == constants.EntityTopic {
+			TopicService.OnComment(e.Comment.EntityId, e.Comment.CreateTime)
+		} else if e.Comment.EntityType == constants.EntityTweet {
+			TweetService.OnComment(e.Comment.EntityId)
+		}
+		UserService.IncrCommentCount(e.Comment.UserId)        // 用户跟帖计数
+		UserScoreService.IncrementPostCommentScore(e.Comment) // 获得积分
+	})
+	event.SubscribeAsync(func(e *event.CommentCreated) {
+		MessageService.SendCommentMsg(e.Comment)        // 发送消息
+		MentionService.SendCommentMentionMsg(e.Comment) // @提及消息
+	})
+	event.SubscribeAsync(func(e *event.CommentCreated) {
+		WebhookService.Dispatch(constants.EventCommentPublish, map[string]interface{}{
+			"id":          e.Comment.Id,
+			"userId":      e.Comment.UserId,
+			"entityType":  e.Comment.EntityType,
+			"entityId":    e.Comment.EntityId,
+			"quoteId":     e.Comment.QuoteId,
+			"parentId":    e.Comment.ParentId,
+			"rootId":      e.Comment.RootId,
+			"content":     e.Comment.Content,
+			"contentType": e.Comment.ContentType,
+			"createTime":  e.Comment.CreateTime,
+		})
+	})
+
+	// 用户注册
+	event.SubscribeAsync(func(e *event.UserSignedUp) {
+		WebhookService.Dispatch(constants.EventUserSignUp, map[string]interface{}{
+			"id":         e.User.Id,
+			"username":   e.User.Username.String,
+			"nickname":   e.User.Nickname,
+			"url":        urls.UserUrl(e.User.Id),
+			"createTime": e.User.CreateTime,
+		})
+	})
+
+	// 管理操作
+	event.SubscribeAsync(func(e *event.ContentDeleted) {
+		if webhookEvent := contentDeletedWebhookEvents[e.EntityType]; len(webhookEvent) > 0 {
+			WebhookService.Dispatch(webhookEvent, map[string]interface{}{
+				"id":         e.EntityId,
+				"operatorId": e.OperatorId,
+			})
+		}
+	})
+	event.SubscribeAsync(func(e *event.ContentDeleted) {
+		ActivityPubService.OnDeleted(e.EntityType, e.EntityId) // 通知ActivityPub关注者
+	})
+	event.SubscribeAsync(func(e *event.ContentRestored) {
+		if e.EntityType == constants.EntityTopic {
+			WebhookService.Dispatch(constants.EventTopicUndelete, map[string]interface{}{
+				"id":         e.EntityId,
+				"operatorId": e.OperatorId,
+			})
+		}
+	})
+	event.SubscribeAsync(func(e *event.TopicRecommended) {
+		webhookEvent := constants.EventTopicRecommend
+		if !e.Recommend {
+			webhookEvent = constants.EventTopicRemoveRecommend
+		}
+		WebhookService.Dispatch(webhookEvent, map[string]interface{}{
+			"id":         e.TopicId,
+			"operatorId": e.OperatorId,
+		})
+	})
+	event.SubscribeAsync(func(e *event.UserForbidden) {
+		WebhookService.Dispatch(constants.EventUserForbidden, map[string]interface{}{
+			"operatorId":       e.OperatorId,
+			"userId":           e.UserId,
+			"days":             e.Days,
+			"forbiddenEndTime": e.ForbiddenEndTime,
+			"reason":           e.Reason,
+		})
+	})
+	event.SubscribeAsync(func(e *event.UserForbiddenRemoved) {
+		WebhookService.Dispatch(constants.EventUserRemoveForbidden, map[string]interface{}{
+			"operatorId": e.OperatorId,
+			"userId":     e.UserId,
+		})
+	})
+	event.SubscribeAsync(func(e *event.UsersMerged) {
+		WebhookService.Dispatch(constants.EventUserMerge, map[string]interface{}{
+			"operatorId": e.OperatorId,
+			"fromUserId": e.FromUserId,
+			"toUserId":   e.ToUserId,
+		})
+	})
+	event.SubscribeAsync(func(e *event.UserDeleted) {
+		WebhookService.Dispatch(constants.EventUserDelete, map[string]interface{}{
+			"operatorId": e.OperatorId,
+			"userId":     e.UserId,
+			"purge":      e.Purge,
+		})
+	})
+
+	// 监控指标
+	event.Subscribe(func(e *event.TopicPublished) {
+		metrics.ContentCreated.Inc(constants.EntityTopic)
+	})
+	event.Subscribe(func(e *event.ArticlePublished) {
+		metrics.ContentCreated.Inc(constants.EntityArticle)
+	})
+	event.Subscribe(func(e *event.TweetPublished) {
+		metrics.ContentCreated.Inc(constants.EntityTweet)
+	})
+	event.Subscribe(func(e *event.CommentCreated) {
+		metrics.ContentCreated.Inc(constants.EntityComment)
+	})
+}
+
+// 删除内容对应的Webhook事件
+var contentDeletedWebhookEvents = map[string]string{
+	constants.EntityTopic:   constants.EventTopicDelete,
+	constants.EntityArticle: constants.EventArticleDelete,
+	constants.EntityComment: constants.EventCommentDelete,
+	constants.EntityTweet:   constants.EventTweetDelete,
+}
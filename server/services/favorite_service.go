@@ -2,10 +2,13 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"context"
 	"errors"
 
+	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
 
+	"bbs-go/common/tracing"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
@@ -70,29 +73,32 @@ func (s *favoriteService) GetBy(userId int64, entityType string, entityId int64)
 }
 
 // 收藏文章
-func (s *favoriteService) AddArticleFavorite(userId, articleId int64) error {
-	article := repositories.ArticleRepository.Get(simple.DB(), articleId)
+func (s *favoriteService) AddArticleFavorite(ctx context.Context, userId, articleId int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	article := repositories.ArticleRepository.Get(db, articleId)
 	if article == nil || article.Status != constants.StatusOk {
 		return errors.New("收藏的文章不存在")
 	}
-	return s.addFavorite(userId, constants.EntityArticle, articleId)
+	return s.addFavorite(db, userId, constants.EntityArticle, articleId)
 }
 
 // 收藏主题
-func (s *favoriteService) AddTopicFavorite(userId, topicId int64) error {
-	topic := repositories.TopicRepository.Get(simple.DB(), topicId)
+func (s *favoriteService) AddTopicFavorite(ctx context.Context, userId, topicId int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	topic := repositories.TopicRepository.Get(db, topicId)
 	if topic == nil || topic.Status != constants.StatusOk {
 		return errors.New("收藏的话题不存在")
 	}
-	return s.addFavorite(userId, constants.EntityTopic, topicId)
+	return s.addFavorite(db, userId, constants.EntityTopic, topicId)
 }
 
-func (s *favoriteService) addFavorite(userId int64, entityType string, entityId int64) error {
-	temp := s.GetBy(userId, entityType, entityId)
+func (s *favoriteService) addFavorite(db *gorm.DB, userId int64, entityType string, entityId int64) error {
+	temp := repositories.FavoriteRepository.Take(db, "user_id = ? and entity_type = ? and entity_id = ?",
+		userId, entityType, entityId)
 	if temp != nil { // 已经收藏
 		return nil
 	}
-	return repositories.FavoriteRepository.Create(simple.DB(), &model.Favorite{
+	return repositories.FavoriteRepository.Create(db, &model.Favorite{
 		UserId:     userId,
 		EntityType: entityType,
 		EntityId:   entityId,
@@ -0,0 +1,276 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/goburrow/cache"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	bbscache "bbs-go/cache"
+	"bbs-go/common"
+	"bbs-go/common/feed"
+	"bbs-go/common/urls"
+	"bbs-go/config"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+// 订阅源类型
+const (
+	FeedTypeNode          = "node"           // 节点下的话题
+	FeedTypeTag           = "tag"            // 标签下的话题和文章
+	FeedTypeUser          = "user"           // 用户发表的话题和文章
+	FeedTypeTopicComments = "topic-comments" // 话题的评论
+)
+
+var FeedService = newFeedService()
+
+func newFeedService() *feedService {
+	return &feedService{
+		cache: cache.New(cache.WithMaximumSize(1000)),
+	}
+}
+
+type feedService struct {
+	cache cache.Cache
+}
+
+// FeedContent 输出后的订阅源
+type FeedContent struct {
+	ContentType  string
+	Body         string
+	ETag         string
+	LastModified int64 // 条目最后更新时间，毫秒
+	createTime   time.Time
+}
+
+// Get 获取订阅源，订阅对象不存在时返回nil，结果按配置的时长缓存
+func (s *feedService) Get(feedType string, id int64, format string) (*FeedContent, error) {
+	format = feed.ParseFormat(format)
+	key := feedType + ":" + strconv.FormatInt(id, 10) + ":" + format
+	if val, found := s.cache.GetIfPresent(key); found {
+		if content := val.(*FeedContent); time.Since(content.createTime) < s.CacheDuration() {
+			return content, nil
+		}
+	}
+
+	f := s.build(feedType, id)
+	if f == nil {
+		return nil, nil
+	}
+	contentType, body, err := f.Render(format)
+	if err != nil {
+		return nil, err
+	}
+	sum := md5.Sum([]byte(body))
+	content := &FeedContent{
+		ContentType:  contentType,
+		Body:         body,
+		ETag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		LastModified: f.LastModified(),
+		createTime:   time.Now(),
+	}
+	s.cache.Put(key, content)
+	return content, nil
+}
+
+func (s *feedService) build(feedType string, id int64) *feed.Feed {
+	switch feedType {
+	case FeedTypeNode:
+		return s.buildNodeFeed(id)
+	case FeedTypeTag:
+		return s.buildTagFeed(id)
+	case FeedTypeUser:
+		return s.buildUserFeed(id)
+	case FeedTypeTopicComments:
+		return s.buildTopicCommentsFeed(id)
+	}
+	return nil
+}
+
+func (s *feedService) buildNodeFeed(nodeId int64) *feed.Feed {
+	node := repositories.TopicNodeRepository.Get(simple.DB(), nodeId)
+	if node == nil {
+		return nil
+	}
+	f := feed.New(node.Name+" - "+s.siteTitle(), urls.TopicNodeUrl(node.Id), node.Description)
+	topics := repositories.TopicRepository.Find(simple.DB(), simple.NewSqlCnd().Eq("node_id", node.Id).
+		Eq("status", constants.StatusOk).Desc("id").Limit(s.size()))
+	for i := range topics {
+		s.addTopic(f, &topics[i])
+	}
+	return f
+}
+
+func (s *feedService) buildTagFeed(tagId int64) *feed.Feed {
+	tag := bbscache.TagCache.Get(tagId)
+	if tag == nil {
+		return nil
+	}
+	f := feed.New(tag.Name+" - "+s.siteTitle(), urls.TagTopicsUrl(tag.Id), tag.Description)
+
+	topicTags := repositories.TopicTagRepository.Find(simple.DB(), simple.NewSqlCnd().Eq("tag_id", tag.Id).
+		Eq("status", constants.StatusOk).Desc("id").Limit(s.size()))
+	var topicIds []int64
+	for _, topicTag := range topicTags {
+		topicIds = append(topicIds, topicTag.TopicId)
+	}
+	articleTags := repositories.ArticleTagRepository.Find(simple.DB(), simple.NewSqlCnd().Eq("tag_id", tag.Id).
+		Eq("status", constants.StatusOk).Desc("id").Limit(s.size()))
+	var articleIds []int64
+	for _, articleTag := range articleTags {
+		articleIds = append(articleIds, articleTag.ArticleId)
+	}
+	s.addTopicsAndArticles(f, topicIds, articleIds)
+	return f
+}
+
+func (s *feedService) buildUserFeed(userId int64) *feed.Feed {
+	user := bbscache.UserCache.Get(userId)
+	if user == nil || user.Status != constants.StatusOk {
+		return nil
+	}
+	f := feed.New(user.Nickname+" - "+s.siteTitle(), urls.UserUrl(user.Id), user.Description)
+
+	topics := repositories.TopicRepository.Find(simple.DB(), simple.NewSqlCnd("id").Eq("user_id", user.Id).
+		Eq("status", constants.StatusOk).Desc("id").Limit(s.size()))
+	var topicIds []int64
+	for _, topic := range topics {
+		topicIds = append(topicIds, topic.Id)
+	}
+	articles := repositories.ArticleRepository.Find(simple.DB(), simple.NewSqlCnd("id").Eq("user_id", user.Id).
+		Eq("status", constants.StatusOk).Desc("id").Limit(s.size()))
+	var articleIds []int64
+	for _, article := range articles {
+		articleIds = append(articleIds, article.Id)
+	}
+	s.addTopicsAndArticles(f, topicIds, articleIds)
+	return f
+}
+
+func (s *feedService) buildTopicCommentsFeed(topicId int64) *feed.Feed {
+	topic := repositories.TopicRepository.Get(simple.DB(), topicId)
+	if topic == nil || topic.Status != constants.StatusOk {
+		return nil
+	}
+	topicUrl := urls.TopicUrl(topic.Id)
+	f := feed.New(topic.Title+" - "+s.siteTitle(), topicUrl, common.GetMarkdownSummary(topic.Content))
+	comments := repositories.CommentRepository.Find(simple.DB(), simple.NewSqlCnd().
+		Eq("entity_type", constants.EntityTopic).Eq("entity_id", topic.Id).
+		Eq("status", constants.StatusOk).Eq("delete_time", 0).Desc("id").Limit(s.size()))
+	for _, comment := range comments {
+		user := bbscache.UserCache.Get(comment.UserId)
+		if user == nil {
+			continue
+		}
+		f.Add(&feed.Item{
+			Title:       "Re: " + topic.Title,
+			Link:        urls.CommentUrl(topicUrl, comment.Id),
+			Description: common.GetSummary(comment.ContentType, comment.Content),
+			Author:      user,
+			Created:     comment.CreateTime,
+			Updated:     comment.UpdateTime,
+		})
+	}
+	return f
+}
+
+// addTopicsAndArticles 话题和文章合并后按发布时间倒序，最多保留配置的条目数
+func (s *feedService) addTopicsAndArticles(f *feed.Feed, topicIds, articleIds []int64) {
+	var (
+		topics   []model.Topic
+		articles []model.Article
+	)
+	if len(topicIds) > 0 {
+		topics = repositories.TopicRepository.Find(simple.DB(), simple.NewSqlCnd().In("id", topicIds).
+			Eq("status", constants.StatusOk).Desc("create_time"))
+	}
+	if len(articleIds) > 0 {
+		articles = repositories.ArticleRepository.Find(simple.DB(), simple.NewSqlCnd().In("id", articleIds).
+			Eq("status", constants.StatusOk).Desc("create_time"))
+	}
+	i, j := 0, 0
+	for len(f.Items) < s.size() && (i < len(topics) || j < len(articles)) {
+		if j >= len(articles) || (i < len(topics) && topics[i].CreateTime >= articles[j].CreateTime) {
+			s.addTopic(f, &topics[i])
+			i++
+		} else {
+			s.addArticle(f, &articles[j])
+			j++
+		}
+	}
+}
+
+func (s *feedService) addTopic(f *feed.Feed, topic *model.Topic) {
+	user := bbscache.UserCache.Get(topic.UserId)
+	if user == nil {
+		return
+	}
+	f.Add(&feed.Item{
+		Title:       topic.Title,
+		Link:        urls.TopicUrl(topic.Id),
+		Description: common.GetMarkdownSummary(topic.Content),
+		Author:      user,
+		Created:     topic.CreateTime,
+	})
+}
+
+func (s *feedService) addArticle(f *feed.Feed, article *model.Article) {
+	user := bbscache.UserCache.Get(article.UserId)
+	if user == nil {
+		return
+	}
+	f.Add(&feed.Item{
+		Title:       article.Title,
+		Link:        urls.ArticleUrl(article.Id),
+		Description: common.GetSummary(article.ContentType, article.Content),
+		Author:      user,
+		Created:     article.CreateTime,
+		Updated:     article.UpdateTime,
+	})
+}
+
+// NewSiteFeed 全站订阅源
+func (s *feedService) NewSiteFeed() *feed.Feed {
+	return feed.New(s.siteTitle(), config.Instance.BaseUrl,
+		bbscache.SysConfigCache.GetValue(constants.SysConfigSiteDescription))
+}
+
+// WriteStatic 将订阅源写入静态文件目录
+func (s *feedService) WriteStatic(f *feed.Feed, atomFilename, rssFilename string) {
+	if atom, err := f.ToAtom(); err != nil {
+		logrus.Error(err)
+	} else {
+		_ = simple.WriteString(path.Join(config.Instance.StaticPath, atomFilename), atom, false)
+	}
+	if rss, err := f.ToRss(); err != nil {
+		logrus.Error(err)
+	} else {
+		_ = simple.WriteString(path.Join(config.Instance.StaticPath, rssFilename), rss, false)
+	}
+}
+
+func (s *feedService) siteTitle() string {
+	return bbscache.SysConfigCache.GetValue(constants.SysConfigSiteTitle)
+}
+
+func (s *feedService) size() int {
+	if config.Instance.Feed.Size > 0 {
+		return config.Instance.Feed.Size
+	}
+	return 50
+}
+
+// CacheDuration 订阅源缓存时长
+func (s *feedService) CacheDuration() time.Duration {
+	if config.Instance.Feed.CacheSeconds > 0 {
+		return time.Duration(config.Instance.Feed.CacheSeconds) * time.Second
+	}
+	return 5 * time.Minute
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/goburrow/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/common/hashtag"
+	"bbs-go/config"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+// 每条动态最多关联的话题标签数量
+const maxTweetHashtags = 10
+
+var HashtagService = newHashtagService()
+
+func newHashtagService() *hashtagService {
+	s := &hashtagService{}
+	s.trendingCache = cache.NewLoadingCache(
+		func(key cache.Key) (cache.Value, error) {
+			return s.findTrending(key.(int)), nil
+		},
+		cache.WithMaximumSize(10),
+		cache.WithRefreshAfterWrite(5*time.Minute),
+	)
+	return s
+}
+
+type hashtagService struct {
+	trendingCache cache.LoadingCache
+}
+
+// TrendingHashtag 热门话题标签
+type TrendingHashtag struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"` // 统计时间范围内的动态数量
+}
+
+func (s *hashtagService) GetByName(name string) *model.Hashtag {
+	return repositories.HashtagRepository.Take(simple.DB(), "name = ?", name)
+}
+
+// AddTweetHashtags 提取动态内容中的话题标签并建立索引
+func (s *hashtagService) AddTweetHashtags(tx *gorm.DB, tweet *model.Tweet) error {
+	for _, name := range hashtag.Extract(tweet.Content, maxTweetHashtags) {
+		tag, err := s.incrByName(tx, name, tweet.CreateTime)
+		if err != nil {
+			return err
+		}
+		if err := repositories.TweetHashtagRepository.Create(tx, &model.TweetHashtag{
+			TweetId:    tweet.Id,
+			HashtagId:  tag.Id,
+			CreateTime: tweet.CreateTime,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// incrByName 话题标签的动态数量+1，标签不存在时创建；同时发表的动态可能引用同一个新标签，由唯一索引保证只创建一个
+func (s *hashtagService) incrByName(tx *gorm.DB, name string, tweetTime int64) (*model.Hashtag, error) {
+	update := func() (int64, error) {
+		ret := tx.Model(&model.Hashtag{}).Where("name = ?", name).Updates(map[string]interface{}{
+			"tweet_count":     gorm.Expr("tweet_count + 1"),
+			"last_tweet_time": tweetTime,
+		})
+		return ret.RowsAffected, ret.Error
+	}
+	if rows, err := update(); err != nil {
+		return nil, err
+	} else if rows == 0 {
+		tag := &model.Hashtag{
+			Name:          name,
+			TweetCount:    1,
+			CreateTime:    tweetTime,
+			LastTweetTime: tweetTime,
+		}
+		err := repositories.HashtagRepository.Create(tx, tag)
+		if err == nil {
+			return tag, nil
+		}
+		// 违反唯一索引：其他动态已经创建，重新更新
+		if rows, e := update(); e != nil || rows == 0 {
+			return nil, err
+		}
+	}
+	tag := repositories.HashtagRepository.Take(tx, "name = ?", name)
+	if tag == nil {
+		return nil, errors.New("话题标签不存在：" + name)
+	}
+	return tag, nil
+}
+
+// IncrTweetCount 动态删除或恢复时更新其关联的话题标签的动态数量，关联关系保留以便恢复
+func (s *hashtagService) IncrTweetCount(tx *gorm.DB, tweetId int64, incr int) error {
+	return tx.Exec("update t_hashtag set tweet_count = tweet_count + ? where tweet_count + ? >= 0 and "+
+		"id in (select hashtag_id from t_tweet_hashtag where tweet_id = ?)", incr, incr, tweetId).Error
+}
+
+// GetTweets 话题标签下的动态，cursor 为上一页最后一条动态的编号
+func (s *hashtagService) GetTweets(name string, cursor int64) (tweets []model.Tweet, nextCursor int64) {
+	tag := s.GetByName(strings.TrimPrefix(name, "#"))
+	if tag == nil {
+		return nil, cursor
+	}
+	db := simple.DB().Model(&model.Tweet{}).
+		Joins("inner join t_tweet_hashtag on t_tweet_hashtag.tweet_id = t_tweet.id").
+		Where("t_tweet_hashtag.hashtag_id = ? and t_tweet.status = ?", tag.Id, constants.StatusOk)
+	if cursor > 0 {
+		db = db.Where("t_tweet.id < ?", cursor)
+	}
+	db.Order("t_tweet.id desc").Limit(20).Select("t_tweet.*").Find(&tweets)
+	if len(tweets) > 0 {
+		nextCursor = tweets[len(tweets)-1].Id
+	} else {
+		nextCursor = cursor
+	}
+	return
+}
+
+// GetTrending 最近一段时间内动态数量最多的话题标签，结果缓存5分钟
+func (s *hashtagService) GetTrending(limit int) []TrendingHashtag {
+	val, err := s.trendingCache.Get(limit)
+	if err != nil || val == nil {
+		return nil
+	}
+	return val.([]TrendingHashtag)
+}
+
+func (s *hashtagService) findTrending(limit int) []TrendingHashtag {
+	hours := config.Instance.Tweet.TrendingHours
+	if hours <= 0 {
+		hours = 24
+	}
+	since := simple.NowTimestamp() - int64(hours)*int64(time.Hour/time.Millisecond)
+	var ret []TrendingHashtag
+	simple.DB().Table("t_tweet_hashtag").
+		Select("t_hashtag.name as name, count(*) as count").
+		Joins("inner join t_hashtag on t_hashtag.id = t_tweet_hashtag.hashtag_id").
+		Joins("inner join t_tweet on t_tweet.id = t_tweet_hashtag.tweet_id").
+		Where("t_tweet_hashtag.create_time >= ? and t_tweet.status = ?", since, constants.StatusOk).
+		Group("t_hashtag.id, t_hashtag.name").
+		Order("count desc").
+		Limit(limit).
+		Scan(&ret)
+	return ret
+}
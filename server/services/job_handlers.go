@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"bbs-go/model"
+	"bbs-go/model/constants"
+)
+
+// 注册后台任务处理函数
+func init() {
+	// 创建站内消息
+	JobService.RegisterHandler(constants.JobTypeMessageCreate, func(ctx context.Context, payload []byte) error {
+		msg := &model.Message{}
+		if err := json.Unmarshal(payload, msg); err != nil {
+			return err
+		}
+		return MessageService.Consume(msg)
+	})
+
+	// 发送消息邮件提醒
+	JobService.RegisterHandler(constants.JobTypeMessageEmail, func(ctx context.Context, payload []byte) error {
+		var messageId int64
+		if err := json.Unmarshal(payload, &messageId); err != nil {
+			return err
+		}
+		message := MessageService.Get(messageId)
+		if message == nil {
+			return errors.New("消息不存在")
+		}
+		return MessageService.SendEmailNotice(message)
+	})
+
+	// 发送摘要邮件，参数为摘要周期
+	JobService.RegisterHandler(constants.JobTypeDigest, func(ctx context.Context, payload []byte) error {
+		var period string
+		if err := json.Unmarshal(payload, &period); err != nil {
+			return err
+		}
+		return NotificationSettingService.SendDigests(period)
+	})
+
+	// 给单个用户发送摘要邮件
+	JobService.RegisterHandler(constants.JobTypeDigestUser, func(ctx context.Context, payload []byte) error {
+		job := &digestJob{}
+		if err := json.Unmarshal(payload, job); err != nil {
+			return err
+		}
+		return NotificationSettingService.SendDigest(job.UserId, job.Period)
+	})
+
+	// 发送发件箱中的邮件
+	JobService.RegisterHandler(constants.JobTypeEmailSend, func(ctx context.Context, payload []byte) error {
+		var outboxId int64
+		if err := json.Unmarshal(payload, &outboxId); err != nil {
+			return err
+		}
+		return EmailOutboxService.Deliver(ctx, outboxId)
+	})
+
+	// ActivityPub推送内容
+	JobService.RegisterHandler(constants.JobTypeApPublish, func(ctx context.Context, payload []byte) error {
+		job := &apPublishJob{}
+		if err := json.Unmarshal(payload, job); err != nil {
+			return err
+		}
+		return ActivityPubService.Publish(job)
+	})
+
+	// ActivityPub投递活动
+	JobService.RegisterHandler(constants.JobTypeApDeliver, func(ctx context.Context, payload []byte) error {
+		job := &apDeliverJob{}
+		if err := json.Unmarshal(payload, job); err != nil {
+			return err
+		}
+		return ActivityPubService.Deliver(job)
+	})
+
+	// 生成用户数据导出
+	JobService.RegisterHandler(constants.JobTypeUserExport, func(ctx context.Context, payload []byte) error {
+		var exportId int64
+		if err := json.Unmarshal(payload, &exportId); err != nil {
+			return err
+		}
+		return UserExportService.Build(exportId)
+	})
+
+	// 执行注销账号
+	JobService.RegisterHandler(constants.JobTypeUserDelete, func(ctx context.Context, payload []byte) error {
+		var deletionId int64
+		if err := json.Unmarshal(payload, &deletionId); err != nil {
+			return err
+		}
+		return UserDeletionService.Execute(deletionId)
+	})
+
+	// 同步用户计数
+	JobService.RegisterHandler(constants.JobTypeSyncUserCount, func(ctx context.Context, payload []byte) error {
+		UserService.SyncUserCount()
+		return nil
+	})
+}
@@ -0,0 +1,377 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/tracing"
+	"bbs-go/config"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+// JobHandler 任务处理函数，返回error时任务会按指数退避重试；ctx 中带有本次执行的 span
+type JobHandler func(ctx context.Context, payload []byte) error
+
+const (
+	jobPollInterval  = 2 * time.Second  // 没有任务时的轮询间隔
+	jobRetryBase     = 10 * time.Second // 第一次重试的间隔，之后每次翻倍
+	jobRetryMax      = time.Hour        // 最大重试间隔
+	jobHeartbeat     = time.Minute      // 执行中的任务更新心跳的间隔
+	jobRunningExpire = 5 * time.Minute  // 执行中的任务超过该时间没有心跳时认为执行任务的进程已退出，重新执行
+)
+
+var JobService = newJobService()
+
+func newJobService() *jobService {
+	return &jobService{
+		handlers: make(map[string]JobHandler),
+		notify:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+}
+
+type jobService struct {
+	mutex     sync.RWMutex
+	handlers  map[string]JobHandler
+	notify    chan struct{} // 有新任务时通知空闲的协程
+	stop      chan struct{} // 关闭后不再领取新任务
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+func (s *jobService) Get(id int64) *model.Job {
+	return repositories.JobRepository.Get(simple.DB(), id)
+}
+
+func (s *jobService) Take(where ...interface{}) *model.Job {
+	return repositories.JobRepository.Take(simple.DB(), where...)
+}
+
+func (s *jobService) Find(cnd *simple.SqlCnd) []model.Job {
+	return repositories.JobRepository.Find(simple.DB(), cnd)
+}
+
+func (s *jobService) FindOne(cnd *simple.SqlCnd) *model.Job {
+	return repositories.JobRepository.FindOne(simple.DB(), cnd)
+}
+
+func (s *jobService) FindPageByParams(params *simple.QueryParams) (list []model.Job, paging *simple.Paging) {
+	return repositories.JobRepository.FindPageByParams(simple.DB(), params)
+}
+
+func (s *jobService) FindPageByCnd(cnd *simple.SqlCnd) (list []model.Job, paging *simple.Paging) {
+	return repositories.JobRepository.FindPageByCnd(simple.DB(), cnd)
+}
+
+func (s *jobService) Count(cnd *simple.SqlCnd) int {
+	return repositories.JobRepository.Count(simple.DB(), cnd)
+}
+
+func (s *jobService) Create(t *model.Job) error {
+	return repositories.JobRepository.Create(simple.DB(), t)
+}
+
+func (s *jobService) Update(t *model.Job) error {
+	return repositories.JobRepository.Update(simple.DB(), t)
+}
+
+func (s *jobService) Updates(id int64, columns map[string]interface{}) error {
+	return repositories.JobRepository.Updates(simple.DB(), id, columns)
+}
+
+func (s *jobService) UpdateColumn(id int64, name string, value interface{}) error {
+	return repositories.JobRepository.UpdateColumn(simple.DB(), id, name, value)
+}
+
+func (s *jobService) Delete(id int64) {
+	repositories.JobRepository.Delete(simple.DB(), id)
+}
+
+// RegisterHandler 注册任务处理函数
+func (s *jobService) RegisterHandler(jobType string, handler JobHandler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handlers[jobType] = handler
+}
+
+func (s *jobService) getHandler(jobType string) JobHandler {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.handlers[jobType]
+}
+
+// Enqueue 添加任务，payload 会序列化为JSON
+func (s *jobService) Enqueue(jobType string, payload interface{}) (*model.Job, error) {
+	return s.EnqueueTx(simple.DB(), jobType, payload)
+}
+
+// EnqueueTx 在事务中添加任务，事务回滚时任务不会执行
+func (s *jobService) EnqueueTx(tx *gorm.DB, jobType string, payload interface{}) (*model.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts := config.Instance.Job.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+	now := simple.NowTimestamp()
+	job := &model.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      constants.JobStatusPending,
+		MaxAttempts: maxAttempts,
+		RunTime:     now,
+		CreateTime:  now,
+		UpdateTime:  now,
+	}
+	if err := repositories.JobRepository.Create(tx, job); err != nil {
+		return nil, err
+	}
+	s.wakeup()
+	return job, nil
+}
+
+// Retry 重新执行死信任务
+func (s *jobService) Retry(id int64) error {
+	job := s.Get(id)
+	if job == nil {
+		return errors.New("任务不存在")
+	}
+	if job.Status != constants.JobStatusDead {
+		return errors.New("只能重新执行失败的任务")
+	}
+	err := s.Updates(id, map[string]interface{}{
+		"status":      constants.JobStatusPending,
+		"attempts":    0,
+		"run_time":    simple.NowTimestamp(),
+		"finish_time": 0,
+		"update_time": simple.NowTimestamp(),
+	})
+	if err == nil {
+		s.wakeup()
+	}
+	return err
+}
+
+// JobQueueStat 某类型、状态的任务数
+type JobQueueStat struct {
+	Type   string
+	Status int
+	Count  int64
+}
+
+// QueueStats 待执行和执行中的任务数，按类型统计，用于监控队列积压（站内消息、邮件等都通过任务队列处理）
+func (s *jobService) QueueStats() (list []JobQueueStat, err error) {
+	err = simple.DB().Model(&model.Job{}).Select("type, status, count(*) as count").
+		Where("status in (?)", []int{constants.JobStatusPending, constants.JobStatusRunning}).
+		Group("type, status").Scan(&list).Error
+	return
+}
+
+// Start 启动执行任务的协程
+func (s *jobService) Start() {
+	s.startOnce.Do(func() {
+		workers := config.Instance.Job.Workers
+		if workers <= 0 {
+			workers = 4
+		}
+		for i := 0; i < workers; i++ {
+			s.wg.Add(1)
+			go s.work()
+		}
+		s.wg.Add(1)
+		go s.resetExpired()
+		logrus.Infof("job workers started, workers=%d", workers)
+	})
+}
+
+// Shutdown 停止领取新任务，并等待执行中的任务完成，超时后返回false
+// 超时未完成的任务在进程退出后不再更新心跳，jobRunningExpire 之后会被重新执行
+func (s *jobService) Shutdown(timeout time.Duration) bool {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (s *jobService) wakeup() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *jobService) work() {
+	defer s.wg.Done()
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		if job := s.claim(); job != nil {
+			s.run(job)
+			continue
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case <-s.notify:
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// claim 领取一个到达执行时间的任务，多个协程、多个实例同时领取时只有一个能成功
+func (s *jobService) claim() *model.Job {
+	now := simple.NowTimestamp()
+	job := s.FindOne(simple.NewSqlCnd().Eq("status", constants.JobStatusPending).Lte("run_time", now).Asc("id"))
+	if job == nil {
+		return nil
+	}
+	ret := simple.DB().Model(&model.Job{}).Where("id = ? and status = ? and attempts = ?", job.Id, constants.JobStatusPending, job.Attempts).
+		Updates(map[string]interface{}{
+			"status":         constants.JobStatusRunning,
+			"attempts":       job.Attempts + 1,
+			"start_time":     now,
+			"heartbeat_time": now,
+			"update_time":    now,
+		})
+	if ret.Error != nil || ret.RowsAffected != 1 {
+		// 被其他协程领取了，通知自己继续领取下一个
+		s.wakeup()
+		return nil
+	}
+	job.Status = constants.JobStatusRunning
+	job.Attempts++
+	job.StartTime = now
+	return job
+}
+
+func (s *jobService) run(job *model.Job) {
+	ctx, span := tracing.Start(context.Background(), "job "+job.Type, tracing.KindInternal)
+	span.SetAttribute("job.id", job.Id)
+	span.SetAttribute("job.type", job.Type)
+	span.SetAttribute("job.attempts", job.Attempts)
+	defer span.End()
+
+	done := make(chan struct{})
+	go s.heartbeat(job, done)
+	err := s.execute(ctx, job)
+	close(done)
+	span.RecordError(err)
+	now := simple.NowTimestamp()
+	columns := map[string]interface{}{
+		"update_time": now,
+	}
+	if err == nil {
+		columns["status"] = constants.JobStatusSuccess
+		columns["finish_time"] = now
+		columns["last_error"] = ""
+	} else if job.Attempts >= job.MaxAttempts {
+		logrus.WithContext(ctx).Errorf("job dead, id=%d type=%s attempts=%d: %v", job.Id, job.Type, job.Attempts, err)
+		columns["status"] = constants.JobStatusDead
+		columns["finish_time"] = now
+		columns["last_error"] = err.Error()
+	} else {
+		logrus.WithContext(ctx).Warnf("job failed, id=%d type=%s attempts=%d: %v", job.Id, job.Type, job.Attempts, err)
+		columns["status"] = constants.JobStatusPending
+		columns["run_time"] = now + jobBackoff(job.Attempts).Milliseconds()
+		columns["last_error"] = err.Error()
+	}
+	if err := s.Updates(job.Id, columns); err != nil {
+		logrus.WithContext(ctx).Error(err)
+	}
+}
+
+// heartbeat 执行期间定期更新心跳，执行时间较长的任务（例如数据导出）不会被当作进程已退出而重复执行
+func (s *jobService) heartbeat(job *model.Job, done chan struct{}) {
+	ticker := time.NewTicker(jobHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := simple.DB().Model(&model.Job{}).Where("id = ? and status = ? and attempts = ?", job.Id,
+				constants.JobStatusRunning, job.Attempts).UpdateColumn("heartbeat_time", simple.NowTimestamp()).Error; err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// execute 执行任务，处理函数panic时按执行失败处理
+func (s *jobService) execute(ctx context.Context, job *model.Job) (err error) {
+	handler := s.getHandler(job.Type)
+	if handler == nil {
+		// 可能是新版本添加的任务类型，不立即进入死信状态
+		return errors.New("未注册的任务类型：" + job.Type)
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			logrus.WithContext(ctx).Errorf("job panic, id=%d type=%s: %v\n%s", job.Id, job.Type, e, debug.Stack())
+			err = fmt.Errorf("panic: %v", e)
+		}
+	}()
+	return handler(ctx, []byte(job.Payload))
+}
+
+// resetExpired 定期将长时间没有心跳的任务（执行任务的进程已退出）重置为待执行
+func (s *jobService) resetExpired() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		expireTime := simple.NowTimestamp() - jobRunningExpire.Milliseconds()
+		if err := simple.DB().Model(&model.Job{}).Where("status = ? and heartbeat_time < ?", constants.JobStatusRunning, expireTime).
+			Updates(map[string]interface{}{
+				"status":      constants.JobStatusPending,
+				"run_time":    simple.NowTimestamp(),
+				"last_error":  "执行任务的进程已退出",
+				"update_time": simple.NowTimestamp(),
+			}).Error; err != nil {
+			logrus.Error(err)
+		}
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// jobBackoff 第n次执行失败后的重试间隔
+func jobBackoff(attempts int) time.Duration {
+	d := time.Duration(float64(jobRetryBase) * math.Pow(2, float64(attempts-1)))
+	if d > jobRetryMax || d <= 0 {
+		return jobRetryMax
+	}
+	return d
+}
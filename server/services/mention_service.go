@@ -0,0 +1,142 @@
+package services
+
+import (
+	"html"
+	"strings"
+
+	"github.com/mlogclub/simple"
+	"github.com/mlogclub/simple/markdown"
+
+	"bbs-go/cache"
+	"bbs-go/common"
+	"bbs-go/common/mention"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+var MentionService = newMentionService()
+
+func newMentionService() *mentionService {
+	return &mentionService{}
+}
+
+// 每篇内容最多通知的用户数，超出的提及不发送消息
+const maxMentionsPerPost = 10
+
+type mentionService struct {
+}
+
+// GetMentionedUsers 解析内容中@提及的用户，去重并排除 excludeIds，最多返回 maxMentionsPerPost 个
+func (s *mentionService) GetMentionedUsers(contentType, content string, excludeIds ...int64) []*model.User {
+	if !strings.Contains(content, "@") {
+		return nil
+	}
+	seen := make(map[int64]bool)
+	for _, id := range excludeIds {
+		seen[id] = true
+	}
+	var users []*model.User
+	for _, name := range mention.Extract(s.toHtml(contentType, content)) {
+		if len(users) >= maxMentionsPerPost {
+			break
+		}
+		user := cache.UserCache.GetByName(name)
+		if user == nil || seen[user.Id] {
+			continue
+		}
+		seen[user.Id] = true
+		users = append(users, user)
+	}
+	return users
+}
+
+// SendTopicMentionMsg 话题中提及的用户
+func (s *mentionService) SendTopicMentionMsg(topic *model.Topic) {
+	s.send(topic.UserId, constants.ContentTypeMarkdown, topic.Content, "在话题中提到了你", "《"+topic.Title+"》",
+		map[string]interface{}{
+			"entityType": constants.EntityTopic,
+			"entityId":   topic.Id,
+		})
+}
+
+// SendArticleMentionMsg 文章中提及的用户，待审核的文章不发送
+func (s *mentionService) SendArticleMentionMsg(article *model.Article) {
+	if article.Status != constants.StatusOk {
+		return
+	}
+	s.send(article.UserId, article.ContentType, article.Content, "在文章中提到了你", "《"+article.Title+"》",
+		map[string]interface{}{
+			"entityType": constants.EntityArticle,
+			"entityId":   article.Id,
+		})
+}
+
+// SendTweetMentionMsg 动态中提及的用户
+func (s *mentionService) SendTweetMentionMsg(tweet *model.Tweet) {
+	s.send(tweet.UserId, constants.ContentTypeText, tweet.Content, "在动态中提到了你", tweet.Content,
+		map[string]interface{}{
+			"entityType": constants.EntityTweet,
+			"entityId":   tweet.Id,
+		})
+}
+
+// SendCommentMentionMsg 评论中提及的用户，已经收到回复消息的作者和被引用人不再重复通知
+func (s *mentionService) SendCommentMentionMsg(comment *model.Comment) {
+	var (
+		excludeIds   []int64
+		quoteContent string
+	)
+	if quote := MessageService.getQuoteComment(comment); quote != nil {
+		excludeIds = append(excludeIds, quote.UserId)
+	}
+	switch comment.EntityType {
+	case constants.EntityArticle:
+		if article := repositories.ArticleRepository.Get(simple.DB(), comment.EntityId); article != nil {
+			excludeIds = append(excludeIds, article.UserId)
+			quoteContent = "《" + article.Title + "》"
+		}
+	case constants.EntityTopic:
+		if topic := repositories.TopicRepository.Get(simple.DB(), comment.EntityId); topic != nil {
+			excludeIds = append(excludeIds, topic.UserId)
+			quoteContent = "《" + topic.Title + "》"
+		}
+	case constants.EntityTweet:
+		if tweet := repositories.TweetRepository.Get(simple.DB(), comment.EntityId); tweet != nil {
+			excludeIds = append(excludeIds, tweet.UserId)
+			quoteContent = tweet.Content
+		}
+	}
+	s.send(comment.UserId, comment.ContentType, comment.Content,
+		"在评论中提到了你："+common.GetSummary(comment.ContentType, comment.Content), quoteContent,
+		map[string]interface{}{
+			"entityType": comment.EntityType,
+			"entityId":   comment.EntityId,
+			"commentId":  comment.Id,
+			"quoteId":    comment.QuoteId,
+		}, excludeIds...)
+}
+
+func (s *mentionService) send(fromId int64, contentType, content, action, quoteContent string,
+	extraDataMap map[string]interface{}, excludeIds ...int64) {
+	from := cache.UserCache.Get(fromId)
+	if from == nil {
+		return
+	}
+	for _, user := range s.GetMentionedUsers(contentType, content, append(excludeIds, fromId)...) {
+		MessageService.Produce(fromId, user.Id, from.Nickname+" "+action, quoteContent, constants.MsgTypeMention,
+			extraDataMap)
+	}
+}
+
+func (s *mentionService) toHtml(contentType, content string) string {
+	switch contentType {
+	case constants.ContentTypeMarkdown:
+		htmlContent, _ := markdown.New(markdown.SummaryLen(0)).Run(content)
+		return htmlContent
+	case constants.ContentTypeHtml:
+		return content
+	default:
+		return html.EscapeString(content)
+	}
+}
@@ -3,8 +3,8 @@ package services
 import (
 	"bbs-go/common/urls"
 	"bbs-go/model/constants"
-	"sync"
 
+	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
 	"github.com/sirupsen/logrus"
 
@@ -22,14 +22,10 @@ var messageLog = logrus.WithFields(logrus.Fields{
 })
 
 func newMessageService() *messageService {
-	return &messageService{
-		messagesChan: make(chan *model.Message),
-	}
+	return &messageService{}
 }
 
 type messageService struct {
-	messagesChan        chan *model.Message
-	messagesConsumeOnce sync.Once
 }
 
 func (s *messageService) Get(id int64) *model.Message {
@@ -91,7 +87,7 @@ func (s *messageService) MarkRead(userId int64) {
 // 评论被回复消息
 func (s *messageService) SendCommentMsg(comment *model.Comment) {
 	user := cache.UserCache.Get(comment.UserId)
-	quote := s.getQuoteComment(comment.QuoteId)
+	quote := s.getQuoteComment(comment)
 	summary := common.GetSummary(comment.ContentType, comment.Content)
 
 	var (
@@ -158,22 +154,28 @@ func (s *messageService) SendCommentMsg(comment *model.Comment) {
 	}
 }
 
-func (s *messageService) getQuoteComment(quoteId int64) *model.Comment {
+// getQuoteComment 被回复的评论，优先取引用的评论，其次取楼中楼的上级评论
+func (s *messageService) getQuoteComment(comment *model.Comment) *model.Comment {
+	quoteId := comment.QuoteId
+	if quoteId <= 0 {
+		quoteId = comment.ParentId
+	}
 	if quoteId <= 0 {
 		return nil
 	}
 	return repositories.CommentRepository.Get(simple.DB(), quoteId)
 }
 
-// 生产，将消息数据放入chan
+// 生产，添加创建消息的后台任务
 func (s *messageService) Produce(fromId, toId int64, content, quoteContent string, msgType int,
 	extraDataMap map[string]interface{}) {
 	to := cache.UserCache.Get(toId)
 	if to == nil || to.Type != constants.UserTypeNormal {
 		return
 	}
-
-	s.Consume()
+	if NotificationSettingService.GetMode(toId, msgType) == constants.NotifyModeOff {
+		return
+	}
 
 	var (
 		extraData string
@@ -182,7 +184,7 @@ func (s *messageService) Produce(fromId, toId int64, content, quoteContent strin
 	if extraData, err = simple.FormatJson(extraDataMap); err != nil {
 		messageLog.Error("格式化extraData错误", err)
 	}
-	s.messagesChan <- &model.Message{
+	if _, err := JobService.Enqueue(constants.JobTypeMessageCreate, &model.Message{
 		FromId:       fromId,
 		UserId:       toId,
 		Content:      content,
@@ -191,44 +193,50 @@ func (s *messageService) Produce(fromId, toId int64, content, quoteContent strin
 		ExtraData:    extraData,
 		Status:       constants.MsgStatusUnread,
 		CreateTime:   simple.NowTimestamp(),
+	}); err != nil {
+		messageLog.Error("添加消息任务失败", err)
 	}
 }
 
-// 消费，消费chan中的消息
-func (s *messageService) Consume() {
-	s.messagesConsumeOnce.Do(func() {
-		go func() {
-			messageLog.Info("开始消费系统消息...")
-			for {
-				msg := <-s.messagesChan
-				messageLog.Info("处理消息：from=", msg.FromId, " to=", msg.UserId)
-
-				if err := s.Create(msg); err != nil {
-					messageLog.Info("创建消息发生异常...", err)
-				} else {
-					s.SendEmailNotice(msg)
-				}
-			}
-		}()
+// 消费，创建消息，通知方式为立即发送邮件时添加发送邮件通知的后台任务；摘要方式的消息由摘要任务统一发送
+func (s *messageService) Consume(msg *model.Message) error {
+	messageLog.Info("处理消息：from=", msg.FromId, " to=", msg.UserId)
+	return simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		if err := repositories.MessageRepository.Create(tx, msg); err != nil {
+			return err
+		}
+		if !email.Enabled() {
+			return nil
+		}
+		if NotificationSettingService.GetMode(msg.UserId, msg.Type) != constants.NotifyModeEmail {
+			return nil
+		}
+		user := cache.UserCache.Get(msg.UserId)
+		if user == nil || len(user.Email.String) == 0 {
+			messageLog.Info("邮件未发送，没设置邮箱...")
+			return nil
+		}
+		_, err := JobService.EnqueueTx(tx, constants.JobTypeMessageEmail, msg.Id)
+		return err
 	})
 }
 
 // 发送邮件通知
-func (s *messageService) SendEmailNotice(message *model.Message) {
+func (s *messageService) SendEmailNotice(message *model.Message) error {
 	user := cache.UserCache.Get(message.UserId)
-	if user != nil && len(user.Email.String) > 0 {
-		var (
-			siteTitle  = cache.SysConfigCache.GetValue(constants.SysConfigSiteTitle)
-			emailTitle = "新消息提醒 - " + siteTitle
-		)
-
-		_ = email.SendTemplateEmail(user.Email.String, emailTitle, emailTitle, message.Content,
-			message.QuoteContent, &model.ActionLink{
-				Title: "点击查看详情",
-				Url:   urls.AbsUrl("/user/messages"),
-			})
-		messageLog.Info("发送邮件...email=", user.Email)
-	} else {
+	if user == nil || len(user.Email.String) == 0 {
 		messageLog.Info("邮件未发送，没设置邮箱...")
+		return nil
+	}
+	_, err := EmailOutboxService.Send(user.Email.String, constants.EmailTemplateNotice, map[string]interface{}{
+		"Content":        message.Content,
+		"QuoteContent":   message.QuoteContent,
+		"Url":            urls.AbsUrl("/user/messages"),
+		"UnsubscribeUrl": NotificationSettingService.UnsubscribeUrl(user.Id),
+	}, NotificationSettingService.UnsubscribeHeaders(user.Id))
+	if err != nil {
+		return err
 	}
+	messageLog.Info("发送邮件...email=", user.Email)
+	return nil
 }
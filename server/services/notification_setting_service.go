@@ -0,0 +1,435 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/cache"
+	"bbs-go/common/email"
+	"bbs-go/common/urls"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+var NotificationSettingService = newNotificationSettingService()
+
+func newNotificationSettingService() *notificationSettingService {
+	return &notificationSettingService{}
+}
+
+type notificationSettingService struct {
+}
+
+func (s *notificationSettingService) Get(id int64) *model.NotificationSetting {
+	return repositories.NotificationSettingRepository.Get(simple.DB(), id)
+}
+
+func (s *notificationSettingService) Take(where ...interface{}) *model.NotificationSetting {
+	return repositories.NotificationSettingRepository.Take(simple.DB(), where...)
+}
+
+func (s *notificationSettingService) Find(cnd *simple.SqlCnd) []model.NotificationSetting {
+	return repositories.NotificationSettingRepository.Find(simple.DB(), cnd)
+}
+
+func (s *notificationSettingService) FindOne(cnd *simple.SqlCnd) *model.NotificationSetting {
+	return repositories.NotificationSettingRepository.FindOne(simple.DB(), cnd)
+}
+
+func (s *notificationSettingService) FindPageByParams(params *simple.QueryParams) (list []model.NotificationSetting, paging *simple.Paging) {
+	return repositories.NotificationSettingRepository.FindPageByParams(simple.DB(), params)
+}
+
+func (s *notificationSettingService) FindPageByCnd(cnd *simple.SqlCnd) (list []model.NotificationSetting, paging *simple.Paging) {
+	return repositories.NotificationSettingRepository.FindPageByCnd(simple.DB(), cnd)
+}
+
+func (s *notificationSettingService) Count(cnd *simple.SqlCnd) int {
+	return repositories.NotificationSettingRepository.Count(simple.DB(), cnd)
+}
+
+func (s *notificationSettingService) Create(t *model.NotificationSetting) error {
+	return repositories.NotificationSettingRepository.Create(simple.DB(), t)
+}
+
+func (s *notificationSettingService) Update(t *model.NotificationSetting) error {
+	return repositories.NotificationSettingRepository.Update(simple.DB(), t)
+}
+
+func (s *notificationSettingService) Updates(id int64, columns map[string]interface{}) error {
+	return repositories.NotificationSettingRepository.Updates(simple.DB(), id, columns)
+}
+
+func (s *notificationSettingService) UpdateColumn(id int64, name string, value interface{}) error {
+	return repositories.NotificationSettingRepository.UpdateColumn(simple.DB(), id, name, value)
+}
+
+func (s *notificationSettingService) Delete(id int64) {
+	repositories.NotificationSettingRepository.Delete(simple.DB(), id)
+}
+
+// NotifyMsgType 可设置通知方式的消息类型
+type NotifyMsgType struct {
+	Type int    `json:"type"`
+	Name string `json:"name"`
+}
+
+// NotifyMsgTypes 可设置通知方式的消息类型，新增消息类型时在这里添加
+var NotifyMsgTypes = []NotifyMsgType{
+	{Type: constants.MsgTypeComment, Name: "回复我的"},
+	{Type: constants.MsgTypeMention, Name: "提到我的"},
+}
+
+// NotifyModes 可选的通知方式
+var NotifyModes = []string{constants.NotifyModeInApp, constants.NotifyModeEmail, constants.NotifyModeDaily,
+	constants.NotifyModeWeekly, constants.NotifyModeOff}
+
+// 摘要邮件设置
+const (
+	digestMaxMessages = 20 // 最多包含的消息数量
+	digestMaxTopics   = 10 // 最多包含的话题数量
+	digestMaxNodes    = 20 // 最多关注的节点数量
+)
+
+// 发送单个用户摘要邮件的任务参数
+type digestJob struct {
+	UserId int64  `json:"userId"`
+	Period string `json:"period"`
+}
+
+func (s *notificationSettingService) GetByUserId(userId int64) *model.NotificationSetting {
+	return repositories.NotificationSettingRepository.Take(simple.DB(), "user_id = ?", userId)
+}
+
+// GetPreferences 各消息类型的通知方式，未设置的使用默认方式
+func (s *notificationSettingService) GetPreferences(setting *model.NotificationSetting) map[int]string {
+	prefs := make(map[int]string)
+	for _, t := range NotifyMsgTypes {
+		prefs[t.Type] = constants.NotifyModeEmail
+	}
+	if setting != nil && len(setting.Preferences) > 0 {
+		saved := make(map[int]string)
+		if err := json.Unmarshal([]byte(setting.Preferences), &saved); err != nil {
+			logrus.Error(err)
+		}
+		for msgType, mode := range saved {
+			if _, ok := prefs[msgType]; ok && s.isValidMode(mode) {
+				prefs[msgType] = mode
+			}
+		}
+	}
+	return prefs
+}
+
+// GetMode 获取用户对某类消息的通知方式，默认为站内消息并立即发送邮件
+func (s *notificationSettingService) GetMode(userId int64, msgType int) string {
+	if mode, ok := s.GetPreferences(s.GetByUserId(userId))[msgType]; ok {
+		return mode
+	}
+	return constants.NotifyModeEmail
+}
+
+// GetDigestNodeIds 摘要关注的节点
+func (s *notificationSettingService) GetDigestNodeIds(setting *model.NotificationSetting) []int64 {
+	var nodeIds []int64
+	if setting == nil {
+		return nodeIds
+	}
+	for _, str := range strings.Split(setting.DigestNodeIds, ",") {
+		if nodeId, err := strconv.ParseInt(strings.TrimSpace(str), 10, 64); err == nil && nodeId > 0 {
+			nodeIds = append(nodeIds, nodeId)
+		}
+	}
+	return nodeIds
+}
+
+// Save 保存通知设置
+func (s *notificationSettingService) Save(userId int64, prefs map[int]string, topicDigest string, nodeIds []int64) (*model.NotificationSetting, error) {
+	saved := s.GetPreferences(nil)
+	for msgType, mode := range prefs {
+		if _, ok := saved[msgType]; !ok {
+			return nil, errors.New("消息类型错误")
+		}
+		if !s.isValidMode(mode) {
+			return nil, errors.New("通知方式错误")
+		}
+		saved[msgType] = mode
+	}
+	if len(topicDigest) == 0 {
+		topicDigest = constants.NotifyModeOff
+	}
+	if topicDigest != constants.NotifyModeOff && !s.isDigestPeriod(topicDigest) {
+		return nil, errors.New("话题摘要周期错误")
+	}
+	if len(nodeIds) > digestMaxNodes {
+		return nil, errors.New("最多关注" + strconv.Itoa(digestMaxNodes) + "个节点")
+	}
+	var nodeIdStrs []string
+	for _, nodeId := range nodeIds {
+		if TopicNodeService.Get(nodeId) == nil {
+			return nil, errors.New("节点不存在")
+		}
+		nodeIdStrs = append(nodeIdStrs, strconv.FormatInt(nodeId, 10))
+	}
+	preferences, err := json.Marshal(saved)
+	if err != nil {
+		return nil, err
+	}
+	return s.save(userId, string(preferences), topicDigest, strings.Join(nodeIdStrs, ","))
+}
+
+// Unsubscribe 退订所有邮件通知，消息仍然以站内消息的方式通知
+func (s *notificationSettingService) Unsubscribe(userId int64) error {
+	setting := s.GetByUserId(userId)
+	prefs := s.GetPreferences(setting)
+	for msgType, mode := range prefs {
+		if mode != constants.NotifyModeOff {
+			prefs[msgType] = constants.NotifyModeInApp
+		}
+	}
+	preferences, err := json.Marshal(prefs)
+	if err != nil {
+		return err
+	}
+	digestNodeIds := ""
+	if setting != nil {
+		digestNodeIds = setting.DigestNodeIds
+	}
+	_, err = s.save(userId, string(preferences), constants.NotifyModeOff, digestNodeIds)
+	return err
+}
+
+func (s *notificationSettingService) save(userId int64, preferences, topicDigest, digestNodeIds string) (*model.NotificationSetting, error) {
+	setting := s.GetByUserId(userId)
+	if setting == nil {
+		setting = &model.NotificationSetting{
+			UserId:     userId,
+			CreateTime: simple.NowTimestamp(),
+		}
+	}
+	setting.Preferences = preferences
+	setting.TopicDigest = topicDigest
+	setting.DigestNodeIds = digestNodeIds
+	setting.UpdateTime = simple.NowTimestamp()
+
+	var err error
+	if setting.Id > 0 {
+		err = s.Update(setting)
+	} else {
+		err = s.Create(setting)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return setting, nil
+}
+
+// UnsubscribeToken 生成退订令牌，格式为：用户编号.签名，无需登录即可退订
+func (s *notificationSettingService) UnsubscribeToken(userId int64) string {
+	id := strconv.FormatInt(userId, 10)
+	return id + "." + s.sign(id)
+}
+
+// ParseUnsubscribeToken 校验退订令牌，返回用户编号
+func (s *notificationSettingService) ParseUnsubscribeToken(token string) (int64, error) {
+	i := strings.LastIndex(token, ".")
+	if i <= 0 || !hmac.Equal([]byte(token[i+1:]), []byte(s.sign(token[:i]))) {
+		return 0, errors.New("退订链接无效")
+	}
+	userId, err := strconv.ParseInt(token[:i], 10, 64)
+	if err != nil || userId <= 0 {
+		return 0, errors.New("退订链接无效")
+	}
+	return userId, nil
+}
+
+// UnsubscribeUrl 退订页面地址
+func (s *notificationSettingService) UnsubscribeUrl(userId int64) string {
+	return urls.AbsUrl("/user/unsubscribe?token=" + url.QueryEscape(s.UnsubscribeToken(userId)))
+}
+
+// OneClickUnsubscribeUrl 一键退订地址，邮件客户端直接POST该地址退订（RFC 8058）
+func (s *notificationSettingService) OneClickUnsubscribeUrl(userId int64) string {
+	return urls.AbsUrl("/api/notification/unsubscribe?token=" + url.QueryEscape(s.UnsubscribeToken(userId)))
+}
+
+// UnsubscribeHeaders 一键退订邮件头（RFC 8058）
+func (s *notificationSettingService) UnsubscribeHeaders(userId int64) map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      "<" + s.OneClickUnsubscribeUrl(userId) + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+}
+
+func (s *notificationSettingService) sign(data string) string {
+	mac := hmac.New(sha256.New, []byte(SysConfigService.GetSecret()))
+	mac.Write([]byte("unsubscribe:" + data))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// SendDigests 给开启了摘要的用户添加发送摘要邮件的后台任务，period 为 daily 或 weekly
+func (s *notificationSettingService) SendDigests(period string) error {
+	if !s.isDigestPeriod(period) {
+		return errors.New("摘要周期错误")
+	}
+	var cursor int64
+	for {
+		list := repositories.NotificationSettingRepository.Find(simple.DB(),
+			simple.NewSqlCnd().Gt("id", cursor).Asc("id").Limit(200))
+		if len(list) == 0 {
+			return nil
+		}
+		for _, setting := range list {
+			cursor = setting.Id
+			if !s.hasDigest(&setting, period) {
+				continue
+			}
+			if _, err := JobService.Enqueue(constants.JobTypeDigestUser, &digestJob{
+				UserId: setting.UserId,
+				Period: period,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// SendDigest 给用户发送摘要邮件，包含上次发送后的未读消息和关注节点的热门话题；
+// 距上次发送不足一个周期时不重复发送，所以任务重复执行是安全的
+func (s *notificationSettingService) SendDigest(userId int64, period string) error {
+	setting := s.GetByUserId(userId)
+	if setting == nil || !s.hasDigest(setting, period) {
+		return nil
+	}
+	user := cache.UserCache.Get(userId)
+	if user == nil || len(user.Email.String) == 0 {
+		return nil
+	}
+
+	var (
+		now       = simple.NowTimestamp()
+		interval  = 24 * time.Hour
+		lastTime  = setting.LastDailyDigestTime
+		timeField = "last_daily_digest_time"
+		name      = "每日"
+	)
+	if period == constants.NotifyModeWeekly {
+		interval = 7 * 24 * time.Hour
+		lastTime = setting.LastWeeklyDigestTime
+		timeField = "last_weekly_digest_time"
+		name = "每周"
+	}
+	// 预留一小时，避免定时任务执行时间的偏差导致跳过
+	if now-lastTime < (interval - time.Hour).Milliseconds() {
+		return nil
+	}
+	since := lastTime
+	if since < now-interval.Milliseconds() {
+		since = now - interval.Milliseconds()
+	}
+
+	var sections []*email.Section
+	if section := s.buildMessageSection(setting, period, since); section != nil {
+		sections = append(sections, section)
+	}
+	if section := s.buildTopicSection(setting, period, since); section != nil {
+		sections = append(sections, section)
+	}
+	if len(sections) > 0 {
+		if _, err := EmailOutboxService.Send(user.Email.String, constants.EmailTemplateDigest, map[string]interface{}{
+			"PeriodName":     name,
+			"Sections":       sections,
+			"UnsubscribeUrl": s.UnsubscribeUrl(userId),
+		}, s.UnsubscribeHeaders(userId)); err != nil {
+			return err
+		}
+	}
+	return s.UpdateColumn(setting.Id, timeField, now)
+}
+
+// buildMessageSection 通知方式为该摘要周期的未读消息
+func (s *notificationSettingService) buildMessageSection(setting *model.NotificationSetting, period string, since int64) *email.Section {
+	var msgTypes []int
+	for msgType, mode := range s.GetPreferences(setting) {
+		if mode == period {
+			msgTypes = append(msgTypes, msgType)
+		}
+	}
+	if len(msgTypes) == 0 {
+		return nil
+	}
+	cnd := simple.NewSqlCnd().Eq("user_id", setting.UserId).Eq("status", constants.MsgStatusUnread).
+		In("type", msgTypes).Gt("create_time", since)
+	count := cnd.Count(simple.DB(), &model.Message{})
+	if count == 0 {
+		return nil
+	}
+	messages := repositories.MessageRepository.Find(simple.DB(), cnd.Desc("id").Limit(digestMaxMessages))
+	section := &email.Section{Title: "你有 " + strconv.Itoa(count) + " 条未读消息"}
+	for _, message := range messages {
+		section.Items = append(section.Items, &model.ActionLink{
+			Title: message.Content,
+			Url:   urls.AbsUrl("/user/messages"),
+		})
+	}
+	return section
+}
+
+// buildTopicSection 关注节点中该周期内的热门话题
+func (s *notificationSettingService) buildTopicSection(setting *model.NotificationSetting, period string, since int64) *email.Section {
+	nodeIds := s.GetDigestNodeIds(setting)
+	if setting.TopicDigest != period || len(nodeIds) == 0 {
+		return nil
+	}
+	topics := repositories.TopicRepository.Find(simple.DB(), simple.NewSqlCnd().In("node_id", nodeIds).
+		Eq("status", constants.StatusOk).Gt("create_time", since).
+		Desc("comment_count").Desc("like_count").Limit(digestMaxTopics))
+	if len(topics) == 0 {
+		return nil
+	}
+	section := &email.Section{Title: "热门话题"}
+	for _, topic := range topics {
+		section.Items = append(section.Items, &model.ActionLink{
+			Title: topic.Title,
+			Url:   urls.TopicUrl(topic.Id),
+		})
+	}
+	return section
+}
+
+// hasDigest 是否需要发送该周期的摘要邮件
+func (s *notificationSettingService) hasDigest(setting *model.NotificationSetting, period string) bool {
+	if setting.TopicDigest == period && len(s.GetDigestNodeIds(setting)) > 0 {
+		return true
+	}
+	for _, mode := range s.GetPreferences(setting) {
+		if mode == period {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *notificationSettingService) isValidMode(mode string) bool {
+	for _, m := range NotifyModes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *notificationSettingService) isDigestPeriod(period string) bool {
+	return period == constants.NotifyModeDaily || period == constants.NotifyModeWeekly
+}
@@ -3,17 +3,13 @@ package services
 import (
 	"bbs-go/model/constants"
 	"math"
-	"path"
-	"time"
 
-	"github.com/gorilla/feeds"
 	"github.com/mlogclub/simple"
-	"github.com/sirupsen/logrus"
 
 	"bbs-go/cache"
 	"bbs-go/common"
+	"bbs-go/common/feed"
 	"bbs-go/common/urls"
-	"bbs-go/config"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
@@ -124,44 +120,19 @@ func (s *projectService) GenerateRss() {
 	projects := repositories.ProjectRepository.Find(simple.DB(),
 		simple.NewSqlCnd().Where("1 = 1").Desc("id").Limit(2000))
 
-	var items []*feeds.Item
+	f := FeedService.NewSiteFeed()
 	for _, project := range projects {
-		projectUrl := urls.ProjectUrl(project.Id)
 		user := cache.UserCache.Get(project.UserId)
 		if user == nil {
 			continue
 		}
-		description := common.GetSummary(project.ContentType, project.Content)
-		item := &feeds.Item{
+		f.Add(&feed.Item{
 			Title:       project.Name + " - " + project.Title,
-			Link:        &feeds.Link{Href: projectUrl},
-			Description: description,
-			Author:      &feeds.Author{Name: user.Avatar, Email: user.Email.String},
-			Created:     simple.TimeFromTimestamp(project.CreateTime),
-		}
-		items = append(items, item)
-	}
-	siteTitle := cache.SysConfigCache.GetValue(constants.SysConfigSiteTitle)
-	siteDescription := cache.SysConfigCache.GetValue(constants.SysConfigSiteDescription)
-	feed := &feeds.Feed{
-		Title:       siteTitle,
-		Link:        &feeds.Link{Href: config.Instance.BaseUrl},
-		Description: siteDescription,
-		Author:      &feeds.Author{Name: siteTitle},
-		Created:     time.Now(),
-		Items:       items,
-	}
-	atom, err := feed.ToAtom()
-	if err != nil {
-		logrus.Error(err)
-	} else {
-		_ = simple.WriteString(path.Join(config.Instance.StaticPath, "project_atom.xml"), atom, false)
-	}
-
-	rss, err := feed.ToRss()
-	if err != nil {
-		logrus.Error(err)
-	} else {
-		_ = simple.WriteString(path.Join(config.Instance.StaticPath, "project_rss.xml"), rss, false)
+			Link:        urls.ProjectUrl(project.Id),
+			Description: common.GetSummary(project.ContentType, project.Content),
+			Author:      user,
+			Created:     project.CreateTime,
+		})
 	}
+	FeedService.WriteStatic(f, "project_atom.xml", "project_rss.xml")
 }
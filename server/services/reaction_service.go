@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+
+	"bbs-go/config"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+// 默认可用的表情
+var defaultReactionEmojis = []string{"👍", "👎", "😄", "🎉", "😕", "❤️", "🚀", "👀"}
+
+var ReactionService = newReactionService()
+
+func newReactionService() *reactionService {
+	return &reactionService{}
+}
+
+type reactionService struct {
+}
+
+// GetEmojis 可用的表情
+func (s *reactionService) GetEmojis() []string {
+	if len(config.Instance.Reaction.Emojis) > 0 {
+		return config.Instance.Reaction.Emojis
+	}
+	return defaultReactionEmojis
+}
+
+// Add 添加表情回应
+func (s *reactionService) Add(userId int64, entityType string, entityId int64, emoji string) error {
+	if err := s.check(entityType, entityId, emoji); err != nil {
+		return err
+	}
+	if s.Exists(userId, entityType, entityId, emoji) {
+		return errors.New("已回应")
+	}
+	return simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		if err := repositories.UserReactionRepository.Create(tx, &model.UserReaction{
+			UserId:     userId,
+			EntityType: entityType,
+			EntityId:   entityId,
+			Emoji:      emoji,
+			CreateTime: simple.NowTimestamp(),
+		}); err != nil {
+			return err
+		}
+		return s.incrCount(tx, entityType, entityId, emoji)
+	})
+}
+
+// Remove 取消表情回应
+func (s *reactionService) Remove(userId int64, entityType string, entityId int64, emoji string) error {
+	return simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		db := tx.Where("user_id = ? and entity_type = ? and entity_id = ? and emoji = ?", userId, entityType, entityId, emoji).
+			Delete(model.UserReaction{})
+		if db.Error != nil || db.RowsAffected == 0 {
+			return db.Error
+		}
+		return tx.Model(&model.ReactionCount{}).
+			Where("entity_type = ? and entity_id = ? and emoji = ? and count > 0", entityType, entityId, emoji).
+			UpdateColumn("count", gorm.Expr("count - 1")).Error
+	})
+}
+
+// Exists 是否已使用该表情回应
+func (s *reactionService) Exists(userId int64, entityType string, entityId int64, emoji string) bool {
+	return repositories.UserReactionRepository.FindOne(simple.DB(), simple.NewSqlCnd().Eq("user_id", userId).
+		Eq("entity_type", entityType).Eq("entity_id", entityId).Eq("emoji", emoji)) != nil
+}
+
+// GetCounts 各表情的回应数量，按可用表情的顺序排列，不包含数量为0的表情
+func (s *reactionService) GetCounts(entityType string, entityId int64) []model.ReactionCountResponse {
+	return s.GetCountsByEntityIds(entityType, []int64{entityId})[entityId]
+}
+
+// GetCountsByEntityIds 批量查询各实体的回应数量，用于列表，key为实体编号
+func (s *reactionService) GetCountsByEntityIds(entityType string, entityIds []int64) map[int64][]model.ReactionCountResponse {
+	if len(entityIds) == 0 {
+		return nil
+	}
+	counts := repositories.ReactionCountRepository.Find(simple.DB(), simple.NewSqlCnd().
+		Eq("entity_type", entityType).In("entity_id", entityIds).Gt("count", 0).Asc("id"))
+	if len(counts) == 0 {
+		return nil
+	}
+	group := make(map[int64][]model.ReactionCount)
+	for _, count := range counts {
+		group[count.EntityId] = append(group[count.EntityId], count)
+	}
+	ret := make(map[int64][]model.ReactionCountResponse, len(group))
+	for entityId, list := range group {
+		ret[entityId] = s.sortCounts(list)
+	}
+	return ret
+}
+
+// sortCounts 按可用表情的顺序排列，已从配置中移除的表情排在最后
+func (s *reactionService) sortCounts(counts []model.ReactionCount) []model.ReactionCountResponse {
+	countMap := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		countMap[count.Emoji] = count.Count
+	}
+	var ret []model.ReactionCountResponse
+	for _, emoji := range s.GetEmojis() {
+		if count, ok := countMap[emoji]; ok {
+			ret = append(ret, model.ReactionCountResponse{Emoji: emoji, Count: count})
+			delete(countMap, emoji)
+		}
+	}
+	for _, count := range counts {
+		if _, ok := countMap[count.Emoji]; ok {
+			ret = append(ret, model.ReactionCountResponse{Emoji: count.Emoji, Count: count.Count})
+		}
+	}
+	return ret
+}
+
+// GetUserEmojis 用户对实体使用过的表情
+func (s *reactionService) GetUserEmojis(userId int64, entityType string, entityId int64) []string {
+	reactions := repositories.UserReactionRepository.Find(simple.DB(), simple.NewSqlCnd().Eq("user_id", userId).
+		Eq("entity_type", entityType).Eq("entity_id", entityId))
+	var ret []string
+	for _, reaction := range reactions {
+		ret = append(ret, reaction.Emoji)
+	}
+	return ret
+}
+
+// Recent 最近回应，emoji 为空时查询所有表情
+func (s *reactionService) Recent(entityType string, entityId int64, emoji string, count int) []model.UserReaction {
+	cnd := simple.NewSqlCnd().Eq("entity_type", entityType).Eq("entity_id", entityId).Desc("id").Limit(count)
+	if len(emoji) > 0 {
+		cnd.Eq("emoji", emoji)
+	}
+	return repositories.UserReactionRepository.Find(simple.DB(), cnd)
+}
+
+// incrCount 回应数量+1，记录不存在时创建；并发回应同一表情时由唯一索引保证只创建一条记录
+func (s *reactionService) incrCount(tx *gorm.DB, entityType string, entityId int64, emoji string) error {
+	update := func() (int64, error) {
+		ret := tx.Model(&model.ReactionCount{}).Where("entity_type = ? and entity_id = ? and emoji = ?", entityType, entityId, emoji).
+			UpdateColumn("count", gorm.Expr("count + 1"))
+		return ret.RowsAffected, ret.Error
+	}
+	if rows, err := update(); err != nil || rows > 0 {
+		return err
+	}
+	if err := repositories.ReactionCountRepository.Create(tx, &model.ReactionCount{
+		EntityType: entityType,
+		EntityId:   entityId,
+		Emoji:      emoji,
+		Count:      1,
+	}); err != nil {
+		// 违反唯一索引：其他请求已经创建，重新更新
+		if rows, e := update(); e != nil || rows == 0 {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *reactionService) check(entityType string, entityId int64, emoji string) error {
+	if !s.isEmojiEnabled(emoji) {
+		return errors.New("不支持该表情")
+	}
+	switch entityType {
+	case constants.EntityTopic:
+		topic := repositories.TopicRepository.Get(simple.DB(), entityId)
+		if topic == nil || topic.Status != constants.StatusOk {
+			return errors.New("话题不存在")
+		}
+	case constants.EntityComment:
+		comment := repositories.CommentRepository.Get(simple.DB(), entityId)
+		if comment == nil || comment.Status != constants.StatusOk || comment.DeleteTime > 0 {
+			return errors.New("评论不存在")
+		}
+	case constants.EntityTweet:
+		tweet := repositories.TweetRepository.Get(simple.DB(), entityId)
+		if tweet == nil || tweet.Status != constants.StatusOk {
+			return errors.New("动态不存在")
+		}
+	default:
+		return errors.New("不支持回应该内容")
+	}
+	return nil
+}
+
+func (s *reactionService) isEmojiEnabled(emoji string) bool {
+	for _, item := range s.GetEmojis() {
+		if item == emoji {
+			return true
+		}
+	}
+	return false
+}
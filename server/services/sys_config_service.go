@@ -2,10 +2,13 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"github.com/mlogclub/simple/number"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
@@ -13,6 +16,7 @@ import (
 	"github.com/tidwall/gjson"
 
 	"bbs-go/cache"
+	"bbs-go/config"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
@@ -24,6 +28,7 @@ func newSysConfigService() *sysConfigService {
 }
 
 type sysConfigService struct {
+	secretMutex sync.Mutex
 }
 
 func (s *sysConfigService) Get(id int64) *model.SysConfig {
@@ -116,6 +121,35 @@ func (s *sysConfigService) setSingle(db *gorm.DB, key, value, name, description
 	}
 }
 
+// GetSecret 签名密钥，优先使用配置文件中的Secret，未配置时自动生成并保存到系统配置中
+func (s *sysConfigService) GetSecret() string {
+	if len(config.Instance.Secret) > 0 {
+		return config.Instance.Secret
+	}
+	if secret := cache.SysConfigCache.GetValue(constants.SysConfigSecret); len(secret) > 0 {
+		return secret
+	}
+
+	s.secretMutex.Lock()
+	defer s.secretMutex.Unlock()
+	if secret := cache.SysConfigCache.GetValue(constants.SysConfigSecret); len(secret) > 0 {
+		return secret
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	secret := hex.EncodeToString(b)
+	if err := s.Set(constants.SysConfigSecret, secret, "签名密钥", "自动生成，用于生成退订链接等，修改后已发出的链接将失效"); err != nil {
+		logrus.Error(err)
+	}
+	// 多个实例同时生成时以数据库中保存的为准
+	if saved := cache.SysConfigCache.GetValue(constants.SysConfigSecret); len(saved) > 0 {
+		return saved
+	}
+	return secret
+}
+
 func (s *sysConfigService) GetTokenExpireDays() int {
 	tokenExpireDaysStr := cache.SysConfigCache.GetValue(constants.SysConfigTokenExpireDays)
 	tokenExpireDays, err := strconv.Atoi(tokenExpireDaysStr)
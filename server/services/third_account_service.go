@@ -2,20 +2,31 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"context"
 	"database/sql"
+	"errors"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/goburrow/cache"
 	"github.com/mlogclub/simple"
 
 	"bbs-go/common/github"
+	"bbs-go/common/ldap"
+	"bbs-go/common/oidc"
 	"bbs-go/common/qq"
+	"bbs-go/config"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
 
 var ThirdAccountService = newThirdAccountService()
 
+// 绑定第三方账号的授权state，value为发起绑定的用户编号
+var linkStateCache = cache.New(cache.WithMaximumSize(1000), cache.WithExpireAfterAccess(10*time.Minute))
+
 func newThirdAccountService() *thirdAccountService {
 	return &thirdAccountService{}
 }
@@ -71,8 +82,113 @@ func (s *thirdAccountService) GetThirdAccount(thirdType string, thirdId string)
 	return repositories.ThirdAccountRepository.Take(simple.DB(), "third_type = ? and third_id = ?", thirdType, thirdId)
 }
 
-func (s *thirdAccountService) GetOrCreateByGithub(code, state string) (*model.ThirdAccount, error) {
-	userInfo, err := github.GetUserInfoByCode(code, state)
+// GetUserThirdAccounts 用户绑定的第三方账号
+func (s *thirdAccountService) GetUserThirdAccounts(userId int64) []model.ThirdAccount {
+	return s.Find(simple.NewSqlCnd().Eq("user_id", userId).Asc("id"))
+}
+
+// GetUserThirdAccount 用户绑定的指定类型的第三方账号
+func (s *thirdAccountService) GetUserThirdAccount(userId int64, thirdType string) *model.ThirdAccount {
+	return s.FindOne(simple.NewSqlCnd().Eq("user_id", userId).Eq("third_type", thirdType))
+}
+
+// GetAuthorizeUrl 获取第三方登录授权地址
+func (s *thirdAccountService) GetAuthorizeUrl(thirdType string, params map[string]string) (string, error) {
+	switch thirdType {
+	case constants.ThirdAccountTypeGithub:
+		return github.AuthCodeURL(params), nil
+	case constants.ThirdAccountTypeQQ:
+		return qq.AuthorizeUrl(params), nil
+	}
+	provider := oidc.GetProvider(thirdType)
+	if provider == nil {
+		return "", errors.New("登录方式不存在：" + thirdType)
+	}
+	return provider.AuthCodeURL(params)
+}
+
+// GetOrCreate 根据授权回调获取第三方账号，不存在时创建
+func (s *thirdAccountService) GetOrCreate(thirdType, code, state string) (*model.ThirdAccount, error) {
+	switch thirdType {
+	case constants.ThirdAccountTypeGithub:
+		return s.GetOrCreateByGithub(code, state)
+	case constants.ThirdAccountTypeQQ:
+		return s.GetOrCreateByQQ(code, state)
+	}
+	return s.GetOrCreateByOidc(thirdType, code, state)
+}
+
+// GetLinkAuthorizeUrl 已登录用户绑定第三方账号时获取授权地址，授权state与当前用户关联，防止将他人的第三方账号绑定到当前用户
+func (s *thirdAccountService) GetLinkAuthorizeUrl(userId int64, thirdType, ref string) (string, error) {
+	if thirdType == constants.ThirdAccountTypeLdap {
+		return "", errors.New("LDAP账号不能绑定")
+	}
+	if s.GetUserThirdAccount(userId, thirdType) != nil {
+		return "", errors.New("已绑定该类型的账号，请先解除绑定")
+	}
+	authorizeUrl, err := s.GetAuthorizeUrl(thirdType, map[string]string{"ref": ref, "action": "link"})
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(authorizeUrl)
+	if err != nil {
+		return "", err
+	}
+	linkStateCache.Put(u.Query().Get("state"), userId)
+	return authorizeUrl, nil
+}
+
+// Link 绑定第三方账号到当前用户
+func (s *thirdAccountService) Link(userId int64, thirdType, code, state string) (*model.ThirdAccount, error) {
+	val, found := linkStateCache.GetIfPresent(state)
+	if !found || val.(int64) != userId {
+		return nil, errors.New("绑定已过期，请重新绑定")
+	}
+	linkStateCache.Invalidate(state)
+
+	account, err := s.GetOrCreate(thirdType, code, state)
+	if err != nil {
+		return nil, err
+	}
+	if account.UserId.Valid {
+		if account.UserId.Int64 == userId {
+			return account, nil
+		}
+		return nil, errors.New("该账号已绑定其他用户")
+	}
+	if s.GetUserThirdAccount(userId, thirdType) != nil {
+		return nil, errors.New("已绑定该类型的账号，请先解除绑定")
+	}
+	account.UserId = sql.NullInt64{Int64: userId, Valid: true}
+	account.UpdateTime = simple.NowTimestamp()
+	if err := s.Updates(account.Id, map[string]interface{}{
+		"user_id":     account.UserId,
+		"update_time": account.UpdateTime,
+	}); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Unlink 解除绑定第三方账号，解除后用户将无法登录时不允许解除
+func (s *thirdAccountService) Unlink(user *model.User, thirdType string) error {
+	if thirdType == constants.ThirdAccountTypeLdap {
+		return errors.New("LDAP账号不能解除绑定")
+	}
+	account := s.GetUserThirdAccount(user.Id, thirdType)
+	if account == nil {
+		return errors.New("未绑定该类型的账号")
+	}
+	passwordLogin := user.HasPasswordLogin() && !(ldap.IsEnabled() && config.Instance.Ldap.DisablePasswordLogin)
+	if !passwordLogin && len(s.GetUserThirdAccounts(user.Id)) <= 1 {
+		return errors.New("解除绑定后将无法登录，请先设置用户名或邮箱以及密码")
+	}
+	s.Delete(account.Id)
+	return nil
+}
+
+func (s *thirdAccountService) GetOrCreateByGithub(ctx context.Context, code, state string) (*model.ThirdAccount, error) {
+	userInfo, err := github.GetUserInfoByCode(ctx, code, state)
 	if err != nil {
 		return nil, err
 	}
@@ -105,8 +221,8 @@ func (s *thirdAccountService) GetOrCreateByGithub(code, state string) (*model.Th
 	return account, nil
 }
 
-func (s *thirdAccountService) GetOrCreateByQQ(code, state string) (*model.ThirdAccount, error) {
-	userInfo, err := qq.GetUserInfoByCode(code, state)
+func (s *thirdAccountService) GetOrCreateByQQ(ctx context.Context, code, state string) (*model.ThirdAccount, error) {
+	userInfo, err := qq.GetUserInfoByCode(ctx, code, state)
 	if err != nil {
 		return nil, err
 	}
@@ -133,3 +249,37 @@ func (s *thirdAccountService) GetOrCreateByQQ(code, state string) (*model.ThirdA
 	}
 	return account, nil
 }
+
+// GetOrCreateByOidc OpenID Connect / OAuth2 登录，第三方账号类型为登录配置的名称
+func (s *thirdAccountService) GetOrCreateByOidc(ctx context.Context, providerName, code, state string) (*model.ThirdAccount, error) {
+	provider := oidc.GetProvider(providerName)
+	if provider == nil {
+		return nil, errors.New("登录方式不存在：" + providerName)
+	}
+	userInfo, err := provider.GetUserInfoByCode(ctx, code, state)
+	if err != nil {
+		return nil, err
+	}
+
+	account := s.GetThirdAccount(provider.Name, userInfo.Subject)
+	if account != nil {
+		return account, nil
+	}
+
+	userInfoJson, _ := simple.FormatJson(userInfo)
+	account = &model.ThirdAccount{
+		UserId:     sql.NullInt64{},
+		Avatar:     userInfo.Avatar,
+		Nickname:   userInfo.Nickname,
+		ThirdType:  provider.Name,
+		ThirdId:    userInfo.Subject,
+		ExtraData:  userInfoJson,
+		CreateTime: simple.NowTimestamp(),
+		UpdateTime: simple.NowTimestamp(),
+	}
+	err = s.Create(account)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
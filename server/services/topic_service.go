@@ -2,20 +2,15 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"context"
 	"math"
-	"path"
-	"time"
 
-	"github.com/gorilla/feeds"
 	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
-	"github.com/sirupsen/logrus"
 
 	"bbs-go/cache"
-	"bbs-go/common"
-	"bbs-go/common/baiduseo"
-	"bbs-go/common/urls"
-	"bbs-go/config"
+	"bbs-go/common/event"
+	"bbs-go/common/tracing"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
@@ -73,11 +68,12 @@ func (s *topicService) UpdateColumn(id int64, name string, value interface{}) er
 }
 
 // 删除
-func (s *topicService) Delete(id int64) error {
-	err := repositories.TopicRepository.UpdateColumn(simple.DB(), id, "status", constants.StatusDeleted)
+func (s *topicService) Delete(ctx context.Context, id int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	err := repositories.TopicRepository.UpdateColumn(db, id, "status", constants.StatusDeleted)
 	if err == nil {
 		// 删掉标签文章
-		TopicTagService.DeleteByTopicId(id)
+		TopicTagService.DeleteByTopicId(db, id)
 	}
 	return err
 }
@@ -93,7 +89,7 @@ func (s *topicService) Undelete(id int64) error {
 }
 
 // 发表
-func (s *topicService) Publish(userId, nodeId int64, tags []string, title, content string) (*model.Topic, *simple.CodeError) {
+func (s *topicService) Publish(ctx context.Context, userId, nodeId int64, tags []string, title, content string) (*model.Topic, *simple.CodeError) {
 	if len(title) == 0 {
 		return nil, simple.NewErrorMsg("标题不能为空")
 	}
@@ -108,7 +104,8 @@ func (s *topicService) Publish(userId, nodeId int64, tags []string, title, conte
 			return nil, simple.NewErrorMsg("请配置默认节点")
 		}
 	}
-	node := repositories.TopicNodeRepository.Get(simple.DB(), nodeId)
+	db := tracing.DB(ctx, simple.DB())
+	node := repositories.TopicNodeRepository.Get(db, nodeId)
 	if node == nil || node.Status != constants.StatusOk {
 		return nil, simple.NewErrorMsg("节点不存在")
 	}
@@ -124,7 +121,7 @@ func (s *topicService) Publish(userId, nodeId int64, tags []string, title, conte
 		CreateTime:      now,
 	}
 
-	err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+	err := simple.Tx(db, func(tx *gorm.DB) error {
 		tagIds := repositories.TagRepository.GetOrCreates(tx, tags)
 		err := repositories.TopicRepository.Create(tx, topic)
 		if err != nil {
@@ -135,18 +132,13 @@ func (s *topicService) Publish(userId, nodeId int64, tags []string, title, conte
 		return nil
 	})
 	if err == nil {
-		// 用户话题计数
-		UserService.IncrTopicCount(userId)
-		// 获得积分
-		UserScoreService.IncrementPostTopicScore(topic)
-		// 百度链接推送
-		baiduseo.PushUrl(urls.TopicUrl(topic.Id))
+		event.Publish(&event.TopicPublished{Topic: topic, Tags: tags})
 	}
 	return topic, simple.FromError(err)
 }
 
 // 更新
-func (s *topicService) Edit(topicId, nodeId int64, tags []string, title, content string) *simple.CodeError {
+func (s *topicService) Edit(ctx context.Context, topicId, nodeId int64, tags []string, title, content string) *simple.CodeError {
 	if len(title) == 0 {
 		return simple.NewErrorMsg("标题不能为空")
 	}
@@ -155,13 +147,14 @@ func (s *topicService) Edit(topicId, nodeId int64, tags []string, title, content
 		return simple.NewErrorMsg("标题长度不能超过128")
 	}
 
-	node := repositories.TopicNodeRepository.Get(simple.DB(), nodeId)
+	db := tracing.DB(ctx, simple.DB())
+	node := repositories.TopicNodeRepository.Get(db, nodeId)
 	if node == nil || node.Status != constants.StatusOk {
 		return simple.NewErrorMsg("节点不存在")
 	}
 
-	err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
-		err := repositories.TopicRepository.Updates(simple.DB(), topicId, map[string]interface{}{
+	err := simple.Tx(db, func(tx *gorm.DB) error {
+		err := repositories.TopicRepository.Updates(tx, topicId, map[string]interface{}{
 			"node_id": nodeId,
 			"title":   title,
 			"content": content,
@@ -256,45 +249,11 @@ func (s *topicService) GenerateRss() {
 	topics := repositories.TopicRepository.Find(simple.DB(),
 		simple.NewSqlCnd().Where("status = ?", constants.StatusOk).Desc("id").Limit(1000))
 
-	var items []*feeds.Item
-	for _, topic := range topics {
-		topicUrl := urls.TopicUrl(topic.Id)
-		user := cache.UserCache.Get(topic.UserId)
-		if user == nil {
-			continue
-		}
-		item := &feeds.Item{
-			Title:       topic.Title,
-			Link:        &feeds.Link{Href: topicUrl},
-			Description: common.GetMarkdownSummary(topic.Content),
-			Author:      &feeds.Author{Name: user.Avatar, Email: user.Email.String},
-			Created:     simple.TimeFromTimestamp(topic.CreateTime),
-		}
-		items = append(items, item)
-	}
-	siteTitle := cache.SysConfigCache.GetValue(constants.SysConfigSiteTitle)
-	siteDescription := cache.SysConfigCache.GetValue(constants.SysConfigSiteDescription)
-	feed := &feeds.Feed{
-		Title:       siteTitle,
-		Link:        &feeds.Link{Href: config.Instance.BaseUrl},
-		Description: siteDescription,
-		Author:      &feeds.Author{Name: siteTitle},
-		Created:     time.Now(),
-		Items:       items,
-	}
-	atom, err := feed.ToAtom()
-	if err != nil {
-		logrus.Error(err)
-	} else {
-		_ = simple.WriteString(path.Join(config.Instance.StaticPath, "topic_atom.xml"), atom, false)
-	}
-
-	rss, err := feed.ToRss()
-	if err != nil {
-		logrus.Error(err)
-	} else {
-		_ = simple.WriteString(path.Join(config.Instance.StaticPath, "topic_rss.xml"), rss, false)
+	f := FeedService.NewSiteFeed()
+	for i := range topics {
+		FeedService.addTopic(f, &topics[i])
 	}
+	FeedService.WriteStatic(f, "topic_atom.xml", "topic_rss.xml")
 }
 
 // 倒序扫描
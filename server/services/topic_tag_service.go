@@ -2,6 +2,7 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
 
 	"bbs-go/model"
@@ -57,8 +58,8 @@ func (s *topicTagService) UpdateColumn(id int64, name string, value interface{})
 	return repositories.TopicTagRepository.UpdateColumn(simple.DB(), id, name, value)
 }
 
-func (s *topicTagService) DeleteByTopicId(topicId int64) {
-	simple.DB().Model(model.TopicTag{}).Where("topic_id = ?", topicId).UpdateColumn("status", constants.StatusDeleted)
+func (s *topicTagService) DeleteByTopicId(db *gorm.DB, topicId int64) {
+	db.Model(model.TopicTag{}).Where("topic_id = ?", topicId).UpdateColumn("status", constants.StatusDeleted)
 }
 
 func (s *topicTagService) UndeleteByTopicId(topicId int64) {
@@ -2,8 +2,14 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"context"
+	"errors"
+
+	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
 
+	"bbs-go/common/event"
+	"bbs-go/common/tracing"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
@@ -45,12 +51,12 @@ func (s *tweetService) Count(cnd *simple.SqlCnd) int {
 	return repositories.TweetRepository.Count(simple.DB(), cnd)
 }
 
-func (s *tweetService) GetTweets(cursor int64) (tweets []model.Tweet, nextCursor int64) {
+func (s *tweetService) GetTweets(ctx context.Context, cursor int64) (tweets []model.Tweet, nextCursor int64) {
 	cnd := simple.NewSqlCnd().Eq("status", constants.StatusOk).Desc("id").Limit(20)
 	if cursor > 0 {
 		cnd.Lt("id", cursor)
 	}
-	tweets = repositories.TweetRepository.Find(simple.DB(), cnd)
+	tweets = repositories.TweetRepository.Find(tracing.DB(ctx, simple.DB()), cnd)
 	if len(tweets) > 0 {
 		nextCursor = tweets[len(tweets)-1].Id
 	} else {
@@ -65,20 +71,57 @@ func (s *tweetService) GetNewest() (tweets []model.Tweet) {
 	return
 }
 
-func (s *tweetService) Publish(userId int64, content, imageList string) (*model.Tweet, error) {
+// Publish 发表动态，repostId 不为0时为转发，内容为空时为直接转发，否则为引用转发
+func (s *tweetService) Publish(ctx context.Context, userId int64, content, imageList string, repostId int64) (*model.Tweet, error) {
+	db := tracing.DB(ctx, simple.DB())
+	if repostId > 0 {
+		original := repositories.TweetRepository.Get(db, repostId)
+		if original == nil || original.Status != constants.StatusOk {
+			return nil, errors.New("转发的动态不存在")
+		}
+		// 转发直接转发的动态时，转发原动态
+		if s.isPlainRepost(original) {
+			repostId = original.RepostId
+		}
+		if len(content) == 0 && len(imageList) == 0 && repositories.TweetRepository.Take(db,
+			"user_id = ? and repost_id = ? and content = '' and status = ?", userId, repostId, constants.StatusOk) != nil {
+			return nil, errors.New("已转发")
+		}
+	} else if len(content) == 0 && len(imageList) == 0 {
+		return nil, errors.New("请输入动态内容")
+	}
+
 	tweet := &model.Tweet{
 		UserId:     userId,
 		Content:    content,
 		ImageList:  imageList,
+		RepostId:   repostId,
 		Status:     constants.StatusOk,
 		CreateTime: simple.NowTimestamp(),
 	}
-	if err := repositories.TweetRepository.Create(simple.DB(), tweet); err != nil {
+	err := simple.Tx(db, func(tx *gorm.DB) error {
+		if err := repositories.TweetRepository.Create(tx, tweet); err != nil {
+			return err
+		}
+		if tweet.RepostId > 0 {
+			if err := tx.Exec("update t_tweet set repost_count = repost_count + 1 where id = ?", tweet.RepostId).Error; err != nil {
+				return err
+			}
+		}
+		return HashtagService.AddTweetHashtags(tx, tweet)
+	})
+	if err != nil {
 		return nil, err
 	}
+	event.Publish(&event.TweetPublished{Tweet: tweet})
 	return tweet, nil
 }
 
+// isPlainRepost 是否是直接转发（没有内容和图片）
+func (s *tweetService) isPlainRepost(tweet *model.Tweet) bool {
+	return tweet.RepostId > 0 && len(tweet.Content) == 0 && len(tweet.ImageList) == 0
+}
+
 func (s *tweetService) OnComment(tweetId int64) {
 	simple.DB().Exec("update t_tweet set comment_count = comment_count + 1 where id = ?", tweetId)
 }
@@ -99,6 +142,48 @@ func (s *tweetService) UpdateColumn(id int64, name string, value interface{}) er
 	return repositories.TweetRepository.UpdateColumn(simple.DB(), id, name, value)
 }
 
-func (s *tweetService) Delete(id int64) {
-	repositories.TweetRepository.Delete(simple.DB(), id)
+// Delete 删除动态，转发了该动态的内容会显示为原动态已删除
+func (s *tweetService) Delete(id int64) error {
+	tweet := s.Get(id)
+	if tweet == nil || tweet.Status == constants.StatusDeleted {
+		return nil
+	}
+	return simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		return s.deleteTx(tx, tweet)
+	})
+}
+
+// deleteTx 在调用方的事务中删除动态，同时更新话题标签的动态数量和原动态的转发数量
+func (s *tweetService) deleteTx(tx *gorm.DB, tweet *model.Tweet) error {
+	if err := repositories.TweetRepository.UpdateColumn(tx, tweet.Id, "status", constants.StatusDeleted); err != nil {
+		return err
+	}
+	if err := HashtagService.IncrTweetCount(tx, tweet.Id, -1); err != nil {
+		return err
+	}
+	if tweet.RepostId > 0 { // 更新原动态的转发数量
+		return tx.Exec("update t_tweet set repost_count = repost_count - 1 where id = ? and repost_count > 0",
+			tweet.RepostId).Error
+	}
+	return nil
+}
+
+// 恢复删除
+func (s *tweetService) Undelete(id int64) error {
+	tweet := s.Get(id)
+	if tweet == nil || tweet.Status != constants.StatusDeleted {
+		return nil
+	}
+	return simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		if err := repositories.TweetRepository.UpdateColumn(tx, id, "status", constants.StatusOk); err != nil {
+			return err
+		}
+		if err := HashtagService.IncrTweetCount(tx, id, 1); err != nil {
+			return err
+		}
+		if tweet.RepostId > 0 {
+			return tx.Exec("update t_tweet set repost_count = repost_count + 1 where id = ?", tweet.RepostId).Error
+		}
+		return nil
+	})
 }
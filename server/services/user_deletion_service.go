@@ -0,0 +1,332 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/cache"
+	"bbs-go/common/event"
+	"bbs-go/config"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+var UserDeletionService = newUserDeletionService()
+
+func newUserDeletionService() *userDeletionService {
+	return &userDeletionService{}
+}
+
+type userDeletionService struct {
+}
+
+func (s *userDeletionService) Get(id int64) *model.UserDeletion {
+	return repositories.UserDeletionRepository.Get(simple.DB(), id)
+}
+
+func (s *userDeletionService) FindPageByParams(params *simple.QueryParams) (list []model.UserDeletion, paging *simple.Paging) {
+	return repositories.UserDeletionRepository.FindPageByParams(simple.DB(), params)
+}
+
+// GetPending 用户处于冷静期的注销申请
+func (s *userDeletionService) GetPending(userId int64) *model.UserDeletion {
+	return repositories.UserDeletionRepository.FindOne(simple.DB(), simple.NewSqlCnd().
+		Eq("user_id", userId).Eq("status", constants.UserDeletionPending).Desc("id"))
+}
+
+// Request 用户申请注销账号，冷静期结束后执行，设置了密码的用户需要校验密码
+func (s *userDeletionService) Request(user *model.User, password, reason string, purge bool, r *http.Request) (*model.UserDeletion, error) {
+	if user.HasAnyRole(constants.RoleOwner) {
+		return nil, errors.New("站长账号不能注销")
+	}
+	if len(user.Password) > 0 && !simple.ValidatePassword(user.Password, password) {
+		return nil, errors.New("密码错误")
+	}
+	if s.GetPending(user.Id) != nil {
+		return nil, errors.New("已申请注销，请勿重复申请")
+	}
+	deletion := &model.UserDeletion{
+		UserId:      user.Id,
+		OperatorId:  user.Id,
+		Purge:       purge,
+		Reason:      reason,
+		Status:      constants.UserDeletionPending,
+		ScheduledAt: simple.Timestamp(time.Now().Add(s.graceDuration())),
+		CreateTime:  simple.NowTimestamp(),
+		UpdateTime:  simple.NowTimestamp(),
+	}
+	if err := repositories.UserDeletionRepository.Create(simple.DB(), deletion); err != nil {
+		return nil, err
+	}
+	OperateLogService.AddOperateLog(user.Id, constants.OpTypeCancelAccount, constants.EntityUser, user.Id, "申请注销账号", r)
+	MessageService.Produce(0, user.Id, "你已申请注销账号，账号将于 "+
+		simple.TimeFormat(simple.TimeFromTimestamp(deletion.ScheduledAt), "2006-01-02 15:04")+
+		" 注销，在此之前可以在「编辑资料」页面撤销", "", constants.MsgTypeSystem, nil)
+	return deletion, nil
+}
+
+// Cancel 冷静期内撤销注销申请
+func (s *userDeletionService) Cancel(userId int64, r *http.Request) error {
+	deletion := s.GetPending(userId)
+	if deletion == nil {
+		return errors.New("没有待执行的注销申请")
+	}
+	if err := repositories.UserDeletionRepository.Updates(simple.DB(), deletion.Id, map[string]interface{}{
+		"status":      constants.UserDeletionCanceled,
+		"update_time": simple.NowTimestamp(),
+	}); err != nil {
+		return err
+	}
+	OperateLogService.AddOperateLog(userId, constants.OpTypeCancelAccount, constants.EntityUser, userId, "撤销注销账号", r)
+	return nil
+}
+
+// Delete 管理员注销账号，立即执行
+func (s *userDeletionService) Delete(operatorId, userId int64, purge bool, reason string, r *http.Request) error {
+	user := UserService.Get(userId)
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	if user.Status == constants.UserStatusCanceled {
+		return errors.New("用户已注销")
+	}
+	if user.HasAnyRole(constants.RoleOwner) {
+		return errors.New("站长账号不能注销")
+	}
+	if user.HasAnyRole(constants.RoleAdmin) {
+		if operator := UserService.Get(operatorId); operator == nil || !operator.HasAnyRole(constants.RoleOwner) {
+			return errors.New("只有站长可以注销管理员账号")
+		}
+	}
+	deletion := s.GetPending(userId)
+	if deletion == nil {
+		deletion = &model.UserDeletion{
+			UserId:     userId,
+			Status:     constants.UserDeletionPending,
+			CreateTime: simple.NowTimestamp(),
+		}
+	}
+	deletion.OperatorId = operatorId
+	deletion.Purge = purge
+	deletion.Reason = reason
+	deletion.ScheduledAt = simple.NowTimestamp()
+	deletion.UpdateTime = simple.NowTimestamp()
+
+	var err error
+	if deletion.Id > 0 {
+		err = repositories.UserDeletionRepository.Update(simple.DB(), deletion)
+	} else {
+		err = repositories.UserDeletionRepository.Create(simple.DB(), deletion)
+	}
+	if err != nil {
+		return err
+	}
+	return s.execute(deletion, r)
+}
+
+// EnqueueDue 添加执行冷静期结束的注销申请的后台任务
+func (s *userDeletionService) EnqueueDue() {
+	list := repositories.UserDeletionRepository.Find(simple.DB(), simple.NewSqlCnd().
+		Eq("status", constants.UserDeletionPending).Lte("scheduled_at", simple.NowTimestamp()).Asc("id").Limit(100))
+	for _, deletion := range list {
+		if _, err := JobService.Enqueue(constants.JobTypeUserDelete, deletion.Id); err != nil {
+			logrus.Error(err)
+		}
+	}
+}
+
+// Execute 执行注销：匿名化用户资料，删除第三方账号、登录授权、点赞、积分记录、联邦密钥等个人数据；
+// Purge 为 true 时同时删除发表的话题、文章、动态和评论，否则内容保留并显示为已注销用户
+func (s *userDeletionService) Execute(deletionId int64) error {
+	deletion := s.Get(deletionId)
+	if deletion == nil || deletion.Status != constants.UserDeletionPending {
+		return nil
+	}
+	if deletion.ScheduledAt > simple.NowTimestamp() {
+		return nil
+	}
+	return s.execute(deletion, nil)
+}
+
+func (s *userDeletionService) execute(deletion *model.UserDeletion, r *http.Request) error {
+	userId := deletion.UserId
+
+	user := UserService.Get(userId)
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	var (
+		userTokens = UserTokenService.Find(simple.NewSqlCnd().Eq("user_id", userId))
+		apiTokens  = ApiTokenService.GetUserTokens(userId)
+		purged     = make(map[string][]int64)
+	)
+	if deletion.Purge {
+		tables := map[string]interface{}{
+			constants.EntityTopic:   &model.Topic{},
+			constants.EntityArticle: &model.Article{},
+			constants.EntityTweet:   &model.Tweet{},
+		}
+		for entityType, table := range tables {
+			var ids []int64
+			if err := simple.DB().Model(table).Where("user_id = ? and status = ?", userId, constants.StatusOk).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			purged[entityType] = ids
+		}
+	}
+
+	err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		if deletion.Purge {
+			if err := s.purgeContent(tx, userId); err != nil {
+				return err
+			}
+		}
+
+		// 点赞和表情回应删除前先更新对应的数量
+		for entityType, table := range map[string]string{constants.EntityTopic: "t_topic", constants.EntityTweet: "t_tweet",
+			constants.EntityComment: "t_comment"} {
+			if err := tx.Exec("update "+table+" set like_count = like_count - 1 where like_count > 0 and id in "+
+				"(select entity_id from t_user_like where user_id = ? and entity_type = ?)", userId, entityType).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("update t_reaction_count set count = count - 1 where count > 0 and exists "+
+			"(select 1 from t_user_reaction r where r.user_id = ? and r.entity_type = t_reaction_count.entity_type "+
+			"and r.entity_id = t_reaction_count.entity_id and r.emoji = t_reaction_count.emoji)", userId).Error; err != nil {
+			return err
+		}
+
+		// 发给该用户的邮件中包含邮箱和个人信息
+		if user.Email.Valid && len(user.Email.String) > 0 {
+			if err := tx.Exec("delete from t_email_outbox where email = ?", user.Email.String).Error; err != nil {
+				return err
+			}
+		}
+
+		// 个人数据
+		for _, table := range []string{"t_third_account", "t_user_token", "t_api_token", "t_notification_setting",
+			"t_favorite", "t_message", "t_check_in", "t_email_code", "t_user_like", "t_user_reaction",
+			"t_user_score", "t_user_score_log", "t_signup_analyze", "t_ap_actor_key", "t_ap_follower"} {
+			if err := tx.Exec("delete from "+table+" where user_id = ?", userId).Error; err != nil {
+				return err
+			}
+		}
+
+		columns := map[string]interface{}{
+			"username":           sql.NullString{},
+			"email":              sql.NullString{},
+			"email_verified":     false,
+			"nickname":           s.nickname(),
+			"avatar":             "",
+			"password":           "",
+			"home_page":          "",
+			"description":        "",
+			"roles":              "",
+			"forbidden_end_time": 0,
+			"status":             constants.UserStatusCanceled,
+			"update_time":        simple.NowTimestamp(),
+		}
+		if deletion.Purge {
+			columns["topic_count"] = 0
+			columns["comment_count"] = 0
+		}
+		if err := repositories.UserRepository.Updates(tx, userId, columns); err != nil {
+			return err
+		}
+		return repositories.UserDeletionRepository.Updates(tx, deletion.Id, map[string]interface{}{
+			"status":      constants.UserDeletionDone,
+			"update_time": simple.NowTimestamp(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, userToken := range userTokens {
+		cache.UserTokenCache.Invalidate(userToken.Token)
+	}
+	for _, apiToken := range apiTokens {
+		cache.ApiTokenCache.Invalidate(apiToken.TokenHash)
+	}
+	cache.UserCache.Invalidate(userId)
+	cache.UserCache.InvalidateScore(userId)
+	cache.UserCache.InvalidateName(user.Username.String, user.Nickname)
+	UserExportService.DeleteByUser(userId)
+
+	description := "注销账号"
+	if deletion.Purge {
+		description = "注销账号并删除发表的内容"
+	}
+	if len(deletion.Reason) > 0 {
+		description += "，原因：" + deletion.Reason
+	}
+	OperateLogService.AddOperateLog(deletion.OperatorId, constants.OpTypeCancelAccount, constants.EntityUser, userId, description, r)
+	for entityType, ids := range purged {
+		for _, id := range ids {
+			event.Publish(&event.ContentDeleted{EntityType: entityType, EntityId: id, OperatorId: deletion.OperatorId})
+		}
+	}
+	event.Publish(&event.UserDeleted{UserId: userId, Purge: deletion.Purge, OperatorId: deletion.OperatorId})
+	return nil
+}
+
+// purgeContent 删除用户发表的内容，评论和动态通过各自的删除逻辑更新其他内容的评论数、回复数、转发数和话题标签数
+func (s *userDeletionService) purgeContent(tx *gorm.DB, userId int64) error {
+	var comments []model.Comment
+	if err := tx.Where("user_id = ? and status = ?", userId, constants.StatusOk).Find(&comments).Error; err != nil {
+		return err
+	}
+	for i := range comments {
+		if err := CommentService.deleteTx(tx, &comments[i]); err != nil {
+			return err
+		}
+	}
+
+	var tweets []model.Tweet
+	if err := tx.Where("user_id = ? and status = ?", userId, constants.StatusOk).Find(&tweets).Error; err != nil {
+		return err
+	}
+	for i := range tweets {
+		if err := TweetService.deleteTx(tx, &tweets[i]); err != nil {
+			return err
+		}
+	}
+
+	// 话题和文章同 TopicService.Delete、ArticleService.Delete，同时删除标签关联
+	if err := tx.Exec("update t_topic_tag set status = ? where topic_id in (select id from t_topic where user_id = ?)",
+		constants.StatusDeleted, userId).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("update t_article_tag set status = ? where article_id in (select id from t_article where user_id = ?)",
+		constants.StatusDeleted, userId).Error; err != nil {
+		return err
+	}
+	for _, table := range []string{"t_topic", "t_article"} {
+		if err := tx.Exec("update "+table+" set status = ? where user_id = ?", constants.StatusDeleted, userId).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *userDeletionService) nickname() string {
+	if len(config.Instance.AccountDeletion.Nickname) > 0 {
+		return config.Instance.AccountDeletion.Nickname
+	}
+	return "已注销用户"
+}
+
+func (s *userDeletionService) graceDuration() time.Duration {
+	if config.Instance.AccountDeletion.GraceDays > 0 {
+		return time.Duration(config.Instance.AccountDeletion.GraceDays) * 24 * time.Hour
+	}
+	return 15 * 24 * time.Hour
+}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"archive/zip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/uploader"
+	"bbs-go/common/urls"
+	"bbs-go/config"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+const (
+	exportMaxImages    = 1000         // 压缩包中最多包含的图片数量
+	exportStaleSeconds = 24 * 60 * 60 // 超过该时长仍未生成完成的导出视为失败
+	exportTimeFormat   = "2006-01-02 15:04"
+)
+
+// 内容中的链接，用于查找引用的上传图片
+var exportUrlRegexp = regexp.MustCompile(`https?://[^\s"'()<>\[\]]+`)
+
+var UserExportService = newUserExportService()
+
+func newUserExportService() *userExportService {
+	return &userExportService{}
+}
+
+type userExportService struct {
+}
+
+func (s *userExportService) Get(id int64) *model.UserExport {
+	return repositories.UserExportRepository.Get(simple.DB(), id)
+}
+
+// GetLatest 用户最近一次导出
+func (s *userExportService) GetLatest(userId int64) *model.UserExport {
+	return repositories.UserExportRepository.FindOne(simple.DB(), simple.NewSqlCnd().Eq("user_id", userId).Desc("id"))
+}
+
+// Request 申请导出，同一用户同时只能有一个正在进行的导出
+func (s *userExportService) Request(userId int64) (*model.UserExport, error) {
+	running := repositories.UserExportRepository.FindOne(simple.DB(), simple.NewSqlCnd().Eq("user_id", userId).
+		In("status", []int{constants.UserExportPending, constants.UserExportRunning}))
+	if running != nil {
+		return nil, errors.New("已有正在进行的导出，完成后会通过消息通知你")
+	}
+	export := &model.UserExport{
+		UserId:     userId,
+		Status:     constants.UserExportPending,
+		CreateTime: simple.NowTimestamp(),
+		UpdateTime: simple.NowTimestamp(),
+	}
+	if err := repositories.UserExportRepository.Create(simple.DB(), export); err != nil {
+		return nil, err
+	}
+	if _, err := JobService.Enqueue(constants.JobTypeUserExport, export.Id); err != nil {
+		repositories.UserExportRepository.Delete(simple.DB(), export.Id)
+		return nil, err
+	}
+	return export, nil
+}
+
+// Build 生成导出压缩包并通知用户，生成失败时标记为失败并通知用户重新申请
+func (s *userExportService) Build(exportId int64) error {
+	export := s.Get(exportId)
+	if export == nil {
+		return nil
+	}
+	// 只从待处理状态开始，避免同一个导出被同时生成多次
+	ret := simple.DB().Model(&model.UserExport{}).Where("id = ? and status = ?", export.Id, constants.UserExportPending).
+		Updates(map[string]interface{}{
+			"status":      constants.UserExportRunning,
+			"update_time": simple.NowTimestamp(),
+		})
+	if ret.Error != nil {
+		return ret.Error
+	}
+	if ret.RowsAffected != 1 {
+		// 执行中的任务有心跳，只有执行任务的进程退出后才会被重新执行，此时上一次生成已经中断
+		if export.Status == constants.UserExportRunning {
+			s.fail(export, "生成过程中断")
+		}
+		return nil
+	}
+
+	fileName := "export-" + strconv.FormatInt(export.UserId, 10) + "-" + strconv.FormatInt(export.Id, 10) + ".zip"
+	fileSize, err := s.writeArchive(export.UserId, filepath.Join(s.dir(), fileName))
+	if err != nil {
+		logrus.Error("用户数据导出失败：userId=", export.UserId, " ", err)
+		s.fail(export, err.Error())
+		return nil
+	}
+
+	expiredAt := time.Now().Add(s.expireDuration())
+	if err := repositories.UserExportRepository.Updates(simple.DB(), export.Id, map[string]interface{}{
+		"status":      constants.UserExportSuccess,
+		"file_name":   fileName,
+		"file_size":   fileSize,
+		"expired_at":  simple.Timestamp(expiredAt),
+		"update_time": simple.NowTimestamp(),
+	}); err != nil {
+		_ = os.Remove(filepath.Join(s.dir(), fileName))
+		return err
+	}
+	downloadUrl := s.DownloadUrl(export.Id)
+	MessageService.Produce(0, export.UserId, "你的数据导出已完成，下载链接在 "+
+		simple.TimeFormat(expiredAt, exportTimeFormat)+" 前有效", "", constants.MsgTypeSystem, map[string]interface{}{
+		"exportId": export.Id,
+		"url":      downloadUrl,
+	})
+	return nil
+}
+
+func (s *userExportService) fail(export *model.UserExport, errorMessage string) {
+	if err := repositories.UserExportRepository.Updates(simple.DB(), export.Id, map[string]interface{}{
+		"status":        constants.UserExportFailed,
+		"error_message": errorMessage,
+		"update_time":   simple.NowTimestamp(),
+	}); err != nil {
+		logrus.Error(err)
+	}
+	MessageService.Produce(0, export.UserId, "你的数据导出失败，请稍后重新申请", "", constants.MsgTypeSystem,
+		map[string]interface{}{"exportId": export.Id})
+}
+
+// writeArchive 写入压缩包，先写临时文件，完成后再重命名
+func (s *userExportService) writeArchive(userId int64, filename string) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(filename), os.ModePerm); err != nil {
+		return 0, err
+	}
+	tmp := filename + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	zw := zip.NewWriter(file)
+	err = s.writeEntries(zw, userId)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, filename)
+	}
+	if err != nil {
+		_ = os.Remove(tmp)
+		return 0, err
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *userExportService) writeEntries(zw *zip.Writer, userId int64) error {
+	user := repositories.UserRepository.Get(simple.DB(), userId)
+	if user == nil {
+		return errors.New("用户不存在")
+	}
+	profile := map[string]interface{}{
+		"id":            user.Id,
+		"username":      user.Username.String,
+		"email":         user.Email.String,
+		"emailVerified": user.EmailVerified,
+		"nickname":      user.Nickname,
+		"avatar":        user.Avatar,
+		"homePage":      user.HomePage,
+		"description":   user.Description,
+		"roles":         user.Roles,
+		"topicCount":    user.TopicCount,
+		"commentCount":  user.CommentCount,
+		"score":         0,
+		"createTime":    user.CreateTime,
+		"updateTime":    user.UpdateTime,
+	}
+	if userScore := UserScoreService.GetByUserId(user.Id); userScore != nil {
+		profile["score"] = userScore.Score
+	}
+
+	var (
+		db        = simple.DB()
+		byUser    = func() *simple.SqlCnd { return simple.NewSqlCnd().Eq("user_id", userId).Asc("id") }
+		topics    = repositories.TopicRepository.Find(db, byUser())
+		articles  = repositories.ArticleRepository.Find(db, byUser())
+		tweets    = repositories.TweetRepository.Find(db, byUser())
+		comments  = repositories.CommentRepository.Find(db, byUser())
+		favorites = repositories.FavoriteRepository.Find(db, byUser())
+		likes     = repositories.UserLikeRepository.Find(db, byUser())
+		scoreLogs = repositories.UserScoreLogRepository.Find(db, byUser())
+		messages  = repositories.MessageRepository.Find(db, byUser())
+		imageUrls = []string{user.Avatar}
+	)
+	entries := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", profile},
+		{"topics.json", topics},
+		{"articles.json", articles},
+		{"tweets.json", tweets},
+		{"comments.json", comments},
+		{"favorites.json", favorites},
+		{"likes.json", likes},
+		{"score_logs.json", scoreLogs},
+		{"messages.json", messages},
+	}
+	for _, entry := range entries {
+		if err := s.writeJson(zw, entry.name, entry.data); err != nil {
+			return err
+		}
+	}
+
+	for _, topic := range topics {
+		imageUrls = append(imageUrls, exportUrlRegexp.FindAllString(topic.Content, -1)...)
+	}
+	for _, article := range articles {
+		imageUrls = append(imageUrls, exportUrlRegexp.FindAllString(article.Content, -1)...)
+	}
+	for _, tweet := range tweets {
+		imageUrls = append(imageUrls, exportUrlRegexp.FindAllString(tweet.Content, -1)...)
+		imageUrls = append(imageUrls, exportUrlRegexp.FindAllString(tweet.ImageList, -1)...)
+	}
+	for _, comment := range comments {
+		imageUrls = append(imageUrls, exportUrlRegexp.FindAllString(comment.Content, -1)...)
+	}
+	return s.writeImages(zw, imageUrls)
+}
+
+func (s *userExportService) writeJson(zw *zip.Writer, name string, v interface{}) error {
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// writeImages 写入内容中引用的本站上传图片，读取失败的图片跳过
+func (s *userExportService) writeImages(zw *zip.Writer, imageUrls []string) error {
+	written := make(map[string]bool)
+	for _, imageUrl := range imageUrls {
+		if len(written) >= exportMaxImages {
+			break
+		}
+		key, ok := uploader.ObjectKey(imageUrl)
+		if !ok || written[key] {
+			continue
+		}
+		written[key] = true
+		data, err := uploader.GetObject(key)
+		if err != nil {
+			logrus.Warn("导出图片读取失败：", imageUrl, " ", err)
+			continue
+		}
+		w, err := zw.Create(path.Join("images", key))
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DownloadToken 下载令牌，格式为：导出编号.签名，有效期以导出记录的过期时间为准
+func (s *userExportService) DownloadToken(exportId int64) string {
+	id := strconv.FormatInt(exportId, 10)
+	return id + "." + s.sign(id)
+}
+
+// DownloadUrl 下载地址
+func (s *userExportService) DownloadUrl(exportId int64) string {
+	return urls.AbsUrl("/api/user/export/download?token=" + url.QueryEscape(s.DownloadToken(exportId)))
+}
+
+// GetDownloadFile 校验下载令牌，返回导出记录和压缩包路径
+func (s *userExportService) GetDownloadFile(token string) (*model.UserExport, string, error) {
+	i := strings.LastIndex(token, ".")
+	if i <= 0 || !hmac.Equal([]byte(token[i+1:]), []byte(s.sign(token[:i]))) {
+		return nil, "", errors.New("下载链接无效")
+	}
+	exportId, err := strconv.ParseInt(token[:i], 10, 64)
+	if err != nil {
+		return nil, "", errors.New("下载链接无效")
+	}
+	export := s.Get(exportId)
+	if export == nil || export.Status != constants.UserExportSuccess || export.ExpiredAt < simple.NowTimestamp() {
+		return nil, "", errors.New("下载链接已失效，请重新申请导出")
+	}
+	return export, filepath.Join(s.dir(), export.FileName), nil
+}
+
+func (s *userExportService) sign(data string) string {
+	mac := hmac.New(sha256.New, []byte(SysConfigService.GetSecret()))
+	mac.Write([]byte("export:" + data))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// CleanExpired 删除过期的导出文件，长时间未完成的导出标记为失败
+func (s *userExportService) CleanExpired() {
+	now := simple.NowTimestamp()
+	expired := repositories.UserExportRepository.Find(simple.DB(), simple.NewSqlCnd().
+		Eq("status", constants.UserExportSuccess).Lt("expired_at", now).Limit(1000))
+	for _, export := range expired {
+		if err := os.Remove(filepath.Join(s.dir(), export.FileName)); err != nil && !os.IsNotExist(err) {
+			logrus.Error(err)
+			continue
+		}
+		if err := repositories.UserExportRepository.Updates(simple.DB(), export.Id, map[string]interface{}{
+			"status":      constants.UserExportExpired,
+			"update_time": now,
+		}); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	stale := repositories.UserExportRepository.Find(simple.DB(), simple.NewSqlCnd().
+		In("status", []int{constants.UserExportPending, constants.UserExportRunning}).
+		Lt("create_time", now-exportStaleSeconds*1000).Limit(1000))
+	for i := range stale {
+		s.fail(&stale[i], "导出超时")
+	}
+}
+
+// DeleteByUser 删除用户的导出记录和导出文件，注销账号时调用
+func (s *userExportService) DeleteByUser(userId int64) {
+	list := repositories.UserExportRepository.Find(simple.DB(), simple.NewSqlCnd().Eq("user_id", userId))
+	for _, export := range list {
+		if len(export.FileName) > 0 {
+			if err := os.Remove(filepath.Join(s.dir(), export.FileName)); err != nil && !os.IsNotExist(err) {
+				logrus.Error(err)
+			}
+		}
+		repositories.UserExportRepository.Delete(simple.DB(), export.Id)
+	}
+}
+
+func (s *userExportService) dir() string {
+	if len(config.Instance.Export.Path) > 0 {
+		return config.Instance.Export.Path
+	}
+	return "exports"
+}
+
+func (s *userExportService) expireDuration() time.Duration {
+	if config.Instance.Export.ExpireHours > 0 {
+		return time.Duration(config.Instance.Export.ExpireHours) * time.Hour
+	}
+	return 72 * time.Hour
+}
@@ -2,11 +2,13 @@ package services
 
 import (
 	"bbs-go/model/constants"
+	"context"
 	"errors"
 
 	"github.com/jinzhu/gorm"
 	"github.com/mlogclub/simple"
 
+	"bbs-go/common/tracing"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
@@ -83,13 +85,14 @@ func (s *userLikeService) Exists(userId int64, entityType string, entityId int64
 }
 
 // 话题点赞
-func (s *userLikeService) TopicLike(userId int64, topicId int64) error {
-	topic := repositories.TopicRepository.Get(simple.DB(), topicId)
+func (s *userLikeService) TopicLike(ctx context.Context, userId int64, topicId int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	topic := repositories.TopicRepository.Get(db, topicId)
 	if topic == nil || topic.Status != constants.StatusOk {
 		return errors.New("话题不存在")
 	}
 
-	return simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+	return simple.Tx(db, func(tx *gorm.DB) error {
 		if err := s.like(tx, userId, constants.EntityTopic, topicId); err != nil {
 			return err
 		}
@@ -99,12 +102,13 @@ func (s *userLikeService) TopicLike(userId int64, topicId int64) error {
 }
 
 // 动态点赞
-func (s *userLikeService) TweetLike(userId int64, tweetId int64) error {
-	tweet := repositories.TweetRepository.Get(simple.DB(), tweetId)
+func (s *userLikeService) TweetLike(ctx context.Context, userId int64, tweetId int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	tweet := repositories.TweetRepository.Get(db, tweetId)
 	if tweet == nil || tweet.Status != constants.StatusOk {
 		return errors.New("动态不存在")
 	}
-	return simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+	return simple.Tx(db, func(tx *gorm.DB) error {
 		if err := s.like(tx, userId, constants.EntityTweet, tweetId); err != nil {
 			return err
 		}
@@ -113,9 +117,46 @@ func (s *userLikeService) TweetLike(userId int64, tweetId int64) error {
 	})
 }
 
+// 评论点赞
+func (s *userLikeService) CommentLike(ctx context.Context, userId int64, commentId int64) error {
+	db := tracing.DB(ctx, simple.DB())
+	comment := repositories.CommentRepository.Get(db, commentId)
+	if comment == nil || comment.Status != constants.StatusOk || comment.DeleteTime > 0 {
+		return errors.New("评论不存在")
+	}
+	return simple.Tx(db, func(tx *gorm.DB) error {
+		if err := s.like(tx, userId, constants.EntityComment, commentId); err != nil {
+			return err
+		}
+		// 更新点赞数
+		return tx.Exec("update t_comment set like_count = like_count + 1 where id = ?", commentId).Error
+	})
+}
+
+// 取消评论点赞
+func (s *userLikeService) CommentUnlike(ctx context.Context, userId int64, commentId int64) error {
+	return simple.Tx(tracing.DB(ctx, simple.DB()), func(tx *gorm.DB) error {
+		return s.unlike(tx, userId, constants.EntityComment, commentId, "t_comment")
+	})
+}
+
+// LikedIds 用户点赞过的实体编号
+func (s *userLikeService) LikedIds(userId int64, entityType string, entityIds []int64) map[int64]bool {
+	ret := make(map[int64]bool)
+	if userId <= 0 || len(entityIds) == 0 {
+		return ret
+	}
+	likes := s.Find(simple.NewSqlCnd().Eq("user_id", userId).Eq("entity_type", entityType).In("entity_id", entityIds))
+	for _, like := range likes {
+		ret[like.EntityId] = true
+	}
+	return ret
+}
+
 func (s *userLikeService) like(db *gorm.DB, userId int64, entityType string, entityId int64) error {
 	// 判断是否已经点赞了
-	if s.Exists(userId, entityType, entityId) {
+	if repositories.UserLikeRepository.FindOne(db, simple.NewSqlCnd().Eq("user_id", userId).
+		Eq("entity_type", entityType).Eq("entity_id", entityId)) != nil {
 		return errors.New("已点赞")
 	}
 	// 点赞
@@ -126,3 +167,16 @@ func (s *userLikeService) like(db *gorm.DB, userId int64, entityType string, ent
 		CreateTime: simple.NowTimestamp(),
 	})
 }
+
+// unlike 删除点赞记录并减少点赞数，table为实体所在的表
+func (s *userLikeService) unlike(db *gorm.DB, userId int64, entityType string, entityId int64, table string) error {
+	ret := db.Where("user_id = ? and entity_type = ? and entity_id = ?", userId, entityType, entityId).Delete(&model.UserLike{})
+	if ret.Error != nil {
+		return ret.Error
+	}
+	if ret.RowsAffected == 0 {
+		return errors.New("未点赞")
+	}
+	// 更新点赞数
+	return db.Exec("update "+table+" set like_count = like_count - 1 where id = ? and like_count > 0", entityId).Error
+}
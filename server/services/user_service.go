@@ -2,14 +2,20 @@ package services
 
 import (
 	"bbs-go/common"
-	"bbs-go/common/email"
+	"bbs-go/common/event"
+	"bbs-go/common/ldap"
+	"bbs-go/common/metrics"
+	"bbs-go/common/oidc"
+	"bbs-go/common/ratelimit"
 	"bbs-go/common/urls"
 	"bbs-go/common/validate"
 	"bbs-go/model/constants"
 	"database/sql"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	neturl "net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -22,6 +28,7 @@ import (
 	"bbs-go/cache"
 	"bbs-go/common/avatar"
 	"bbs-go/common/uploader"
+	"bbs-go/config"
 
 	"bbs-go/model"
 	"bbs-go/repositories"
@@ -34,6 +41,9 @@ const emailVerifyExpireHour = 24
 
 const resetTokenExpiredAfterHour = 1
 
+// 用户最后发表内容时间
+var postIntervalLimiter = ratelimit.NewIntervalLimiter()
+
 var UserService = newUserService()
 
 func newUserService() *userService {
@@ -128,6 +138,13 @@ func (s *userService) Forbidden(operatorId, userId int64, days int, reason strin
 		}
 		OperateLogService.AddOperateLog(operatorId, constants.OpTypeForbidden, constants.EntityUser, userId,
 			description, r)
+		event.Publish(&event.UserForbidden{
+			UserId:           userId,
+			Days:             days,
+			ForbiddenEndTime: forbiddenEndTime,
+			Reason:           reason,
+			OperatorId:       operatorId,
+		})
 	}
 	return nil
 }
@@ -140,6 +157,7 @@ func (s *userService) RemoveForbidden(operatorId, userId int64, r *http.Request)
 	}
 	if repositories.UserRepository.UpdateColumn(simple.DB(), userId, "forbidden_end_time", 0) == nil {
 		OperateLogService.AddOperateLog(operatorId, constants.OpTypeRemoveForbidden, constants.EntityUser, userId, "", r)
+		event.Publish(&event.UserForbiddenRemoved{UserId: userId, OperatorId: operatorId})
 	}
 }
 
@@ -153,8 +171,25 @@ func (s *userService) GetByUsername(username string) *model.User {
 	return repositories.UserRepository.GetByUsername(simple.DB(), username)
 }
 
+// SearchForMention 按用户名、昵称前缀搜索用户，用于编辑器中@提及的自动补全
+func (s *userService) SearchForMention(keyword string, limit int) []model.User {
+	keyword = strings.TrimSpace(keyword)
+	if len(keyword) == 0 {
+		return nil
+	}
+	// 转义LIKE通配符
+	prefix := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(keyword) + "%"
+	return repositories.UserRepository.Find(simple.DB(), simple.NewSqlCnd().
+		Where("(username like ? or nickname like ?)", prefix, prefix).
+		Eq("status", constants.StatusOk).Eq("type", constants.UserTypeNormal).
+		Asc("id").Limit(limit))
+}
+
 // SignUp 注册
 func (s *userService) SignUp(username, email, nickname, password, rePassword string, flag string) (*model.User, error) {
+	if ldap.IsEnabled() && config.Instance.Ldap.DisablePasswordLogin {
+		return nil, errors.New("本站已关闭注册，请使用LDAP账号登录")
+	}
 	username = strings.TrimSpace(username)
 	email = strings.TrimSpace(email)
 	nickname = strings.TrimSpace(nickname)
@@ -215,19 +250,21 @@ func (s *userService) SignUp(username, email, nickname, password, rePassword str
 		if err := repositories.UserRepository.UpdateColumn(tx, user.Id, "avatar", avatarUrl); err != nil {
 			return err
 		}
+
+		// 注册来源统计
+		if len(flag) > 0 {
+			if err := tx.Create(&model.SignupAnalyze{Source: flag, UserId: user.Id}).Error; err != nil {
+				return err
+			}
+		}
 		return nil
 	})
 
 	if err != nil {
 		return nil, err
 	}
-
-	if len(flag) > 0 {
-		go func(id int64, source string) {
-			analyze := model.SignupAnalyze{Source: source, UserId: id}
-			simple.DB().Model(&analyze).Create(&analyze)
-		}(user.Id, flag)
-	}
+	metrics.Signups.Inc("password")
+	event.Publish(&event.UserSignedUp{User: user})
 	return user, nil
 }
 
@@ -239,6 +276,25 @@ func (s *userService) SignIn(username, password string) (*model.User, error) {
 	if len(password) == 0 {
 		return nil, errors.New("密码不能为空")
 	}
+	if ldap.IsEnabled() {
+		userInfo, err := ldap.Authenticate(username, password)
+		if err == nil {
+			return s.SignInByLdap(userInfo)
+		}
+		// 只有确定密码错误时直接返回，用户不存在或LDAP服务不可用（连接失败、超时等）时使用本地账号登录
+		if err == ldap.ErrInvalidCredentials {
+			return nil, err
+		}
+		if err != ldap.ErrUserNotFound {
+			logrus.Warnf("LDAP登录失败，username=%s, err=%v", username, err)
+		}
+		if config.Instance.Ldap.DisablePasswordLogin {
+			if err != ldap.ErrUserNotFound {
+				return nil, errors.New("LDAP服务不可用，请稍后重试")
+			}
+			return nil, errors.New("用户不存在或被禁用")
+		}
+	}
 	var user *model.User = nil
 	if err := validate.IsEmail(username); err == nil { // 如果用户输入的是邮箱
 		user = s.GetByEmail(username)
@@ -251,9 +307,85 @@ func (s *userService) SignIn(username, password string) (*model.User, error) {
 	if !simple.ValidatePassword(user.Password, password) {
 		return nil, errors.New("密码错误")
 	}
+	metrics.Logins.Inc("password")
+	return user, nil
+}
+
+// SignInByLdap LDAP登录校验通过后，首次登录时自动创建用户，每次登录时按组同步用户角色
+func (s *userService) SignInByLdap(userInfo *ldap.UserInfo) (*model.User, error) {
+	thirdAccount := ThirdAccountService.GetThirdAccount(constants.ThirdAccountTypeLdap, userInfo.Username)
+	userInfoJson, _ := simple.FormatJson(userInfo)
+	if thirdAccount == nil {
+		thirdAccount = &model.ThirdAccount{
+			UserId:     sql.NullInt64{},
+			Nickname:   userInfo.Nickname,
+			ThirdType:  constants.ThirdAccountTypeLdap,
+			ThirdId:    userInfo.Username,
+			ExtraData:  userInfoJson,
+			CreateTime: simple.NowTimestamp(),
+			UpdateTime: simple.NowTimestamp(),
+		}
+		if err := ThirdAccountService.Create(thirdAccount); err != nil {
+			return nil, err
+		}
+	} else {
+		thirdAccount.ExtraData = userInfoJson
+		thirdAccount.UpdateTime = simple.NowTimestamp()
+		_ = ThirdAccountService.Updates(thirdAccount.Id, map[string]interface{}{
+			"extra_data":  thirdAccount.ExtraData,
+			"update_time": thirdAccount.UpdateTime,
+		})
+	}
+
+	user, codeErr := s.SignInByThirdAccount(thirdAccount, "")
+	if codeErr != nil {
+		return nil, codeErr
+	}
+	if err := s.syncLdapRoles(user, userInfo.Groups); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
+// syncLdapRoles 按组与角色的对应关系同步用户角色，未在对应关系中出现的角色保持不变
+func (s *userService) syncLdapRoles(user *model.User, groups []string) error {
+	groupRoles := config.Instance.Ldap.GroupRoles
+	if len(groupRoles) == 0 {
+		return nil
+	}
+	var roles []string
+	for _, role := range user.GetRoles() {
+		managed := false
+		for _, groupRole := range groupRoles {
+			if groupRole.Role == role {
+				managed = true
+				break
+			}
+		}
+		if !managed {
+			roles = append(roles, role)
+		}
+	}
+	for _, groupRole := range groupRoles {
+		for _, group := range groups {
+			if strings.EqualFold(groupRole.Group, group) && !simple.Contains(groupRole.Role, roles) {
+				roles = append(roles, groupRole.Role)
+			}
+		}
+	}
+
+	rolesStr := strings.Join(roles, ",")
+	if rolesStr == user.Roles {
+		return nil
+	}
+	if err := s.UpdateColumn(user.Id, "roles", rolesStr); err != nil {
+		return err
+	}
+	user.Roles = rolesStr
+	cache.UserCache.Invalidate(user.Id)
+	return nil
+}
+
 // SignInByThirdAccount 第三方账号登录
 func (s *userService) SignInByThirdAccount(thirdAccount *model.ThirdAccount, source string) (*model.User, *simple.CodeError) {
 	user := s.Get(thirdAccount.UserId.Int64)
@@ -261,6 +393,7 @@ func (s *userService) SignInByThirdAccount(thirdAccount *model.ThirdAccount, sou
 		if user.Status != constants.StatusOk {
 			return nil, simple.NewErrorMsg("用户已被禁用")
 		}
+		metrics.Logins.Inc(thirdAccount.ThirdType)
 		return user, nil
 	}
 
@@ -276,14 +409,24 @@ func (s *userService) SignInByThirdAccount(thirdAccount *model.ThirdAccount, sou
 		description = gjson.Get(thirdAccount.ExtraData, "bio").String()
 	}
 
+	// OpenID Connect、LDAP 登录时使用已验证的邮箱，邮箱已被占用时忽略
+	var userEmail sql.NullString
+	if thirdAccount.ThirdType == constants.ThirdAccountTypeLdap || oidc.GetProvider(thirdAccount.ThirdType) != nil {
+		if value := gjson.Get(thirdAccount.ExtraData, "email").String(); validate.IsEmail(value) == nil && !s.isEmailExists(value) {
+			userEmail = simple.SqlNullString(value)
+		}
+	}
+
 	user = &model.User{
-		Username:    sql.NullString{},
-		Nickname:    thirdAccount.Nickname,
-		Status:      constants.StatusOk,
-		HomePage:    homePage,
-		Description: description,
-		CreateTime:  simple.NowTimestamp(),
-		UpdateTime:  simple.NowTimestamp(),
+		Username:      sql.NullString{},
+		Email:         userEmail,
+		EmailVerified: userEmail.Valid,
+		Nickname:      thirdAccount.Nickname,
+		Status:        constants.StatusOk,
+		HomePage:      homePage,
+		Description:   description,
+		CreateTime:    simple.NowTimestamp(),
+		UpdateTime:    simple.NowTimestamp(),
 	}
 	err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
 		if err := repositories.UserRepository.Create(tx, user); err != nil {
@@ -315,6 +458,8 @@ func (s *userService) SignInByThirdAccount(thirdAccount *model.ThirdAccount, sou
 		}(user.Id, source)
 	}
 	cache.UserCache.Invalidate(user.Id)
+	metrics.Signups.Inc(thirdAccount.ThirdType)
+	metrics.Logins.Inc(thirdAccount.ThirdType)
 	return user, nil
 }
 
@@ -468,9 +613,7 @@ func (s *userService) SendEmailVerifyEmail(userId int64) error {
 	var (
 		token     = simple.UUID()
 		url       = urls.AbsUrl("/user/email/verify?token=" + token)
-		link      = &model.ActionLink{Title: "点击这里验证邮箱>>", Url: url}
 		siteTitle = cache.SysConfigCache.GetValue(constants.SysConfigSiteTitle)
-		subject   = "邮箱验证 - " + siteTitle
 		title     = "邮箱验证 - " + siteTitle
 		content   = "该邮件用于验证你在 " + siteTitle + " 中设置邮箱的正确性，请在" + strconv.Itoa(emailVerifyExpireHour) + "小时内完成验证。验证链接：" + url
 	)
@@ -486,12 +629,14 @@ func (s *userService) SendEmailVerifyEmail(userId int64) error {
 			Used:       false,
 			CreateTime: simple.NowTimestamp(),
 		}); err != nil {
-			return nil
-		}
-		if err := email.SendTemplateEmail(user.Email.String, subject, title, content, "", link); err != nil {
 			return err
 		}
-		return nil
+		_, err := EmailOutboxService.SendTx(tx, user.Email.String, constants.EmailTemplateVerify, map[string]interface{}{
+			"Nickname":    user.Nickname,
+			"Url":         url,
+			"ExpireHours": emailVerifyExpireHour,
+		}, nil)
+		return err
 	})
 }
 
@@ -551,10 +696,8 @@ func (s *userService) SendPasswordResetEmail(userEmail string) error {
 	}
 	var (
 		token     = simple.UUID()
-		url       = urls.AbsUrl(fmt.Sprintf("/user/email/reset?token=%s&email=%s", token, user.Email.String))
-		link      = &model.ActionLink{Title: "点击这里重置密码>>", Url: url}
+		url       = urls.AbsUrl(fmt.Sprintf("/user/email/reset?token=%s&email=%s", token, neturl.QueryEscape(user.Email.String)))
 		siteTitle = cache.SysConfigCache.GetValue(constants.SysConfigSiteTitle)
-		subject   = "重置密码 - " + siteTitle
 		title     = "重置密码 - " + siteTitle
 		content   = "该邮件用于重置你在 " + siteTitle + " 中的密码，请在" + strconv.Itoa(resetTokenExpiredAfterHour) + "小时内完成验证。验证链接：" + url
 	)
@@ -570,12 +713,14 @@ func (s *userService) SendPasswordResetEmail(userEmail string) error {
 			Used:       false,
 			CreateTime: simple.NowTimestamp(),
 		}); err != nil {
-			return nil
-		}
-		if err := email.SendTemplateEmail(user.Email.String, subject, title, content, "", link); err != nil {
 			return err
 		}
-		return nil
+		_, err := EmailOutboxService.SendTx(tx, user.Email.String, constants.EmailTemplateResetPassword, map[string]interface{}{
+			"Nickname":    user.Nickname,
+			"Url":         url,
+			"ExpireHours": resetTokenExpiredAfterHour,
+		}, nil)
+		return err
 	})
 }
 
@@ -597,6 +742,35 @@ func (s *userService) CheckPostStatus(user *model.User) *simple.CodeError {
 	return nil
 }
 
+// CheckPostInterval 检查两次发表内容的间隔，观察期内的用户间隔更长
+func (s *userService) CheckPostInterval(user *model.User) *simple.CodeError {
+	rateLimit := config.Instance.RateLimit
+	if rateLimit.Disabled {
+		return nil
+	}
+	intervalSeconds := rateLimit.PostIntervalSeconds
+	observeSeconds := rateLimit.ObserveSeconds
+	if observeSeconds <= 0 {
+		observeSeconds = SysConfigService.GetInt(constants.SysConfigUserObserveSeconds)
+	}
+	if user.InObservationPeriod(observeSeconds) && rateLimit.ObservePostIntervalSeconds > intervalSeconds {
+		intervalSeconds = rateLimit.ObservePostIntervalSeconds
+	}
+	if intervalSeconds <= 0 {
+		return nil
+	}
+	remaining := postIntervalLimiter.Remaining(strconv.FormatInt(user.Id, 10), time.Second*time.Duration(intervalSeconds))
+	if remaining > 0 {
+		return simple.NewError(common.TooManyRequests.Code, "发表内容过于频繁，请"+strconv.Itoa(int(math.Ceil(remaining.Seconds())))+"秒后再试")
+	}
+	return nil
+}
+
+// OnPosted 记录用户发表内容的时间
+func (s *userService) OnPosted(userId int64) {
+	postIntervalLimiter.Mark(strconv.FormatInt(userId, 10))
+}
+
 func (s *userService) GetRoles() *[]string {
 	var roleList []string
 	roles := repositories.UserRepository.GetAllRoles(simple.DB())
@@ -614,3 +788,133 @@ func (s *userService) GetRoles() *[]string {
 	From(roleList).Distinct().ToSlice(&result)
 	return &result
 }
+
+// Merge 合并重复的账号，将 fromUserId 的帖子、评论、动态、积分、第三方账号等转移到 toUserId，然后删除 fromUserId
+func (s *userService) Merge(operatorId, fromUserId, toUserId int64, r *http.Request) error {
+	if fromUserId == toUserId {
+		return errors.New("不能合并同一个账号")
+	}
+	from := s.Get(fromUserId)
+	to := s.Get(toUserId)
+	if from == nil || to == nil {
+		return errors.New("用户不存在")
+	}
+	if from.Status != constants.StatusOk || to.Status != constants.StatusOk {
+		return errors.New("用户已被删除")
+	}
+	// 管理员账号不能合并，避免通过合并获取其他管理员的邮箱和第三方账号
+	if from.HasAnyRole(constants.RoleOwner, constants.RoleAdmin) || to.HasAnyRole(constants.RoleOwner, constants.RoleAdmin) {
+		return errors.New("不能合并管理员账号")
+	}
+
+	fromTokens := UserTokenService.Find(simple.NewSqlCnd().Eq("user_id", fromUserId).Eq("status", constants.StatusOk))
+	fromApiTokens := ApiTokenService.GetUserTokens(fromUserId)
+
+	err := simple.Tx(simple.DB(), func(tx *gorm.DB) error {
+		// 内容转移
+		for _, table := range []string{"t_topic", "t_comment", "t_tweet", "t_article", "t_project", "t_favorite", "t_user_score_log"} {
+			if err := tx.Exec("update "+table+" set user_id = ? where user_id = ?", toUserId, fromUserId).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("update t_message set user_id = ? where user_id = ?", toUserId, fromUserId).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("update t_message set from_id = ? where from_id = ?", toUserId, fromUserId).Error; err != nil {
+			return err
+		}
+
+		// 点赞转移，两个账号都点赞过的保留一个
+		if err := tx.Exec("delete from t_user_like where user_id = ? and (entity_type, entity_id) in "+
+			"(select entity_type, entity_id from (select entity_type, entity_id from t_user_like where user_id = ?) t)",
+			fromUserId, toUserId).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("update t_user_like set user_id = ? where user_id = ?", toUserId, fromUserId).Error; err != nil {
+			return err
+		}
+
+		// 第三方账号转移，目标账号已绑定同类型账号的删除
+		if err := tx.Exec("delete from t_third_account where user_id = ? and third_type in "+
+			"(select third_type from (select third_type from t_third_account where user_id = ?) t)",
+			fromUserId, toUserId).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("update t_third_account set user_id = ? where user_id = ?", toUserId, fromUserId).Error; err != nil {
+			return err
+		}
+
+		// 积分合并
+		if fromScore := repositories.UserScoreRepository.FindOne(tx, simple.NewSqlCnd().Eq("user_id", fromUserId)); fromScore != nil {
+			toScore := repositories.UserScoreRepository.FindOne(tx, simple.NewSqlCnd().Eq("user_id", toUserId))
+			if toScore == nil {
+				if err := repositories.UserScoreRepository.UpdateColumn(tx, fromScore.Id, "user_id", toUserId); err != nil {
+					return err
+				}
+			} else {
+				if err := repositories.UserScoreRepository.UpdateColumn(tx, toScore.Id, "score", toScore.Score+fromScore.Score); err != nil {
+					return err
+				}
+				repositories.UserScoreRepository.Delete(tx, fromScore.Id)
+			}
+		}
+		if err := tx.Exec("delete from t_check_in where user_id = ?", fromUserId).Error; err != nil {
+			return err
+		}
+
+		// 目标账号没有邮箱时使用被合并账号的邮箱
+		if len(to.Email.String) == 0 && len(from.Email.String) > 0 {
+			if err := repositories.UserRepository.UpdateColumn(tx, fromUserId, "email", sql.NullString{}); err != nil {
+				return err
+			}
+			if err := repositories.UserRepository.Updates(tx, toUserId, map[string]interface{}{
+				"email":          from.Email,
+				"email_verified": from.EmailVerified,
+			}); err != nil {
+				return err
+			}
+		}
+
+		// 登录授权失效
+		if err := tx.Exec("update t_user_token set status = ? where user_id = ?", constants.StatusDeleted, fromUserId).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("update t_api_token set status = ? where user_id = ?", constants.StatusDeleted, fromUserId).Error; err != nil {
+			return err
+		}
+
+		topicCount := repositories.TopicRepository.Count(tx, simple.NewSqlCnd().Eq("user_id", toUserId).Eq("status", constants.StatusOk))
+		commentCount := repositories.CommentRepository.Count(tx, simple.NewSqlCnd().Eq("user_id", toUserId).Eq("status", constants.StatusOk))
+		if err := repositories.UserRepository.Updates(tx, toUserId, map[string]interface{}{
+			"topic_count":   topicCount,
+			"comment_count": commentCount,
+			"update_time":   simple.NowTimestamp(),
+		}); err != nil {
+			return err
+		}
+		return repositories.UserRepository.Updates(tx, fromUserId, map[string]interface{}{
+			"status":        constants.StatusDeleted,
+			"topic_count":   0,
+			"comment_count": 0,
+			"update_time":   simple.NowTimestamp(),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, userToken := range fromTokens {
+		cache.UserTokenCache.Invalidate(userToken.Token)
+	}
+	for _, apiToken := range fromApiTokens {
+		cache.ApiTokenCache.Invalidate(apiToken.TokenHash)
+	}
+	for _, userId := range []int64{fromUserId, toUserId} {
+		cache.UserCache.Invalidate(userId)
+		cache.UserCache.InvalidateScore(userId)
+	}
+	OperateLogService.AddOperateLog(operatorId, constants.OpTypeMerge, constants.EntityUser, toUserId,
+		"合并账号："+strconv.FormatInt(fromUserId, 10), r)
+	event.Publish(&event.UsersMerged{FromUserId: fromUserId, ToUserId: toUserId, OperatorId: operatorId})
+	return nil
+}
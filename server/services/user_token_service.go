@@ -8,6 +8,7 @@ import (
 	"github.com/mlogclub/simple"
 
 	"bbs-go/cache"
+	"bbs-go/common/logging"
 	"bbs-go/model"
 	"bbs-go/repositories"
 )
@@ -54,9 +55,20 @@ func (s *userTokenService) GetCurrentUserId(ctx iris.Context) int64 {
 	return 0
 }
 
-// 获取当前登录用户
+// 获取当前登录用户，未携带登录token时尝试使用个人访问令牌；获取到时记录到请求上下文中，之后的日志会带上用户编号
 func (s *userTokenService) GetCurrent(ctx iris.Context) *model.User {
+	user := s.getCurrent(ctx)
+	if user != nil {
+		logging.SetUserId(ctx.Request().Context(), user.Id)
+	}
+	return user
+}
+
+func (s *userTokenService) getCurrent(ctx iris.Context) *model.User {
 	token := s.GetUserToken(ctx)
+	if len(token) == 0 {
+		return ApiTokenService.GetCurrentUser(ctx)
+	}
 	userToken := cache.UserTokenCache.Get(token)
 	// 没找到授权
 	if userToken == nil || userToken.Status == constants.StatusDeleted {
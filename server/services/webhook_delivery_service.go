@@ -0,0 +1,202 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/webhook"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+var WebhookDeliveryService = newWebhookDeliveryService()
+
+func newWebhookDeliveryService() *webhookDeliveryService {
+	return &webhookDeliveryService{}
+}
+
+type webhookDeliveryService struct {
+}
+
+func (s *webhookDeliveryService) Get(id int64) *model.WebhookDelivery {
+	return repositories.WebhookDeliveryRepository.Get(simple.DB(), id)
+}
+
+func (s *webhookDeliveryService) Take(where ...interface{}) *model.WebhookDelivery {
+	return repositories.WebhookDeliveryRepository.Take(simple.DB(), where...)
+}
+
+func (s *webhookDeliveryService) Find(cnd *simple.SqlCnd) []model.WebhookDelivery {
+	return repositories.WebhookDeliveryRepository.Find(simple.DB(), cnd)
+}
+
+func (s *webhookDeliveryService) FindOne(cnd *simple.SqlCnd) *model.WebhookDelivery {
+	return repositories.WebhookDeliveryRepository.FindOne(simple.DB(), cnd)
+}
+
+func (s *webhookDeliveryService) FindPageByParams(params *simple.QueryParams) (list []model.WebhookDelivery, paging *simple.Paging) {
+	return repositories.WebhookDeliveryRepository.FindPageByParams(simple.DB(), params)
+}
+
+func (s *webhookDeliveryService) FindPageByCnd(cnd *simple.SqlCnd) (list []model.WebhookDelivery, paging *simple.Paging) {
+	return repositories.WebhookDeliveryRepository.FindPageByCnd(simple.DB(), cnd)
+}
+
+func (s *webhookDeliveryService) Count(cnd *simple.SqlCnd) int {
+	return repositories.WebhookDeliveryRepository.Count(simple.DB(), cnd)
+}
+
+func (s *webhookDeliveryService) Create(t *model.WebhookDelivery) error {
+	return repositories.WebhookDeliveryRepository.Create(simple.DB(), t)
+}
+
+func (s *webhookDeliveryService) Update(t *model.WebhookDelivery) error {
+	return repositories.WebhookDeliveryRepository.Update(simple.DB(), t)
+}
+
+func (s *webhookDeliveryService) Updates(id int64, columns map[string]interface{}) error {
+	return repositories.WebhookDeliveryRepository.Updates(simple.DB(), id, columns)
+}
+
+func (s *webhookDeliveryService) UpdateColumn(id int64, name string, value interface{}) error {
+	return repositories.WebhookDeliveryRepository.UpdateColumn(simple.DB(), id, name, value)
+}
+
+func (s *webhookDeliveryService) Delete(id int64) {
+	repositories.WebhookDeliveryRepository.Delete(simple.DB(), id)
+}
+
+// 单次投递的最长耗时，超过该时间未完成的投递会被定时任务重新投递
+const webhookDeliveryLease = time.Minute
+
+// create 创建投递记录
+func (s *webhookDeliveryService) create(webhookId int64, event string, timestamp int64, data interface{}) (*model.WebhookDelivery, error) {
+	deliveryId := simple.UUID()
+	payload, err := json.Marshal(&webhookPayload{
+		Id:        deliveryId,
+		Event:     event,
+		Timestamp: timestamp,
+		Data:      data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := simple.NowTimestamp()
+	delivery := &model.WebhookDelivery{
+		WebhookId:     webhookId,
+		DeliveryId:    deliveryId,
+		Event:         event,
+		Payload:       string(payload),
+		Status:        constants.WebhookDeliveryPending,
+		NextRetryTime: now + webhookDeliveryLease.Milliseconds(),
+		CreateTime:    now,
+		UpdateTime:    now,
+	}
+	if err := s.Create(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// deliver 投递，并根据结果更新状态、计算下次重试时间
+func (s *webhookDeliveryService) deliver(hook *model.Webhook, delivery *model.WebhookDelivery) {
+	result := webhook.Send(hook.Url, hook.Secret, delivery.Event, delivery.DeliveryId, []byte(delivery.Payload))
+
+	delivery.Attempts++
+	delivery.ResponseCode = result.StatusCode
+	delivery.ResponseBody = result.ResponseBody
+	delivery.Error = result.Error
+	delivery.Duration = result.Duration
+	delivery.UpdateTime = simple.NowTimestamp()
+	if result.Success() {
+		delivery.Status = constants.WebhookDeliverySuccess
+		delivery.NextRetryTime = 0
+	} else if backoff, ok := webhook.NextRetry(delivery.Attempts); ok {
+		delivery.Status = constants.WebhookDeliveryPending
+		delivery.NextRetryTime = delivery.UpdateTime + backoff.Milliseconds()
+	} else {
+		delivery.Status = constants.WebhookDeliveryFailed
+		delivery.NextRetryTime = 0
+	}
+	if err := repositories.WebhookDeliveryRepository.Updates(simple.DB(), delivery.Id, map[string]interface{}{
+		"attempts":        delivery.Attempts,
+		"response_code":   delivery.ResponseCode,
+		"response_body":   delivery.ResponseBody,
+		"error":           delivery.Error,
+		"duration":        delivery.Duration,
+		"status":          delivery.Status,
+		"next_retry_time": delivery.NextRetryTime,
+		"update_time":     delivery.UpdateTime,
+	}); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// claim 抢占待投递的记录，多个实例同时重试时只有一个能抢占成功
+func (s *webhookDeliveryService) claim(delivery *model.WebhookDelivery) bool {
+	nextRetryTime := simple.NowTimestamp() + webhookDeliveryLease.Milliseconds()
+	ret := simple.DB().Model(&model.WebhookDelivery{}).
+		Where("id = ? and status = ? and next_retry_time = ?", delivery.Id, constants.WebhookDeliveryPending, delivery.NextRetryTime).
+		UpdateColumn("next_retry_time", nextRetryTime)
+	if ret.Error != nil || ret.RowsAffected != 1 {
+		return false
+	}
+	delivery.NextRetryTime = nextRetryTime
+	return true
+}
+
+// RetryPending 重新投递到达重试时间的记录，由定时任务调用
+func (s *webhookDeliveryService) RetryPending() {
+	deliveries := s.Find(simple.NewSqlCnd().Eq("status", constants.WebhookDeliveryPending).
+		Lte("next_retry_time", simple.NowTimestamp()).Asc("id").Limit(100))
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		hook := WebhookService.Get(delivery.WebhookId)
+		if hook == nil || hook.Status != constants.StatusOk {
+			// Webhook已删除或停用，不再投递
+			_ = s.Updates(delivery.Id, map[string]interface{}{
+				"status":          constants.WebhookDeliveryFailed,
+				"error":           "webhook已停用",
+				"next_retry_time": 0,
+				"update_time":     simple.NowTimestamp(),
+			})
+			continue
+		}
+		if s.claim(delivery) {
+			s.deliver(hook, delivery)
+		}
+	}
+}
+
+// Redeliver 重新投递，使用原始的推送内容和投递编号创建新的投递记录并立即投递
+func (s *webhookDeliveryService) Redeliver(id int64) (*model.WebhookDelivery, error) {
+	delivery := s.Get(id)
+	if delivery == nil {
+		return nil, errors.New("投递记录不存在")
+	}
+	hook := WebhookService.Get(delivery.WebhookId)
+	if hook == nil {
+		return nil, errors.New("Webhook不存在")
+	}
+	now := simple.NowTimestamp()
+	newDelivery := &model.WebhookDelivery{
+		WebhookId:     delivery.WebhookId,
+		DeliveryId:    delivery.DeliveryId,
+		Event:         delivery.Event,
+		Payload:       delivery.Payload,
+		Status:        constants.WebhookDeliveryPending,
+		NextRetryTime: now + webhookDeliveryLease.Milliseconds(),
+		CreateTime:    now,
+		UpdateTime:    now,
+	}
+	if err := s.Create(newDelivery); err != nil {
+		return nil, err
+	}
+	s.deliver(hook, newDelivery)
+	return newDelivery, nil
+}
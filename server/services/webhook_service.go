@@ -0,0 +1,157 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+var WebhookService = newWebhookService()
+
+func newWebhookService() *webhookService {
+	return &webhookService{}
+}
+
+type webhookService struct {
+}
+
+func (s *webhookService) Get(id int64) *model.Webhook {
+	return repositories.WebhookRepository.Get(simple.DB(), id)
+}
+
+func (s *webhookService) Take(where ...interface{}) *model.Webhook {
+	return repositories.WebhookRepository.Take(simple.DB(), where...)
+}
+
+func (s *webhookService) Find(cnd *simple.SqlCnd) []model.Webhook {
+	return repositories.WebhookRepository.Find(simple.DB(), cnd)
+}
+
+func (s *webhookService) FindOne(cnd *simple.SqlCnd) *model.Webhook {
+	return repositories.WebhookRepository.FindOne(simple.DB(), cnd)
+}
+
+func (s *webhookService) FindPageByParams(params *simple.QueryParams) (list []model.Webhook, paging *simple.Paging) {
+	return repositories.WebhookRepository.FindPageByParams(simple.DB(), params)
+}
+
+func (s *webhookService) FindPageByCnd(cnd *simple.SqlCnd) (list []model.Webhook, paging *simple.Paging) {
+	return repositories.WebhookRepository.FindPageByCnd(simple.DB(), cnd)
+}
+
+func (s *webhookService) Count(cnd *simple.SqlCnd) int {
+	return repositories.WebhookRepository.Count(simple.DB(), cnd)
+}
+
+func (s *webhookService) Create(t *model.Webhook) error {
+	return repositories.WebhookRepository.Create(simple.DB(), t)
+}
+
+func (s *webhookService) Update(t *model.Webhook) error {
+	return repositories.WebhookRepository.Update(simple.DB(), t)
+}
+
+func (s *webhookService) Updates(id int64, columns map[string]interface{}) error {
+	return repositories.WebhookRepository.Updates(simple.DB(), id, columns)
+}
+
+func (s *webhookService) UpdateColumn(id int64, name string, value interface{}) error {
+	return repositories.WebhookRepository.UpdateColumn(simple.DB(), id, name, value)
+}
+
+func (s *webhookService) Delete(id int64) {
+	repositories.WebhookRepository.Delete(simple.DB(), id)
+}
+
+// 所有可订阅的事件
+var WebhookEvents = []string{
+	constants.EventTopicPublish,
+	constants.EventArticlePublish,
+	constants.EventCommentPublish,
+	constants.EventTweetPublish,
+	constants.EventUserSignUp,
+	constants.EventUserForbidden,
+	constants.EventUserRemoveForbidden,
+	constants.EventUserMerge,
+	constants.EventUserDelete,
+	constants.EventTopicDelete,
+	constants.EventTopicUndelete,
+	constants.EventTopicRecommend,
+	constants.EventTopicRemoveRecommend,
+	constants.EventArticleDelete,
+	constants.EventCommentDelete,
+	constants.EventTweetDelete,
+}
+
+// 推送内容
+type webhookPayload struct {
+	Id        string      `json:"id"`        // 投递编号
+	Event     string      `json:"event"`     // 事件类型
+	Timestamp int64       `json:"timestamp"` // 事件发生时间
+	Data      interface{} `json:"data"`      // 事件数据
+}
+
+// Check 校验Webhook配置
+func (s *webhookService) Check(t *model.Webhook) error {
+	t.Name = strings.TrimSpace(t.Name)
+	t.Url = strings.TrimSpace(t.Url)
+	if len(t.Name) == 0 {
+		return errors.New("请输入名称")
+	}
+	u, err := url.Parse(t.Url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		return errors.New("推送地址错误")
+	}
+	var events []string
+	for _, event := range strings.Split(t.Events, ",") {
+		event = strings.TrimSpace(event)
+		if len(event) == 0 {
+			continue
+		}
+		if !simple.Contains(event, WebhookEvents) {
+			return errors.New("不支持的事件：" + event)
+		}
+		events = append(events, event)
+	}
+	t.Events = strings.Join(events, ",")
+	return nil
+}
+
+// IsSubscribed 是否订阅了该事件
+func (s *webhookService) IsSubscribed(t *model.Webhook, event string) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, item := range strings.Split(t.Events, ",") {
+		if item == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatch 推送给所有订阅了该事件的Webhook，推送失败的由定时任务重试；
+// 推送会阻塞当前协程，由事件总线的异步处理函数调用
+func (s *webhookService) Dispatch(event string, data interface{}) {
+	timestamp := simple.NowTimestamp()
+	webhooks := s.Find(simple.NewSqlCnd().Eq("status", constants.StatusOk))
+	for i := range webhooks {
+		hook := &webhooks[i]
+		if !s.IsSubscribed(hook, event) {
+			continue
+		}
+		delivery, err := WebhookDeliveryService.create(hook.Id, event, timestamp, data)
+		if err != nil {
+			logrus.Error(err)
+			continue
+		}
+		WebhookDeliveryService.deliver(hook, delivery)
+	}
+}
@@ -1,20 +1,18 @@
 package sitemap
 
 import (
-	"bbs-go/model/constants"
-	"bytes"
-	"compress/gzip"
-	"time"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync/atomic"
 
-	"github.com/ikeikeikeike/go-sitemap-generator/v2/stm"
 	"github.com/mlogclub/simple"
 	"github.com/sirupsen/logrus"
 
-	"bbs-go/common/uploader"
-	"bbs-go/common/urls"
 	"bbs-go/config"
 	"bbs-go/model"
-	"bbs-go/services"
+	"bbs-go/repositories"
 )
 
 const (
@@ -27,151 +25,149 @@ const (
 	changefreqNever   = "never"
 )
 
-var building = false
+const (
+	defaultShardSize = 10000
+	maxShardSize     = 50000 // 协议规定单个站点地图文件最多包含50000个链接
+	indexFilename    = "sitemap.xml"
+)
+
+var building int32
+
+// 站点地图分片
+type shard struct {
+	Name        string
+	Fingerprint string
+	LastMod     int64
+	Build       func() []sitemapUrl
+}
 
-// Generate
+// Generate 生成站点地图索引和分片，只重新生成内容有变化的分片
 func Generate() {
-	if config.Instance.Env != "prod" {
-		return
-	}
-	if building {
+	if !atomic.CompareAndSwapInt32(&building, 0, 1) {
 		logrus.Info("Sitemap in building...")
 		return
 	}
-	building = true
-	defer func() {
-		building = false
-	}()
-
-	sm := stm.NewSitemap(0)
-	sm.SetDefaultHost(config.Instance.BaseUrl)                  // 网站host
-	sm.SetSitemapsHost(config.Instance.Uploader.AliyunOss.Host) // 上传到阿里云所以host设置为阿里云
-	sm.SetSitemapsPath("sitemap2")                              // sitemap存放目录
-	sm.SetVerbose(false)
-	sm.SetPretty(false)
-	sm.SetCompress(true)
-	sm.SetAdapter(&myAdapter{})
-	sm.Create()
-
-	sm.Add(stm.URL{
-		{"loc", urls.AbsUrl("/")},
-		{"lastmod", time.Now()},
-		{"changefreq", changefreqDaily},
-		{"priority", 1.0},
-	})
-
-	sm.Add(stm.URL{
-		{"loc", urls.AbsUrl("/topics")},
-		{"lastmod", time.Now()},
-		{"changefreq", changefreqDaily},
-		{"priority", 1.0},
-	})
-
-	sm.Add(stm.URL{
-		{"loc", urls.AbsUrl("/articles")},
-		{"lastmod", time.Now()},
-		{"changefreq", changefreqDaily},
-		{"priority", 1.0},
-	})
-
-	sm.Add(stm.URL{
-		{"loc", urls.AbsUrl("/projects")},
-		{"lastmod", time.Now()},
-		{"changefreq", changefreqDaily},
-		{"priority", 1.0},
-	})
+	defer atomic.StoreInt32(&building, 0)
 
 	var (
-		dateFrom = simple.Timestamp(time.Now().AddDate(0, -1, 0))
-		dateTo   = simple.NowTimestamp()
+		shardSize = getShardSize()
+		store     = getStorage()
+		shards    []shard
+		lastMods  = make(map[string]int64) // 各类内容的最后更新时间
 	)
-
-	services.ArticleService.ScanDescWithDate(dateFrom, dateTo, func(articles []model.Article) {
-		for _, article := range articles {
-			if article.Status == constants.StatusOk {
-				articleUrl := urls.ArticleUrl(article.Id)
-				sm.Add(stm.URL{
-					{"loc", articleUrl},
-					{"lastmod", simple.TimeFromTimestamp(article.UpdateTime)},
-					{"changefreq", changefreqDaily},
-					{"priority", 0.9},
-				})
-			}
-		}
-	})
-
-	services.TopicService.ScanDescWithDate(dateFrom, dateTo, func(topics []model.Topic) {
-		for _, topic := range topics {
-			if topic.Status == constants.StatusOk {
-				topicUrl := urls.TopicUrl(topic.Id)
-				sm.Add(stm.URL{
-					{"loc", topicUrl},
-					{"lastmod", simple.TimeFromTimestamp(topic.LastCommentTime)},
-					{"changefreq", changefreqDaily},
-					{"priority", 0.9},
-				})
-			}
+	for _, src := range sources {
+		for _, stat := range src.stats(shardSize) {
+			shards = append(shards, src.shard(stat, shardSize, store))
+			lastMods[src.Name] = latest(lastMods[src.Name], stat.MaxLastMod)
 		}
-	})
-
-	services.ProjectService.ScanDescWithDate(dateFrom, dateTo, func(projects []model.Project) {
-		for _, project := range projects {
-			sm.Add(stm.URL{
-				{"loc", urls.ProjectUrl(project.Id)},
-				{"lastmod", simple.TimeFromTimestamp(project.CreateTime)},
-				{"changefreq", changefreqWeekly},
-			})
-		}
-	})
-
-	services.TagService.Scan(func(tags []model.Tag) {
-		for _, tag := range tags {
-			tagUrl := urls.TagArticlesUrl(tag.Id)
-			sm.Add(stm.URL{
-				{"loc", tagUrl},
-				{"lastmod", time.Now()},
-				{"changefreq", changefreqDaily},
-			})
+	}
+	// 页面和节点数量较少，直接生成
+	shards = append([]shard{eagerShard("pages", store, buildPages(lastMods))}, shards...)
+	if nodes := buildNodes(); len(nodes) > 0 {
+		shards = append(shards, eagerShard("nodes", store, nodes))
+	}
+
+	var (
+		items   []indexItem
+		current = make(map[string]bool)
+	)
+	for _, s := range shards {
+		current[s.Name] = true
+		shardUrl, err := putShard(store, s)
+		if err != nil {
+			logrus.Error("Generate sitemap shard error: ", s.Name, ", ", err)
+			continue
 		}
-	})
-
-	services.UserService.Scan(func(users []model.User) {
-		for _, user := range users {
-			sm.Add(stm.URL{
-				{"loc", urls.UserUrl(user.Id)},
-				{"lastmod", time.Now()},
-				{"changefreq", changefreqWeekly},
-			})
+		items = append(items, indexItem{Loc: shardUrl, LastMod: formatLastMod(s.LastMod)})
+	}
+
+	// 清理已经没有内容的分片记录
+	for _, record := range repositories.SitemapShardRepository.Find(simple.DB(), simple.NewSqlCnd()) {
+		if !current[record.Name] {
+			repositories.SitemapShardRepository.Delete(simple.DB(), record.Id)
 		}
-	})
+	}
 
-	sm.Finalize().PingSearchEngines("http://www.google.cn/webmasters/tools/ping?sitemap=%s")
+	data, err := encodeIndex(items)
+	if err != nil {
+		logrus.Error("Generate sitemap index error: ", err)
+		return
+	}
+	if indexUrl, err := store.Put(indexFilename, data); err != nil {
+		logrus.Error("Upload sitemap index error: ", err)
+	} else {
+		logrus.Info("Sitemap generated: ", indexUrl)
+	}
 }
 
-// My Adapter
-type myAdapter struct {
+// putShard 分片内容指纹未变化时直接使用上次生成的地址，否则重新生成并保存
+func putShard(store storage, s shard) (string, error) {
+	record := repositories.SitemapShardRepository.Take(simple.DB(), "name = ?", s.Name)
+	if record != nil && record.Fingerprint == s.Fingerprint && len(record.Url) > 0 {
+		return record.Url, nil
+	}
+	data, err := encodeUrlSet(s.Build())
+	if err != nil {
+		return "", err
+	}
+	shardUrl, err := store.Put(s.Name+".xml.gz", data)
+	if err != nil {
+		return "", err
+	}
+	if record == nil {
+		err = repositories.SitemapShardRepository.Create(simple.DB(), &model.SitemapShard{
+			Name:        s.Name,
+			Fingerprint: s.Fingerprint,
+			Url:         shardUrl,
+			LastMod:     s.LastMod,
+			UpdateTime:  simple.NowTimestamp(),
+		})
+	} else {
+		err = repositories.SitemapShardRepository.Updates(simple.DB(), record.Id, map[string]interface{}{
+			"fingerprint": s.Fingerprint,
+			"url":         shardUrl,
+			"last_mod":    s.LastMod,
+			"update_time": simple.NowTimestamp(),
+		})
+	}
+	logrus.Info("Upload sitemap: ", shardUrl)
+	return shardUrl, err
 }
 
-// Bytes gets written content.
-func (adp *myAdapter) Bytes() [][]byte {
-	return nil
+// eagerShard 链接数量较少的分片直接生成，使用链接和更新时间计算指纹
+func eagerShard(name string, store storage, list []sitemapUrl) shard {
+	var (
+		sb      strings.Builder
+		lastMod int64
+	)
+	sb.WriteString(store.Name())
+	for _, u := range list {
+		sb.WriteString(u.Loc)
+		sb.WriteString(u.LastMod)
+		lastMod = latest(lastMod, u.lastModTime)
+	}
+	sum := md5.Sum([]byte(sb.String()))
+	return shard{
+		Name:        name,
+		Fingerprint: hex.EncodeToString(sum[:]),
+		LastMod:     lastMod,
+		Build: func() []sitemapUrl {
+			return list
+		},
+	}
 }
 
-// Write will create sitemap xml file into the file systems.
-func (adp *myAdapter) Write(loc *stm.Location, data []byte) {
-	var out []byte
-	if stm.GzipPtn.MatchString(loc.Filename()) {
-		var in bytes.Buffer
-		w := gzip.NewWriter(&in)
-		_, _ = w.Write(data)
-		_ = w.Close()
-		out = in.Bytes()
-	} else {
-		out = data
+func getShardSize() int64 {
+	size := config.Instance.Sitemap.ShardSize
+	if size <= 0 {
+		return defaultShardSize
 	}
-	if _url, err := uploader.PutObject(loc.PathInPublic(), out); err != nil {
-		logrus.Error("Upload sitemap error:", err)
-	} else {
-		logrus.Info("Upload sitemap:", _url)
+	if size > maxShardSize {
+		return maxShardSize
 	}
+	return int64(size)
+}
+
+func shardName(name string, no int64) string {
+	return fmt.Sprintf("%s-%d", name, no+1)
 }
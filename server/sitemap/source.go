@@ -0,0 +1,243 @@
+package sitemap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/mlogclub/simple"
+	"github.com/sirupsen/logrus"
+
+	"bbs-go/common/urls"
+	"bbs-go/model"
+	"bbs-go/model/constants"
+	"bbs-go/repositories"
+)
+
+// 按编号分片的内容，通过一次聚合查询计算所有分片的指纹
+type source struct {
+	Name    string        // 分片名称前缀
+	Table   string        // 表名
+	Where   string        // 公开内容的查询条件
+	Args    []interface{} // 查询条件参数
+	LastMod string        // 最后更新时间的sql表达式，需要和Load中的计算方式一致
+	Load    func(db *gorm.DB) []sitemapUrl
+}
+
+// 分片统计，用于判断分片内容是否有变化
+type shardStat struct {
+	Shard      int64
+	Count      int64
+	SumId      int64
+	SumLastMod int64
+	MaxLastMod int64
+}
+
+var sources = []*source{
+	{
+		Name:    "topics",
+		Table:   "t_topic",
+		Where:   "status = ?",
+		Args:    []interface{}{constants.StatusOk},
+		LastMod: latestSql("create_time", "last_comment_time"),
+		Load:    loadTopics,
+	},
+	{
+		Name:    "articles",
+		Table:   "t_article",
+		Where:   "status = ?",
+		Args:    []interface{}{constants.StatusOk},
+		LastMod: latestSql("create_time", "update_time"),
+		Load:    loadArticles,
+	},
+	{
+		Name:    "tweets",
+		Table:   "t_tweet",
+		Where:   "status = ? and (repost_id = 0 or content <> '')", // 直接转发的动态没有单独的内容，不收录
+		Args:    []interface{}{constants.StatusOk},
+		LastMod: "create_time",
+		Load:    loadTweets,
+	},
+	{
+		Name:    "projects",
+		Table:   "t_project",
+		Where:   "id > ?",
+		Args:    []interface{}{0},
+		LastMod: "create_time",
+		Load:    loadProjects,
+	},
+	{
+		Name:    "tags",
+		Table:   "t_tag",
+		Where:   "status = ?",
+		Args:    []interface{}{constants.StatusOk},
+		LastMod: latestSql("create_time", "update_time"),
+		Load:    loadTags,
+	},
+	{
+		Name:    "users",
+		Table:   "t_user",
+		Where:   "status = ?",
+		Args:    []interface{}{constants.StatusOk},
+		LastMod: latestSql("create_time", "update_time"),
+		Load:    loadUsers,
+	},
+}
+
+// stats 各分片的统计，分片n包含编号在 (n*shardSize, (n+1)*shardSize] 范围内的内容
+func (s *source) stats(shardSize int64) (list []shardStat) {
+	err := simple.DB().Table(s.Table).
+		Select(fmt.Sprintf("floor((id - 1) / %d) as shard, count(*) as count, sum(id) as sum_id, "+
+			"sum(%s) as sum_last_mod, max(%s) as max_last_mod", shardSize, s.LastMod, s.LastMod)).
+		Where(s.Where, s.Args...).
+		Group("shard").
+		Order("shard asc").
+		Scan(&list).Error
+	if err != nil {
+		logrus.Error("Sitemap stats error: ", s.Name, ", ", err)
+	}
+	return
+}
+
+func (s *source) shard(stat shardStat, shardSize int64, store storage) shard {
+	fromId, toId := stat.Shard*shardSize, (stat.Shard+1)*shardSize
+	return shard{
+		Name:        shardName(s.Name, stat.Shard),
+		Fingerprint: fmt.Sprintf("%s:%d:%d:%d:%d", store.Name(), shardSize, stat.Count, stat.SumId, stat.SumLastMod),
+		LastMod:     stat.MaxLastMod,
+		Build: func() []sitemapUrl {
+			return s.Load(simple.DB().Where("id > ? and id <= ?", fromId, toId).Where(s.Where, s.Args...).Order("id asc"))
+		},
+	}
+}
+
+func loadTopics(db *gorm.DB) (list []sitemapUrl) {
+	var topics []model.Topic
+	db.Select("id, create_time, last_comment_time").Find(&topics)
+	for _, topic := range topics {
+		list = append(list, newUrl(urls.TopicUrl(topic.Id), latest(topic.CreateTime, topic.LastCommentTime), changefreqDaily, 0.9))
+	}
+	return
+}
+
+func loadArticles(db *gorm.DB) (list []sitemapUrl) {
+	var articles []model.Article
+	db.Select("id, create_time, update_time").Find(&articles)
+	for _, article := range articles {
+		list = append(list, newUrl(urls.ArticleUrl(article.Id), latest(article.CreateTime, article.UpdateTime), changefreqWeekly, 0.9))
+	}
+	return
+}
+
+func loadTweets(db *gorm.DB) (list []sitemapUrl) {
+	var tweets []model.Tweet
+	db.Select("id, image_list, create_time").Find(&tweets)
+	for _, tweet := range tweets {
+		var images []string
+		if simple.IsNotBlank(tweet.ImageList) {
+			if err := simple.ParseJson(tweet.ImageList, &images); err != nil {
+				logrus.Error(err)
+			}
+		}
+		list = append(list, newUrl(urls.TweetUrl(tweet.Id), tweet.CreateTime, changefreqWeekly, 0.6).
+			withImages(absImageUrls(images...)...))
+	}
+	return
+}
+
+func loadProjects(db *gorm.DB) (list []sitemapUrl) {
+	var projects []model.Project
+	db.Select("id, logo, create_time").Find(&projects)
+	for _, project := range projects {
+		list = append(list, newUrl(urls.ProjectUrl(project.Id), project.CreateTime, changefreqMonthly, 0.7).
+			withImages(absImageUrls(project.Logo)...))
+	}
+	return
+}
+
+func loadTags(db *gorm.DB) (list []sitemapUrl) {
+	var tags []model.Tag
+	db.Select("id, create_time, update_time").Find(&tags)
+	for _, tag := range tags {
+		list = append(list, newUrl(urls.TagArticlesUrl(tag.Id), latest(tag.CreateTime, tag.UpdateTime), changefreqWeekly, 0.6))
+	}
+	return
+}
+
+func loadUsers(db *gorm.DB) (list []sitemapUrl) {
+	var users []model.User
+	db.Select("id, create_time, update_time").Find(&users)
+	for _, user := range users {
+		list = append(list, newUrl(urls.UserUrl(user.Id), latest(user.CreateTime, user.UpdateTime), changefreqWeekly, 0.5))
+	}
+	return
+}
+
+// buildPages 首页和各列表页，更新时间为对应内容的最后更新时间
+func buildPages(lastMods map[string]int64) []sitemapUrl {
+	var all int64
+	for _, lastMod := range lastMods {
+		all = latest(all, lastMod)
+	}
+	return []sitemapUrl{
+		newUrl(urls.AbsUrl("/"), all, changefreqHourly, 1.0),
+		newUrl(urls.AbsUrl("/topics"), lastMods["topics"], changefreqHourly, 1.0),
+		newUrl(urls.AbsUrl("/articles"), lastMods["articles"], changefreqDaily, 1.0),
+		newUrl(urls.AbsUrl("/tweets"), lastMods["tweets"], changefreqHourly, 0.8),
+		newUrl(urls.AbsUrl("/projects"), lastMods["projects"], changefreqDaily, 0.8),
+		newUrl(urls.AbsUrl("/tags"), lastMods["tags"], changefreqWeekly, 0.6),
+	}
+}
+
+// buildNodes 话题节点，更新时间为节点下话题的最后更新时间
+func buildNodes() (list []sitemapUrl) {
+	var rows []struct {
+		NodeId  int64
+		LastMod int64
+	}
+	if err := simple.DB().Model(&model.Topic{}).
+		Select("node_id, max("+latestSql("create_time", "last_comment_time")+") as last_mod").
+		Where("status = ?", constants.StatusOk).
+		Group("node_id").
+		Scan(&rows).Error; err != nil {
+		logrus.Error("Sitemap stats error: nodes, ", err)
+	}
+	lastMods := make(map[int64]int64, len(rows))
+	for _, row := range rows {
+		lastMods[row.NodeId] = row.LastMod
+	}
+	nodes := repositories.TopicNodeRepository.Find(simple.DB(), simple.NewSqlCnd().
+		Eq("status", constants.StatusOk).Asc("sort_no").Asc("id"))
+	for _, node := range nodes {
+		list = append(list, newUrl(urls.TopicNodeUrl(node.Id), latest(node.CreateTime, lastMods[node.Id]), changefreqDaily, 0.8))
+	}
+	return
+}
+
+// absImageUrls 图片地址转换为绝对地址
+func absImageUrls(images ...string) (ret []string) {
+	for _, image := range images {
+		if simple.IsBlank(image) {
+			continue
+		}
+		if strings.HasPrefix(image, "/") && !strings.HasPrefix(image, "//") {
+			image = urls.AbsUrl(image)
+		}
+		ret = append(ret, image)
+	}
+	return
+}
+
+// latestSql 两列中较大值的sql表达式，greatest 在sqlite中不可用，使用各数据库都支持的 case 表达式
+func latestSql(a, b string) string {
+	return fmt.Sprintf("(case when %s > %s then %s else %s end)", a, b, a, b)
+}
+
+func latest(times ...int64) (ret int64) {
+	for _, t := range times {
+		if t > ret {
+			ret = t
+		}
+	}
+	return
+}
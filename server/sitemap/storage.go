@@ -0,0 +1,66 @@
+package sitemap
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/mlogclub/simple"
+
+	"bbs-go/common/uploader"
+	"bbs-go/common/urls"
+	"bbs-go/config"
+)
+
+// 站点地图存储
+type storage interface {
+	// Name 存储标识，存储方式或目录变化后需要重新生成所有分片
+	Name() string
+	// Put 保存文件，返回访问地址
+	Put(filename string, data []byte) (string, error)
+}
+
+func getStorage() storage {
+	dir := config.Instance.Sitemap.Path
+	if simple.IsBlank(dir) {
+		dir = "sitemap"
+	}
+	if simple.EqualsIgnoreCase(config.Instance.Sitemap.Storage, "static") {
+		return &staticStorage{dir: dir}
+	}
+	return &uploaderStorage{dir: dir}
+}
+
+// 使用配置的Uploader上传
+type uploaderStorage struct {
+	dir string
+}
+
+func (s *uploaderStorage) Name() string {
+	return "uploader:" + config.Instance.Uploader.Enable + ":" + s.dir
+}
+
+func (s *uploaderStorage) Put(filename string, data []byte) (string, error) {
+	return uploader.PutObject(path.Join(s.dir, filename), data)
+}
+
+// 写入StaticPath，通过网站地址访问
+type staticStorage struct {
+	dir string
+}
+
+func (s *staticStorage) Name() string {
+	return "static:" + s.dir
+}
+
+func (s *staticStorage) Put(filename string, data []byte) (string, error) {
+	fullpath := filepath.Join(config.Instance.StaticPath, s.dir, filename)
+	if err := os.MkdirAll(filepath.Dir(fullpath), os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(fullpath, data, 0644); err != nil {
+		return "", err
+	}
+	return urls.AbsUrl("/" + path.Join(s.dir, filename)), nil
+}
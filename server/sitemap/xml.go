@@ -0,0 +1,108 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/mlogclub/simple"
+)
+
+const (
+	xmlnsSitemap = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	xmlnsImage   = "http://www.google.com/schemas/sitemap-image/1.1"
+	maxImages    = 1000 // 协议规定每个链接最多包含1000张图片
+)
+
+type urlSet struct {
+	XMLName    xml.Name     `xml:"urlset"`
+	Xmlns      string       `xml:"xmlns,attr"`
+	XmlnsImage string       `xml:"xmlns:image,attr"`
+	Urls       []sitemapUrl `xml:"url"`
+}
+
+type sitemapUrl struct {
+	Loc        string         `xml:"loc"`
+	LastMod    string         `xml:"lastmod,omitempty"`
+	ChangeFreq string         `xml:"changefreq,omitempty"`
+	Priority   float64        `xml:"priority,omitempty"`
+	Images     []sitemapImage `xml:"image:image"`
+
+	lastModTime int64
+}
+
+type sitemapImage struct {
+	Loc string `xml:"image:loc"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name    `xml:"sitemapindex"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Sitemaps []indexItem `xml:"sitemap"`
+}
+
+type indexItem struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+func newUrl(loc string, lastMod int64, changefreq string, priority float64) sitemapUrl {
+	return sitemapUrl{
+		Loc:         loc,
+		LastMod:     formatLastMod(lastMod),
+		ChangeFreq:  changefreq,
+		Priority:    priority,
+		lastModTime: lastMod,
+	}
+}
+
+// withImages 添加图片
+func (u sitemapUrl) withImages(images ...string) sitemapUrl {
+	for _, image := range images {
+		if len(u.Images) >= maxImages {
+			break
+		}
+		if simple.IsNotBlank(image) {
+			u.Images = append(u.Images, sitemapImage{Loc: image})
+		}
+	}
+	return u
+}
+
+// encodeUrlSet 生成gzip压缩后的站点地图
+func encodeUrlSet(list []sitemapUrl) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if err := writeXml(w, &urlSet{Xmlns: xmlnsSitemap, XmlnsImage: xmlnsImage, Urls: list}); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeIndex 生成站点地图索引，索引文件不压缩
+func encodeIndex(items []indexItem) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeXml(&buf, &sitemapIndex{Xmlns: xmlnsSitemap, Sitemaps: items}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeXml(w io.Writer, v interface{}) error {
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v)
+}
+
+func formatLastMod(timestamp int64) string {
+	if timestamp <= 0 {
+		return ""
+	}
+	return simple.TimeFromTimestamp(timestamp).Format(time.RFC3339)
+}
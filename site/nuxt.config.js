@@ -1,3 +1,11 @@
+// 后端接口地址，/api/ 等路径代理到该地址
+const serverUrl =
+  process.env.NODE_ENV === 'production'
+    ? 'https://mlog.club'
+    : process.env.NODE_ENV === 'docker'
+    ? 'http://bbs-go-server:8082'
+    : 'http://127.0.0.1:8082'
+
 export default {
   server: {
     port: 3000,
@@ -108,12 +116,10 @@ export default {
   },
 
   proxy: {
-    '/api/':
-      process.env.NODE_ENV === 'production'
-        ? 'https://mlog.club'
-        : process.env.NODE_ENV === 'docker'
-        ? 'http://bbs-go-server:8082'
-        : 'http://127.0.0.1:8082',
+    '/api/': serverUrl,
+    '/feed/': serverUrl,
+    '/ap/': serverUrl,
+    '/.well-known/webfinger': serverUrl,
   },
 
   // Doc: https://github.com/shakee93/vue-toasted
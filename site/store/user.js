@@ -76,6 +76,20 @@ export const actions = {
     return ret.user
   },
 
+  // OpenID Connect / OAuth2 登录
+  async signinByOidc(context, { provider, code, state }) {
+    const API = addSource('/api/login/oidc/callback')
+    const ret = await this.$axios.get(API, {
+      params: {
+        provider,
+        code,
+        state,
+      },
+    })
+    context.dispatch('loginSuccess', ret)
+    return ret.user
+  },
+
   async signup(
     context,
     { captchaId, captchaCode, nickname, username, email, password, rePassword }